@@ -0,0 +1,99 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var ErrPaddingNotZero = errors.New("the padding is not zero")
+
+//the number of bytes needed to move pos to the next multiple of n.
+func paddingLen(pos, n int64) int64 {
+	if n <= 0 {
+		panic(fmt.Sprint(n, " is not a valid alignment."))
+	}
+	return (n - pos%n) % n
+}
+
+//Write n zero bytes into Writer.
+func (w *Writer) WriteZeros(n int) {
+	w.WritePadding(n, 0)
+}
+
+//Write n padByte into Writer.
+func (w *Writer) WritePadding(n int, padByte byte) {
+	if n < 0 {
+		panic(fmt.Sprint(n, " is not a valid value."))
+	}
+	w.write(bytes.Repeat([]byte{padByte}, n))
+}
+
+//Write padByte until the position is a multiple of n.
+func (w *Writer) Align(n int, padByte byte) {
+	w.AlignFrom(0, n, padByte)
+}
+
+//Write padByte until the position counted from base is a multiple of n,
+//used for sections that are aligned relative to their own start.
+func (w *Writer) AlignFrom(base int64, n int, padByte byte) {
+	w.WritePadding(int(paddingLen(w.pos-base, int64(n))), padByte)
+}
+
+//Write zero bytes until the position is offset.
+func (w *Writer) PadTo(offset int64) {
+	if offset < w.pos {
+		panic(fmt.Sprintf("the position is already %v,it can't be padded to %v", w.pos, offset))
+	}
+	w.WriteZeros(int(offset - w.pos))
+}
+
+//skip n bytes of padding.
+func (r *ReadSeeker) SkipPadding(n int) error {
+	return r.skipPadding(n, false)
+}
+
+//skip n bytes of padding,an error wrapping ErrPaddingNotZero is returned if they are not all zero.
+func (r *ReadSeeker) SkipZeroPadding(n int) error {
+	return r.skipPadding(n, true)
+}
+
+func (r *ReadSeeker) skipPadding(n int, mustBeZero bool) error {
+	defer r.traceEnter("SkipPadding")() //padding is not traced as a read
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	bt, err := r.ReadBytes(n)
+	if err != nil {
+		return err
+	}
+	if mustBeZero {
+		for i, b := range bt {
+			if b != 0 {
+				r.MoveTo(currentPos)
+				return fmt.Errorf("the padding byte at position %v is 0x%02X: %w", currentPos+int64(i), b, ErrPaddingNotZero)
+			}
+		}
+	}
+	return nil
+}
+
+//skip the padding up to the next position that is a multiple of n,
+//positions of a Section are relative to the start of the section.
+func (r *ReadSeeker) Align(n int) error {
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	return r.SkipPadding(int(paddingLen(currentPos, int64(n))))
+}
+
+//like Align,but the padding must be zero.
+func (r *ReadSeeker) AlignZero(n int) error {
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	return r.SkipZeroPadding(int(paddingLen(currentPos, int64(n))))
+}
@@ -0,0 +1,103 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	wr := NewBytesBuffer()
+	wr.WriteString("abc")
+	wr.Align(4, 0)
+	wr.WriteString("d")
+	wr.Align(4, 0xff)
+	wr.WriteZeros(2)
+	wr.PadTo(12)
+	wr.WriteString("ef")
+	wr.AlignFrom(12, 4, 0)
+	want := []byte{'a', 'b', 'c', 0, 'd', 0xff, 0xff, 0xff, 0, 0, 0, 0, 'e', 'f', 0, 0}
+	if !bytes.Equal(wr.Bytes(), want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", wr.Bytes(), want)
+	}
+	//already aligned
+	wr.Align(4, 0)
+	if len(wr.Bytes()) != 16 {
+		t.Fatalf("unexpected value obtained; got %v want %v", len(wr.Bytes()), 16)
+	}
+	rd := NewReadSeekerFromBytes(want)
+	rd.ReadString(3)
+	if err := rd.AlignZero(4); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	rd.ReadString(1)
+	err := rd.AlignZero(4)
+	if !errors.Is(err, ErrPaddingNotZero) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrPaddingNotZero)
+	}
+	if pos, _ := rd.CurPos(); pos != 5 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 5)
+	}
+	if err = rd.Align(4); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if err = rd.SkipZeroPadding(4); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	//alignment inside a section is relative to the section
+	sec, err := rd.Section(13, 15)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	s, _ := sec.ReadString(1)
+	if s != "f" {
+		t.Fatalf("unexpected value obtained; got %v want %v", s, "f")
+	}
+	if err = sec.AlignZero(2); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if pos, _ := sec.CurPos(); pos != 2 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 2)
+	}
+	if pos, _ := rd.CurPos(); pos != 12 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 12)
+	}
+}
+
+func TestSection(t *testing.T) {
+	data := []byte("0123456789")
+	//a parent without io.ReaderAt shares its position
+	for _, rd := range []*ReadSeeker{NewReadSeekerFromBytes(data), NewReadSeeker(oneByteReadSeeker{bytes.NewReader(data)})} {
+		rd.MoveTo(1)
+		sec, err := rd.Section(2, 7)
+		if err != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		if sec.Size() != 6 {
+			t.Fatalf("unexpected value obtained; got %v want %v", sec.Size(), 6)
+		}
+		s, err := sec.ReadString(4)
+		if err != nil || s != "2345" {
+			t.Fatalf("unexpected value obtained; got %v %v want %v", s, err, "2345")
+		}
+		if sec.Index([]byte("67")) != 4 {
+			t.Fatalf("unexpected value obtained; got %v want %v", sec.Index([]byte("67")), 4)
+		}
+		_, err = sec.ReadString(3)
+		if err == nil {
+			t.Fatalf("unexpected value obtained; got %v want an error", err)
+		}
+		s, _ = rd.ReadString(1)
+		if s != "1" {
+			t.Fatalf("unexpected value obtained; got %v want %v", s, "1")
+		}
+		empty, err := rd.Section(10, 9)
+		if err != nil || empty.Size() != 0 {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		_, err = rd.Section(5, 10)
+		if err == nil {
+			t.Fatalf("unexpected value obtained; got %v want an error", err)
+		}
+	}
+}
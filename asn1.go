@@ -0,0 +1,754 @@
+package iox
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidASN1 = errors.New("the ASN.1 data is not valid")
+	ErrNotDER      = errors.New("the ASN.1 data is valid BER but not DER")
+)
+
+//ASN1Class is the class of the tag of an ASN.1 element.
+type ASN1Class int
+
+const (
+	ASN1Universal ASN1Class = iota
+	ASN1Application
+	ASN1ContextSpecific
+	ASN1Private
+)
+
+func (c ASN1Class) String() string {
+	switch c {
+	case ASN1Universal:
+		return "universal"
+	case ASN1Application:
+		return "application"
+	case ASN1ContextSpecific:
+		return "context-specific"
+	case ASN1Private:
+		return "private"
+	}
+	return fmt.Sprintf("ASN1Class(%d)", int(c))
+}
+
+//the tags of the universal class.
+const (
+	ASN1TagBoolean         = 1
+	ASN1TagInteger         = 2
+	ASN1TagBitString       = 3
+	ASN1TagOctetString     = 4
+	ASN1TagNull            = 5
+	ASN1TagOID             = 6
+	ASN1TagEnumerated      = 10
+	ASN1TagUTF8String      = 12
+	ASN1TagSequence        = 16
+	ASN1TagSet             = 17
+	ASN1TagNumericString   = 18
+	ASN1TagPrintableString = 19
+	ASN1TagT61String       = 20
+	ASN1TagIA5String       = 22
+	ASN1TagUTCTime         = 23
+	ASN1TagGeneralizedTime = 24
+	ASN1TagVisibleString   = 26
+	ASN1TagUniversalString = 28
+	ASN1TagBMPString       = 30
+)
+
+//the deepest nesting accepted,also across Children,certificates and CMS messages stay within
+//a dozen levels while parse and WalkASN1 recurse once per level.
+const maxASN1Depth = 64
+
+//ASN1Options tunes the reading of ASN.1 elements.
+type ASN1Options struct {
+	//reject what DER forbids with an error wrapping ErrNotDER:indefinite and non-minimal lengths,
+	//long form tags below 31,booleans other than 0x00 and 0xff,constructed strings,
+	//non-zero unused bits of bit strings and times that are not UTC with seconds.
+	DER bool
+}
+
+//ASN1Element is a tag-length-value element of BER or DER data,its methods decode the content
+//without looking at the tag,so they work for IMPLICIT tags as well.
+type ASN1Element struct {
+	Class       ASN1Class
+	Constructed bool
+	Tag         int
+	Offset      int64 //of the identifier in the data
+	HeaderSize  int64 //the identifier and the length
+	Length      int64 //of the content,the end-of-contents of an indefinite length is not counted
+	Indefinite  bool
+	Content     *ReadSeeker
+	der         bool
+	src         *ReadSeeker //the data the element was read from,Offset is a position in it
+	depth       int         //of the element below the one read by ReadASN1
+}
+
+//asn1Parser finds elements in the data of r.
+type asn1Parser struct {
+	r   *ReadSeeker
+	der bool
+}
+
+//parse the element at pos of a parent that ends before end,returns the element and the position after it.
+func (p asn1Parser) parse(pos, end int64, depth int) (*ASN1Element, int64, error) {
+	if depth > maxASN1Depth {
+		return nil, 0, fmt.Errorf("the element at position %v is nested deeper than %v: %w", pos, maxASN1Depth, ErrInvalidASN1)
+	}
+	//the longest header is 1 byte,a tag of 5 bytes,1 byte and a length of 8 bytes
+	n := end - pos
+	if n > 15 {
+		n = 15
+	}
+	if n < 2 {
+		return nil, 0, fmt.Errorf("the element at position %v has no header: %w", pos, io.ErrUnexpectedEOF)
+	}
+	header, err := p.r.bytesAt(pos, n)
+	if err != nil {
+		return nil, 0, err
+	}
+	i := 0
+	next := func() (byte, error) {
+		if i >= len(header) {
+			return 0, fmt.Errorf("the header of the element at position %v is truncated: %w", pos, io.ErrUnexpectedEOF)
+		}
+		i++
+		return header[i-1], nil
+	}
+	b, _ := next()
+	e := &ASN1Element{Class: ASN1Class(b >> 6), Constructed: b&0x20 != 0, Tag: int(b & 0x1f), Offset: pos, der: p.der, src: p.r, depth: depth}
+	if e.Tag == 0x1f {
+		e.Tag = 0
+		for k := 0; ; k++ {
+			if b, err = next(); err != nil {
+				return nil, 0, err
+			}
+			if k == 0 && b == 0x80 {
+				return nil, 0, fmt.Errorf("the tag at position %v has a leading zero: %w", pos, ErrInvalidASN1)
+			}
+			if k == 4 {
+				return nil, 0, fmt.Errorf("the tag at position %v is too big: %w", pos, ErrInvalidASN1)
+			}
+			e.Tag = e.Tag<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		if e.Tag < 0x1f && p.der {
+			return nil, 0, fmt.Errorf("the tag %v at position %v is in the long form: %w", e.Tag, pos, ErrNotDER)
+		}
+	}
+	if b, err = next(); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case b < 0x80:
+		e.Length = int64(b)
+	case b == 0x80:
+		if !e.Constructed {
+			return nil, 0, fmt.Errorf("the primitive element at position %v has an indefinite length: %w", pos, ErrInvalidASN1)
+		}
+		if p.der {
+			return nil, 0, fmt.Errorf("the element at position %v has an indefinite length: %w", pos, ErrNotDER)
+		}
+		e.Indefinite = true
+	case b == 0xff || b&0x7f > 8:
+		return nil, 0, fmt.Errorf("the length of the element at position %v has %v bytes: %w", pos, b&0x7f, ErrInvalidASN1)
+	default:
+		var length uint64
+		for k := 0; k < int(b&0x7f); k++ {
+			c, err := next()
+			if err != nil {
+				return nil, 0, err
+			}
+			if k == 0 && c == 0 && p.der {
+				return nil, 0, fmt.Errorf("the length of the element at position %v has a leading zero: %w", pos, ErrNotDER)
+			}
+			length = length<<8 | uint64(c)
+		}
+		if length < 0x80 && p.der {
+			return nil, 0, fmt.Errorf("the length %v of the element at position %v is in the long form: %w", length, pos, ErrNotDER)
+		}
+		if length > uint64(end-pos-int64(i)) {
+			return nil, 0, fmt.Errorf("the element at position %v has %v bytes,but only %v bytes left: %w", pos, length, end-pos-int64(i), io.ErrUnexpectedEOF)
+		}
+		e.Length = int64(length)
+	}
+	e.HeaderSize = int64(i)
+	if e.Length > end-pos-e.HeaderSize {
+		return nil, 0, fmt.Errorf("the element at position %v has %v bytes,but only %v bytes left: %w", pos, e.Length, end-pos-e.HeaderSize, io.ErrUnexpectedEOF)
+	}
+	begin := pos + e.HeaderSize
+	after := begin + e.Length
+	if e.Indefinite {
+		//the content ends with the end-of-contents 00 00 at the same level
+		for cur := begin; ; {
+			if end-cur < 2 {
+				return nil, 0, fmt.Errorf("the element at position %v has no end-of-contents: %w", pos, io.ErrUnexpectedEOF)
+			}
+			eoc, err := p.r.bytesAt(cur, 2)
+			if err != nil {
+				return nil, 0, err
+			}
+			if eoc[0] == 0 && eoc[1] == 0 {
+				e.Length = cur - begin
+				after = cur + 2
+				break
+			}
+			_, childEnd, err := p.parse(cur, end, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			cur = childEnd
+		}
+	}
+	if e.Content, err = p.r.Section(begin, begin+e.Length-1); err != nil {
+		return nil, 0, err
+	}
+	return e, after, nil
+}
+
+//read the ASN.1 element at the position and move past it,its content is read by the methods of the element.
+func (r *ReadSeeker) ReadASN1(opts ASN1Options) (*ASN1Element, error) {
+	defer r.traceEnter("ReadASN1")()
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	e, next, err := asn1Parser{r, opts.DER}.parse(pos, r.Size(), 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.readSeeker.Seek(next, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r.traceValue(fmt.Sprintf("%v %v %v bytes", e.Class, e.Tag, e.Length))
+	return e, nil
+}
+
+//WalkASN1 reads the element at the position like ReadASN1 and calls f with it and every element
+//inside it depth first,depth is 0 for the element read.an error of f stops the walk and is returned.
+func (r *ReadSeeker) WalkASN1(opts ASN1Options, f func(e *ASN1Element, depth int) error) error {
+	e, err := r.ReadASN1(opts)
+	if err != nil {
+		return err
+	}
+	return e.walk(f, 0)
+}
+
+func (e *ASN1Element) walk(f func(e *ASN1Element, depth int) error, depth int) error {
+	if err := f(e, depth); err != nil {
+		return err
+	}
+	if !e.Constructed {
+		return nil
+	}
+	children, err := e.Children()
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err = child.walk(f, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Children returns the elements of the content of a constructed element.
+func (e *ASN1Element) Children() ([]*ASN1Element, error) {
+	if !e.Constructed {
+		return nil, fmt.Errorf("the element at position %v is primitive: %w", e.Offset, ErrInvalidASN1)
+	}
+	//the children are parsed in the data of e,a Section of Content for every level would make
+	//deep nesting slow
+	p := asn1Parser{e.src, e.der}
+	begin := e.Offset + e.HeaderSize
+	var children []*ASN1Element
+	for pos := begin; pos < begin+e.Length; {
+		child, next, err := p.parse(pos, begin+e.Length, e.depth+1)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		pos = next
+	}
+	return children, nil
+}
+
+//Bytes returns the content,the segments of a constructed string of BER are joined.
+func (e *ASN1Element) Bytes() ([]byte, error) {
+	if !e.Constructed {
+		return e.Content.allBytes()
+	}
+	if e.der {
+		return nil, fmt.Errorf("the string at position %v is constructed: %w", e.Offset, ErrNotDER)
+	}
+	children, err := e.Children()
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	for _, child := range children {
+		b, err := child.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, b...)
+	}
+	return content, nil
+}
+
+//the content of a primitive element.
+func (e *ASN1Element) primitive(what string) ([]byte, error) {
+	if e.Constructed {
+		return nil, fmt.Errorf("the %v at position %v is constructed: %w", what, e.Offset, ErrInvalidASN1)
+	}
+	return e.Content.allBytes()
+}
+
+//Bool decodes a BOOLEAN.
+func (e *ASN1Element) Bool() (bool, error) {
+	b, err := e.primitive("boolean")
+	if err != nil {
+		return false, err
+	}
+	if len(b) != 1 {
+		return false, fmt.Errorf("the boolean at position %v has %v bytes: %w", e.Offset, len(b), ErrInvalidASN1)
+	}
+	if e.der && b[0] != 0 && b[0] != 0xff {
+		return false, fmt.Errorf("the boolean at position %v is 0x%02x: %w", e.Offset, b[0], ErrNotDER)
+	}
+	return b[0] != 0, nil
+}
+
+//the content of an INTEGER or ENUMERATED,which must be minimal.
+func (e *ASN1Element) integer() ([]byte, error) {
+	b, err := e.primitive("integer")
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("the integer at position %v is empty: %w", e.Offset, ErrInvalidASN1)
+	}
+	if len(b) > 1 && (b[0] == 0 && b[1] < 0x80 || b[0] == 0xff && b[1] >= 0x80) {
+		return nil, fmt.Errorf("the integer at position %v is not minimal: %w", e.Offset, ErrInvalidASN1)
+	}
+	return b, nil
+}
+
+//Int64 decodes an INTEGER or ENUMERATED that fits an int64.
+func (e *ASN1Element) Int64() (int64, error) {
+	b, err := e.integer()
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > 8 {
+		return 0, fmt.Errorf("the integer at position %v has %v bytes,more than an int64: %w", e.Offset, len(b), ErrInvalidASN1)
+	}
+	var v int64
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	//sign extend
+	shift := 64 - 8*uint(len(b))
+	return v << shift >> shift, nil
+}
+
+//BigInt decodes an INTEGER of any size.
+func (e *ASN1Element) BigInt() (*big.Int, error) {
+	b, err := e.integer()
+	if err != nil {
+		return nil, err
+	}
+	v := new(big.Int)
+	if b[0] < 0x80 {
+		return v.SetBytes(b), nil
+	}
+	//the two's complement of a negative number
+	inverted := make([]byte, len(b))
+	for i, c := range b {
+		inverted[i] = ^c
+	}
+	v.SetBytes(inverted)
+	v.Add(v, big.NewInt(1))
+	return v.Neg(v), nil
+}
+
+//OID decodes an OBJECT IDENTIFIER.
+func (e *ASN1Element) OID() (asn1.ObjectIdentifier, error) {
+	b, err := e.primitive("object identifier")
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("the object identifier at position %v is empty: %w", e.Offset, ErrInvalidASN1)
+	}
+	var oid asn1.ObjectIdentifier
+	for i := 0; i < len(b); {
+		if b[i] == 0x80 {
+			return nil, fmt.Errorf("the object identifier at position %v has a leading zero: %w", e.Offset, ErrInvalidASN1)
+		}
+		v := 0
+		for ; ; i++ {
+			if i == len(b) {
+				return nil, fmt.Errorf("the object identifier at position %v is truncated: %w", e.Offset, ErrInvalidASN1)
+			}
+			if v > 1<<24 {
+				return nil, fmt.Errorf("the object identifier at position %v is too big: %w", e.Offset, ErrInvalidASN1)
+			}
+			v = v<<7 | int(b[i]&0x7f)
+			if b[i] < 0x80 {
+				i++
+				break
+			}
+		}
+		if len(oid) == 0 {
+			if v < 80 {
+				oid = append(oid, v/40, v%40)
+			} else {
+				oid = append(oid, 2, v-80)
+			}
+			continue
+		}
+		oid = append(oid, v)
+	}
+	return oid, nil
+}
+
+//BitString decodes a BIT STRING into its bytes and the number of unused bits of the last byte.
+func (e *ASN1Element) BitString() ([]byte, int, error) {
+	b, err := e.primitive("bit string")
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(b) == 0 || b[0] > 7 || len(b) == 1 && b[0] != 0 {
+		return nil, 0, fmt.Errorf("the bit string at position %v is not valid: %w", e.Offset, ErrInvalidASN1)
+	}
+	unused := int(b[0])
+	if e.der && len(b) > 1 && b[len(b)-1]&(1<<unused-1) != 0 {
+		return nil, 0, fmt.Errorf("the unused bits of the bit string at position %v are not zero: %w", e.Offset, ErrNotDER)
+	}
+	return b[1:], unused, nil
+}
+
+//Text decodes a string type,BMPString is UTF-16,UniversalString is UTF-32 and
+//UTF8String must be valid UTF-8,the other types are returned as they are.
+func (e *ASN1Element) Text() (string, error) {
+	b, err := e.Bytes()
+	if err != nil {
+		return "", err
+	}
+	if e.Class != ASN1Universal {
+		return string(b), nil
+	}
+	switch e.Tag {
+	case ASN1TagUTF8String:
+		if !utf8.Valid(b) {
+			return "", fmt.Errorf("the UTF8String at position %v is not valid UTF-8: %w", e.Offset, ErrInvalidASN1)
+		}
+	case ASN1TagBMPString:
+		if len(b)%2 != 0 {
+			return "", fmt.Errorf("the BMPString at position %v has an odd length: %w", e.Offset, ErrInvalidASN1)
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+		return string(utf16.Decode(units)), nil
+	case ASN1TagUniversalString:
+		if len(b)%4 != 0 {
+			return "", fmt.Errorf("the UniversalString at position %v has a length that is not a multiple of 4: %w", e.Offset, ErrInvalidASN1)
+		}
+		var sb strings.Builder
+		for i := 0; i < len(b); i += 4 {
+			sb.WriteRune(rune(bytesToUint(b[i:i+4], true)))
+		}
+		return sb.String(), nil
+	}
+	return string(b), nil
+}
+
+//Time decodes a UTCTime if the tag is the universal UTCTime,otherwise a GeneralizedTime.
+//the years 50 to 99 of a UTCTime are 1950 to 1999.
+func (e *ASN1Element) Time() (time.Time, error) {
+	b, err := e.primitive("time")
+	if err != nil {
+		return time.Time{}, err
+	}
+	s := string(b)
+	utc := e.Class == ASN1Universal && e.Tag == ASN1TagUTCTime
+	layouts := []string{"20060102150405Z0700", "200601021504Z0700"}
+	if utc {
+		layouts = []string{"060102150405Z0700", "0601021504Z0700"}
+	}
+	if e.der && (!strings.HasSuffix(s, "Z") || len(s) < len(layouts[0])-4 || strings.ContainsRune(s, ',') ||
+		strings.Contains(s, ".") && strings.HasSuffix(s, "0Z")) {
+		return time.Time{}, fmt.Errorf("the time %q at position %v is not UTC with seconds: %w", s, e.Offset, ErrNotDER)
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if utc && t.Year() >= 2050 {
+			t = t.AddDate(-100, 0, 0)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("the time %q at position %v is not valid: %w", s, e.Offset, ErrInvalidASN1)
+}
+
+//the identifier and the length of an element with a content of n bytes in DER.
+func asn1Header(class ASN1Class, constructed bool, tag int, n int) []byte {
+	if tag < 0 {
+		panic(fmt.Sprint(tag, " is not a valid tag."))
+	}
+	b := byte(class) << 6
+	if constructed {
+		b |= 0x20
+	}
+	var header []byte
+	if tag < 0x1f {
+		header = append(header, b|byte(tag))
+	} else {
+		header = append(header, b|0x1f)
+		header = appendBase128(header, tag)
+	}
+	if n < 0x80 {
+		return append(header, byte(n))
+	}
+	var length []byte
+	for ; n > 0; n >>= 8 {
+		length = append([]byte{byte(n)}, length...)
+	}
+	header = append(header, 0x80|byte(len(length)))
+	return append(header, length...)
+}
+
+//append v in base 128 with the high bit set on all but the last byte.
+func appendBase128(b []byte, v int) []byte {
+	n := 1
+	for x := v >> 7; x > 0; x >>= 7 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := byte(v>>(7*uint(i))) & 0x7f
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+//BeginASN1 starts a constructed element,the writes until EndASN1 are its content.
+//DER needs the length before the content,so the content is kept in memory and reaches the Writer
+//with EndASN1 of the outermost element,Pos does not move until then.
+//Seek and WriteAt should not be used inside an element,and chunks and compressed regions panic there.
+func (w *Writer) BeginASN1(class ASN1Class, tag int) {
+	if w.region != nil {
+		panic("an ASN.1 element can't be written inside a compressed region")
+	}
+	w.nested = append(w.nested, &nestedRegion{class: class, tag: tag})
+}
+
+//BeginASN1Sequence starts a SEQUENCE,see BeginASN1.
+func (w *Writer) BeginASN1Sequence() {
+	w.BeginASN1(ASN1Universal, ASN1TagSequence)
+}
+
+//BeginASN1Set starts a SET,EndASN1 sorts its elements by tag as DER requires.
+func (w *Writer) BeginASN1Set() {
+	w.BeginASN1(ASN1Universal, ASN1TagSet)
+}
+
+//BeginASN1SetOf starts a SET OF,EndASN1 sorts its elements by their encoding as DER requires.
+func (w *Writer) BeginASN1SetOf() {
+	w.BeginASN1(ASN1Universal, ASN1TagSet)
+	w.nested[len(w.nested)-1].setOf = true
+}
+
+//EndASN1 writes the element of the last BeginASN1 with the minimal length,returns the length of the content.
+func (w *Writer) EndASN1() int {
+	region := w.endNested(false)
+	content := region.content
+	if region.class == ASN1Universal && region.tag == ASN1TagSet {
+		content = sortASN1Set(content, region.setOf)
+	}
+	w.write(asn1Header(region.class, true, region.tag, len(content)))
+	w.write(content)
+	return len(content)
+}
+
+//sort the elements of a SET by class and tag,or those of a SET OF by their encoding,
+//the content is left alone if it is not made of elements.
+func sortASN1Set(content []byte, setOf bool) []byte {
+	r := NewReadSeekerFromBytes(content)
+	p := asn1Parser{r: r, der: true}
+	var elements [][]byte
+	var headers []*ASN1Element
+	for pos := int64(0); pos < int64(len(content)); {
+		e, next, err := p.parse(pos, int64(len(content)), 0)
+		if err != nil {
+			return content
+		}
+		elements = append(elements, content[pos:next])
+		headers = append(headers, e)
+		pos = next
+	}
+	sort.Sort(asn1SetOrder{elements, headers, setOf})
+	return bytes.Join(elements, nil)
+}
+
+//asn1SetOrder sorts the elements of a SET or a SET OF with their headers.
+type asn1SetOrder struct {
+	elements [][]byte
+	headers  []*ASN1Element
+	setOf    bool
+}
+
+func (o asn1SetOrder) Len() int {
+	return len(o.elements)
+}
+
+func (o asn1SetOrder) Less(i, j int) bool {
+	if o.setOf {
+		return bytes.Compare(o.elements[i], o.elements[j]) < 0
+	}
+	a, b := o.headers[i], o.headers[j]
+	if a.Class != b.Class {
+		return a.Class < b.Class
+	}
+	return a.Tag < b.Tag
+}
+
+func (o asn1SetOrder) Swap(i, j int) {
+	o.elements[i], o.elements[j] = o.elements[j], o.elements[i]
+	o.headers[i], o.headers[j] = o.headers[j], o.headers[i]
+}
+
+//WriteASN1 writes a primitive element.
+func (w *Writer) WriteASN1(class ASN1Class, tag int, content []byte) {
+	w.write(asn1Header(class, false, tag, len(content)))
+	w.write(content)
+}
+
+//WriteASN1Bool writes a BOOLEAN.
+func (w *Writer) WriteASN1Bool(b bool) {
+	if b {
+		w.WriteASN1(ASN1Universal, ASN1TagBoolean, []byte{0xff})
+	} else {
+		w.WriteASN1(ASN1Universal, ASN1TagBoolean, []byte{0})
+	}
+}
+
+//WriteASN1Int64 writes an INTEGER.
+func (w *Writer) WriteASN1Int64(i int64) {
+	n := 1
+	for v := i; v > 127 || v < -128; v >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for k := n - 1; k >= 0; k-- {
+		b[k] = byte(i)
+		i >>= 8
+	}
+	w.WriteASN1(ASN1Universal, ASN1TagInteger, b)
+}
+
+//WriteASN1BigInt writes an INTEGER of any size.
+func (w *Writer) WriteASN1BigInt(i *big.Int) {
+	var b []byte
+	switch i.Sign() {
+	case 0:
+		b = []byte{0}
+	case 1:
+		b = i.Bytes()
+		if b[0] >= 0x80 {
+			b = append([]byte{0}, b...)
+		}
+	default:
+		//the two's complement of -i-1 is the inverse of its bytes
+		v := new(big.Int).Neg(i)
+		b = v.Sub(v, big.NewInt(1)).Bytes()
+		for k := range b {
+			b[k] = ^b[k]
+		}
+		if len(b) == 0 || b[0] < 0x80 {
+			b = append([]byte{0xff}, b...)
+		}
+	}
+	w.WriteASN1(ASN1Universal, ASN1TagInteger, b)
+}
+
+//WriteASN1Null writes a NULL.
+func (w *Writer) WriteASN1Null() {
+	w.WriteASN1(ASN1Universal, ASN1TagNull, nil)
+}
+
+//WriteASN1OID writes an OBJECT IDENTIFIER,it panics if oid is not valid.
+func (w *Writer) WriteASN1OID(oid asn1.ObjectIdentifier) {
+	if len(oid) < 2 || oid[0] > 2 || oid[0] < 2 && oid[1] >= 40 {
+		panic(fmt.Sprint(oid, " is not a valid object identifier."))
+	}
+	b := appendBase128(nil, oid[0]*40+oid[1])
+	for _, v := range oid[2:] {
+		if v < 0 {
+			panic(fmt.Sprint(oid, " is not a valid object identifier."))
+		}
+		b = appendBase128(b, v)
+	}
+	w.WriteASN1(ASN1Universal, ASN1TagOID, b)
+}
+
+//WriteASN1OctetString writes an OCTET STRING.
+func (w *Writer) WriteASN1OctetString(p []byte) {
+	w.WriteASN1(ASN1Universal, ASN1TagOctetString, p)
+}
+
+//WriteASN1BitString writes a BIT STRING whose last byte has unusedBits bits that are not used,
+//they are cleared as DER requires.
+func (w *Writer) WriteASN1BitString(p []byte, unusedBits int) {
+	if unusedBits < 0 || unusedBits > 7 || len(p) == 0 && unusedBits != 0 {
+		panic(fmt.Sprint(unusedBits, " is not a valid number of unused bits."))
+	}
+	b := append([]byte{byte(unusedBits)}, p...)
+	b[len(b)-1] &^= 1<<uint(unusedBits) - 1
+	w.WriteASN1(ASN1Universal, ASN1TagBitString, b)
+}
+
+//WriteASN1String writes a string type such as ASN1TagUTF8String or ASN1TagPrintableString,
+//a BMPString is written as UTF-16.
+func (w *Writer) WriteASN1String(tag int, s string) {
+	if tag != ASN1TagBMPString {
+		w.WriteASN1(ASN1Universal, tag, []byte(s))
+		return
+	}
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	w.WriteASN1(ASN1Universal, tag, b)
+}
+
+//WriteASN1Time writes t in UTC as a UTCTime for the years 1950 to 2049 and as a GeneralizedTime otherwise,
+//like X.509 does.fractions of a second are dropped.
+func (w *Writer) WriteASN1Time(t time.Time) {
+	t = t.UTC()
+	if year := t.Year(); year >= 1950 && year < 2050 {
+		w.WriteASN1(ASN1Universal, ASN1TagUTCTime, []byte(t.Format("060102150405Z")))
+		return
+	}
+	w.WriteASN1(ASN1Universal, ASN1TagGeneralizedTime, []byte(t.Format("20060102150405Z")))
+}
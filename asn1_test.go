@@ -0,0 +1,237 @@
+package iox
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestASN1Writer(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	//the same bytes as encoding/asn1
+	type record struct {
+		N    int
+		OID  asn1.ObjectIdentifier
+		Name string `asn1:"utf8"`
+		OK   bool
+		Big  *big.Int
+	}
+	big70 := new(big.Int).Lsh(big.NewInt(1), 70)
+	want, err := asn1.Marshal(record{-129, oid, "héllo", true, new(big.Int).Neg(big70)})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	w := NewBytesBuffer()
+	w.BeginASN1Sequence()
+	w.WriteASN1Int64(-129)
+	w.WriteASN1OID(oid)
+	w.WriteASN1String(ASN1TagUTF8String, "héllo")
+	w.WriteASN1Bool(true)
+	w.WriteASN1BigInt(new(big.Int).Neg(big70))
+	if n := w.EndASN1(); n != len(want)-2 {
+		t.Fatalf("unexpected value obtained; got %v want %v", n, len(want)-2)
+	}
+	if !bytes.Equal(w.Bytes(), want) {
+		t.Fatalf("unexpected value obtained; got %x want %x", w.Bytes(), want)
+	}
+	for _, c := range []struct {
+		write func(w *Writer)
+		want  string
+	}{
+		{func(w *Writer) { w.WriteASN1Int64(128) }, "02020080"},
+		{func(w *Writer) { w.WriteASN1Int64(-128) }, "020180"},
+		{func(w *Writer) { w.WriteASN1BigInt(big.NewInt(0)) }, "020100"},
+		{func(w *Writer) { w.WriteASN1BigInt(big.NewInt(-256)) }, "0202ff00"},
+		{func(w *Writer) { w.WriteASN1OctetString(make([]byte, 300)) }, "0482012c" + hex.EncodeToString(make([]byte, 300))},
+		{func(w *Writer) { w.WriteASN1BitString([]byte{0xff}, 3) }, "030203f8"},
+		{func(w *Writer) { w.WriteASN1(ASN1ContextSpecific, 100, []byte{1}) }, "9f6401" + "01"},
+		{func(w *Writer) { w.WriteASN1String(ASN1TagBMPString, "hi") }, "1e0400680069"},
+		{func(w *Writer) { w.WriteASN1Time(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)) }, "170d3230303130323033303430355a"},
+		{func(w *Writer) { w.WriteASN1Time(time.Date(2050, 1, 2, 3, 4, 5, 0, time.UTC)) }, "180f32303530303130323033303430355a"},
+		//the elements of a SET are sorted by tag and those of a SET OF by encoding
+		{func(w *Writer) {
+			w.BeginASN1Set()
+			w.WriteASN1(ASN1ContextSpecific, 0, nil)
+			w.WriteASN1Null()
+			w.WriteASN1Int64(300)
+			w.WriteASN1(ASN1Application, 1, nil)
+			w.EndASN1()
+		}, "310a" + "0202012c" + "0500" + "4100" + "8000"},
+		{func(w *Writer) {
+			w.BeginASN1SetOf()
+			w.WriteASN1OctetString([]byte("bb"))
+			w.WriteASN1OctetString([]byte("c"))
+			w.WriteASN1OctetString([]byte("a"))
+			w.EndASN1()
+		}, "310a" + "040161" + "040163" + "04026262"},
+		{func(w *Writer) {
+			w.BeginASN1(ASN1ContextSpecific, 0)
+			w.WriteASN1Null()
+			w.EndASN1()
+		}, "a0020500"},
+	} {
+		w := NewBytesBuffer()
+		c.write(w)
+		if got := hex.EncodeToString(w.Bytes()); got != c.want {
+			t.Fatalf("unexpected value obtained; got %v want %v", got, c.want)
+		}
+	}
+}
+
+func TestASN1Reader(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteUint8(0xaa)
+	w.BeginASN1Sequence()
+	w.WriteASN1Int64(-129)
+	w.WriteASN1BigInt(new(big.Int).Lsh(big.NewInt(1), 70))
+	w.WriteASN1OID(asn1.ObjectIdentifier{2, 999, 3})
+	w.BeginASN1(ASN1ContextSpecific, 0)
+	w.WriteASN1String(ASN1TagBMPString, "wide")
+	w.WriteASN1(ASN1ContextSpecific, 1, []byte{0x7f})
+	w.EndASN1()
+	w.WriteASN1BitString([]byte{0xf0}, 4)
+	w.WriteASN1Time(time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC))
+	w.WriteASN1Bool(false)
+	w.EndASN1()
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	rd.MoveTo(1)
+	var got []string
+	err := rd.WalkASN1(ASN1Options{DER: true}, func(e *ASN1Element, depth int) error {
+		var v interface{}
+		var err error
+		switch {
+		case e.Constructed:
+			v = "-"
+		case e.Class == ASN1ContextSpecific:
+			v, err = e.Int64()
+		case e.Tag == ASN1TagInteger && e.Length > 8:
+			v, err = e.BigInt()
+		case e.Tag == ASN1TagInteger:
+			v, err = e.Int64()
+		case e.Tag == ASN1TagOID:
+			v, err = e.OID()
+		case e.Tag == ASN1TagBMPString:
+			v, err = e.Text()
+		case e.Tag == ASN1TagBitString:
+			b, unused, err2 := e.BitString()
+			v, err = fmt.Sprintf("%x/%v", b, unused), err2
+		case e.Tag == ASN1TagUTCTime:
+			var tm time.Time
+			tm, err = e.Time()
+			v = tm.Format(time.RFC3339)
+		case e.Tag == ASN1TagBoolean:
+			v, err = e.Bool()
+		}
+		got = append(got, fmt.Sprintf("%v:%v/%v@%v=%v", depth, e.Class, e.Tag, e.Offset, v))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	want := "[0:universal/16@1=- 1:universal/2@3=-129 1:universal/2@7=1180591620717411303424 1:universal/6@18=2.999.3 " +
+		"1:context-specific/0@23=- 2:universal/30@25=wide 2:context-specific/1@35=127 1:universal/3@38=f0/4 " +
+		"1:universal/23@42=1999-12-31T23:59:59Z 1:universal/1@57=false]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	if pos, _ := rd.CurPos(); pos != rd.Size() {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, rd.Size())
+	}
+}
+
+func TestASN1BER(t *testing.T) {
+	//an indefinite SEQUENCE with an INTEGER and a constructed OCTET STRING
+	ber, _ := hex.DecodeString("3080020105248004026162040163000000000500")
+	rd := NewReadSeekerFromBytes(ber)
+	e, err := rd.ReadASN1(ASN1Options{})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if !e.Indefinite || e.Length != 14 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", e, "an indefinite length of 14")
+	}
+	children, err := e.Children()
+	if err != nil || len(children) != 2 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", children, err, 2)
+	}
+	if b, err := children[1].Bytes(); err != nil || string(b) != "abc" {
+		t.Fatalf("unexpected value obtained; got %q %v want %v", b, err, "abc")
+	}
+	//the next element follows the end-of-contents
+	if e, err = rd.ReadASN1(ASN1Options{}); err != nil || e.Tag != ASN1TagNull || e.Offset != 18 {
+		t.Fatalf("unexpected value obtained; got %+v %v want %v", e, err, "NULL at 18")
+	}
+	for _, c := range []struct {
+		hex string
+		ber error
+		der error
+	}{
+		{"3080020105248004026162040163000000000500", nil, ErrNotDER},
+		{"04810161", nil, ErrNotDER},
+		{"0482000161", nil, ErrNotDER},
+		{"1f0500", nil, ErrNotDER},
+		{"010101", nil, ErrNotDER},
+		{"03020201", nil, ErrNotDER},
+		{"170b323030313032303330345a", nil, ErrNotDER},
+		{"02020001", ErrInvalidASN1, ErrInvalidASN1},
+		{"0600", ErrInvalidASN1, ErrInvalidASN1},
+		{"0480", ErrInvalidASN1, ErrInvalidASN1},
+		{"30050201", io.ErrUnexpectedEOF, io.ErrUnexpectedEOF},
+		{"308002010500", io.ErrUnexpectedEOF, ErrNotDER},
+	} {
+		data, _ := hex.DecodeString(c.hex)
+		for _, mode := range []struct {
+			opts ASN1Options
+			want error
+		}{{ASN1Options{}, c.ber}, {ASN1Options{DER: true}, c.der}} {
+			//decode every value to find the errors of the content as well
+			err := NewReadSeekerFromBytes(data).WalkASN1(mode.opts, func(e *ASN1Element, depth int) error {
+				var err error
+				switch {
+				case e.Constructed && e.Tag == ASN1TagOctetString:
+					_, err = e.Bytes()
+				case e.Constructed:
+				case e.Tag == ASN1TagBoolean:
+					_, err = e.Bool()
+				case e.Tag == ASN1TagInteger:
+					_, err = e.Int64()
+				case e.Tag == ASN1TagOID:
+					_, err = e.OID()
+				case e.Tag == ASN1TagBitString:
+					_, _, err = e.BitString()
+				case e.Tag == ASN1TagUTCTime:
+					_, err = e.Time()
+				}
+				return err
+			})
+			if mode.want == nil && err != nil || mode.want != nil && !errors.Is(err, mode.want) {
+				t.Fatalf("%v DER:%v: unexpected value obtained; got %v want %v", c.hex, mode.opts.DER, err, mode.want)
+			}
+		}
+	}
+}
+
+func TestASN1Depth(t *testing.T) {
+	w := NewBytesBuffer()
+	for i := 0; i < 100; i++ {
+		w.BeginASN1Sequence()
+	}
+	for i := 0; i < 100; i++ {
+		w.EndASN1()
+	}
+	//the depth is counted across the levels of Children
+	deepest := 0
+	err := NewReadSeekerFromBytes(w.Bytes()).WalkASN1(ASN1Options{DER: true}, func(e *ASN1Element, depth int) error {
+		deepest = depth
+		return nil
+	})
+	if !errors.Is(err, ErrInvalidASN1) || deepest != maxASN1Depth {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v,%v", deepest, err, maxASN1Depth, ErrInvalidASN1)
+	}
+}
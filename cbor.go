@@ -0,0 +1,832 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidCBOR = errors.New("the CBOR data is not valid")
+	ErrCBORType    = errors.New("the CBOR value does not have the wanted type")
+)
+
+//CBORMajorType is the major type in the high 3 bits of the head of a CBOR data item.
+type CBORMajorType int
+
+const (
+	CBORMajorUnsigned CBORMajorType = iota
+	CBORMajorNegative
+	CBORMajorBytes
+	CBORMajorText
+	CBORMajorArray
+	CBORMajorMap
+	CBORMajorTag
+	CBORMajorSimple //simple values,floats and the break
+)
+
+func (t CBORMajorType) String() string {
+	switch t {
+	case CBORMajorUnsigned:
+		return "unsigned"
+	case CBORMajorNegative:
+		return "negative"
+	case CBORMajorBytes:
+		return "bytes"
+	case CBORMajorText:
+		return "text"
+	case CBORMajorArray:
+		return "array"
+	case CBORMajorMap:
+		return "map"
+	case CBORMajorTag:
+		return "tag"
+	case CBORMajorSimple:
+		return "simple"
+	}
+	return fmt.Sprintf("CBORMajorType(%d)", int(t))
+}
+
+//CBORSimpleValue is a simple value other than false,true and null.
+type CBORSimpleValue uint8
+
+//the simple value undefined.
+const CBORUndefined CBORSimpleValue = 23
+
+//CBORTag is a tagged data item whose tag is not decoded by ReadCBOR.
+type CBORTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+//the tags decoded by ReadCBOR and written by WriteCBOR.
+const (
+	cborTagDateString = 0
+	cborTagEpoch      = 1
+	cborTagBignum     = 2
+	cborTagNegBignum  = 3
+)
+
+//CBOROptions tunes the writing of Go values by WriteCBOR.
+type CBOROptions struct {
+	//sort the keys of maps and the fields of structs by their encoded bytes,for the deterministic
+	//encoding of RFC 8949 section 4.2.1,integers,lengths and floats are always in their shortest form
+	//and WriteCBOR never writes indefinite lengths.
+	Deterministic bool
+}
+
+//CBORToken is the head of a data item read by ReadCBORToken,with the content of a definite string.
+type CBORToken struct {
+	Major  CBORMajorType
+	Offset int64 //of the head in the data
+	//the value of an unsigned integer,n of the negative integer -1-n,the length of a definite string,
+	//the count of a definite array or map,the number of a tag,a simple value or the bits of a float
+	Arg        uint64
+	Indefinite bool        //a string,array or map whose items follow until a break
+	Break      bool        //the break that ends an indefinite item
+	FloatSize  int         //2,4 or 8 for a float16,float32 or float64 in Arg
+	Data       *ReadSeeker //the content of a definite string,the position has moved past it
+}
+
+//Float returns the value of a float token.
+func (t *CBORToken) Float() float64 {
+	switch t.FloatSize {
+	case 2:
+		return float16ToFloat64(uint16(t.Arg))
+	case 4:
+		return float64(math.Float32frombits(uint32(t.Arg)))
+	}
+	return math.Float64frombits(t.Arg)
+}
+
+//convert an IEEE 754 half precision float,like the decoder of RFC 8949 appendix D.
+func float16ToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+//convert f to a half precision float if that keeps its value,NaN becomes the quiet NaN 0x7e00.
+func float64ToFloat16(f float64) (uint16, bool) {
+	if math.IsNaN(f) {
+		return 0x7e00, true
+	}
+	var sign uint16
+	if math.Signbit(f) {
+		sign, f = 0x8000, -f
+	}
+	switch {
+	case math.IsInf(f, 0):
+		return sign | 0x7c00, true
+	case f == 0:
+		return sign, true
+	}
+	frac, exp := math.Frexp(f) //f is frac*2^exp with frac in [0.5,1)
+	switch exp--; {
+	case exp >= -14 && exp <= 15:
+		mant := (frac*2 - 1) * 1024
+		if mant != math.Trunc(mant) {
+			return 0, false
+		}
+		return sign | uint16(exp+15)<<10 | uint16(mant), true
+	case exp >= -24 && exp < -14:
+		mant := math.Ldexp(f, 24) //subnormal,f is mant*2^-24
+		if mant != math.Trunc(mant) {
+			return 0, false
+		}
+		return sign | uint16(mant), true
+	}
+	return 0, false
+}
+
+//read the head of a data item at pos:the major type,the additional information and the argument,
+//io.EOF is returned if the data ends before the head.
+func (r *ReadSeeker) readCBORHead(pos int64) (CBORMajorType, byte, uint64, error) {
+	b, err := r.ReadUint8()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info := CBORMajorType(b>>5), b&0x1f
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info == 24:
+		var v uint8
+		v, err = r.ReadUint8()
+		arg = uint64(v)
+	case info == 25:
+		var v uint16
+		v, err = r.ReadUint16BigEndian()
+		arg = uint64(v)
+	case info == 26:
+		var v uint32
+		v, err = r.ReadUint32BigEndian()
+		arg = uint64(v)
+	case info == 27:
+		arg, err = r.ReadUint64BigEndian()
+	case info == 31 && major != CBORMajorUnsigned && major != CBORMajorNegative && major != CBORMajorTag:
+	default:
+		return 0, 0, 0, fmt.Errorf("the head 0x%02x at position %v is not well-formed: %w", b, pos, ErrInvalidCBOR)
+	}
+	if err == io.EOF {
+		err = fmt.Errorf("the head at position %v is truncated: %w", pos, io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if major == CBORMajorSimple && info == 24 && arg < 32 {
+		return 0, 0, 0, fmt.Errorf("the simple value %v at position %v is in two bytes: %w", arg, pos, ErrInvalidCBOR)
+	}
+	return major, info, arg, nil
+}
+
+//the content of a definite string of n bytes at the position must be in the data.
+func (r *ReadSeeker) checkCBORLength(n uint64, pos int64) error {
+	if left := r.LenUnRead(); n > uint64(left) {
+		return fmt.Errorf("the data item at position %v has %v bytes,but only %v bytes left: %w", pos, n, left, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+//ReadCBORToken reads the head of the next data item and moves past it,io.EOF is returned at the end of the data.
+//the content of a definite byte or text string is skipped and returned as the Data of the token,
+//the items of arrays,maps,tags and indefinite strings are the next tokens,so a large payload can be walked
+//without holding it in memory,and the nesting is left to the caller.the position is left unchanged on error.
+func (r *ReadSeeker) ReadCBORToken() (*CBORToken, error) {
+	defer r.traceEnter("ReadCBORToken")()
+	var t *CBORToken
+	err := r.restoreOnError(func() error {
+		pos, err := r.CurPos()
+		if err != nil {
+			return err
+		}
+		major, info, arg, err := r.readCBORHead(pos)
+		if err != nil {
+			return err
+		}
+		t = &CBORToken{Major: major, Offset: pos, Arg: arg}
+		switch {
+		case info == 31 && major == CBORMajorSimple:
+			t.Break = true
+		case info == 31:
+			t.Indefinite = true
+		case major == CBORMajorSimple && info >= 25:
+			t.FloatSize = 1 << (info - 24)
+		case major == CBORMajorBytes || major == CBORMajorText:
+			if err = r.checkCBORLength(arg, pos); err != nil {
+				return err
+			}
+			begin, _ := r.CurPos()
+			if t.Data, err = r.Section(begin, begin+int64(arg)-1); err != nil {
+				return err
+			}
+			_, err = r.readSeeker.Seek(begin+int64(arg), io.SeekStart)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(fmt.Sprintf("%v %v", t.Major, t.Arg))
+	return t, nil
+}
+
+//ReadCBOR reads a data item of any type and moves past it,io.EOF is returned at the end of the data.
+//integers are int64,or uint64 and *big.Int if they don't fit,floats are float64,strings are []byte and string
+//with the chunks of indefinite strings joined,arrays are []interface{},maps are map[string]interface{}
+//if all keys are strings and map[interface{}]interface{} otherwise,null is nil,other simple values are
+//CBORSimpleValue,the tags 0 and 1 are time.Time,the bignums of tags 2 and 3 are *big.Int,
+//and other tags are CBORTag.the position is left unchanged on error.
+func (r *ReadSeeker) ReadCBOR() (interface{}, error) {
+	defer r.traceEnter("ReadCBOR")()
+	var v interface{}
+	err := r.restoreOnError(func() (err error) {
+		v, err = r.readCBORValue(0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(v)
+	return v, nil
+}
+
+//ReadCBORInto reads a data item like ReadCBOR and stores it in the value v points to,like json.Unmarshal:
+//structs are decoded from maps by the names of the cbor struct tags,or the field names,
+//unknown keys are skipped,and null sets pointers,slices,maps and interfaces to nil.
+//the position is left unchanged on error.
+func (r *ReadSeeker) ReadCBORInto(v interface{}) error {
+	defer r.traceEnter("ReadCBORInto")()
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%T is not a non-nil pointer: %w", v, ErrCBORType)
+	}
+	return r.restoreOnError(func() error {
+		x, err := r.readCBORValue(0)
+		if err != nil {
+			return err
+		}
+		return setDecoded(rv.Elem(), x, "", cborFormat)
+	})
+}
+
+var cborFormat = valueFormat{"cbor", ErrCBORType}
+
+func (r *ReadSeeker) readCBORValue(depth int) (interface{}, error) {
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	if depth > maxCodecDepth {
+		return nil, fmt.Errorf("the data item at position %v is nested deeper than %v: %w", pos, maxCodecDepth, ErrInvalidCBOR)
+	}
+	major, info, arg, err := r.readCBORHead(pos)
+	if err != nil {
+		return nil, err
+	}
+	v, err := r.readCBORContent(major, info, arg, pos, depth)
+	if err == io.EOF {
+		err = fmt.Errorf("the data item at position %v is truncated: %w", pos, io.ErrUnexpectedEOF)
+	}
+	return v, err
+}
+
+//consume the break at the position if there is one.
+func (r *ReadSeeker) readCBORBreak(pos int64) (bool, error) {
+	cur, _ := r.CurPos()
+	if cur >= r.Size() {
+		return false, fmt.Errorf("the indefinite data item at position %v has no break: %w", pos, io.ErrUnexpectedEOF)
+	}
+	b, err := r.bytesAt(cur, 1)
+	if err != nil || b[0] != 0xff {
+		return false, err
+	}
+	_, err = r.readSeeker.Seek(cur+1, io.SeekStart)
+	return err == nil, err
+}
+
+//read the content of the data item whose head at pos has been read.
+func (r *ReadSeeker) readCBORContent(major CBORMajorType, info byte, arg uint64, pos int64, depth int) (interface{}, error) {
+	indefinite := info == 31
+	switch major {
+	case CBORMajorUnsigned:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case CBORMajorNegative:
+		if arg > math.MaxInt64 {
+			n := new(big.Int).SetUint64(arg)
+			return n.Sub(big.NewInt(-1), n), nil
+		}
+		return -1 - int64(arg), nil
+	case CBORMajorBytes, CBORMajorText:
+		var bt []byte
+		if !indefinite {
+			if err := r.checkCBORLength(arg, pos); err != nil {
+				return nil, err
+			}
+			var err error
+			if bt, err = r.ReadBytes(int(arg)); err == io.EOF {
+				bt, err = []byte{}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			bt = []byte{}
+			for {
+				end, err := r.readCBORBreak(pos)
+				if err != nil {
+					return nil, err
+				}
+				if end {
+					break
+				}
+				cur, _ := r.CurPos()
+				m, info, n, err := r.readCBORHead(cur)
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				if err != nil {
+					return nil, err
+				}
+				if m != major || info == 31 {
+					return nil, fmt.Errorf("the chunk at position %v of the indefinite string at position %v is not a definite %v string: %w", cur, pos, major, ErrInvalidCBOR)
+				}
+				if err = r.checkCBORLength(n, cur); err != nil {
+					return nil, err
+				}
+				chunk, err := r.readBytesAfterPrefix(int(n))
+				if err != nil {
+					return nil, err
+				}
+				if major == CBORMajorText && !utf8.Valid(chunk) {
+					return nil, fmt.Errorf("the text chunk at position %v is not valid UTF-8: %w", cur, ErrInvalidCBOR)
+				}
+				bt = append(bt, chunk...)
+			}
+		}
+		if major == CBORMajorBytes {
+			return bt, nil
+		}
+		if !utf8.Valid(bt) {
+			return nil, fmt.Errorf("the text at position %v is not valid UTF-8: %w", pos, ErrInvalidCBOR)
+		}
+		return string(bt), nil
+	case CBORMajorArray, CBORMajorMap:
+		size := uint64(1)
+		if major == CBORMajorMap {
+			size = 2
+		}
+		var items []interface{}
+		if !indefinite {
+			//a key or a value of a map takes a byte at least,like an item of an array
+			if !r.countFits(arg, size) {
+				return nil, fmt.Errorf("the data item at position %v has %v items,but only %v bytes left: %w", pos, arg, r.LenUnRead(), io.ErrUnexpectedEOF)
+			}
+			items = make([]interface{}, 0, arg*size)
+		}
+		for i := uint64(0); indefinite || i < arg*size; i++ {
+			if indefinite {
+				end, err := r.readCBORBreak(pos)
+				if err != nil {
+					return nil, err
+				}
+				if end {
+					break
+				}
+			}
+			v, err := r.readCBORValue(depth + 1)
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		if major == CBORMajorArray {
+			if items == nil {
+				items = []interface{}{}
+			}
+			return items, nil
+		}
+		if len(items)%2 != 0 {
+			return nil, fmt.Errorf("the indefinite map at position %v has a key without a value: %w", pos, ErrInvalidCBOR)
+		}
+		keys, vals := make([]interface{}, len(items)/2), make([]interface{}, len(items)/2)
+		for i := range keys {
+			keys[i], vals[i] = items[2*i], items[2*i+1]
+		}
+		m, ok := decodedMap(keys, vals)
+		if !ok {
+			return nil, fmt.Errorf("the map at position %v has a key that can't be a key of a Go map: %w", pos, ErrCBORType)
+		}
+		return m, nil
+	case CBORMajorTag:
+		content, err := r.readCBORValue(depth + 1)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cborTagValue(arg, content, pos)
+	}
+	switch {
+	case info == 31:
+		return nil, fmt.Errorf("the break at position %v is not inside an indefinite data item: %w", pos, ErrInvalidCBOR)
+	case info >= 25:
+		t := CBORToken{Arg: arg, FloatSize: 1 << (info - 24)}
+		return t.Float(), nil
+	case arg == 20:
+		return false, nil
+	case arg == 21:
+		return true, nil
+	case arg == 22:
+		return nil, nil
+	}
+	return CBORSimpleValue(arg), nil
+}
+
+//decode the content of the tags known by ReadCBOR.
+func cborTagValue(number uint64, content interface{}, pos int64) (interface{}, error) {
+	invalid := func() error {
+		return fmt.Errorf("the content of the tag %v at position %v is %T: %w", number, pos, content, ErrInvalidCBOR)
+	}
+	switch number {
+	case cborTagDateString:
+		s, ok := content.(string)
+		if !ok {
+			return nil, invalid()
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("the date of the tag 0 at position %v: %v: %w", pos, err, ErrInvalidCBOR)
+		}
+		return t, nil
+	case cborTagEpoch:
+		switch n := content.(type) {
+		case int64:
+			return time.Unix(n, 0).UTC(), nil
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) || math.Abs(n) > 1<<62 {
+				return nil, invalid()
+			}
+			sec := math.Floor(n)
+			return time.Unix(int64(sec), int64(math.Round((n-sec)*1e9))).UTC(), nil
+		}
+		return nil, invalid()
+	case cborTagBignum, cborTagNegBignum:
+		bt, ok := content.([]byte)
+		if !ok {
+			return nil, invalid()
+		}
+		n := new(big.Int).SetBytes(bt)
+		if number == cborTagNegBignum {
+			n.Sub(big.NewInt(-1), n)
+		}
+		return n, nil
+	}
+	return CBORTag{number, content}, nil
+}
+
+//write the head of a data item with the argument in the shortest form.
+func (w *Writer) writeCBORHead(major CBORMajorType, arg uint64) {
+	b := uint8(major) << 5
+	switch {
+	case arg < 24:
+		w.WriteUint8(b | uint8(arg))
+	case arg <= math.MaxUint8:
+		w.WriteUint8(b | 24)
+		w.WriteUint8(uint8(arg))
+	case arg <= math.MaxUint16:
+		w.WriteUint8(b | 25)
+		w.WriteUint16BigEndian(uint16(arg))
+	case arg <= math.MaxUint32:
+		w.WriteUint8(b | 26)
+		w.WriteUint32BigEndian(uint32(arg))
+	default:
+		w.WriteUint8(b | 27)
+		w.WriteUint64BigEndian(arg)
+	}
+}
+
+//WriteCBORUint writes an unsigned integer.
+func (w *Writer) WriteCBORUint(u uint64) {
+	w.writeCBORHead(CBORMajorUnsigned, u)
+}
+
+//WriteCBORInt writes an integer,negative ones as the major type 1.
+func (w *Writer) WriteCBORInt(i int64) {
+	if i >= 0 {
+		w.writeCBORHead(CBORMajorUnsigned, uint64(i))
+	} else {
+		w.writeCBORHead(CBORMajorNegative, uint64(-1-i))
+	}
+}
+
+//WriteCBORBigInt writes an integer of any size,as a bignum of the tag 2 or 3 if it doesn't fit in the head.
+func (w *Writer) WriteCBORBigInt(i *big.Int) {
+	major, tag, n := CBORMajorUnsigned, uint64(cborTagBignum), i
+	if i.Sign() < 0 {
+		major, tag, n = CBORMajorNegative, cborTagNegBignum, new(big.Int).Sub(big.NewInt(-1), i)
+	}
+	if n.IsUint64() {
+		w.writeCBORHead(major, n.Uint64())
+		return
+	}
+	w.WriteCBORTag(tag)
+	w.WriteCBORBytes(n.Bytes())
+}
+
+//WriteCBORBytes writes a byte string.
+func (w *Writer) WriteCBORBytes(p []byte) {
+	w.writeCBORHead(CBORMajorBytes, uint64(len(p)))
+	w.write(p)
+}
+
+//WriteCBORText writes a text string,s should be valid UTF-8.
+func (w *Writer) WriteCBORText(s string) {
+	w.writeCBORHead(CBORMajorText, uint64(len(s)))
+	w.WriteString(s)
+}
+
+//WriteCBORArrayHeader writes the head of an array of n items,the items are written next.
+func (w *Writer) WriteCBORArrayHeader(n int) {
+	if n < 0 {
+		panic(fmt.Sprint("the CBOR item count is negative:", n))
+	}
+	w.writeCBORHead(CBORMajorArray, uint64(n))
+}
+
+//WriteCBORMapHeader writes the head of a map of n pairs,the pairs are written next as a key followed by its value.
+func (w *Writer) WriteCBORMapHeader(n int) {
+	if n < 0 {
+		panic(fmt.Sprint("the CBOR item count is negative:", n))
+	}
+	w.writeCBORHead(CBORMajorMap, uint64(n))
+}
+
+//WriteCBORIndefinite starts a byte string,text string,array or map of indefinite length,
+//its items are written next and WriteCBORBreak ends it,the chunks of a string must be definite strings of its type.
+func (w *Writer) WriteCBORIndefinite(major CBORMajorType) {
+	if major < CBORMajorBytes || major > CBORMajorMap {
+		panic(fmt.Sprint("the CBOR major type can't have an indefinite length:", major))
+	}
+	w.WriteUint8(uint8(major)<<5 | 31)
+}
+
+//WriteCBORBreak ends the item of the last WriteCBORIndefinite.
+func (w *Writer) WriteCBORBreak() {
+	w.WriteUint8(0xff)
+}
+
+//WriteCBORTag writes the head of a tag,its content is written next.
+func (w *Writer) WriteCBORTag(number uint64) {
+	w.writeCBORHead(CBORMajorTag, number)
+}
+
+//WriteCBORBool writes false or true.
+func (w *Writer) WriteCBORBool(b bool) {
+	if b {
+		w.WriteUint8(0xf5)
+	} else {
+		w.WriteUint8(0xf4)
+	}
+}
+
+//WriteCBORNull writes null.
+func (w *Writer) WriteCBORNull() {
+	w.WriteUint8(0xf6)
+}
+
+//WriteCBORUndefined writes undefined.
+func (w *Writer) WriteCBORUndefined() {
+	w.WriteUint8(0xf7)
+}
+
+//WriteCBORSimple writes a simple value,the values 24 to 31 are reserved and panic.
+func (w *Writer) WriteCBORSimple(v CBORSimpleValue) {
+	if v >= 24 && v < 32 {
+		panic(fmt.Sprint("the CBOR simple value is reserved:", v))
+	}
+	w.writeCBORHead(CBORMajorSimple, uint64(v))
+}
+
+//WriteCBORFloat writes f as the shortest of float16,float32 and float64 that keeps its value,
+//the preferred serialization of RFC 8949,NaN is written as the float16 0x7e00.
+func (w *Writer) WriteCBORFloat(f float64) {
+	if h, ok := float64ToFloat16(f); ok {
+		w.WriteUint8(0xf9)
+		w.WriteUint16BigEndian(h)
+	} else if float64(float32(f)) == f {
+		w.WriteCBORFloat32(float32(f))
+	} else {
+		w.WriteCBORFloat64(f)
+	}
+}
+
+//WriteCBORFloat32 writes a float32 without looking for a shorter form.
+func (w *Writer) WriteCBORFloat32(f float32) {
+	w.WriteUint8(0xfa)
+	w.WriteFloat32BigEndian(f)
+}
+
+//WriteCBORFloat64 writes a float64 without looking for a shorter form.
+func (w *Writer) WriteCBORFloat64(f float64) {
+	w.WriteUint8(0xfb)
+	w.WriteFloat64BigEndian(f)
+}
+
+//WriteCBORTime writes t as the epoch seconds of the tag 1,an integer if t has no fraction of a second
+//and a float64 otherwise,which keeps about microseconds.
+func (w *Writer) WriteCBORTime(t time.Time) {
+	w.WriteCBORTag(cborTagEpoch)
+	if t.Nanosecond() == 0 {
+		w.WriteCBORInt(t.Unix())
+	} else {
+		w.WriteCBORFloat(float64(t.Unix()) + float64(t.Nanosecond())/1e9)
+	}
+}
+
+//WriteCBOR writes v of any type in the preferred serialization,[]byte and byte arrays are byte strings,
+//time.Time is written by WriteCBORTime,big.Int by WriteCBORBigInt,CBORTag and CBORSimpleValue as they are,
+//and structs are maps of their exported fields named by the cbor struct tags,see ReadCBORInto.
+//the keys of maps are sorted if they are strings or numbers,or by their encoding with opts.Deterministic.
+//nothing is written if v has a value of another type or contains itself.
+func (w *Writer) WriteCBOR(v interface{}, opts CBOROptions) error {
+	buf := NewBytesBuffer()
+	if err := buf.writeCBORValue(reflect.ValueOf(v), "", opts, refStack{}); err != nil {
+		return err
+	}
+	w.write(buf.Bytes())
+	return nil
+}
+
+func cborCycle(v reflect.Value, path string) error {
+	return fmt.Errorf("%v: the %v contains itself: %w", strings.TrimPrefix(path, "."), v.Type(), ErrCBORType)
+}
+
+var (
+	cborTagType    = reflect.TypeOf(CBORTag{})
+	cborSimpleType = reflect.TypeOf(CBORSimpleValue(0))
+)
+
+func (w *Writer) writeCBORValue(v reflect.Value, path string, opts CBOROptions, refs refStack) error {
+	if !v.IsValid() {
+		w.WriteCBORNull()
+		return nil
+	}
+	switch v.Type() {
+	case timeType:
+		w.WriteCBORTime(v.Interface().(time.Time))
+		return nil
+	case bigIntType:
+		if !v.CanAddr() {
+			c := reflect.New(bigIntType).Elem()
+			c.Set(v)
+			v = c
+		}
+		w.WriteCBORBigInt(v.Addr().Interface().(*big.Int))
+		return nil
+	case cborTagType:
+		tag := v.Interface().(CBORTag)
+		w.WriteCBORTag(tag.Number)
+		return w.writeCBORValue(reflect.ValueOf(tag.Content), path, opts, refs)
+	case cborSimpleType:
+		w.WriteCBORSimple(CBORSimpleValue(v.Uint()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.WriteCBORNull()
+			return nil
+		}
+		if !refs.push(v) {
+			return cborCycle(v, path)
+		}
+		defer refs.pop(v)
+		return w.writeCBORValue(v.Elem(), path, opts, refs)
+	case reflect.Bool:
+		w.WriteCBORBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteCBORInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.WriteCBORUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		w.WriteCBORFloat(v.Float())
+	case reflect.String:
+		w.WriteCBORText(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			w.WriteCBORNull()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			bt := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(bt), v)
+			w.WriteCBORBytes(bt)
+			return nil
+		}
+		if !refs.push(v) {
+			return cborCycle(v, path)
+		}
+		defer refs.pop(v)
+		w.WriteCBORArrayHeader(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := w.writeCBORValue(v.Index(i), fmt.Sprintf("%v[%v]", path, i), opts, refs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.WriteCBORNull()
+			return nil
+		}
+		if !refs.push(v) {
+			return cborCycle(v, path)
+		}
+		defer refs.pop(v)
+		keys := v.MapKeys()
+		sortMapKeys(keys)
+		pairs := make([]cborPair, len(keys))
+		for i, k := range keys {
+			pairs[i] = cborPair{key: k, val: v.MapIndex(k), path: fmt.Sprintf("%v[%v]", path, k)}
+		}
+		return w.writeCBORPairs(pairs, path, opts, refs)
+	case reflect.Struct:
+		var pairs []cborPair
+		for _, f := range taggedFields(v.Type(), "cbor") {
+			if !f.omitEmpty || !v.Field(f.index).IsZero() {
+				pairs = append(pairs, cborPair{key: reflect.ValueOf(f.name), val: v.Field(f.index), path: path + "." + f.name})
+			}
+		}
+		return w.writeCBORPairs(pairs, path, opts, refs)
+	default:
+		return fmt.Errorf("%v: %v can't be written as CBOR: %w", strings.TrimPrefix(path, "."), v.Type(), ErrCBORType)
+	}
+	return nil
+}
+
+//cborPair is a key and a value of a map or a struct.
+type cborPair struct {
+	key, val reflect.Value
+	path     string
+	encoded  []byte //the key for the deterministic order
+}
+
+//write a map of the pairs,sorted by the bytes of the keys with opts.Deterministic.
+func (w *Writer) writeCBORPairs(pairs []cborPair, path string, opts CBOROptions, refs refStack) error {
+	if opts.Deterministic {
+		for i := range pairs {
+			kw := NewBytesBuffer()
+			if err := kw.writeCBORValue(pairs[i].key, path, opts, refs); err != nil {
+				return err
+			}
+			pairs[i].encoded = kw.Bytes()
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return bytes.Compare(pairs[i].encoded, pairs[j].encoded) < 0
+		})
+	}
+	w.WriteCBORMapHeader(len(pairs))
+	for _, p := range pairs {
+		if p.encoded != nil {
+			w.WriteBytes(p.encoded)
+		} else if err := w.writeCBORValue(p.key, path, opts, refs); err != nil {
+			return err
+		}
+		if err := w.writeCBORValue(p.val, p.path, opts, refs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package iox
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustBigInt(s string) *big.Int {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(s)
+	}
+	return i
+}
+
+func cborEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case *big.Int:
+		y, ok := b.(*big.Int)
+		return ok && x.Cmp(y) == 0
+	case float64:
+		y, ok := b.(float64)
+		return ok && (x == y && math.Signbit(x) == math.Signbit(y) || math.IsNaN(x) && math.IsNaN(y))
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+//the examples of RFC 8949 appendix A,reencode is false for the ones not in the preferred serialization.
+var cborVectors = []struct {
+	hex      string
+	want     interface{}
+	reencode bool
+}{
+	{"00", int64(0), true},
+	{"01", int64(1), true},
+	{"0a", int64(10), true},
+	{"17", int64(23), true},
+	{"1818", int64(24), true},
+	{"1819", int64(25), true},
+	{"1864", int64(100), true},
+	{"1903e8", int64(1000), true},
+	{"1a000f4240", int64(1000000), true},
+	{"1b000000e8d4a51000", int64(1000000000000), true},
+	{"1bffffffffffffffff", uint64(18446744073709551615), true},
+	{"c249010000000000000000", mustBigInt("18446744073709551616"), true},
+	{"3bffffffffffffffff", mustBigInt("-18446744073709551616"), true},
+	{"c349010000000000000000", mustBigInt("-18446744073709551617"), true},
+	{"20", int64(-1), true},
+	{"29", int64(-10), true},
+	{"3863", int64(-100), true},
+	{"3903e7", int64(-1000), true},
+	{"f90000", 0.0, true},
+	{"f98000", math.Copysign(0, -1), true},
+	{"f93c00", 1.0, true},
+	{"fb3ff199999999999a", 1.1, true},
+	{"f93e00", 1.5, true},
+	{"f97bff", 65504.0, true},
+	{"fa47c35000", 100000.0, true},
+	{"fa7f7fffff", 3.4028234663852886e+38, true},
+	{"fb7e37e43c8800759c", 1.0e+300, true},
+	{"f90001", 5.960464477539063e-8, true},
+	{"f90400", 0.00006103515625, true},
+	{"f9c400", -4.0, true},
+	{"fbc010666666666666", -4.1, true},
+	{"f97c00", math.Inf(1), true},
+	{"f97e00", math.NaN(), true},
+	{"f9fc00", math.Inf(-1), true},
+	{"fa7f800000", math.Inf(1), false},
+	{"fa7fc00000", math.NaN(), false},
+	{"faff800000", math.Inf(-1), false},
+	{"fb7ff0000000000000", math.Inf(1), false},
+	{"fb7ff8000000000000", math.NaN(), false},
+	{"fbfff0000000000000", math.Inf(-1), false},
+	{"f4", false, true},
+	{"f5", true, true},
+	{"f6", nil, true},
+	{"f7", CBORUndefined, true},
+	{"f0", CBORSimpleValue(16), true},
+	{"f8ff", CBORSimpleValue(255), true},
+	{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), false},
+	{"c11a514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), true},
+	{"c1fb41d452d9ec200000", time.Date(2013, 3, 21, 20, 4, 0, 5e8, time.UTC), true},
+	{"d74401020304", CBORTag{23, []byte{1, 2, 3, 4}}, true},
+	{"d818456449455446", CBORTag{24, []byte("dIETF")}, true},
+	{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", CBORTag{32, "http://www.example.com"}, true},
+	{"40", []byte{}, true},
+	{"4401020304", []byte{1, 2, 3, 4}, true},
+	{"60", "", true},
+	{"6161", "a", true},
+	{"6449455446", "IETF", true},
+	{"62225c", "\"\\", true},
+	{"62c3bc", "ü", true},
+	{"63e6b0b4", "水", true},
+	{"64f0908591", "\U00010151", true},
+	{"80", []interface{}{}, true},
+	{"83010203", []interface{}{int64(1), int64(2), int64(3)}, true},
+	{"8301820203820405", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, true},
+	{"98190102030405060708090a0b0c0d0e0f101112131415161718181819", cborOneTo25(), true},
+	{"a0", map[string]interface{}{}, true},
+	{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}, true},
+	{"a26161016162820203", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}, true},
+	{"826161a161626163", []interface{}{"a", map[string]interface{}{"b": "c"}}, true},
+	{"a56161614161626142616361436164614461656145", map[string]interface{}{"a": "A", "b": "B", "c": "C", "d": "D", "e": "E"}, true},
+	{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}, false},
+	{"7f657374726561646d696e67ff", "streaming", false},
+	{"9fff", []interface{}{}, false},
+	{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, false},
+	{"9f01820203820405ff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, false},
+	{"83018202039f0405ff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, false},
+	{"83019f0203ff820405", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, false},
+	{"9f0102030405060708090a0b0c0d0e0f101112131415161718181819ff", cborOneTo25(), false},
+	{"bf61610161629f0203ffff", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}, false},
+	{"826161bf61626163ff", []interface{}{"a", map[string]interface{}{"b": "c"}}, false},
+	{"bf6346756ef563416d7421ff", map[string]interface{}{"Fun": true, "Amt": int64(-2)}, false},
+}
+
+func cborOneTo25() []interface{} {
+	a := make([]interface{}, 25)
+	for i := range a {
+		a[i] = int64(i + 1)
+	}
+	return a
+}
+
+func TestCBORVectors(t *testing.T) {
+	for _, c := range cborVectors {
+		b, _ := hex.DecodeString(c.hex)
+		r := NewReadSeekerFromBytes(b)
+		got, err := r.ReadCBOR()
+		if err != nil {
+			t.Fatalf("%v: %v", c.hex, err)
+		}
+		if !cborDeepEqual(got, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %#v want %#v", c.hex, got, c.want)
+		}
+		if _, err = r.ReadCBOR(); err != io.EOF {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, err, io.EOF)
+		}
+		if !c.reencode {
+			continue
+		}
+		w := NewBytesBuffer()
+		if err = w.WriteCBOR(got, CBOROptions{Deterministic: true}); err != nil {
+			t.Fatal(err)
+		}
+		if enc := hex.EncodeToString(w.Bytes()); enc != c.hex {
+			t.Fatalf("unexpected value obtained; got %v want %v", enc, c.hex)
+		}
+	}
+}
+
+//compare decoded values,with the floats,bignums and times inside arrays,maps and tags.
+func cborDeepEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !cborDeepEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case CBORTag:
+		y, ok := b.(CBORTag)
+		return ok && x.Number == y.Number && cborDeepEqual(x.Content, y.Content)
+	}
+	return cborEqual(a, b)
+}
+
+func TestCBORWriter(t *testing.T) {
+	for _, c := range []struct {
+		write func(w *Writer)
+		want  string
+	}{
+		{func(w *Writer) { w.WriteCBORInt(math.MinInt64) }, "3b7fffffffffffffff"},
+		{func(w *Writer) { w.WriteCBORUint(math.MaxUint32) }, "1affffffff"},
+		{func(w *Writer) { w.WriteCBORBigInt(big.NewInt(-1)) }, "20"},
+		{func(w *Writer) { w.WriteCBORText(strings.Repeat("a", 24)) }, "7818" + strings.Repeat("61", 24)},
+		{func(w *Writer) { w.WriteCBORFloat32(1.5) }, "fa3fc00000"},
+		{func(w *Writer) { w.WriteCBORFloat64(1.5) }, "fb3ff8000000000000"},
+		{func(w *Writer) { w.WriteCBORFloat(1.0 / 3) }, "fb3fd5555555555555"},
+		{func(w *Writer) { w.WriteCBORFloat(0.1) }, "fb3fb999999999999a"},
+		//65536 doesn't fit in a float16,65504 is its largest value
+		{func(w *Writer) { w.WriteCBORFloat(65536) }, "fa47800000"},
+		//the smallest float16 normal and a float16 subnormal that is not a power of two
+		{func(w *Writer) { w.WriteCBORFloat(math.Ldexp(1, -14)) }, "f90400"},
+		{func(w *Writer) { w.WriteCBORFloat(math.Ldexp(3, -24)) }, "f90003"},
+		{func(w *Writer) { w.WriteCBORFloat(math.Ldexp(1, -25)) }, "fa33000000"},
+		{func(w *Writer) { w.WriteCBORUndefined() }, "f7"},
+		{func(w *Writer) { w.WriteCBORSimple(32) }, "f820"},
+		{func(w *Writer) {
+			w.WriteCBORIndefinite(CBORMajorText)
+			w.WriteCBORText("strea")
+			w.WriteCBORText("ming")
+			w.WriteCBORBreak()
+		}, "7f657374726561646d696e67ff"},
+		{func(w *Writer) {
+			w.WriteCBORIndefinite(CBORMajorMap)
+			w.WriteCBORText("Fun")
+			w.WriteCBORBool(true)
+			w.WriteCBORText("Amt")
+			w.WriteCBORInt(-2)
+			w.WriteCBORBreak()
+		}, "bf6346756ef563416d7421ff"},
+	} {
+		w := NewBytesBuffer()
+		c.write(w)
+		if got := hex.EncodeToString(w.Bytes()); got != c.want {
+			t.Fatalf("unexpected value obtained; got %v want %v", got, c.want)
+		}
+	}
+}
+
+func TestCBORDeterministic(t *testing.T) {
+	m := map[interface{}]interface{}{"aa": 1, "b": 2, int64(10): 3, int64(-1): 4, false: 5}
+	w := NewBytesBuffer()
+	if err := w.WriteCBOR(m, CBOROptions{Deterministic: true}); err != nil {
+		t.Fatal(err)
+	}
+	//the keys by their bytes:10 is 0a,-1 is 20,"b" is 6162,"aa" is 626161 and false is f4
+	want := "a50a03200461620262616101f405"
+	if got := hex.EncodeToString(w.Bytes()); got != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	//without the option string keys are sorted as strings
+	w = NewBytesBuffer()
+	if err := w.WriteCBOR(map[string]int{"b": 2, "aa": 1}, CBOROptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want = "a262616101616202"
+	if got := hex.EncodeToString(w.Bytes()); got != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+}
+
+func TestCBORStruct(t *testing.T) {
+	type inner struct {
+		Tags []string `cbor:"tags"`
+	}
+	type record struct {
+		ID      int64          `cbor:"id"`
+		Name    string         `cbor:"name,omitempty"`
+		Data    []byte         `cbor:"data"`
+		Big     big.Int        `cbor:"big"`
+		When    time.Time      `cbor:"when"`
+		Ratio   float32        `cbor:"ratio"`
+		Inner   *inner         `cbor:"inner"`
+		Extra   map[string]int `cbor:"extra"`
+		Skipped int            `cbor:"-"`
+	}
+	in := record{
+		ID:    -5,
+		Data:  []byte{1, 2},
+		When:  time.Unix(1363896240, 0).UTC(),
+		Ratio: 0.5,
+		Inner: &inner{Tags: []string{"x", "y"}},
+		Extra: map[string]int{"k": 7},
+	}
+	in.Big.SetString("123456789012345678901234567890", 10)
+	w := NewBytesBuffer()
+	if err := w.WriteCBOR(&in, CBOROptions{Deterministic: true}); err != nil {
+		t.Fatal(err)
+	}
+	var out record
+	r := NewReadSeekerFromBytes(w.Bytes())
+	if err := r.ReadCBORInto(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || out.Name != "" || !reflect.DeepEqual(out.Data, in.Data) || out.Big.Cmp(&in.Big) != 0 ||
+		!out.When.Equal(in.When) || out.Ratio != in.Ratio || !reflect.DeepEqual(out.Inner, in.Inner) || !reflect.DeepEqual(out.Extra, in.Extra) {
+		t.Fatalf("unexpected value obtained; got %+v want %+v", out, in)
+	}
+	//"id" is the shortest key,so it comes first in the deterministic order
+	if got, want := hex.EncodeToString(w.Bytes()[:5]), "a762696424"; got != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	var wrong struct {
+		ID string `cbor:"id"`
+	}
+	r = NewReadSeekerFromBytes(w.Bytes())
+	if err := r.ReadCBORInto(&wrong); !errors.Is(err, ErrCBORType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrCBORType)
+	}
+	if err := NewBytesBuffer().WriteCBOR(make(chan int), CBOROptions{}); !errors.Is(err, ErrCBORType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrCBORType)
+	}
+}
+
+func TestCBORWriteErrors(t *testing.T) {
+	//nothing of a value is written when a part of it fails
+	w := NewBytesBuffer()
+	w.WriteUint8(0x11)
+	err := w.WriteCBOR([]interface{}{1, 2, make(chan int)}, CBOROptions{})
+	if !errors.Is(err, ErrCBORType) || !reflect.DeepEqual(w.Bytes(), []byte{0x11}) {
+		t.Fatalf("unexpected value obtained; got %x %v want %x", w.Bytes(), err, []byte{0x11})
+	}
+	//a value containing itself is an error instead of endless recursion
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+	m := map[string]interface{}{}
+	m["m"] = CBORTag{Number: 100, Content: m}
+	s := []interface{}{nil}
+	s[0] = s
+	for _, v := range []interface{}{n, m, s} {
+		if err := NewBytesBuffer().WriteCBOR(v, CBOROptions{Deterministic: true}); !errors.Is(err, ErrCBORType) || !strings.Contains(err.Error(), "contains itself") {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, ErrCBORType)
+		}
+	}
+	//the same pointer twice is not a cycle
+	p := &node{}
+	if err := NewBytesBuffer().WriteCBOR([]*node{p, p}, CBOROptions{}); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+}
+
+func TestCBORToken(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteCBORIndefinite(CBORMajorArray)
+	w.WriteCBORInt(-3)
+	w.WriteCBORBytes(make([]byte, 1000))
+	w.WriteCBORTag(1)
+	w.WriteCBORFloat(1.5)
+	w.WriteCBORBreak()
+	r := NewReadSeekerFromBytes(w.Bytes())
+	var got []string
+	for {
+		tok, err := r.ReadCBORToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case tok.Break:
+			got = append(got, "break")
+		case tok.Indefinite:
+			got = append(got, tok.Major.String()+"(_")
+		case tok.FloatSize != 0:
+			got = append(got, fmt.Sprint("float", tok.FloatSize*8, " ", tok.Float()))
+		case tok.Data != nil:
+			got = append(got, fmt.Sprint(tok.Major, " ", tok.Data.Size()))
+		default:
+			got = append(got, fmt.Sprint(tok.Major, " ", tok.Arg))
+		}
+	}
+	want := []string{"array(_", "negative 2", "bytes 1000", "tag 1", "float16 1.5", "break"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+}
+
+func TestCBORErrors(t *testing.T) {
+	for _, c := range []struct {
+		hex  string
+		want error
+	}{
+		{"18", io.ErrUnexpectedEOF},
+		{"6261", io.ErrUnexpectedEOF},
+		{"8201", io.ErrUnexpectedEOF},
+		{"9f01", io.ErrUnexpectedEOF},
+		{"c1", io.ErrUnexpectedEOF},
+		{"1c", ErrInvalidCBOR},
+		{"1f", ErrInvalidCBOR},
+		{"df", ErrInvalidCBOR},
+		{"ff", ErrInvalidCBOR},
+		{"f818", ErrInvalidCBOR},
+		{"5f6161ff", ErrInvalidCBOR},
+		{"5f5f4101ffff", ErrInvalidCBOR},
+		{"61ff", ErrInvalidCBOR},
+		{"bf01ff", ErrInvalidCBOR},
+		{"c001", ErrInvalidCBOR},
+		{"c26161", ErrInvalidCBOR},
+		{"a1800102", ErrCBORType},
+		{strings.Repeat("81", maxCodecDepth+2) + "01", ErrInvalidCBOR},
+	} {
+		b, _ := hex.DecodeString(c.hex)
+		r := NewReadSeekerFromBytes(b)
+		if _, err := r.ReadCBOR(); !errors.Is(err, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, err, c.want)
+		}
+		if pos, _ := r.CurPos(); pos != 0 {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, pos, 0)
+		}
+	}
+	r := NewReadSeekerFromBytes([]byte{0x5a, 0xff, 0xff, 0xff, 0xff})
+	if _, err := r.ReadCBORToken(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,187 @@
+package iox
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"io"
+)
+
+var ErrChecksumMismatch = errors.New("the checksum does not match")
+
+//feed the data between beginPos and endPos(both included) into h and returns h.Sum(nil),
+//the position is left unchanged.
+func (r *ReadSeeker) Checksum(beginPos, endPos int64, h hash.Hash) ([]byte, error) {
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	defer r.readSeeker.Seek(initialPos, io.SeekStart)
+	if size := r.Size(); beginPos < 0 || endPos >= size || endPos+1 < beginPos {
+		return nil, fmt.Errorf("beginPos:%v or endPos:%v is not a valid value,the size of the data is %v", beginPos, endPos, size)
+	}
+	if _, err = r.readSeeker.Seek(beginPos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err = io.CopyN(h, r.readSeeker, endPos-beginPos+1); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+//returns the CRC32(IEEE) of the data between beginPos and endPos(both included).
+func (r *ReadSeeker) CRC32(beginPos, endPos int64) (uint32, error) {
+	h := crc32.NewIEEE()
+	if _, err := r.Checksum(beginPos, endPos, h); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
+//returns the Adler-32 of the data between beginPos and endPos(both included).
+func (r *ReadSeeker) Adler32(beginPos, endPos int64) (uint32, error) {
+	h := adler32.New()
+	if _, err := r.Checksum(beginPos, endPos, h); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
+//returns the CRC-16 of the data between beginPos and endPos(both included),such as CRC16CCITT.
+func (r *ReadSeeker) CRC16(beginPos, endPos int64, params CRC16Params) (uint16, error) {
+	h := NewCRC16(params)
+	if _, err := r.Checksum(beginPos, endPos, h); err != nil {
+		return 0, err
+	}
+	return h.Sum16(), nil
+}
+
+//returns the CRC-8 of the data between beginPos and endPos(both included).
+func (r *ReadSeeker) CRC8(beginPos, endPos int64, params CRC8Params) (uint8, error) {
+	h := NewCRC8(params)
+	if _, err := r.Checksum(beginPos, endPos, h); err != nil {
+		return 0, err
+	}
+	return h.Sum8(), nil
+}
+
+//returns the SHA-256 of the data between beginPos and endPos(both included).
+func (r *ReadSeeker) SHA256(beginPos, endPos int64) ([]byte, error) {
+	return r.Checksum(beginPos, endPos, sha256.New())
+}
+
+//read a uint32 at pos without moving the position.
+func (r *ReadSeeker) uint32At(pos int64, bigEndian bool) (uint32, error) {
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return 0, err
+	}
+	defer r.readSeeker.Seek(initialPos, io.SeekStart)
+	if err = r.MoveTo(pos); err != nil {
+		return 0, err
+	}
+	defer r.traceEnter("uint32At")() //verification reads are not traced
+	if bigEndian {
+		return r.ReadUint32BigEndian()
+	}
+	return r.ReadUint32()
+}
+
+func (r *ReadSeeker) verifyCRC32At(beginPos, endPos, sumPos int64, bigEndian bool) error {
+	sum, err := r.uint32At(sumPos, bigEndian)
+	if err != nil {
+		return err
+	}
+	crc, err := r.CRC32(beginPos, endPos)
+	if err != nil {
+		return err
+	}
+	if crc != sum {
+		return fmt.Errorf("the CRC32 of %v-%v is %08X,but %08X is stored at %v: %w", beginPos, endPos, crc, sum, sumPos, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+//check that the uint32 at sumPos is the CRC32(IEEE) of the data between beginPos and endPos(both included),
+//an error wrapping ErrChecksumMismatch is returned if not.
+func (r *ReadSeeker) VerifyCRC32At(beginPos, endPos, sumPos int64) error {
+	return r.verifyCRC32At(beginPos, endPos, sumPos, false)
+}
+
+//check that the uint32(BigEndian) at sumPos is the CRC32(IEEE) of the data between beginPos and endPos(both included).
+func (r *ReadSeeker) VerifyCRC32BigEndianAt(beginPos, endPos, sumPos int64) error {
+	return r.verifyCRC32At(beginPos, endPos, sumPos, true)
+}
+
+//check that the bytes at sumPos are h.Sum of the data between beginPos and endPos(both included).
+func (r *ReadSeeker) VerifyChecksumAt(beginPos, endPos, sumPos int64, h hash.Hash) error {
+	sum, err := r.Checksum(beginPos, endPos, h)
+	if err != nil {
+		return err
+	}
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	defer r.readSeeker.Seek(initialPos, io.SeekStart)
+	if err = r.MoveTo(sumPos); err != nil {
+		return err
+	}
+	stored := make([]byte, len(sum))
+	if err = r.readFull(stored); err != nil {
+		return err
+	}
+	if string(stored) != string(sum) {
+		return fmt.Errorf("the checksum of %v-%v is %X,but %X is stored at %v: %w", beginPos, endPos, sum, stored, sumPos, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+//start a checksum region,from now on every written byte is fed into h
+//until the checksum is written by WriteChecksum,WriteCRC32 or WriteCRC32BigEndian.
+func (w *Writer) BeginChecksum(h hash.Hash) {
+	w.hash = h
+}
+
+//start a CRC32(IEEE) region,see BeginChecksum.
+func (w *Writer) BeginCRC32() {
+	w.BeginChecksum(crc32.NewIEEE())
+}
+
+//end the checksum region and returns its hash,nil if there is no region.
+func (w *Writer) EndChecksum() hash.Hash {
+	h := w.hash
+	w.hash = nil
+	return h
+}
+
+//end the checksum region and write h.Sum of it.
+func (w *Writer) WriteChecksum() {
+	h := w.EndChecksum()
+	if h == nil {
+		panic("there is no checksum region,call BeginChecksum first")
+	}
+	w.write(h.Sum(nil))
+}
+
+//end the checksum region and returns its 32 bit sum.
+func (w *Writer) endChecksum32() uint32 {
+	h, ok := w.EndChecksum().(hash.Hash32)
+	if !ok {
+		panic("there is no 32 bit checksum region,call BeginCRC32 first")
+	}
+	return h.Sum32()
+}
+
+//end the checksum region and write its CRC32 with LittleEndian.
+func (w *Writer) WriteCRC32() {
+	w.WriteUint32(w.endChecksum32())
+}
+
+//end the checksum region and write its CRC32 with BigEndian.
+func (w *Writer) WriteCRC32BigEndian() {
+	w.WriteUint32BigEndian(w.endChecksum32())
+}
@@ -0,0 +1,110 @@
+package iox
+
+import (
+	"crypto/sha256"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+func TestCRC(t *testing.T) {
+	check := []byte("123456789")
+	crc8Cases := []struct {
+		params CRC8Params
+		want   uint8
+	}{
+		{CRC8, 0xf4},
+		{CRC8MAXIM, 0xa1},
+		{CRC8ROHC, 0xd0},
+	}
+	for _, c := range crc8Cases {
+		if got := ChecksumCRC8(check, c.params); got != c.want {
+			t.Fatalf("%+v: unexpected value obtained; got %X want %X", c.params, got, c.want)
+		}
+	}
+	crc16Cases := []struct {
+		params CRC16Params
+		want   uint16
+	}{
+		{CRC16CCITT, 0x29b1},
+		{CRC16XMODEM, 0x31c3},
+		{CRC16KERMIT, 0x2189},
+		{CRC16X25, 0x906e},
+		{CRC16ARC, 0xbb3d},
+		{CRC16MODBUS, 0x4b37},
+		{CRC16USB, 0xb4c8},
+		{CRC16GENIBUS, 0xd64e},
+		{CRC16Params{Poly: 0x8bb7}, 0xd0db},               //CRC-16/T10-DIF
+		{CRC16Params{Poly: 0x1021, Init: 0x1d0f}, 0xe5cc}, //CRC-16/SPI-FUJITSU
+		{CRC16Params{Poly: 0x1021, Init: 0x89ec, RefIn: true, RefOut: true}, 0x26b1}, //CRC-16/TMS37157
+	}
+	for _, c := range crc16Cases {
+		if got := ChecksumCRC16(check, c.params); got != c.want {
+			t.Fatalf("%+v: unexpected value obtained; got %X want %X", c.params, got, c.want)
+		}
+	}
+	//Sum of a hash.Hash is BigEndian
+	h := NewCRC16(CRC16CCITT)
+	h.Write(check)
+	if sum := h.Sum(nil); sum[0] != 0x29 || sum[1] != 0xb1 {
+		t.Fatalf("unexpected value obtained; got %X want %X", sum, []byte{0x29, 0xb1})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	wr := NewBytesBuffer()
+	wr.WriteString("IOX")
+	wr.BeginCRC32()
+	wr.WriteStringUint16("test str")
+	wr.WriteUint32(100)
+	wr.WriteCRC32()
+	wr.BeginCRC32()
+	wr.WriteString("123456789")
+	wr.WriteCRC32BigEndian()
+	wr.BeginChecksum(sha256.New())
+	wr.WriteString("sha")
+	wr.WriteChecksum()
+	data := wr.Bytes()
+	rd := NewReadSeekerFromBytes(data)
+	rd.MoveTo(3)
+	crc, err := rd.CRC32(3, 16)
+	if err != nil || crc != crc32.ChecksumIEEE(data[3:17]) {
+		t.Fatalf("unexpected value obtained; got %X %v want %X", crc, err, crc32.ChecksumIEEE(data[3:17]))
+	}
+	if pos, _ := rd.CurPos(); pos != 3 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 3)
+	}
+	if err = rd.VerifyCRC32At(3, 16, 17); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if err = rd.VerifyCRC32BigEndianAt(21, 29, 30); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if err = rd.VerifyChecksumAt(34, 36, 37, sha256.New()); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	err = rd.VerifyCRC32At(3, 15, 17)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrChecksumMismatch)
+	}
+	crc16, err := rd.CRC16(21, 29, CRC16CCITT)
+	if err != nil || crc16 != 0x29b1 {
+		t.Fatalf("unexpected value obtained; got %X %v want %X", crc16, err, 0x29b1)
+	}
+	crc8, err := rd.CRC8(21, 29, CRC8)
+	if err != nil || crc8 != 0xf4 {
+		t.Fatalf("unexpected value obtained; got %X %v want %X", crc8, err, 0xf4)
+	}
+	adler, err := rd.Adler32(21, 29)
+	if err != nil || adler != 0x091e01de {
+		t.Fatalf("unexpected value obtained; got %X %v want %X", adler, err, 0x091e01de)
+	}
+	sum, err := rd.SHA256(34, 36)
+	if want := sha256.Sum256([]byte("sha")); err != nil || string(sum) != string(want[:]) {
+		t.Fatalf("unexpected value obtained; got %X %v want %X", sum, err, want)
+	}
+	_, err = rd.CRC32(0, rd.Size())
+	if err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
@@ -0,0 +1,317 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var ErrChunkLength = errors.New("the chunk length is not valid")
+
+//ChunkFormat describes the layout of a chunk:[length][tag][payload][padding][crc32],
+//the tag may come first and the length may count the header.
+type ChunkFormat struct {
+	LengthSize           int  //1,2,4 or 8 bytes,0 means 4
+	BigEndian            bool //byte order of the length and the crc32
+	TagSize              int  //0 means 4
+	TagFirst             bool //the tag comes before the length,like RIFF and IFF
+	LengthIncludesHeader bool //the length counts the length and the tag,like ISO-BMFF boxes
+	//a length of 1 is followed by the real length as a uint64 after the tag,
+	//and a length of 0 means up to the end of the parent,like ISO-BMFF boxes
+	LargeSize bool
+	Align     int  //the payload is padded with zero bytes to a multiple of Align,0 or 1 means no padding
+	CRC       bool //a crc32(IEEE) of the tag and the payload follows the payload,like PNG
+	//the tags of the containers whose payload holds chunks after a header of the given size,
+	//such as 4 for the form type of a RIFF chunk
+	Containers map[string]int
+}
+
+var (
+	//PNG chunks,walk them after the 8 byte signature.
+	ChunkPNG = ChunkFormat{BigEndian: true, CRC: true}
+	//RIFF chunks of WAV and AVI files.
+	ChunkRIFF = ChunkFormat{TagFirst: true, Align: 2, Containers: map[string]int{"RIFF": 4, "LIST": 4}}
+	//IFF chunks of AIFF and ILBM files.
+	ChunkIFF = ChunkFormat{BigEndian: true, TagFirst: true, Align: 2, Containers: map[string]int{"FORM": 4, "LIST": 4, "CAT ": 4}}
+	//ISO-BMFF boxes of MP4,MOV and HEIF files,meta is a full box.
+	ChunkBMFF = ChunkFormat{BigEndian: true, LengthIncludesHeader: true, LargeSize: true, Containers: map[string]int{
+		"moov": 0, "trak": 0, "mdia": 0, "minf": 0, "stbl": 0, "dinf": 0, "edts": 0, "udta": 0,
+		"mvex": 0, "moof": 0, "traf": 0, "mfra": 0, "meta": 4,
+	}}
+)
+
+func (f ChunkFormat) lengthSize() int {
+	switch f.LengthSize {
+	case 0:
+		return 4
+	case 1, 2, 4, 8:
+		return f.LengthSize
+	}
+	panic(fmt.Sprint("unknown chunk length size:", f.LengthSize))
+}
+
+func (f ChunkFormat) tagSize() int {
+	if f.TagSize <= 0 {
+		return 4
+	}
+	return f.TagSize
+}
+
+//read n bytes at pos without moving the position.
+func (r *ReadSeeker) bytesAt(pos, n int64) ([]byte, error) {
+	s, err := r.Section(pos, pos+n-1)
+	if err != nil {
+		return nil, err
+	}
+	bt := make([]byte, n)
+	if _, err = io.ReadFull(s.readSeeker, bt); err != nil {
+		return nil, err
+	}
+	return bt, nil
+}
+
+//Chunk is a chunk found by a ChunkWalker.
+type Chunk struct {
+	Tag        string
+	Offset     int64 //of the header in the walked data
+	HeaderSize int64 //the length and the tag
+	Size       int64 //of the payload without padding and crc32
+	Depth      int   //0 for the top level,1 for the chunks of a container and so on
+	Container  bool  //the chunks of the payload are walked next
+	CRC        uint32
+	Data       *ReadSeeker //the payload,positions are relative to it
+}
+
+//ChunkWalker walks the chunks of a range depth first,it is used like bufio.Scanner:
+//
+//	walker := r.Chunks(iox.ChunkRIFF)
+//	for walker.Next() {
+//		chunk := walker.Chunk()
+//	}
+//	if err := walker.Err(); err != nil {
+//	}
+type ChunkWalker struct {
+	r      *ReadSeeker
+	format ChunkFormat
+	levels []chunkLevel
+	chunk  *Chunk
+	err    error
+}
+
+//the chunks of the top level or of a container.
+type chunkLevel struct {
+	pos, end int64 //end is excluded
+}
+
+//Chunks returns a ChunkWalker from the current position to the end of the data.
+func (r *ReadSeeker) Chunks(format ChunkFormat) *ChunkWalker {
+	pos, err := r.CurPos()
+	walker := r.ChunksGen(pos, r.Size()-1, format)
+	if err != nil {
+		walker.err = err
+	}
+	return walker
+}
+
+//ChunksGen returns a ChunkWalker of the chunks between beginPos and endPos(both included),
+//reading chunks does not move the position of r.
+func (r *ReadSeeker) ChunksGen(beginPos, endPos int64, format ChunkFormat) *ChunkWalker {
+	format.lengthSize() //panics on an unknown length size
+	walker := &ChunkWalker{r: r, format: format}
+	if size := r.Size(); beginPos < 0 || endPos >= size || endPos+1 < beginPos {
+		walker.err = fmt.Errorf("beginPos:%v or endPos:%v is not a valid value,the size of the data is %v", beginPos, endPos, size)
+		return walker
+	}
+	walker.levels = []chunkLevel{{beginPos, endPos + 1}}
+	return walker
+}
+
+//Next reads the next chunk,it returns false at the end or on an error.
+func (w *ChunkWalker) Next() bool {
+	w.chunk = nil
+	for w.err == nil && len(w.levels) > 0 {
+		level := &w.levels[len(w.levels)-1]
+		if level.pos >= level.end {
+			w.levels = w.levels[:len(w.levels)-1]
+			continue
+		}
+		chunk, next, err := w.read(level.pos, level.end)
+		if err != nil {
+			w.err = err
+			return false
+		}
+		level.pos = next
+		chunk.Depth = len(w.levels) - 1
+		if header, ok := w.format.Containers[chunk.Tag]; ok && int64(header) <= chunk.Size {
+			chunk.Container = true
+			begin := chunk.Offset + chunk.HeaderSize
+			w.levels = append(w.levels, chunkLevel{begin + int64(header), begin + chunk.Size})
+		}
+		w.chunk = chunk
+		return true
+	}
+	return false
+}
+
+//Chunk returns the chunk read by Next.
+func (w *ChunkWalker) Chunk() *Chunk {
+	return w.chunk
+}
+
+//Err returns the error that stopped Next,a chunk that does not fit its parent returns an error
+//wrapping io.ErrUnexpectedEOF and a bad crc32 an error wrapping ErrChecksumMismatch.
+func (w *ChunkWalker) Err() error {
+	return w.err
+}
+
+//read the chunk at pos of a parent that ends before end,returns the chunk and the position after it.
+func (w *ChunkWalker) read(pos, end int64) (*Chunk, int64, error) {
+	f := w.format
+	lenSize, tagSize := int64(f.lengthSize()), int64(f.tagSize())
+	header := lenSize + tagSize
+	if end-pos < header {
+		return nil, 0, fmt.Errorf("the chunk at position %v needs a header of %v bytes,but only %v bytes left: %w", pos, header, end-pos, io.ErrUnexpectedEOF)
+	}
+	bt, err := w.r.bytesAt(pos, header)
+	if err != nil {
+		return nil, 0, err
+	}
+	tagBytes, lenBytes := bt[lenSize:], bt[:lenSize]
+	if f.TagFirst {
+		tagBytes, lenBytes = bt[:tagSize], bt[tagSize:]
+	}
+	chunk := &Chunk{Tag: string(tagBytes), Offset: pos, HeaderSize: header}
+	length := bytesToUint(lenBytes, f.BigEndian)
+	switch {
+	case f.LargeSize && length == 1:
+		if end-pos < header+8 {
+			return nil, 0, fmt.Errorf("the chunk at position %v has no large size: %w", pos, io.ErrUnexpectedEOF)
+		}
+		if bt, err = w.r.bytesAt(pos+header, 8); err != nil {
+			return nil, 0, err
+		}
+		length = bytesToUint(bt, f.BigEndian)
+		chunk.HeaderSize += 8
+	case f.LargeSize && length == 0:
+		length = uint64(end - pos)
+		if !f.LengthIncludesHeader {
+			length -= uint64(header)
+		}
+	}
+	if f.LengthIncludesHeader {
+		if length < uint64(chunk.HeaderSize) {
+			return nil, 0, fmt.Errorf("the chunk %q at position %v has the length %v,shorter than its header: %w", chunk.Tag, pos, length, ErrChunkLength)
+		}
+		length -= uint64(chunk.HeaderSize)
+	}
+	payload := pos + chunk.HeaderSize
+	if length > uint64(end-payload) {
+		return nil, 0, fmt.Errorf("the chunk %q at position %v has %v bytes,but only %v bytes left: %w", chunk.Tag, pos, length, end-payload, io.ErrUnexpectedEOF)
+	}
+	chunk.Size = int64(length)
+	if chunk.Data, err = w.r.Section(payload, payload+chunk.Size-1); err != nil {
+		return nil, 0, err
+	}
+	next := payload + chunk.Size
+	if f.Align > 1 {
+		//the padding of the last chunk is often missing
+		if next += paddingLen(chunk.Size, int64(f.Align)); next > end {
+			next = end
+		}
+	}
+	if f.CRC {
+		if end-next < 4 {
+			return nil, 0, fmt.Errorf("the chunk %q at position %v has no crc32: %w", chunk.Tag, pos, io.ErrUnexpectedEOF)
+		}
+		if bt, err = w.r.bytesAt(next, 4); err != nil {
+			return nil, 0, err
+		}
+		chunk.CRC = uint32(bytesToUint(bt, f.BigEndian))
+		h := crc32.NewIEEE()
+		h.Write(tagBytes)
+		if _, err = io.Copy(h, chunk.Data.readSeeker); err != nil {
+			return nil, 0, err
+		}
+		chunk.Data.readSeeker.Seek(0, io.SeekStart)
+		if h.Sum32() != chunk.CRC {
+			return nil, 0, fmt.Errorf("the chunk %q at position %v has the crc32 %08x,but %08x is computed: %w", chunk.Tag, pos, chunk.CRC, h.Sum32(), ErrChecksumMismatch)
+		}
+		next += 4
+	}
+	return chunk, next, nil
+}
+
+//chunkRegion is the state of a Writer between BeginChunk and EndChunk.
+type chunkRegion struct {
+	format  ChunkFormat
+	begin   int64 //of the header
+	tagPos  int64
+	lenPos  int64
+	payload int64
+}
+
+//BeginChunk writes the header of a chunk with a placeholder length,the payload is written next
+//and EndChunk back-fills the length,chunks may be nested.a LargeSize format writes the short length.
+//the length is back-filled at its position,so chunks can't be used inside a compressed region
+//or inside BeginASN1 and BeginProtoMessage,where Pos does not move.
+func (w *Writer) BeginChunk(format ChunkFormat, tag string) {
+	if w.region != nil {
+		panic("a chunk can't be written inside a compressed region")
+	}
+	if len(w.nested) > 0 {
+		panic("a chunk can't be written inside an ASN.1 element or a protobuf message")
+	}
+	if len(tag) != format.tagSize() {
+		panic(fmt.Sprintf("the tag %q is not %v bytes", tag, format.tagSize()))
+	}
+	lenSize := format.lengthSize()
+	region := chunkRegion{format: format, begin: w.pos, tagPos: w.pos, lenPos: w.pos + int64(len(tag))}
+	if !format.TagFirst {
+		region.tagPos, region.lenPos = w.pos+int64(lenSize), w.pos
+		w.write(make([]byte, lenSize))
+		w.WriteString(tag)
+	} else {
+		w.WriteString(tag)
+		w.write(make([]byte, lenSize))
+	}
+	region.payload = w.pos
+	w.chunks = append(w.chunks, region)
+}
+
+//EndChunk ends the chunk of the last BeginChunk,it back-fills the length and writes the padding and the crc32,
+//returns the size of the payload.
+func (w *Writer) EndChunk() int64 {
+	if len(w.chunks) == 0 {
+		panic("there is no chunk,call BeginChunk first")
+	}
+	if w.region != nil {
+		panic("a chunk can't be ended inside a compressed region")
+	}
+	if len(w.nested) > 0 {
+		panic("a chunk can't be ended inside an ASN.1 element or a protobuf message")
+	}
+	region := w.chunks[len(w.chunks)-1]
+	w.chunks = w.chunks[:len(w.chunks)-1]
+	f := region.format
+	lenSize := f.lengthSize()
+	size := w.pos - region.payload
+	length := uint64(size)
+	if f.LengthIncludesHeader {
+		length = uint64(w.pos - region.begin)
+	}
+	if lenSize < 8 && length >= 1<<(8*lenSize) {
+		panic(fmt.Sprintf("the length %v of the chunk does not fit %v bytes", length, lenSize))
+	}
+	w.writeAt(uintToBytes(length, lenSize, f.BigEndian), region.lenPos)
+	if f.Align > 1 {
+		w.WriteZeros(int(paddingLen(size, int64(f.Align))))
+	}
+	if f.CRC {
+		h := crc32.NewIEEE()
+		h.Write(w.bytesAt(region.tagPos, int64(f.tagSize())))
+		h.Write(w.bytesAt(region.payload, size))
+		w.write(uintToBytes(uint64(h.Sum32()), 4, f.BigEndian))
+	}
+	return size
+}
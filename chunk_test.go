@@ -0,0 +1,159 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+//walk all chunks and describe them as "depth tag size".
+func walkChunks(t *testing.T, walker *ChunkWalker) []string {
+	var got []string
+	for walker.Next() {
+		c := walker.Chunk()
+		got = append(got, fmt.Sprintf("%v %v %v", c.Depth, c.Tag, c.Size))
+	}
+	if err := walker.Err(); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	return got
+}
+
+func TestChunkPNG(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteString("\x89PNG\r\n\x1a\n")
+	w.BeginChunk(ChunkPNG, "IHDR")
+	w.WriteUint32BigEndian(1)
+	w.WriteUint32BigEndian(1)
+	w.WriteBytes([]byte{8, 0, 0, 0, 0})
+	if n := w.EndChunk(); n != 13 {
+		t.Fatalf("unexpected value obtained; got %v want %v", n, 13)
+	}
+	w.BeginChunk(ChunkPNG, "IEND")
+	w.EndChunk()
+	data := w.Bytes()
+	//the well known IEND chunk
+	if got := fmt.Sprintf("%x", data[len(data)-12:]); got != "0000000049454e44ae426082" {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, "0000000049454e44ae426082")
+	}
+	rd := NewReadSeekerFromBytes(data)
+	rd.MoveTo(8)
+	walker := rd.Chunks(ChunkPNG)
+	if !walker.Next() {
+		t.Fatalf("unexpected value obtained; got %v want %v", walker.Err(), "IHDR")
+	}
+	ihdr := walker.Chunk()
+	width, _ := ihdr.Data.ReadUint32BigEndian()
+	if ihdr.Tag != "IHDR" || ihdr.Offset != 8 || ihdr.Size != 13 || width != 1 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", ihdr, "IHDR")
+	}
+	if got := walkChunks(t, walker); len(got) != 1 || got[0] != "0 IEND 0" {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, "0 IEND 0")
+	}
+	if pos, _ := rd.CurPos(); pos != 8 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 8)
+	}
+	//a bad crc32
+	data[len(data)-1]++
+	walker = NewReadSeekerFromBytes(data).ChunksGen(8, int64(len(data))-1, ChunkPNG)
+	for walker.Next() {
+	}
+	if !errors.Is(walker.Err(), ErrChecksumMismatch) {
+		t.Fatalf("unexpected value obtained; got %v want %v", walker.Err(), ErrChecksumMismatch)
+	}
+	//an element kept in memory has no position for the length
+	w = NewBytesBuffer()
+	w.BeginASN1Sequence()
+	if !panics(func() { w.BeginChunk(ChunkPNG, "tEXt") }) {
+		t.Fatalf("unexpected value obtained; got %v want %v", false, "a panic")
+	}
+	w = NewBytesBuffer()
+	w.BeginChunk(ChunkPNG, "tEXt")
+	w.BeginProtoMessage(1)
+	if !panics(func() { w.EndChunk() }) {
+		t.Fatalf("unexpected value obtained; got %v want %v", false, "a panic")
+	}
+}
+
+func TestChunkRIFF(t *testing.T) {
+	w := NewBytesBuffer()
+	w.BeginChunk(ChunkRIFF, "RIFF")
+	w.WriteString("WAVE")
+	w.BeginChunk(ChunkRIFF, "fmt ")
+	w.WriteZeros(16)
+	w.EndChunk()
+	w.BeginChunk(ChunkRIFF, "LIST")
+	w.WriteString("INFO")
+	w.BeginChunk(ChunkRIFF, "ISFT")
+	w.WriteString("iox")
+	w.EndChunk()
+	w.EndChunk()
+	w.BeginChunk(ChunkRIFF, "data")
+	w.WriteString("12345")
+	w.EndChunk()
+	if n := w.EndChunk(); n != w.Len()-8 {
+		t.Fatalf("unexpected value obtained; got %v want %v", n, w.Len()-8)
+	}
+	//the odd chunks are padded
+	if w.Len() != 4+4+4+8+16+8+4+8+4+8+6 {
+		t.Fatalf("unexpected value obtained; got %v want %v", w.Len(), 74)
+	}
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	got := fmt.Sprint(walkChunks(t, rd.Chunks(ChunkRIFF)))
+	if want := "[0 RIFF 66 1 fmt  16 1 LIST 16 2 ISFT 3 1 data 5]"; got != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	//the padding of the last chunk may be missing
+	data := w.Bytes()[:w.Len()-1]
+	got = fmt.Sprint(walkChunks(t, NewReadSeekerFromBytes(data).ChunksGen(12, int64(len(data))-1, ChunkRIFF)))
+	if want := "[0 fmt  16 0 LIST 16 1 ISFT 3 0 data 5]"; got != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	//a chunk longer than its parent
+	data = w.Bytes()[:w.Len()-3]
+	walker := NewReadSeekerFromBytes(data).ChunksGen(12, int64(len(data))-1, ChunkRIFF)
+	for walker.Next() {
+	}
+	if !errors.Is(walker.Err(), io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", walker.Err(), io.ErrUnexpectedEOF)
+	}
+}
+
+func TestChunkBMFF(t *testing.T) {
+	w := NewBytesBuffer()
+	w.BeginChunk(ChunkBMFF, "ftyp")
+	w.WriteString("isom")
+	w.WriteUint32BigEndian(0)
+	w.EndChunk()
+	w.BeginChunk(ChunkBMFF, "moov")
+	w.BeginChunk(ChunkBMFF, "meta")
+	w.WriteUint32BigEndian(0) //version and flags
+	w.BeginChunk(ChunkBMFF, "hdlr")
+	w.WriteZeros(3)
+	w.EndChunk()
+	w.EndChunk()
+	w.EndChunk()
+	//a box with a large size
+	w.WriteUint32BigEndian(1)
+	w.WriteString("free")
+	w.WriteUint64BigEndian(16 + 2)
+	w.WriteZeros(2)
+	//a box up to the end
+	w.WriteUint32BigEndian(0)
+	w.WriteString("mdat")
+	w.WriteString("payload")
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	got := fmt.Sprint(walkChunks(t, rd.Chunks(ChunkBMFF)))
+	if want := "[0 ftyp 8 0 moov 23 1 meta 15 2 hdlr 3 0 free 2 0 mdat 7]"; got != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	if length := bytesToUint(w.Bytes()[16:20], true); length != 31 {
+		t.Fatalf("unexpected value obtained; got %v want %v", length, 31)
+	}
+	//a length shorter than the header
+	walker := NewReadSeekerFromBytes([]byte("\x00\x00\x00\x04free")).Chunks(ChunkBMFF)
+	if walker.Next() || !errors.Is(walker.Err(), ErrChunkLength) {
+		t.Fatalf("unexpected value obtained; got %v want %v", walker.Err(), ErrChunkLength)
+	}
+}
@@ -0,0 +1,602 @@
+//iox inspects binary files with the ReadSeeker of github.com/yudeguang/iox.
+//
+//	iox hexdump [-offset N] [-length N] [-width N] [-squeeze] file
+//	iox find    [-hex HEX | -string S] [-all | -last] [-offset N] [-length N] file
+//	iox count   [-hex HEX | -string S] [-offset N] [-length N] file
+//	iox read    file TYPE@OFFSET...     such as u32be@0x40,str[4]@0 or bytes[16]@-16
+//	iox extract [-offset N] [-length N] [-o out] file
+//	iox strings [-min N] [-encoding E] [-offset N] [-length N] file
+//	iox diff    [-block N] [-width N] [-full] [-patch out] src dst
+//	iox patch   [-o out] src patchfile
+//
+//numbers may be decimal or 0x hex,a negative offset counts from the end of the file.
+//every command takes -json to print machine-readable JSON instead of text.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yudeguang/iox"
+)
+
+const usage = `usage: iox <command> [flags] file
+
+commands:
+  hexdump  dump a range like hexdump -C
+  find     find the offsets of a hex or string pattern
+  count    count the non-overlapping instances of a pattern
+  read     decode typed values such as u32be@0x40
+  extract  copy a byte range into a file or stdout
+  strings  print the runs of printable characters
+  diff     compare two files side by side and make a patch
+  patch    apply a patch made by diff
+
+run "iox <command> -h" for the flags of a command.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "iox:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return flag.ErrHelp
+	}
+	commands := map[string]func(*command) error{
+		"hexdump": hexdumpCommand,
+		"find":    findCommand,
+		"count":   countCommand,
+		"read":    readCommand,
+		"extract": extractCommand,
+		"strings": stringsCommand,
+		"diff":    diffCommand,
+		"patch":   patchCommand,
+	}
+	f, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	c := &command{name: args[0], args: args[1:], stdout: stdout}
+	c.flags = flag.NewFlagSet("iox "+args[0], flag.ContinueOnError)
+	c.flags.BoolVar(&c.json, "json", false, "print JSON")
+	return f(c)
+}
+
+//command holds what every command shares.
+type command struct {
+	name   string
+	args   []string
+	stdout io.Writer
+	flags  *flag.FlagSet
+	json   bool
+	offset string
+	length string
+	r      *iox.ReadSeeker
+}
+
+//add the -offset and -length flags.
+func (c *command) rangeFlags() {
+	c.flags.StringVar(&c.offset, "offset", "0", "the first byte,negative counts from the end")
+	c.flags.StringVar(&c.length, "length", "", "the number of bytes,default up to the end")
+}
+
+//parse the flags and open the file,the file name may be followed by nargs more arguments.
+func (c *command) parse(nargs int) error {
+	if err := c.flags.Parse(c.args); err != nil {
+		return err
+	}
+	if c.flags.NArg() < 1 || nargs >= 0 && c.flags.NArg() != 1+nargs {
+		c.flags.Usage()
+		return fmt.Errorf("%v: wrong number of arguments", c.name)
+	}
+	r, err := iox.NewReadSeekerFromFile(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	c.r = r
+	return nil
+}
+
+//parseNumber parses a decimal or 0x hex number.
+func parseNumber(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	v, err := strconv.ParseInt(strings.TrimPrefix(s, "-"), 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+//resolve an offset that may count from the end.
+func (c *command) position(s string) (int64, error) {
+	pos, err := parseNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	size := c.r.Size()
+	if pos < 0 {
+		pos += size
+	}
+	if pos < 0 || pos > size {
+		return 0, fmt.Errorf("the offset %v is outside of the file of %v bytes", s, size)
+	}
+	return pos, nil
+}
+
+//returns the range of -offset and -length as beginPos and endPos(both included),
+//endPos is beginPos-1 for an empty range.
+func (c *command) byteRange() (int64, int64, error) {
+	beginPos, err := c.position(c.offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	endPos := c.r.Size() - 1
+	if c.length != "" {
+		length, err := parseNumber(c.length)
+		if err != nil {
+			return 0, 0, err
+		}
+		if length < 0 || beginPos+length-1 > endPos {
+			return 0, 0, fmt.Errorf("the length %v is outside of the file of %v bytes", c.length, c.r.Size())
+		}
+		endPos = beginPos + length - 1
+	}
+	return beginPos, endPos, nil
+}
+
+func (c *command) printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "%s\n", b)
+	return err
+}
+
+func hexdumpCommand(c *command) error {
+	c.rangeFlags()
+	width := c.flags.Int("width", 16, "bytes per line")
+	squeeze := c.flags.Bool("squeeze", false, "replace repeated lines with *")
+	if err := c.parse(0); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	if *width <= 0 {
+		return fmt.Errorf("hexdump: the width must be positive")
+	}
+	beginPos, endPos, err := c.byteRange()
+	if err != nil {
+		return err
+	}
+	if !c.json {
+		if endPos < beginPos {
+			return nil
+		}
+		return c.r.WriteHexDump(c.stdout, beginPos, endPos, iox.HexDumpOptions{Width: *width, Squeeze: *squeeze})
+	}
+	type line struct {
+		Offset int64  `json:"offset"`
+		Hex    string `json:"hex"`
+		ASCII  string `json:"ascii"`
+	}
+	lines := []line{}
+	c.r.MoveTo(beginPos)
+	for pos := beginPos; pos <= endPos; pos += int64(*width) {
+		n := int64(*width)
+		if endPos-pos+1 < n {
+			n = endPos - pos + 1
+		}
+		bt, err := c.r.ReadBytes(int(n))
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line{Offset: pos, Hex: hex.EncodeToString(bt), ASCII: printable(bt)})
+	}
+	return c.printJSON(lines)
+}
+
+//replace the bytes that are not printable ASCII with dots.
+func printable(bt []byte) string {
+	b := make([]byte, len(bt))
+	for i, c := range bt {
+		if c >= 0x20 && c < 0x7f {
+			b[i] = c
+		} else {
+			b[i] = '.'
+		}
+	}
+	return string(b)
+}
+
+//add the -hex and -string flags and returns the pattern once the flags are parsed.
+func (c *command) patternFlags() func() ([]byte, error) {
+	hexPattern := c.flags.String("hex", "", "the pattern in hex,such as \"89 50 4e 47\"")
+	strPattern := c.flags.String("string", "", "the pattern as a string")
+	return func() ([]byte, error) {
+		if (*hexPattern == "") == (*strPattern == "") {
+			return nil, fmt.Errorf("%v: give either -hex or -string", c.name)
+		}
+		if *strPattern != "" {
+			return []byte(*strPattern), nil
+		}
+		sep, err := hex.DecodeString(strings.Join(strings.Fields(*hexPattern), ""))
+		if err == nil && len(sep) == 0 {
+			err = fmt.Errorf("%v: the pattern is empty", c.name)
+		}
+		return sep, err
+	}
+}
+
+func findCommand(c *command) error {
+	c.rangeFlags()
+	pattern := c.patternFlags()
+	all := c.flags.Bool("all", false, "print every non-overlapping match")
+	last := c.flags.Bool("last", false, "print the last match instead of the first")
+	if err := c.parse(0); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	sep, err := pattern()
+	if err != nil {
+		return err
+	}
+	beginPos, endPos, err := c.byteRange()
+	if err != nil {
+		return err
+	}
+	offsets := []int64{}
+	switch {
+	case endPos < beginPos:
+	case *all:
+		offsets = append(offsets, c.r.IndexAllGen(beginPos, endPos, sep)...)
+	case *last:
+		if i := c.r.LastIndexGen(beginPos, endPos, sep); i >= 0 {
+			offsets = append(offsets, i)
+		}
+	default:
+		if i := c.r.IndexGen(beginPos, endPos, sep); i >= 0 {
+			offsets = append(offsets, i)
+		}
+	}
+	if c.json {
+		return c.printJSON(struct {
+			Pattern string  `json:"pattern"`
+			Offsets []int64 `json:"offsets"`
+		}{hex.EncodeToString(sep), offsets})
+	}
+	for _, offset := range offsets {
+		fmt.Fprintf(c.stdout, "%v\t0x%08x\n", offset, offset)
+	}
+	if len(offsets) == 0 {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func countCommand(c *command) error {
+	c.rangeFlags()
+	pattern := c.patternFlags()
+	if err := c.parse(0); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	sep, err := pattern()
+	if err != nil {
+		return err
+	}
+	beginPos, endPos, err := c.byteRange()
+	if err != nil {
+		return err
+	}
+	var count int64
+	if endPos >= beginPos {
+		count = c.r.CountGen(beginPos, endPos, sep)
+	}
+	if c.json {
+		return c.printJSON(struct {
+			Pattern string `json:"pattern"`
+			Count   int64  `json:"count"`
+		}{hex.EncodeToString(sep), count})
+	}
+	_, err = fmt.Fprintln(c.stdout, count)
+	return err
+}
+
+func readCommand(c *command) error {
+	if err := c.parse(-1); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	type value struct {
+		Spec   string      `json:"spec"`
+		Offset int64       `json:"offset"`
+		Type   string      `json:"type"`
+		Value  interface{} `json:"value"`
+	}
+	if c.flags.NArg() < 2 {
+		c.flags.Usage()
+		return fmt.Errorf("read: give at least one TYPE@OFFSET")
+	}
+	var values []value
+	for _, spec := range c.flags.Args()[1:] {
+		i := strings.LastIndexByte(spec, '@')
+		if i < 0 {
+			return fmt.Errorf("%q is not TYPE@OFFSET", spec)
+		}
+		typ := spec[:i]
+		offset, err := c.position(spec[i+1:])
+		if err != nil {
+			return err
+		}
+		//a type is a field of a schema,so every schema type works
+		schema := iox.NewSchema(iox.NewField(typ, "value"))
+		if err = schema.Validate(); err != nil {
+			return err
+		}
+		c.r.MoveTo(offset)
+		vals, err := c.r.ReadSchema(schema)
+		if err != nil {
+			return fmt.Errorf("%v: %w", spec, err)
+		}
+		values = append(values, value{Spec: spec, Offset: offset, Type: typ, Value: jsonValue(vals["value"])})
+	}
+	if c.json {
+		return c.printJSON(values)
+	}
+	for _, v := range values {
+		text := fmt.Sprint(v.Value)
+		switch x := v.Value.(type) {
+		case string:
+			if !strings.HasPrefix(v.Type, "str") {
+				break
+			}
+			text = strconv.Quote(x)
+		case []interface{}:
+			parts := make([]string, len(x))
+			for i, elem := range x {
+				parts[i] = fmt.Sprint(elem)
+			}
+			text = "[" + strings.Join(parts, " ") + "]"
+		case uint8, uint16, uint32, uint64:
+			text = fmt.Sprintf("%v (0x%x)", x, x)
+		}
+		fmt.Fprintf(c.stdout, "%v\t%v\n", v.Spec, text)
+	}
+	return nil
+}
+
+//make a decoded value printable as JSON,[]byte become hex and NaN or Inf become strings.
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []byte:
+		return hex.EncodeToString(x)
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return fmt.Sprint(x)
+		}
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Sprint(x)
+		}
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, elem := range x {
+			out[i] = jsonValue(elem)
+		}
+		return out
+	}
+	return v
+}
+
+func extractCommand(c *command) error {
+	c.rangeFlags()
+	out := c.flags.String("o", "", "the output file,default stdout")
+	if err := c.parse(0); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	beginPos, endPos, err := c.byteRange()
+	if err != nil {
+		return err
+	}
+	if c.json {
+		var bt []byte
+		if endPos >= beginPos {
+			c.r.MoveTo(beginPos)
+			if bt, err = c.r.ReadBytes(int(endPos - beginPos + 1)); err != nil {
+				return err
+			}
+		}
+		return c.printJSON(struct {
+			Offset int64  `json:"offset"`
+			Length int64  `json:"length"`
+			Hex    string `json:"hex"`
+		}{beginPos, endPos - beginPos + 1, hex.EncodeToString(bt)})
+	}
+	if *out == "" {
+		return c.chunks(beginPos, endPos, func(pos int64, bt []byte) error {
+			_, err := c.stdout.Write(bt)
+			return err
+		})
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = c.chunks(beginPos, endPos, func(pos int64, bt []byte) error {
+		_, err := file.Write(bt)
+		return err
+	})
+	//the data may only reach the disk at Close,so its error counts too
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//call f with the data between beginPos and endPos(both included) in chunks of 64KB.
+func (c *command) chunks(beginPos, endPos int64, f func(pos int64, bt []byte) error) error {
+	c.r.MoveTo(beginPos)
+	for pos := beginPos; pos <= endPos; {
+		n := endPos - pos + 1
+		if n > 64<<10 {
+			n = 64 << 10
+		}
+		bt, err := c.r.ReadBytes(int(n))
+		if err != nil {
+			return err
+		}
+		if err = f(pos, bt); err != nil {
+			return err
+		}
+		pos += n
+	}
+	return nil
+}
+
+func stringsCommand(c *command) error {
+	c.rangeFlags()
+	minLen := c.flags.Int("min", 4, "the shortest run printed")
+	encoding := c.flags.String("encoding", "ascii", "ascii,utf8,utf16le or utf16be")
+	if err := c.parse(0); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	opts := iox.StringsOptions{Encoding: -1}
+	for e := iox.StringASCII; e <= iox.StringUTF16BE; e++ {
+		if e.String() == *encoding {
+			opts.Encoding = e
+		}
+	}
+	if opts.Encoding < 0 {
+		return fmt.Errorf("strings: the encoding %q is not known", *encoding)
+	}
+	beginPos, endPos, err := c.byteRange()
+	if err != nil {
+		return err
+	}
+	type run struct {
+		Offset int64  `json:"offset"`
+		Length int64  `json:"length"`
+		String string `json:"string"`
+	}
+	runs := []run{}
+	err = c.r.StringsFunc(beginPos, endPos, *minLen, opts, func(s iox.FoundString) error {
+		if c.json {
+			runs = append(runs, run{s.Offset, s.Length, s.Text})
+			return nil
+		}
+		_, err := fmt.Fprintf(c.stdout, "%8x %v\n", s.Offset, s.Text)
+		return err
+	})
+	if err != nil || !c.json {
+		return err
+	}
+	return c.printJSON(runs)
+}
+
+func diffCommand(c *command) error {
+	block := c.flags.Int("block", 32, "the length of the blocks matched to resync")
+	width := c.flags.Int("width", 16, "bytes per line")
+	full := c.flags.Bool("full", false, "show the equal ranges in full instead of their first and last line")
+	patch := c.flags.String("patch", "", "write a patch that turns src into dst into this file")
+	if err := c.parse(1); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	if *width <= 0 {
+		return fmt.Errorf("diff: the width must be positive")
+	}
+	dst, err := iox.NewReadSeekerFromFile(c.flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	opts := iox.DiffOptions{BlockSize: *block}
+	if *patch != "" {
+		p, err := iox.MakePatch(c.r, dst, opts)
+		if err != nil {
+			return err
+		}
+		w := iox.NewBytesBuffer()
+		w.WritePatch(p)
+		if err = os.WriteFile(*patch, w.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	ranges, err := iox.Diff(c.r, dst, opts)
+	if err != nil {
+		return err
+	}
+	if !c.json {
+		return iox.WriteDiffHexDump(c.stdout, c.r, dst, ranges, iox.HexDumpOptions{Width: *width, Squeeze: !*full})
+	}
+	type diffRange struct {
+		Op        string `json:"op"`
+		SrcOffset int64  `json:"srcOffset"`
+		SrcLength int64  `json:"srcLength"`
+		DstOffset int64  `json:"dstOffset"`
+		DstLength int64  `json:"dstLength"`
+	}
+	out := []diffRange{}
+	for _, rg := range ranges {
+		out = append(out, diffRange{rg.Op.String(), rg.SrcOffset, rg.SrcLength, rg.DstOffset, rg.DstLength})
+	}
+	return c.printJSON(out)
+}
+
+func patchCommand(c *command) error {
+	out := c.flags.String("o", "", "the output file,default stdout")
+	if err := c.parse(1); err != nil {
+		return err
+	}
+	defer c.r.Close()
+	rd, err := iox.NewReadSeekerFromFile(c.flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	p, err := rd.ReadPatch()
+	if err != nil {
+		return err
+	}
+	w := iox.NewBytesBuffer()
+	if err = w.ApplyPatch(c.r, p); err != nil {
+		return err
+	}
+	if *out != "" {
+		if err = os.WriteFile(*out, w.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	if c.json {
+		return c.printJSON(struct {
+			Size  int64  `json:"size"`
+			CRC32 uint32 `json:"crc32"`
+			Ops   int    `json:"ops"`
+		}{p.DstSize, p.DstCRC32, len(p.Ops)})
+	}
+	if *out == "" {
+		_, err = c.stdout.Write(w.Bytes())
+	}
+	return err
+}
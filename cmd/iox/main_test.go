@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testFile(t *testing.T) string {
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR\x00\x00\x01\x00hello world\x00\x00PNG!")
+	name := filepath.Join(t.TempDir(), "test.bin")
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	return name
+}
+
+func runOutput(t *testing.T, args ...string) string {
+	var buf bytes.Buffer
+	if err := run(args, &buf); err != nil {
+		t.Fatalf("%v: unexpected value obtained; got %v want %v", args, err, nil)
+	}
+	return buf.String()
+}
+
+func TestCommands(t *testing.T) {
+	name := testFile(t)
+	for _, c := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"hexdump", "-length", "8", name}, "00000000  89 50 4e 47 0d 0a 1a 0a                           |.PNG....|\n00000008\n"},
+		{[]string{"find", "-string", "PNG", "-all", name}, "1\t0x00000001\n33\t0x00000021\n"},
+		{[]string{"find", "-hex", "50 4e 47", "-last", name}, "33\t0x00000021\n"},
+		{[]string{"find", "-string", "PNG", "-offset", "2", name}, "33\t0x00000021\n"},
+		{[]string{"count", "-hex", "00", "-offset", "-6", name}, "2\n"},
+		{[]string{"read", name, "u32be@8", "str[4]@0xc", "u16@-20", "bytes[2]@0"}, "u32be@8\t13 (0xd)\nstr[4]@0xc\t\"IHDR\"\nu16@-20\t256 (0x100)\nbytes[2]@0\t8950\n"},
+		{[]string{"extract", "-offset", "20", "-length", "5", name}, "hello"},
+		{[]string{"strings", name}, "       c IHDR\n      14 hello world\n      21 PNG!\n"},
+		{[]string{"strings", "-encoding", "utf8", "-min", "5", "-offset", "16", name}, "      14 hello world\n"},
+	} {
+		if got := runOutput(t, c.args...); got != c.want {
+			t.Fatalf("%v: unexpected value obtained; got %q want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestCommandsJSON(t *testing.T) {
+	name := testFile(t)
+	var found struct {
+		Pattern string
+		Offsets []int64
+	}
+	if err := json.Unmarshal([]byte(runOutput(t, "find", "-json", "-all", "-string", "PNG", name)), &found); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if found.Pattern != "504e47" || len(found.Offsets) != 2 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", found, "2 offsets of 504e47")
+	}
+	var values []struct {
+		Offset int64
+		Value  interface{}
+	}
+	if err := json.Unmarshal([]byte(runOutput(t, "read", "-json", name, "u8[2]@0", "bytes[4]@0")), &values); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if len(values) != 2 || values[1].Value != "89504e47" {
+		t.Fatalf("unexpected value obtained; got %+v want %v", values, "89504e47")
+	}
+	var runs []struct {
+		Offset int64
+		String string
+	}
+	if err := json.Unmarshal([]byte(runOutput(t, "strings", "-json", "-min", "5", name)), &runs); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if len(runs) != 1 || runs[0].String != "hello world" {
+		t.Fatalf("unexpected value obtained; got %+v want %v", runs, "hello world")
+	}
+	if out := runOutput(t, "count", "-json", "-string", "l", name); !strings.Contains(out, `"count": 3`) {
+		t.Fatalf("unexpected value obtained; got %v want %v", out, `"count": 3`)
+	}
+	if out := runOutput(t, "hexdump", "-json", "-offset", "20", "-length", "5", name); !strings.Contains(out, `"ascii": "hello"`) {
+		t.Fatalf("unexpected value obtained; got %v want %v", out, `"ascii": "hello"`)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	name := testFile(t)
+	for _, args := range [][]string{
+		{"nothing", name},
+		{"find", name},
+		{"find", "-string", "zzz", name},
+		{"read", name, "u128@0"},
+		{"read", name, "u32@100"},
+		{"read", name, "u32@36"},
+		{"extract", "-length", "100", name},
+		{"strings", "-encoding", "utf32", name},
+		{"hexdump", filepath.Join(t.TempDir(), "missing")},
+	} {
+		if err := run(args, new(bytes.Buffer)); err == nil {
+			t.Fatalf("%v: unexpected value obtained; got %v want an error", args, err)
+		}
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.bin"), filepath.Join(dir, "dst.bin")
+	os.WriteFile(src, []byte("Hello world."), 0644)
+	os.WriteFile(dst, []byte("Hello, world!"), 0644)
+	patch, out := filepath.Join(dir, "patch.bin"), filepath.Join(dir, "out.bin")
+	got := runOutput(t, "diff", "-block", "4", "-patch", patch, src, dst)
+	for _, want := range []string{">  00000005  2c ", "|.|                 |  0000000c  21 ", "0000000c                    "} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("unexpected value obtained; %q is missing from\n%v", want, got)
+		}
+	}
+	if got := runOutput(t, "patch", "-o", out, src, patch); got != "" {
+		t.Fatalf("unexpected value obtained; got %q want %q", got, "")
+	}
+	if b, _ := os.ReadFile(out); string(b) != "Hello, world!" {
+		t.Fatalf("unexpected value obtained; got %q want %q", b, "Hello, world!")
+	}
+	if out := runOutput(t, "diff", "-json", src, dst); !strings.Contains(out, `"op": "replace"`) {
+		t.Fatalf("unexpected value obtained; got %v want %v", out, `"op": "replace"`)
+	}
+	//the patch does not fit another source
+	if err := run([]string{"patch", dst, patch}, new(bytes.Buffer)); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
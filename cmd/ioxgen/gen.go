@@ -0,0 +1,721 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindNumber kind = iota
+	kindString
+	kindBytes
+	kindStruct
+	kindArray
+	kindSlice
+)
+
+//the builtin numbers,method is the name used by the iox methods.
+var numbers = map[string]struct {
+	builtin string
+	method  string
+	size    int
+}{
+	"uint8":   {"uint8", "Uint8", 1},
+	"byte":    {"uint8", "Uint8", 1},
+	"int8":    {"int8", "Int8", 1},
+	"uint16":  {"uint16", "Uint16", 2},
+	"int16":   {"int16", "Int16", 2},
+	"uint32":  {"uint32", "Uint32", 4},
+	"int32":   {"int32", "Int32", 4},
+	"uint64":  {"uint64", "Uint64", 8},
+	"int64":   {"int64", "Int64", 8},
+	"float32": {"float32", "Float32", 4},
+	"float64": {"float64", "Float64", 8},
+}
+
+//the length prefixes of the prefix option.
+var prefixes = map[string]struct {
+	method string
+	max    string
+}{
+	"u8":  {"Uint8", "0xff"},
+	"u16": {"Uint16", "0xffff"},
+	"u32": {"Uint32", "0xffffffff"},
+	"u64": {"Uint64", ""},
+}
+
+//goType is a type supported by the generator.
+type goType struct {
+	kind   kind
+	name   string //the type as written in the source
+	number string //the builtin type of a number,uint8 for byte
+	named  bool   //a named number,string or []byte,values need a conversion
+	elem   *goType
+	length string //the length of an array
+}
+
+type field struct {
+	name      string
+	typ       *goType
+	bigEndian bool
+	length    string //"*",a number or the name of an earlier field
+	lenField  *field //the earlier field of length
+	prefix    string
+}
+
+type structType struct {
+	name   string
+	fields []*field
+}
+
+type generator struct {
+	pkg     string
+	ioxPath string
+	decls   map[string]ast.Expr //every type declared in the package
+	structs map[string]*structType
+	order   []string
+}
+
+func newGenerator(files []*ast.File, names []string, ioxPath string) (*generator, error) {
+	g := &generator{
+		pkg:     files[0].Name.Name,
+		ioxPath: ioxPath,
+		decls:   make(map[string]ast.Expr),
+		structs: make(map[string]*structType),
+	}
+	var tagged []string
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				g.decls[ts.Name.Name] = ts.Type
+				if st, ok := ts.Type.(*ast.StructType); ok && hasIoxTag(st) {
+					tagged = append(tagged, ts.Name.Name)
+				}
+			}
+		}
+	}
+	if names == nil {
+		names = tagged
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("package %v has no struct with an iox tag,name the types with -type", g.pkg)
+	}
+	for _, name := range names {
+		if err := g.addStruct(strings.TrimSpace(name)); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func hasIoxTag(st *ast.StructType) bool {
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		raw, _ := strconv.Unquote(f.Tag.Value)
+		if _, ok := reflect.StructTag(raw).Lookup("iox"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) addStruct(name string) error {
+	if _, ok := g.structs[name]; ok {
+		return nil
+	}
+	st, ok := g.decls[name].(*ast.StructType)
+	if !ok {
+		return fmt.Errorf("%v is not a struct type of package %v", name, g.pkg)
+	}
+	s := &structType{name: name}
+	//registered first so that a struct can contain a slice of itself
+	g.structs[name] = s
+	g.order = append(g.order, name)
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			raw, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(raw).Get("iox")
+		}
+		if tag == "-" {
+			continue
+		}
+		if len(f.Names) == 0 {
+			return fmt.Errorf("%v: the embedded field %v is not supported,tag it with iox:\"-\"", name, types.ExprString(f.Type))
+		}
+		typ, err := g.resolve(f.Type)
+		if err != nil {
+			return fmt.Errorf("%v.%v: %v", name, f.Names[0].Name, err)
+		}
+		for _, ident := range f.Names {
+			if ident.Name == "_" {
+				return fmt.Errorf("%v: blank fields are not supported,use a named field for padding", name)
+			}
+			fd := &field{name: ident.Name, typ: typ}
+			if err = parseTag(fd, tag); err != nil {
+				return fmt.Errorf("%v.%v: %v", name, ident.Name, err)
+			}
+			s.fields = append(s.fields, fd)
+		}
+	}
+	return s.check()
+}
+
+func parseTag(f *field, tag string) error {
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "":
+		case opt == "be":
+			f.bigEndian = true
+		case opt == "le":
+			f.bigEndian = false
+		case strings.HasPrefix(opt, "len="):
+			f.length = strings.TrimPrefix(opt, "len=")
+		case strings.HasPrefix(opt, "prefix="):
+			f.prefix = strings.TrimPrefix(opt, "prefix=")
+			if _, ok := prefixes[f.prefix]; !ok {
+				return fmt.Errorf("the prefix %v is not one of u8,u16,u32 and u64", f.prefix)
+			}
+		default:
+			return fmt.Errorf("the option %q is not known", opt)
+		}
+	}
+	return nil
+}
+
+//check the options of the fields.
+func (s *structType) check() error {
+	for i, f := range s.fields {
+		needsLen := f.typ.kind == kindString || f.typ.kind == kindBytes || f.typ.kind == kindSlice
+		if !needsLen {
+			if f.length != "" || f.prefix != "" {
+				return fmt.Errorf("%v.%v: len and prefix are only for strings,[]byte and slices", s.name, f.name)
+			}
+		} else if (f.length == "") == (f.prefix == "") {
+			return fmt.Errorf("%v.%v: a %v needs either len= or prefix=", s.name, f.name, f.typ.name)
+		}
+		if n, err := strconv.Atoi(f.length); f.length == "*" {
+			if i != len(s.fields)-1 {
+				return fmt.Errorf("%v.%v: len=* is only allowed on the last field", s.name, f.name)
+			}
+		} else if err == nil && n < 0 {
+			return fmt.Errorf("%v.%v: the length %v is negative", s.name, f.name, n)
+		} else if err != nil && f.length != "" {
+			if f.lenField = s.lengthField(f.length, i); f.lenField == nil {
+				return fmt.Errorf("%v.%v: len=%v is not an earlier number field", s.name, f.name, f.length)
+			}
+		}
+		if f.typ.elem != nil {
+			if err := checkElem(f.typ.elem); err != nil {
+				return fmt.Errorf("%v.%v: %v", s.name, f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+//returns the number field name before the i-th field.
+func (s *structType) lengthField(name string, i int) *field {
+	for _, f := range s.fields[:i] {
+		if f.name == name && f.typ.kind == kindNumber && !strings.HasPrefix(f.typ.number, "float") {
+			return f
+		}
+	}
+	return nil
+}
+
+//the elements of arrays and slices can't have a length of their own.
+func checkElem(t *goType) error {
+	switch t.kind {
+	case kindString, kindBytes, kindSlice:
+		return fmt.Errorf("elements of type %v need a length,wrap them in a struct", t.name)
+	case kindArray:
+		return checkElem(t.elem)
+	}
+	return nil
+}
+
+func (g *generator) resolve(expr ast.Expr) (*goType, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return g.resolve(e.X)
+	case *ast.Ident:
+		if n, ok := numbers[e.Name]; ok {
+			return &goType{kind: kindNumber, name: e.Name, number: n.builtin}, nil
+		}
+		if e.Name == "string" {
+			return &goType{kind: kindString, name: e.Name}, nil
+		}
+		decl, ok := g.decls[e.Name]
+		if !ok {
+			break
+		}
+		if _, ok := decl.(*ast.StructType); ok {
+			if err := g.addStruct(e.Name); err != nil {
+				return nil, err
+			}
+			return &goType{kind: kindStruct, name: e.Name}, nil
+		}
+		u, err := g.resolve(decl)
+		if err != nil {
+			return nil, err
+		}
+		if u.kind == kindStruct {
+			return nil, fmt.Errorf("%v is defined from the struct %v,which gives it no methods", e.Name, u.name)
+		}
+		t := *u
+		t.name, t.named = e.Name, true
+		return &t, nil
+	case *ast.ArrayType:
+		elem, err := g.resolve(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if e.Len != nil {
+			return &goType{kind: kindArray, name: types.ExprString(e), elem: elem, length: types.ExprString(e.Len)}, nil
+		}
+		if isByte(elem) {
+			return &goType{kind: kindBytes, name: "[]byte"}, nil
+		}
+		return &goType{kind: kindSlice, name: types.ExprString(e), elem: elem}, nil
+	}
+	return nil, fmt.Errorf("the type %v is not supported", types.ExprString(expr))
+}
+
+func isByte(t *goType) bool {
+	return t.kind == kindNumber && t.number == "uint8" && !t.named
+}
+
+//emitter collects the generated code of a function.
+type emitter struct {
+	buf     bytes.Buffer
+	usesErr bool //the function needs "var err error"
+}
+
+//line writes a line of code,format is written as it is if there are no args.
+func (e *emitter) line(format string, args ...interface{}) {
+	if len(args) == 0 {
+		e.buf.WriteString(format)
+	} else {
+		fmt.Fprintf(&e.buf, format, args...)
+	}
+	e.buf.WriteByte('\n')
+}
+
+//errPath is the format and arguments naming a value in error messages,such as "Header.Items[%v]",i.
+type errPath struct {
+	format string
+	args   []string
+}
+
+func (p errPath) index(i string) errPath {
+	return errPath{format: p.format + "[%v]", args: append(append([]string{}, p.args...), i)}
+}
+
+//returns the statement returning an error with message appended to the path.
+func (p errPath) errorf(message string, args ...string) string {
+	all := append(append([]string{}, p.args...), args...)
+	if len(all) == 0 {
+		return fmt.Sprintf("return fmt.Errorf(%q)", p.format+": "+message)
+	}
+	return fmt.Sprintf("return fmt.Errorf(%q, %v)", p.format+": "+message, strings.Join(all, ", "))
+}
+
+func loopVar(depth int) string {
+	return string(rune('i' + depth))
+}
+
+func numberMethod(t *goType, bigEndian bool) string {
+	n := numbers[t.number]
+	if bigEndian && n.size > 1 {
+		return n.method + "BigEndian"
+	}
+	return n.method
+}
+
+func prefixMethod(f *field) string {
+	method := prefixes[f.prefix].method
+	if f.bigEndian && f.prefix != "u8" {
+		method += "BigEndian"
+	}
+	return method
+}
+
+//the expression of the length of f,empty for len=* and prefixes.
+func lengthExpr(f *field) string {
+	if _, err := strconv.Atoi(f.length); err == nil {
+		return f.length
+	}
+	if f.length == "" || f.length == "*" {
+		return ""
+	}
+	return "int(x." + f.length + ")"
+}
+
+func (g *generator) read(e *emitter, t *goType, f *field, target string, path errPath, depth int) {
+	switch t.kind {
+	case kindNumber:
+		g.assign(e, t, target, "r.Read"+numberMethod(t, f.bigEndian)+"()", path)
+	case kindString, kindBytes:
+		what := "Bytes"
+		if t.kind == kindString {
+			what = "String"
+		}
+		call := ""
+		switch {
+		case f.prefix != "":
+			call = "r.Read" + what + prefixMethod(f) + "()"
+		case f.length == "*":
+			call = "r.Read" + what + "UnRead()"
+		default:
+			if f.lenField != nil {
+				checkCount(e, "x."+f.length, f.lenField.typ.number, path)
+			}
+			call = "r.Read" + what + "(" + lengthExpr(f) + ")"
+		}
+		g.assign(e, t, target, call, path)
+	case kindStruct:
+		e.usesErr = true
+		e.line("if err = %v.ReadFrom(r); err != nil {", target)
+		e.line("%s", path.errorf("%w", "err"))
+		e.line("}")
+	case kindArray:
+		if isByte(t.elem) {
+			e.line("{")
+			e.line("bt, err := r.ReadBytes(len(%v))", target)
+			e.line("if err != nil {")
+			e.line("%s", path.errorf("%w", "err"))
+			e.line("}")
+			e.line("copy(%v[:], bt)", target)
+			e.line("}")
+			return
+		}
+		i := loopVar(depth)
+		e.line("for %v := range %v {", i, target)
+		g.read(e, t.elem, f, target+"["+i+"]", path.index(i), depth+1)
+		e.line("}")
+	case kindSlice:
+		i := loopVar(depth)
+		if f.length == "*" {
+			e.line("%v = nil", target)
+			e.line("for %v := 0; r.LenUnRead() > 0; %v++ {", i, i)
+			e.line("%v = append(%v, make(%v, 1)...)", target, target, t.name)
+			g.read(e, t.elem, f, target+"["+i+"]", path.index(i), depth+1)
+			e.line("}")
+			return
+		}
+		e.line("{")
+		count := lengthExpr(f)
+		if f.prefix != "" {
+			e.line("n, err := r.Read%v()", prefixMethod(f))
+			e.line("if err != nil {")
+			e.line("%s", path.errorf("%w", "err"))
+			e.line("}")
+			checkCount(e, "n", "uint64", path)
+			count = "n"
+		} else if f.lenField != nil {
+			checkCount(e, "x."+f.length, f.lenField.typ.number, path)
+		}
+		e.line("%v = make(%v, %v)", target, t.name, count)
+		e.line("}")
+		e.line("for %v := range %v {", i, target)
+		g.read(e, t.elem, f, target+"["+i+"]", path.index(i), depth+1)
+		e.line("}")
+	}
+}
+
+//check a count or a length taken from the data before make gets it,the elements of a slice
+//take a byte at least,so a valid count is never bigger than the data left.
+//a negative one or one that overflows int would make the generated code panic.
+func checkCount(e *emitter, count, number string, path errPath) {
+	if strings.HasPrefix(number, "int") {
+		e.line("if %v < 0 || uint64(%v) > uint64(r.LenUnRead()) {", count, count)
+	} else {
+		e.line("if uint64(%v) > uint64(r.LenUnRead()) {", count)
+	}
+	e.line("%s", path.errorf("the length %v is negative or bigger than the data left", count))
+	e.line("}")
+}
+
+//assign the result of a read call to target.
+func (g *generator) assign(e *emitter, t *goType, target, call string, path errPath) {
+	if !t.named {
+		e.usesErr = true
+		e.line("if %v, err = %v; err != nil {", target, call)
+		e.line("%s", path.errorf("%w", "err"))
+		e.line("}")
+		return
+	}
+	e.line("{")
+	e.line("v, err := %v", call)
+	e.line("if err != nil {")
+	e.line("%s", path.errorf("%w", "err"))
+	e.line("}")
+	e.line("%v = %v(v)", target, t.name)
+	e.line("}")
+}
+
+//check that the length of target is the one given by len=.
+func checkLength(e *emitter, f *field, lenExpr string, path errPath) {
+	want := lengthExpr(f)
+	if want == "" {
+		return
+	}
+	e.line("if %v != %v {", lenExpr, want)
+	e.line("%s", path.errorf("the length is %v,but %v is expected", lenExpr, want))
+	e.line("}")
+}
+
+//check that the length of target fits the prefix.
+func checkPrefix(e *emitter, f *field, target string, path errPath) {
+	if max := prefixes[f.prefix].max; max != "" {
+		e.line("if uint64(len(%v)) > %v {", target, max)
+		e.line("%s", path.errorf("the length %v is too big for "+f.prefix, "len("+target+")"))
+		e.line("}")
+	}
+}
+
+func (g *generator) write(e *emitter, t *goType, f *field, target string, path errPath, depth int) {
+	switch t.kind {
+	case kindNumber:
+		e.line("w.Write%v(%v(%v))", numberMethod(t, f.bigEndian), t.number, target)
+	case kindString, kindBytes:
+		what, value := "Bytes", target
+		if t.kind == kindString {
+			what = "String"
+		}
+		if t.named {
+			value = strings.ToLower(what[:1]) + what[1:]
+			if what == "Bytes" {
+				value = "[]byte"
+			}
+			value += "(" + target + ")"
+		}
+		if f.prefix != "" {
+			checkPrefix(e, f, target, path)
+			e.line("w.Write%v%v(%v)", what, prefixMethod(f), value)
+			return
+		}
+		checkLength(e, f, "len("+target+")", path)
+		e.line("w.Write%v(%v)", what, value)
+	case kindStruct:
+		e.line("if err := %v.WriteTo(w); err != nil {", target)
+		e.line("%s", path.errorf("%w", "err"))
+		e.line("}")
+	case kindArray:
+		if isByte(t.elem) {
+			e.line("w.WriteBytes(%v[:])", target)
+			return
+		}
+		i := loopVar(depth)
+		e.line("for %v := range %v {", i, target)
+		g.write(e, t.elem, f, target+"["+i+"]", path.index(i), depth+1)
+		e.line("}")
+	case kindSlice:
+		if f.prefix != "" {
+			checkPrefix(e, f, target, path)
+			e.line("w.Write%v(%v(len(%v)))", prefixMethod(f), strings.ToLower(prefixes[f.prefix].method), target)
+		} else {
+			checkLength(e, f, "len("+target+")", path)
+		}
+		i := loopVar(depth)
+		e.line("for %v := range %v {", i, target)
+		g.write(e, t.elem, f, target+"["+i+"]", path.index(i), depth+1)
+		e.line("}")
+	}
+}
+
+//returns the encoded size of a value of type t,false if it is not fixed.
+func (g *generator) size(t *goType, f *field, visiting map[string]bool) (int, bool) {
+	switch t.kind {
+	case kindNumber:
+		return numbers[t.number].size, true
+	case kindString, kindBytes:
+		n, err := strconv.Atoi(f.length)
+		return n, err == nil
+	case kindStruct:
+		return g.structSize(t.name, visiting)
+	case kindArray, kindSlice:
+		count := t.length
+		if t.kind == kindSlice {
+			count = f.length
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, false
+		}
+		elem, ok := g.size(t.elem, f, visiting)
+		return n * elem, ok
+	}
+	return 0, false
+}
+
+func (g *generator) structSize(name string, visiting map[string]bool) (int, bool) {
+	if visiting[name] {
+		return 0, false
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+	total := 0
+	for _, f := range g.structs[name].fields {
+		n, ok := g.size(f.typ, f, visiting)
+		if !ok {
+			return 0, false
+		}
+		total += n
+	}
+	return total, true
+}
+
+//returns the formatted file with the methods of every struct.
+func (g *generator) generate() ([]byte, error) {
+	var body bytes.Buffer
+	for _, name := range g.order {
+		s := g.structs[name]
+		var rd, wr emitter
+		for _, f := range s.fields {
+			path := errPath{format: s.name + "." + f.name}
+			g.read(&rd, f.typ, f, "x."+f.name, path, 0)
+			g.write(&wr, f.typ, f, "x."+f.name, path, 0)
+		}
+		fmt.Fprintf(&body, "\n//ReadFrom reads x from r with the layout given by the iox tags of %v.\n", s.name)
+		fmt.Fprintf(&body, "func (x *%v) ReadFrom(r *iox.ReadSeeker) error {\n", s.name)
+		if rd.usesErr {
+			body.WriteString("var err error\n")
+		}
+		body.Write(rd.buf.Bytes())
+		body.WriteString("return nil\n}\n")
+		fmt.Fprintf(&body, "\n//WriteTo writes x into w with the layout given by the iox tags of %v.\n", s.name)
+		fmt.Fprintf(&body, "func (x *%v) WriteTo(w *iox.Writer) error {\n", s.name)
+		body.Write(wr.buf.Bytes())
+		body.WriteString("return w.Err()\n}\n")
+		if n, ok := g.structSize(s.name, make(map[string]bool)); ok {
+			fmt.Fprintf(&body, "\n//Size returns the encoded size of %v,which has a fixed layout.\n", s.name)
+			fmt.Fprintf(&body, "func (x *%v) Size() int {\nreturn %v\n}\n", s.name, n)
+		}
+	}
+	imports := []string{strconv.Quote(g.ioxPath)}
+	if bytes.Contains(body.Bytes(), []byte("fmt.")) {
+		imports = append([]string{`"fmt"`, ""}, imports...)
+	}
+	return g.format(imports, body.Bytes())
+}
+
+func (g *generator) format(imports []string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by ioxgen. DO NOT EDIT.\n\npackage %v\n\nimport (\n%v\n)\n", g.pkg, strings.Join(imports, "\n"))
+	buf.Write(body)
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("the generated code is not valid: %v\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+//fill target with a sample value in the generated tests.
+func (g *generator) fill(e *emitter, t *goType, f *field, target string, depth int, k *int) {
+	*k++
+	switch t.kind {
+	case kindNumber:
+		e.line("%v = %v", target, *k%100+1)
+	case kindString, kindBytes:
+		n := 2
+		if l, err := strconv.Atoi(f.length); err == nil {
+			n = l
+		}
+		if t.kind == kindString {
+			s := make([]byte, n)
+			for i := range s {
+				s[i] = byte('a' + (*k+i)%26)
+			}
+			e.line("%v = %q", target, s)
+			return
+		}
+		values := make([]string, n)
+		for i := range values {
+			values[i] = strconv.Itoa((*k + i) % 256)
+		}
+		e.line("%v = %v{%v}", target, t.name, strings.Join(values, ", "))
+	case kindStruct:
+		e.line("ioxFill%v(&%v, depth+1)", t.name, target)
+	case kindArray, kindSlice:
+		if t.kind == kindSlice {
+			count := "n"
+			if _, err := strconv.Atoi(f.length); err == nil {
+				count = f.length
+			}
+			e.line("%v = make(%v, %v)", target, t.name, count)
+		}
+		i := loopVar(depth)
+		e.line("for %v := range %v {", i, target)
+		g.fill(e, t.elem, f, target+"["+i+"]", depth+1, k)
+		e.line("}")
+	}
+}
+
+//returns the formatted _test.go file round tripping a sample of every struct.
+func (g *generator) generateTests() ([]byte, error) {
+	var body bytes.Buffer
+	for _, name := range g.order {
+		s := g.structs[name]
+		var e emitter
+		k := 0
+		for _, f := range s.fields {
+			g.fill(&e, f.typ, f, "x."+f.name, 0, &k)
+		}
+		//the length fields follow the values they describe
+		for i, f := range s.fields {
+			if lf := s.lengthField(f.length, i); lf != nil {
+				e.line("x.%v = %v(len(x.%v))", lf.name, lf.typ.name, f.name)
+			}
+		}
+		fmt.Fprintf(&body, "\nfunc ioxFill%v(x *%v, depth int) {\n", s.name, s.name)
+		if bytes.Contains(e.buf.Bytes(), []byte(", n)")) {
+			//nested structs get empty slices so recursive types end
+			body.WriteString("n := 2\nif depth > 2 {\nn = 0\n}\n")
+		}
+		body.Write(e.buf.Bytes())
+		body.WriteString("}\n")
+		fmt.Fprintf(&body, `
+func TestIox%[1]v(t *testing.T) {
+	var x %[1]v
+	ioxFill%[1]v(&x, 0)
+	w := iox.NewBytesBuffer()
+	if err := x.WriteTo(w); err != nil {
+		t.Fatalf("WriteTo: %%v", err)
+	}
+	var y %[1]v
+	if err := y.ReadFrom(iox.NewReadSeekerFromBytes(w.Bytes())); err != nil {
+		t.Fatalf("ReadFrom: %%v", err)
+	}
+	if !reflect.DeepEqual(x, y) {
+		t.Fatalf("unexpected value obtained; got %%+v want %%+v", y, x)
+	}
+`, s.name)
+		if _, ok := g.structSize(s.name, make(map[string]bool)); ok {
+			body.WriteString(`	if len(w.Bytes()) != x.Size() {
+		t.Fatalf("unexpected value obtained; got %v want %v", len(w.Bytes()), x.Size())
+	}
+`)
+		}
+		body.WriteString("}\n")
+	}
+	return g.format([]string{`"reflect"`, `"testing"`, "", strconv.Quote(g.ioxPath)}, body.Bytes())
+}
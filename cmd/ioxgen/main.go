@@ -0,0 +1,133 @@
+//ioxgen generates ReadFrom and WriteTo methods for structs with iox tags,
+//the methods call the typed methods of iox.ReadSeeker and iox.Writer directly,so nothing uses reflect.
+//
+//	//go:generate ioxgen -type=Header,Record
+//
+//	type Header struct {
+//		Magic   [4]byte
+//		Version uint16   `iox:"be"`
+//		NameLen uint8
+//		Name    string   `iox:"len=NameLen"`
+//		Items   []Record `iox:"prefix=u16,be"`
+//		Rest    []byte   `iox:"len=*"`
+//	}
+//
+//the tag options,separated by commas,are
+//
+//	be          BigEndian numbers and length prefixes,LittleEndian is the default
+//	len=N       the length of a string or []byte or the count of a slice,N is a number,
+//	            an earlier number field of the struct,or * for the rest of the data
+//	prefix=u16  the length or count is written before the data as u8,u16,u32 or u64
+//	-           the field is not read or written
+//
+//fields may be sized numbers,string,[]byte,arrays,slices and structs of the package,
+//nested structs get their methods too.for a type with a fixed layout a Size method is generated,
+//and the _test.go file round trips a sample value of every type.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma separated struct names,every struct with an iox tag if empty")
+	output    = flag.String("output", "", "output file name,default <file>_iox.go for go generate or iox_gen.go")
+	tests     = flag.Bool("tests", true, "also generate round trip tests into the _test.go file of the output")
+	ioxPath   = flag.String("iox", "github.com/yudeguang/iox", "import path of the iox package")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ioxgen [flags] [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if err := run(dir); err != nil {
+		fmt.Fprintln(os.Stderr, "ioxgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string) error {
+	out := *output
+	if out == "" {
+		out = "iox_gen.go"
+		if file := os.Getenv("GOFILE"); file != "" {
+			out = strings.TrimSuffix(file, ".go") + "_iox.go"
+		}
+	}
+	out = filepath.Join(dir, out)
+	testOut := strings.TrimSuffix(out, ".go") + "_test.go"
+	files, err := parseDir(dir, out, testOut)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	}
+	g, err := newGenerator(files, names, *ioxPath)
+	if err != nil {
+		return err
+	}
+	src, err := g.generate()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(out, src, 0644); err != nil {
+		return err
+	}
+	if !*tests {
+		return nil
+	}
+	if src, err = g.generateTests(); err != nil {
+		return err
+	}
+	return os.WriteFile(testOut, src, 0644)
+}
+
+//parse the non test files of dir but the generated ones.
+func parseDir(dir string, skip ...string) ([]*ast.File, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") || contains(skip, name) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %v", dir)
+	}
+	return files, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
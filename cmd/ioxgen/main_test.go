@@ -0,0 +1,127 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const testSource = `package records
+
+type Kind uint8
+
+type Name string
+
+type Header struct {
+	Magic   [4]byte
+	Version uint16 ` + "`iox:\"be\"`" + `
+	Kind    Kind
+	NameLen uint8
+	Name    Name     ` + "`iox:\"len=NameLen\"`" + `
+	Items   []Item   ` + "`iox:\"prefix=u16,be\"`" + `
+	Cache   []byte   ` + "`iox:\"-\"`" + `
+	Rest    []byte   ` + "`iox:\"len=*\"`" + `
+}
+
+type Item struct {
+	ID     uint32
+	Scale  float32  ` + "`iox:\"be\"`" + `
+	Coords [3]int16
+	Tag    string   ` + "`iox:\"len=4\"`" + `
+}
+`
+
+func parseTestSource(t *testing.T, src string) []*ast.File {
+	file, err := parser.ParseFile(token.NewFileSet(), "records.go", src, 0)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	return []*ast.File{file}
+}
+
+func TestGenerate(t *testing.T) {
+	g, err := newGenerator(parseTestSource(t, testSource), nil, "github.com/yudeguang/iox")
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	//Item is added as a field type of Header
+	if strings.Join(g.order, ",") != "Header,Item" {
+		t.Fatalf("unexpected value obtained; got %v want %v", g.order, "Header,Item")
+	}
+	src, err := g.generate()
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"// Code generated by ioxgen. DO NOT EDIT.",
+		"func (x *Header) ReadFrom(r *iox.ReadSeeker) error {",
+		"func (x *Header) WriteTo(w *iox.Writer) error {",
+		"x.Version, err = r.ReadUint16BigEndian()",
+		"x.Kind = Kind(v)",
+		"r.ReadString(int(x.NameLen))",
+		"if uint64(x.NameLen) > uint64(r.LenUnRead()) {",
+		"if uint64(n) > uint64(r.LenUnRead()) {",
+		"n, err := r.ReadUint16BigEndian()",
+		"w.WriteUint16BigEndian(uint16(len(x.Items)))",
+		"if len(x.Name) != int(x.NameLen) {",
+		"r.ReadBytesUnRead()",
+		"func (x *Item) Size() int {\n\treturn 18\n}",
+	} {
+		if !strings.Contains(code, want) {
+			t.Fatalf("unexpected value obtained; %q is missing from\n%v", want, code)
+		}
+	}
+	if strings.Contains(code, "Cache") || strings.Contains(code, "func (x *Header) Size") {
+		t.Fatalf("unexpected value obtained; Cache or Header.Size in\n%v", code)
+	}
+	src, err = g.generateTests()
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	for _, want := range []string{"func TestIoxHeader(t *testing.T) {", "x.NameLen = uint8(len(x.Name))", `x.Tag = "`} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("unexpected value obtained; %q is missing from\n%v", want, src)
+		}
+	}
+}
+
+func TestGenerateNegativeCount(t *testing.T) {
+	src := "package p\n\ntype T struct {\n\tN int32\n\tA []uint16 `iox:\"len=N\"`\n\tB string `iox:\"len=N\"`\n}\n"
+	g, err := newGenerator(parseTestSource(t, src), nil, "iox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := g.generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//a negative count is an error before make or ReadString get it
+	want := "if x.N < 0 || uint64(x.N) > uint64(r.LenUnRead()) {\n\t\t\treturn fmt.Errorf(\"T.A: the length %v is negative or bigger than the data left\", x.N)"
+	if !strings.Contains(string(code), want) || strings.Count(string(code), "if x.N < 0 ||") != 2 {
+		t.Fatalf("unexpected value obtained; %q is missing from\n%s", want, code)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, c := range []struct {
+		field string
+		err   string
+	}{
+		{"A string", "needs either len= or prefix="},
+		{"A uint8 `iox:\"len=3\"`", "only for strings"},
+		{"A []byte `iox:\"len=N\"`", "not an earlier number field"},
+		{"A []byte `iox:\"len=*\"`\n\tB uint8", "only allowed on the last field"},
+		{"A []string `iox:\"len=2\"`", "need a length"},
+		{"A int", "not supported"},
+		{"A uint8 `iox:\"little\"`", "not known"},
+	} {
+		src := "package p\n\ntype T struct {\n\t" + c.field + "\n\tZ uint8 `iox:\"\"`\n}\n"
+		_, err := newGenerator(parseTestSource(t, src), nil, "iox")
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.field, err, c.err)
+		}
+	}
+}
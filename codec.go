@@ -0,0 +1,372 @@
+package iox
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+//the reflection shared by the formats that marshal Go values,such as MessagePack and CBOR.
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	bigIntType = reflect.TypeOf(big.Int{})
+)
+
+//the deepest nesting of arrays,maps and tags accepted when reading MessagePack and CBOR,
+//the readers recurse once per level,so hostile data can't exhaust the stack.
+const maxCodecDepth = 512
+
+//reports whether n elements of size bytes at least fit the data left,a count read from the data
+//is checked with it before anything is allocated for the elements.
+func (r *ReadSeeker) countFits(n, size uint64) bool {
+	left := r.LenUnRead()
+	return left >= 0 && n <= uint64(left)/size
+}
+
+//refStack holds the pointers,maps and slices around the value being written,
+//meeting one of them again inside itself means a cycle that would be written forever.
+type refStack map[refKey]bool
+
+type refKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int //of a slice,which may share its array with a shorter one
+}
+
+func refOf(v reflect.Value) (refKey, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return refKey{v.Type(), v.Pointer(), 0}, true
+	case reflect.Slice:
+		return refKey{v.Type(), v.Pointer(), v.Len()}, true
+	}
+	return refKey{}, false
+}
+
+//push v if it's a non nil pointer,map or slice,returns false if it's around the value already.
+//pop it once the value is written.
+func (s refStack) push(v reflect.Value) bool {
+	k, ok := refOf(v)
+	if !ok {
+		return true
+	}
+	if s[k] {
+		return false
+	}
+	s[k] = true
+	return true
+}
+
+func (s refStack) pop(v reflect.Value) {
+	if k, ok := refOf(v); ok {
+		delete(s, k)
+	}
+}
+
+//read a value with read,the position is left unchanged on error.
+func (r *ReadSeeker) restoreOnError(read func() error) error {
+	pos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	if err = read(); err != nil {
+		if _, seekErr := r.readSeeker.Seek(pos, io.SeekStart); seekErr != nil {
+			return seekErr
+		}
+		return err
+	}
+	return nil
+}
+
+//make the map of decoded keys and values,it is a map[string]interface{} if all keys are strings
+//and a map[interface{}]interface{} otherwise,false is returned for a key that can't be a key of a Go map.
+func decodedMap(keys, vals []interface{}) (interface{}, bool) {
+	allStrings := true
+	for _, k := range keys {
+		if _, ok := k.(string); !ok {
+			allStrings = false
+			break
+		}
+	}
+	if allStrings {
+		m := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			m[k.(string)] = vals[i]
+		}
+		return m, true
+	}
+	m := make(map[interface{}]interface{}, len(keys))
+	for i, k := range keys {
+		if !hashable(k) {
+			return nil, false
+		}
+		m[k] = vals[i]
+	}
+	return m, true
+}
+
+//report whether k can be a key of a Go map,a comparable struct may still hold a slice in an interface.
+func hashable(k interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	_ = map[interface{}]bool{k: true}
+	return true
+}
+
+//valueFormat names the struct tag and the type error of a format.
+type valueFormat struct {
+	tag     string
+	typeErr error
+}
+
+//taggedField is an exported field of a struct with its name in maps.
+type taggedField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+//the fields of a struct type,the tag is "name,omitempty",and "-" skips a field.
+func taggedFields(t reflect.Type, key string) []taggedField {
+	var fields []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get(key)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, taggedField{name: name, index: i, omitEmpty: opts == "omitempty"})
+	}
+	return fields
+}
+
+//store the decoded value x in dst,path names dst in errors.
+//x is made of nil,bool,int64,uint64,float32,float64,string,[]byte,[]interface{},
+//map[string]interface{},map[interface{}]interface{} and the values of the format such as time.Time,
+//a value that can be assigned to dst as it is is stored as it is.
+func setDecoded(dst reflect.Value, x interface{}, path string, f valueFormat) error {
+	mismatch := func() error {
+		return fmt.Errorf("%v: can't store %T in %v: %w", strings.TrimPrefix(path, "."), x, dst.Type(), f.typeErr)
+	}
+	if x == nil {
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+		return nil
+	}
+	if dst.Kind() != reflect.Interface && reflect.TypeOf(x).AssignableTo(dst.Type()) {
+		dst.Set(reflect.ValueOf(x))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return setDecoded(dst.Elem(), x, path, f)
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return mismatch()
+		}
+		dst.Set(reflect.ValueOf(x))
+		return nil
+	}
+	switch dst.Type() {
+	case timeType:
+		return mismatch()
+	case bigIntType:
+		i := dst.Addr().Interface().(*big.Int)
+		switch n := x.(type) {
+		case int64:
+			i.SetInt64(n)
+		case uint64:
+			i.SetUint64(n)
+		case *big.Int:
+			i.Set(n)
+		default:
+			return mismatch()
+		}
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Bool:
+		b, ok := x.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := x.(int64)
+		if !ok || dst.OverflowInt(i) {
+			return mismatch()
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch n := x.(type) {
+		case uint64:
+			u = n
+		case int64:
+			if n < 0 {
+				return mismatch()
+			}
+			u = uint64(n)
+		default:
+			return mismatch()
+		}
+		if dst.OverflowUint(u) {
+			return mismatch()
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch n := x.(type) {
+		case float32:
+			dst.SetFloat(float64(n))
+		case float64:
+			dst.SetFloat(n)
+		case int64:
+			dst.SetFloat(float64(n))
+		case uint64:
+			dst.SetFloat(float64(n))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		switch s := x.(type) {
+		case string:
+			dst.SetString(s)
+		case []byte:
+			dst.SetString(string(s))
+		default:
+			return mismatch()
+		}
+	case reflect.Slice, reflect.Array:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			var bt []byte
+			switch s := x.(type) {
+			case []byte:
+				bt = s
+			case string:
+				bt = []byte(s)
+			}
+			if bt != nil {
+				if dst.Kind() == reflect.Slice {
+					dst.SetBytes(append([]byte(nil), bt...))
+					return nil
+				}
+				if len(bt) != dst.Len() {
+					return mismatch()
+				}
+				reflect.Copy(dst, reflect.ValueOf(bt))
+				return nil
+			}
+		}
+		a, ok := x.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if dst.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(dst.Type(), len(a), len(a)))
+		} else if len(a) != dst.Len() {
+			return mismatch()
+		}
+		for i, e := range a {
+			if err := setDecoded(dst.Index(i), e, fmt.Sprintf("%v[%v]", path, i), f); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		return eachDecodedPair(x, mismatch, func(k, v interface{}) error {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := setDecoded(key, k, path, f); err != nil {
+				return err
+			}
+			val := reflect.New(dst.Type().Elem()).Elem()
+			if err := setDecoded(val, v, fmt.Sprintf("%v[%v]", path, k), f); err != nil {
+				return err
+			}
+			dst.SetMapIndex(key, val)
+			return nil
+		})
+	case reflect.Struct:
+		fields := taggedFields(dst.Type(), f.tag)
+		return eachDecodedPair(x, mismatch, func(k, v interface{}) error {
+			name, ok := k.(string)
+			if !ok {
+				return nil
+			}
+			for _, exact := range []bool{true, false} {
+				for _, field := range fields {
+					if exact && field.name == name || !exact && strings.EqualFold(field.name, name) {
+						return setDecoded(dst.Field(field.index), v, path+"."+field.name, f)
+					}
+				}
+			}
+			return nil
+		})
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+//call f with the pairs of a decoded map,mismatch gives the error of other values.
+func eachDecodedPair(x interface{}, mismatch func() error, f func(k, v interface{}) error) error {
+	switch m := x.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			if err := f(k, v); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			if err := f(k, v); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+//sort the keys of a map if they are strings or numbers,so the same map is always written the same way.
+func sortMapKeys(keys []reflect.Value) {
+	if len(keys) == 0 {
+		return
+	}
+	var less func(a, b reflect.Value) bool
+	switch keys[0].Kind() {
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	default:
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+}
@@ -0,0 +1,225 @@
+package iox
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+)
+
+//CompressFormat is the container of deflate data.
+type CompressFormat int
+
+const (
+	CompressGzip    CompressFormat = iota //RFC 1952,members may be concatenated
+	CompressZlib                          //RFC 1950
+	CompressDeflate                       //RFC 1951 without any header
+)
+
+func (f CompressFormat) String() string {
+	switch f {
+	case CompressGzip:
+		return "gzip"
+	case CompressZlib:
+		return "zlib"
+	case CompressDeflate:
+		return "deflate"
+	}
+	return fmt.Sprintf("CompressFormat(%d)", int(f))
+}
+
+//returns a *ReadSeeker over the uncompressed data of rs,which starts at the current position of rs.
+//a checkpoint is recorded every 1MB while decoding,so MoveTo and LastIndexGen restart from
+//the closest checkpoint instead of the beginning.the size is only known once the end has been
+//decoded,so the first call of Size,LenUnRead or MoveTo decodes the whole stream,while reads decode
+//only what they return.a corrupt or truncated stream makes the reads reaching the bad data return an error.
+func NewReadSeekerFromCompressed(rs io.ReadSeeker, format CompressFormat) (*ReadSeeker, error) {
+	s, err := newInflateSeeker(rs, format)
+	if err != nil {
+		return nil, err
+	}
+	return NewReadSeeker(s), nil
+}
+
+//returns a *ReadSeeker over the uncompressed data of a gzip stream,see NewReadSeekerFromCompressed.
+func NewReadSeekerFromGzip(rs io.ReadSeeker) (*ReadSeeker, error) {
+	return NewReadSeekerFromCompressed(rs, CompressGzip)
+}
+
+//returns a *ReadSeeker over the uncompressed data of a zlib stream,see NewReadSeekerFromCompressed.
+func NewReadSeekerFromZlib(rs io.ReadSeeker) (*ReadSeeker, error) {
+	return NewReadSeekerFromCompressed(rs, CompressZlib)
+}
+
+//returns a *ReadSeeker over the uncompressed data of raw deflate data,see NewReadSeekerFromCompressed.
+func NewReadSeekerFromDeflate(rs io.ReadSeeker) (*ReadSeeker, error) {
+	return NewReadSeekerFromCompressed(rs, CompressDeflate)
+}
+
+//returns a *ReadSeeker over the uncompressed data of a .gz file,Close closes the file.
+func NewReadSeekerFromGzipFile(fileName string) (*ReadSeeker, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReadSeekerFromGzip(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+//read n bytes of compressed data and returns a *ReadSeeker over the uncompressed data.
+//the position is left unchanged if the data can't be read or is not valid.
+func (r *ReadSeeker) ReadCompressedBlock(n int, format CompressFormat) (*ReadSeeker, error) {
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	bt, err := r.ReadBytes(n)
+	if err != nil {
+		return nil, err
+	}
+	var dec io.ReadCloser
+	switch format {
+	case CompressGzip:
+		dec, err = gzip.NewReader(bytes.NewReader(bt))
+	case CompressZlib:
+		dec, err = zlib.NewReader(bytes.NewReader(bt))
+	default:
+		dec = flate.NewReader(bytes.NewReader(bt))
+	}
+	var data []byte
+	if err == nil {
+		data, err = io.ReadAll(dec)
+		dec.Close()
+	}
+	if err != nil {
+		r.readSeeker.Seek(initialPos, io.SeekStart)
+		return nil, fmt.Errorf("the %v block at %v: %w", format, initialPos, err)
+	}
+	return NewReadSeekerFromBytes(data), nil
+}
+
+//read n bytes of zlib data and returns a *ReadSeeker over the uncompressed data.
+func (r *ReadSeeker) ReadZlibBlock(n int) (*ReadSeeker, error) {
+	return r.ReadCompressedBlock(n, CompressZlib)
+}
+
+//read n bytes of gzip data and returns a *ReadSeeker over the uncompressed data.
+func (r *ReadSeeker) ReadGzipBlock(n int) (*ReadSeeker, error) {
+	return r.ReadCompressedBlock(n, CompressGzip)
+}
+
+//read n bytes of raw deflate data and returns a *ReadSeeker over the uncompressed data.
+func (r *ReadSeeker) ReadDeflateBlock(n int) (*ReadSeeker, error) {
+	return r.ReadCompressedBlock(n, CompressDeflate)
+}
+
+//compressRegion is the state of a Writer between BeginCompress and EndCompress.
+type compressRegion struct {
+	compressor io.WriteCloser
+	begin      int64 //where the compressed data starts
+	lenPos     int64 //where the compressed length goes,-1 if it is not written
+	lenSize    int
+	bigEndian  bool
+}
+
+//rawWriter writes the output of the compressor into the Writer.
+type rawWriter struct {
+	w *Writer
+}
+
+func (rw rawWriter) Write(p []byte) (int, error) {
+	rw.w.writeRaw(p)
+	return len(p), nil
+}
+
+//start a compressed region,the data written until EndCompress is compressed with format
+//at the level of compress/flate,such as flate.DefaultCompression.
+//the running checksum of BeginChecksum sees the compressed data,Seek and WriteAt
+//should not be used inside the region because the compressor buffers its output.
+//it panics inside BeginASN1 or BeginProtoMessage,and BeginCompressUint32 and the like panic inside BeginChecksum.
+func (w *Writer) BeginCompress(format CompressFormat, level int) {
+	w.beginCompress(format, level, -1, 0, false)
+}
+
+//write a uint32 placeholder with LittleEndian and start a compressed region,
+//EndCompress fills the placeholder with the compressed length.
+func (w *Writer) BeginCompressUint32(format CompressFormat, level int) {
+	w.beginCompress(format, level, w.pos, 4, false)
+}
+
+//write a uint32 placeholder with BigEndian and start a compressed region,
+//EndCompress fills the placeholder with the compressed length.
+func (w *Writer) BeginCompressUint32BigEndian(format CompressFormat, level int) {
+	w.beginCompress(format, level, w.pos, 4, true)
+}
+
+//write a uint64 placeholder with LittleEndian and start a compressed region,
+//EndCompress fills the placeholder with the compressed length.
+func (w *Writer) BeginCompressUint64(format CompressFormat, level int) {
+	w.beginCompress(format, level, w.pos, 8, false)
+}
+
+//write a uint64 placeholder with BigEndian and start a compressed region,
+//EndCompress fills the placeholder with the compressed length.
+func (w *Writer) BeginCompressUint64BigEndian(format CompressFormat, level int) {
+	w.beginCompress(format, level, w.pos, 8, true)
+}
+
+func (w *Writer) beginCompress(format CompressFormat, level int, lenPos int64, lenSize int, bigEndian bool) {
+	if w.region != nil {
+		panic("a compressed region is already open,call EndCompress first")
+	}
+	//the compressed data and the length are written at positions,which an element kept in memory doesn't have
+	if len(w.nested) > 0 {
+		panic("a compressed region can't be inside an ASN.1 element or a protobuf message")
+	}
+	//the running checksum would see the placeholder instead of the length
+	if lenSize > 0 && w.hash != nil {
+		panic("the compressed length can't be back-filled inside a running checksum,use BeginCompress")
+	}
+	w.write(make([]byte, lenSize))
+	region := &compressRegion{begin: w.pos, lenPos: lenPos, lenSize: lenSize, bigEndian: bigEndian}
+	var err error
+	switch format {
+	case CompressGzip:
+		region.compressor, err = gzip.NewWriterLevel(rawWriter{w}, level)
+	case CompressZlib:
+		region.compressor, err = zlib.NewWriterLevel(rawWriter{w}, level)
+	default:
+		region.compressor, err = flate.NewWriter(rawWriter{w}, level)
+	}
+	if err != nil {
+		panic(err)
+	}
+	w.region = region
+}
+
+//end the compressed region and returns the length of the compressed data,
+//the placeholder of BeginCompressUint32 and the like is filled with it.
+func (w *Writer) EndCompress() int64 {
+	region := w.region
+	if region == nil {
+		panic("there is no compressed region,call BeginCompress first")
+	}
+	w.region = nil
+	region.compressor.Close()
+	n := w.pos - region.begin
+	switch {
+	case region.lenSize == 4 && region.bigEndian:
+		w.WriteUint32BigEndianAt(region.lenPos, uint32(n))
+	case region.lenSize == 4:
+		w.WriteUint32At(region.lenPos, uint32(n))
+	case region.lenSize == 8 && region.bigEndian:
+		w.WriteUint64BigEndianAt(region.lenPos, uint64(n))
+	case region.lenSize == 8:
+		w.WriteUint64At(region.lenPos, uint64(n))
+	}
+	return n
+}
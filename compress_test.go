@@ -0,0 +1,263 @@
+package iox
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+//data that compresses into many blocks of every kind.
+func compressTestData() []byte {
+	rnd := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < 3<<20 {
+		switch rnd.Intn(3) {
+		case 0:
+			b := make([]byte, rnd.Intn(4096))
+			rnd.Read(b)
+			buf.Write(b)
+		case 1:
+			buf.Write(bytes.Repeat([]byte{byte(rnd.Intn(256))}, rnd.Intn(4096)))
+		default:
+			for i := rnd.Intn(200); i > 0; i-- {
+				buf.WriteString("the quick brown fox jumps over the lazy dog ")
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestReadSeekerFromCompressed(t *testing.T) {
+	data := compressTestData()
+	var gz, zl, df bytes.Buffer
+	gw, _ := gzip.NewWriterLevel(&gz, flate.BestSpeed)
+	gw.Write(data[:1<<20])
+	gw.Close()
+	//a second member
+	gw, _ = gzip.NewWriterLevel(&gz, flate.HuffmanOnly)
+	gw.Write(data[1<<20:])
+	gw.Close()
+	zw, _ := zlib.NewWriterLevel(&zl, flate.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	fw, _ := flate.NewWriter(&df, flate.NoCompression)
+	fw.Write(data)
+	fw.Close()
+	for _, c := range []struct {
+		format CompressFormat
+		src    []byte
+	}{{CompressGzip, gz.Bytes()}, {CompressZlib, zl.Bytes()}, {CompressDeflate, df.Bytes()}} {
+		rd, err := NewReadSeekerFromCompressed(bytes.NewReader(c.src), c.format)
+		if err != nil {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.format, err, nil)
+		}
+		if size := rd.Size(); size != int64(len(data)) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.format, size, len(data))
+		}
+		rnd := rand.New(rand.NewSource(2))
+		for i := 0; i < 50; i++ {
+			pos := rnd.Int63n(int64(len(data)) - 100)
+			if err = rd.MoveTo(pos); err != nil {
+				t.Fatalf("%v: unexpected value obtained; got %v want %v", c.format, err, nil)
+			}
+			bt, err := rd.ReadBytes(100)
+			if err != nil || !bytes.Equal(bt, data[pos:pos+100]) {
+				t.Fatalf("%v: unexpected value obtained at %v; got %v,%v want %v", c.format, pos, bt, err, data[pos:pos+100])
+			}
+		}
+		rd.MoveTo(0)
+		all, err := io.ReadAll(rd.readSeeker)
+		if err != nil || !bytes.Equal(all, data) {
+			t.Fatalf("%v: unexpected value obtained; got %v bytes,%v want %v bytes", c.format, len(all), err, len(data))
+		}
+		sep := []byte("lazy dog")
+		if got, want := rd.LastIndexGen(0, rd.Size()-1, sep), int64(bytes.LastIndex(data, sep)); got != want {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.format, got, want)
+		}
+	}
+	//a corrupt checksum is reported once the end is reached
+	bad := append([]byte{}, zl.Bytes()...)
+	bad[len(bad)-1] ^= 0xff
+	rd, err := NewReadSeekerFromZlib(bytes.NewReader(bad))
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if _, err = io.ReadAll(rd.readSeeker); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrChecksumMismatch)
+	}
+	if _, err = NewReadSeekerFromGzip(bytes.NewReader(data[:100])); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
+
+func TestWriterCompress(t *testing.T) {
+	wr := NewBytesBuffer()
+	wr.WriteString("head")
+	wr.BeginCompressUint32BigEndian(CompressZlib, flate.DefaultCompression)
+	wr.WriteString("hello hello hello hello")
+	wr.WriteUint32(7)
+	n := wr.EndCompress()
+	wr.WriteString("tail")
+	rd := NewReadSeekerFromBytes(wr.Bytes())
+	rd.ReadString(4)
+	length, _ := rd.ReadUint32BigEndian()
+	if int64(length) != n {
+		t.Fatalf("unexpected value obtained; got %v want %v", length, n)
+	}
+	block, err := rd.ReadZlibBlock(int(length))
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	s, _ := block.ReadString(23)
+	i, _ := block.ReadUint32()
+	if s != "hello hello hello hello" || i != 7 {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v,%v", s, i, "hello hello hello hello", 7)
+	}
+	if s, _ = rd.ReadString(4); s != "tail" {
+		t.Fatalf("unexpected value obtained; got %v want %v", s, "tail")
+	}
+	//an invalid block leaves the position unchanged
+	rd.MoveTo(0)
+	if _, err = rd.ReadGzipBlock(4); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+	//the running checksum covers the compressed data
+	wr = NewBytesBuffer()
+	wr.BeginCRC32()
+	wr.BeginCompress(CompressGzip, flate.BestSpeed)
+	wr.WriteString("abc")
+	wr.EndCompress()
+	wr.WriteCRC32()
+	rd = NewReadSeekerFromBytes(wr.Bytes())
+	if err = rd.VerifyCRC32At(0, rd.Size()-5, rd.Size()-4); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	//a length placeholder can't be hashed,and a region can't be kept in memory by an element
+	wr = NewBytesBuffer()
+	wr.BeginCRC32()
+	if !panics(func() { wr.BeginCompressUint32(CompressZlib, flate.BestSpeed) }) {
+		t.Fatalf("unexpected value obtained; got %v want %v", false, "a panic")
+	}
+	wr = NewBytesBuffer()
+	wr.BeginASN1Sequence()
+	if !panics(func() { wr.BeginCompress(CompressZlib, flate.BestSpeed) }) {
+		t.Fatalf("unexpected value obtained; got %v want %v", false, "a panic")
+	}
+}
+
+func TestReadSeekerFromCompressedErrors(t *testing.T) {
+	data := compressTestData()
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(data)
+	gw.Close()
+	//the first read decodes only what it needs
+	rd, err := NewReadSeekerFromGzip(bytes.NewReader(gz.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bt, err := rd.ReadBytes(10); err != nil || !bytes.Equal(bt, data[:10]) {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v", bt, err, data[:10])
+	}
+	if s := rd.readSeeker.(*inflateSeeker); s.size >= 0 || s.cur > 1<<20 {
+		t.Fatalf("unexpected value obtained; got %v decoded bytes want %v", s.cur, "a few blocks")
+	}
+	//a single block that expands to megabytes is decoded in parts
+	const matches = 1 << 16
+	if rd, err = NewReadSeekerFromDeflate(bytes.NewReader(deflateZeros(matches))); err != nil {
+		t.Fatal(err)
+	}
+	rd.ReadBytes(10)
+	if s := rd.readSeeker.(*inflateSeeker); len(s.hist) > 1<<20 {
+		t.Fatalf("unexpected value obtained; got %v bytes in memory want %v", len(s.hist), "a part of the block")
+	}
+	if size := rd.Size(); size != 1+258*matches {
+		t.Fatalf("unexpected value obtained; got %v want %v", size, 1+258*matches)
+	}
+	rd.MoveTo(1 << 20) //back into the block
+	if bt, err := rd.ReadBytes(10); err != nil || !bytes.Equal(bt, make([]byte, 10)) {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v", bt, err, make([]byte, 10))
+	}
+	truncated := gz.Bytes()[:gz.Len()/2]
+	//the second block has the reserved block type
+	var df bytes.Buffer
+	fw, _ := flate.NewWriter(&df, flate.NoCompression)
+	fw.Write(data[:100])
+	fw.Flush()
+	corrupt := append(df.Bytes(), 0x07)
+	for _, c := range []struct {
+		name   string
+		format CompressFormat
+		src    []byte
+		want   error
+	}{
+		{"truncated", CompressGzip, truncated, io.ErrUnexpectedEOF},
+		{"corrupt", CompressDeflate, corrupt, errCorruptDeflate},
+		{"truncated header", CompressGzip, gz.Bytes()[:12], io.ErrUnexpectedEOF},
+	} {
+		rd, err := NewReadSeekerFromCompressed(bytes.NewReader(c.src), c.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = rd.ReadBytes(len(data)); !errors.Is(err, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.name, err, c.want)
+		}
+		if pos, _ := rd.CurPos(); pos != 0 {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.name, pos, 0)
+		}
+		if _, err = rd.ReadBytesUnRead(); !errors.Is(err, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.name, err, c.want)
+		}
+		//the size is that of the data before the error,which can still be read
+		size := rd.Size()
+		if size >= int64(len(data)) || rd.LenUnRead() != size {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.name, size, "less than the data")
+		}
+		if bt, err := rd.ReadBytes(int(size)); err != nil || !bytes.Equal(bt, data[:size]) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.name, err, nil)
+		}
+		if _, err = rd.ReadBytes(1); err == nil {
+			t.Fatalf("%v: unexpected value obtained; got %v want an error", c.name, err)
+		}
+	}
+}
+
+//a final block of fixed codes with a zero byte followed by n matches of 258 bytes at distance 1.
+func deflateZeros(n int) []byte {
+	var out []byte
+	var bits uint64
+	var count uint
+	put := func(v uint64, n uint) {
+		bits |= v << count
+		for count += n; count >= 8; count -= 8 {
+			out = append(out, byte(bits))
+			bits >>= 8
+		}
+	}
+	//huffman codes go MSB first
+	code := func(c uint64, n uint) {
+		var rev uint64
+		for i := uint(0); i < n; i++ {
+			rev = rev<<1 | c>>i&1
+		}
+		put(rev, n)
+	}
+	put(1, 1) //BFINAL
+	put(1, 2) //fixed codes
+	code(0x30, 8)
+	for i := 0; i < n; i++ {
+		code(0xc5, 8) //the length 258
+		code(0, 5)    //the distance 1
+	}
+	code(0, 7) //the end of the block
+	put(0, 7)
+	return out
+}
@@ -0,0 +1,192 @@
+package iox
+
+//CRC8Params describes a CRC-8 algorithm in the usual Rocksoft model.
+type CRC8Params struct {
+	Poly   uint8
+	Init   uint8
+	RefIn  bool
+	RefOut bool
+	XorOut uint8
+}
+
+//CRC16Params describes a CRC-16 algorithm in the usual Rocksoft model.
+type CRC16Params struct {
+	Poly   uint16
+	Init   uint16
+	RefIn  bool
+	RefOut bool
+	XorOut uint16
+}
+
+//the CRC-8 and CRC-16 variants missing from the standard library,
+//the name is the one of the catalogue of parametrised CRC algorithms.
+var (
+	CRC8         = CRC8Params{Poly: 0x07}
+	CRC8MAXIM    = CRC8Params{Poly: 0x31, RefIn: true, RefOut: true}
+	CRC8ROHC     = CRC8Params{Poly: 0x07, Init: 0xff, RefIn: true, RefOut: true}
+	CRC16CCITT   = CRC16Params{Poly: 0x1021, Init: 0xffff} //CRC-16/CCITT-FALSE
+	CRC16XMODEM  = CRC16Params{Poly: 0x1021}
+	CRC16KERMIT  = CRC16Params{Poly: 0x1021, RefIn: true, RefOut: true}
+	CRC16X25     = CRC16Params{Poly: 0x1021, Init: 0xffff, RefIn: true, RefOut: true, XorOut: 0xffff}
+	CRC16ARC     = CRC16Params{Poly: 0x8005, RefIn: true, RefOut: true}
+	CRC16MODBUS  = CRC16Params{Poly: 0x8005, Init: 0xffff, RefIn: true, RefOut: true}
+	CRC16USB     = CRC16Params{Poly: 0x8005, Init: 0xffff, RefIn: true, RefOut: true, XorOut: 0xffff}
+	CRC16GENIBUS = CRC16Params{Poly: 0x1021, Init: 0xffff, XorOut: 0xffff}
+)
+
+func reverse8(b uint8) uint8 {
+	var r uint8
+	for i := 0; i < 8; i++ {
+		r = r<<1 | b&1
+		b >>= 1
+	}
+	return r
+}
+
+func reverse16(b uint16) uint16 {
+	var r uint16
+	for i := 0; i < 16; i++ {
+		r = r<<1 | b&1
+		b >>= 1
+	}
+	return r
+}
+
+//CRC8Hash computes a CRC-8,it implements hash.Hash.
+type CRC8Hash struct {
+	params CRC8Params
+	table  [256]uint8
+	crc    uint8
+}
+
+//returns a new CRC8Hash computing the CRC-8 described by params.
+func NewCRC8(params CRC8Params) *CRC8Hash {
+	h := new(CRC8Hash)
+	h.params = params
+	for i := 0; i < 256; i++ {
+		crc := uint8(i)
+		for j := 0; j < 8; j++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ params.Poly
+			} else {
+				crc <<= 1
+			}
+		}
+		h.table[i] = crc
+	}
+	h.Reset()
+	return h
+}
+
+//the table works MSB first,so reflected input is reflected byte by byte
+//and the register is reflected once more by Sum8 if RefOut is set.
+func (h *CRC8Hash) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if h.params.RefIn {
+			b = reverse8(b)
+		}
+		h.crc = h.table[h.crc^b]
+	}
+	return len(p), nil
+}
+
+//Sum8 returns the CRC-8 of the data written so far.
+func (h *CRC8Hash) Sum8() uint8 {
+	crc := h.crc
+	if h.params.RefOut {
+		crc = reverse8(crc)
+	}
+	return crc ^ h.params.XorOut
+}
+
+func (h *CRC8Hash) Sum(b []byte) []byte {
+	return append(b, h.Sum8())
+}
+
+func (h *CRC8Hash) Reset() {
+	h.crc = h.params.Init
+}
+
+func (h *CRC8Hash) Size() int {
+	return 1
+}
+
+func (h *CRC8Hash) BlockSize() int {
+	return 1
+}
+
+//returns the CRC-8 of data.
+func ChecksumCRC8(data []byte, params CRC8Params) uint8 {
+	h := NewCRC8(params)
+	h.Write(data)
+	return h.Sum8()
+}
+
+//CRC16Hash computes a CRC-16,it implements hash.Hash.
+type CRC16Hash struct {
+	params CRC16Params
+	table  [256]uint16
+	crc    uint16
+}
+
+//returns a new CRC16Hash computing the CRC-16 described by params.
+func NewCRC16(params CRC16Params) *CRC16Hash {
+	h := new(CRC16Hash)
+	h.params = params
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ params.Poly
+			} else {
+				crc <<= 1
+			}
+		}
+		h.table[i] = crc
+	}
+	h.Reset()
+	return h
+}
+
+func (h *CRC16Hash) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if h.params.RefIn {
+			b = reverse8(b)
+		}
+		h.crc = h.crc<<8 ^ h.table[uint8(h.crc>>8)^b]
+	}
+	return len(p), nil
+}
+
+//Sum16 returns the CRC-16 of the data written so far.
+func (h *CRC16Hash) Sum16() uint16 {
+	crc := h.crc
+	if h.params.RefOut {
+		crc = reverse16(crc)
+	}
+	return crc ^ h.params.XorOut
+}
+
+//Sum appends the CRC-16 in BigEndian like the hashes of the standard library.
+func (h *CRC16Hash) Sum(b []byte) []byte {
+	return append(b, uint16ToBytesBigEndian(h.Sum16())...)
+}
+
+func (h *CRC16Hash) Reset() {
+	h.crc = h.params.Init
+}
+
+func (h *CRC16Hash) Size() int {
+	return 2
+}
+
+func (h *CRC16Hash) BlockSize() int {
+	return 1
+}
+
+//returns the CRC-16 of data.
+func ChecksumCRC16(data []byte, params CRC16Params) uint16 {
+	h := NewCRC16(params)
+	h.Write(data)
+	return h.Sum16()
+}
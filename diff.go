@@ -0,0 +1,545 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidPatch = errors.New("the patch is not valid")
+
+//DiffOp is the kind of a DiffRange.
+type DiffOp int
+
+const (
+	DiffEqual   DiffOp = iota //the bytes are the same on both sides
+	DiffReplace               //the source bytes are replaced by the target bytes,the lengths may differ
+	DiffDelete                //the source bytes are not in the target
+	DiffInsert                //the target bytes are not in the source
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffEqual:
+		return "equal"
+	case DiffReplace:
+		return "replace"
+	case DiffDelete:
+		return "delete"
+	case DiffInsert:
+		return "insert"
+	}
+	return fmt.Sprintf("DiffOp(%d)", int(op))
+}
+
+//DiffRange is a range of the source and the range of the target it turns into,
+//the ranges of a diff cover both sides in order and without gaps.
+type DiffRange struct {
+	Op        DiffOp
+	SrcOffset int64
+	SrcLength int64
+	DstOffset int64
+	DstLength int64
+}
+
+//DiffOptions tunes Diff and MakePatch.
+type DiffOptions struct {
+	BlockSize int //the length of the blocks matched to resync after an insertion or a deletion,0 means 32
+}
+
+//blocks shorter than this are not matched,the gap is compared byte by byte instead.
+const minDiffBlock = 4
+
+func (o DiffOptions) blockSize() int {
+	if o.BlockSize <= 0 {
+		return 32
+	}
+	if o.BlockSize < minDiffBlock {
+		return minDiffBlock
+	}
+	return o.BlockSize
+}
+
+//read the whole data without moving the position.
+func (r *ReadSeeker) allBytes() ([]byte, error) {
+	s, err := r.Section(0, r.Size()-1)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(s.readSeeker)
+}
+
+//Diff compares src with dst and returns the ranges that are equal,replaced,deleted or inserted.
+//after the common head and tail,the blocks of src are looked up in dst to resync after insertions
+//and deletions,the gaps between the matches are compared again with smaller blocks,and what is
+//left is compared byte by byte if both sides have the same length.
+//both sides are read into memory,their positions are left unchanged.
+func Diff(src, dst *ReadSeeker, opts DiffOptions) ([]DiffRange, error) {
+	a, err := src.allBytes()
+	if err != nil {
+		return nil, err
+	}
+	b, err := dst.allBytes()
+	if err != nil {
+		return nil, err
+	}
+	return diffBytes(a, b, opts.blockSize()), nil
+}
+
+func diffBytes(a, b []byte, block int) []DiffRange {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b), block)
+	return d.ranges
+}
+
+//differ collects the ranges of a diff of a and b.
+type differ struct {
+	a, b   []byte
+	ranges []DiffRange
+}
+
+//add n equal bytes at i of a and j of b.
+func (d *differ) equal(i, j, n int) {
+	if n == 0 {
+		return
+	}
+	if last := len(d.ranges) - 1; last >= 0 && d.ranges[last].Op == DiffEqual {
+		d.ranges[last].SrcLength += int64(n)
+		d.ranges[last].DstLength += int64(n)
+		return
+	}
+	d.ranges = append(d.ranges, DiffRange{Op: DiffEqual, SrcOffset: int64(i), SrcLength: int64(n), DstOffset: int64(j), DstLength: int64(n)})
+}
+
+//add a change of aLen bytes at i of a into bLen bytes at j of b,merged with a previous change.
+func (d *differ) change(i, aLen, j, bLen int) {
+	if aLen == 0 && bLen == 0 {
+		return
+	}
+	last := len(d.ranges) - 1
+	if last < 0 || d.ranges[last].Op == DiffEqual {
+		d.ranges = append(d.ranges, DiffRange{SrcOffset: int64(i), DstOffset: int64(j)})
+		last++
+	}
+	rg := &d.ranges[last]
+	rg.SrcLength += int64(aLen)
+	rg.DstLength += int64(bLen)
+	switch {
+	case rg.SrcLength == 0:
+		rg.Op = DiffInsert
+	case rg.DstLength == 0:
+		rg.Op = DiffDelete
+	default:
+		rg.Op = DiffReplace
+	}
+}
+
+//compare a[i0:i1] with b[j0:j1].
+func (d *differ) compare(i0, i1, j0, j1, block int) {
+	n := 0
+	for i0+n < i1 && j0+n < j1 && d.a[i0+n] == d.b[j0+n] {
+		n++
+	}
+	d.equal(i0, j0, n)
+	i0, j0 = i0+n, j0+n
+	m := 0
+	for i1-m > i0 && j1-m > j0 && d.a[i1-m-1] == d.b[j1-m-1] {
+		m++
+	}
+	d.resync(i0, i1-m, j0, j1-m, block)
+	d.equal(i1-m, j1-m, m)
+}
+
+//find the blocks of a[i0:i1] in b[j0:j1] in order,the first match at or after the end of
+//the previous one wins.the gaps are compared with half of the block size.
+func (d *differ) resync(i0, i1, j0, j1, block int) {
+	if i0 == i1 || j0 == j1 || block < minDiffBlock || i1-i0 < block || j1-j0 < block {
+		d.bytewise(i0, i1, j0, j1)
+		return
+	}
+	index := make(map[string][]int)
+	for i := i0; i+block <= i1; i += block {
+		key := string(d.a[i : i+block])
+		index[key] = append(index[key], i)
+	}
+	i, jStart := i0, j0
+	found := false
+	for j := j0; j+block <= j1; {
+		offsets := index[string(d.b[j:j+block])]
+		k := sort.SearchInts(offsets, i)
+		if k == len(offsets) {
+			j++
+			continue
+		}
+		ai, bj := offsets[k], j
+		for ai > i && bj > jStart && d.a[ai-1] == d.b[bj-1] {
+			ai--
+			bj--
+		}
+		n := j - bj + block
+		for ai+n < i1 && bj+n < j1 && d.a[ai+n] == d.b[bj+n] {
+			n++
+		}
+		d.compare(i, ai, jStart, bj, block/2)
+		d.equal(ai, bj, n)
+		i, jStart = ai+n, bj+n
+		j = jStart
+		found = true
+	}
+	if !found {
+		d.resync(i0, i1, j0, j1, block/2)
+		return
+	}
+	d.compare(i, i1, jStart, j1, block/2)
+}
+
+//compare a[i0:i1] with b[j0:j1] byte by byte if they have the same length,
+//otherwise the whole range is a change.
+func (d *differ) bytewise(i0, i1, j0, j1 int) {
+	if i1-i0 != j1-j0 {
+		d.change(i0, i1-i0, j0, j1-j0)
+		return
+	}
+	for k := 0; k < i1-i0; {
+		same := d.a[i0+k] == d.b[j0+k]
+		n := k + 1
+		for n < i1-i0 && (d.a[i0+n] == d.b[j0+n]) == same {
+			n++
+		}
+		if same {
+			d.equal(i0+k, j0+k, n-k)
+		} else {
+			d.change(i0+k, n-k, j0+k, n-k)
+		}
+		k = n
+	}
+}
+
+//DiffHexDump returns the ranges of a Diff of src and dst as hexdumps side by side,the source
+//on the left,the target on the right and a marker between them:" " equal,"|" replaced,
+//"<" deleted and ">" inserted.every range starts a new line,with opts.Squeeze the equal ranges
+//show only their first and last line.the highlights of opts are ignored.
+func DiffHexDump(src, dst *ReadSeeker, ranges []DiffRange, opts HexDumpOptions) (string, error) {
+	var sb strings.Builder
+	err := WriteDiffHexDump(&sb, src, dst, ranges, opts)
+	return sb.String(), err
+}
+
+//writes the side by side hexdump described by DiffHexDump into w.
+func WriteDiffHexDump(w io.Writer, src, dst *ReadSeeker, ranges []DiffRange, opts HexDumpOptions) error {
+	a, err := src.allBytes()
+	if err != nil {
+		return err
+	}
+	b, err := dst.allBytes()
+	if err != nil {
+		return err
+	}
+	width := int64(opts.width())
+	lineLen := len(strings.TrimSuffix(opts.formatLine(0, make([]byte, width)), "\n"))
+	side := func(data []byte, offset, length, line int64) string {
+		begin := line * width
+		if begin >= length {
+			return strings.Repeat(" ", lineLen)
+		}
+		end := begin + width
+		if end > length {
+			end = length
+		}
+		s := strings.TrimSuffix(opts.formatLine(offset+begin, data[offset+begin:offset+end]), "\n")
+		return s + strings.Repeat(" ", lineLen-len(s))
+	}
+	markers := map[DiffOp]string{DiffEqual: " ", DiffReplace: "|", DiffDelete: "<", DiffInsert: ">"}
+	for _, rg := range ranges {
+		marker, ok := markers[rg.Op]
+		if !ok || rg.SrcOffset < 0 || rg.SrcLength < 0 || rg.SrcOffset+rg.SrcLength > int64(len(a)) ||
+			rg.DstOffset < 0 || rg.DstLength < 0 || rg.DstOffset+rg.DstLength > int64(len(b)) {
+			return fmt.Errorf("the range %+v does not fit the source of %v bytes and the target of %v bytes", rg, len(a), len(b))
+		}
+		length := rg.SrcLength
+		if rg.DstLength > length {
+			length = rg.DstLength
+		}
+		lines := (length + width - 1) / width
+		for line := int64(0); line < lines; line++ {
+			if opts.Squeeze && rg.Op == DiffEqual && line > 0 && line < lines-1 {
+				if line == 1 {
+					if _, err = io.WriteString(w, "*\n"); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			row := side(a, rg.SrcOffset, rg.SrcLength, line) + "  " + marker + "  " + side(b, rg.DstOffset, rg.DstLength, line)
+			if _, err = io.WriteString(w, strings.TrimRight(row, " ")+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	end := fmt.Sprintf("%08x", len(a))
+	_, err = fmt.Fprintf(w, "%v%v     %08x\n", end, strings.Repeat(" ", lineLen-len(end)), len(b))
+	return err
+}
+
+//PatchOp is a step of a Patch.
+type PatchOp struct {
+	Copy      bool  //copy Length bytes of the source from SrcOffset,otherwise insert Data
+	SrcOffset int64 //only for a copy
+	Length    int64
+	Data      []byte //only for an insert,Length is len(Data)
+}
+
+//Patch turns a source into a target by copying ranges of the source and inserting new bytes.
+type Patch struct {
+	SrcSize  int64
+	SrcCRC32 uint32
+	DstSize  int64
+	DstCRC32 uint32
+	Ops      []PatchOp
+}
+
+//copy n bytes from offset of the source,merged with a previous copy that ends at offset.
+func (p *Patch) copy(offset, n int64) {
+	if n == 0 {
+		return
+	}
+	if last := len(p.Ops) - 1; last >= 0 && p.Ops[last].Copy && p.Ops[last].SrcOffset+p.Ops[last].Length == offset {
+		p.Ops[last].Length += n
+		return
+	}
+	p.Ops = append(p.Ops, PatchOp{Copy: true, SrcOffset: offset, Length: n})
+}
+
+//insert data,merged with a previous insert.
+func (p *Patch) insert(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if last := len(p.Ops) - 1; last >= 0 && !p.Ops[last].Copy {
+		p.Ops[last].Data = append(p.Ops[last].Data, data...)
+		p.Ops[last].Length = int64(len(p.Ops[last].Data))
+		return
+	}
+	p.Ops = append(p.Ops, PatchOp{Length: int64(len(data)), Data: append([]byte{}, data...)})
+}
+
+//MakePatch returns a Patch that turns src into dst.the equal ranges of Diff become copies,
+//and the blocks of the changed target bytes that are found anywhere in src,such as moved data,
+//become copies as well,everything else is inserted.
+func MakePatch(src, dst *ReadSeeker, opts DiffOptions) (*Patch, error) {
+	a, err := src.allBytes()
+	if err != nil {
+		return nil, err
+	}
+	b, err := dst.allBytes()
+	if err != nil {
+		return nil, err
+	}
+	block := opts.blockSize()
+	p := &Patch{SrcSize: int64(len(a)), SrcCRC32: crc32.ChecksumIEEE(a), DstSize: int64(len(b)), DstCRC32: crc32.ChecksumIEEE(b)}
+	var index map[string][]int
+	for _, rg := range diffBytes(a, b, block) {
+		if rg.Op == DiffEqual {
+			p.copy(rg.SrcOffset, rg.SrcLength)
+			continue
+		}
+		if rg.DstLength == 0 {
+			continue
+		}
+		if index == nil {
+			index = make(map[string][]int)
+			for i := 0; i+block <= len(a); i += block {
+				key := string(a[i : i+block])
+				index[key] = append(index[key], i)
+			}
+		}
+		j0, j1 := int(rg.DstOffset), int(rg.DstOffset+rg.DstLength)
+		literal := j0
+		for j := j0; j+block <= j1; {
+			offsets := index[string(b[j:j+block])]
+			if len(offsets) == 0 {
+				j++
+				continue
+			}
+			ai, bj := offsets[0], j
+			for ai > 0 && bj > literal && a[ai-1] == b[bj-1] {
+				ai--
+				bj--
+			}
+			n := j - bj + block
+			for ai+n < len(a) && bj+n < j1 && a[ai+n] == b[bj+n] {
+				n++
+			}
+			p.insert(b[literal:bj])
+			p.copy(int64(ai), int64(n))
+			j = bj + n
+			literal = j
+		}
+		p.insert(b[literal:j1])
+	}
+	return p, nil
+}
+
+const patchMagic = "IOXP"
+
+//writes p as "IOXP",the uvarint SrcSize,the uint32 SrcCRC32,the uvarint DstSize,the uint32 DstCRC32,
+//the uvarint count of ops and then every op,either 0 with the uvarint SrcOffset and Length of a copy
+//or 1 with the uvarint length and the data of an insert.
+func (w *Writer) WritePatch(p *Patch) {
+	w.WriteString(patchMagic)
+	w.WriteUvarint(uint64(p.SrcSize))
+	w.WriteUint32(p.SrcCRC32)
+	w.WriteUvarint(uint64(p.DstSize))
+	w.WriteUint32(p.DstCRC32)
+	w.WriteUvarint(uint64(len(p.Ops)))
+	for _, op := range p.Ops {
+		if op.Copy {
+			w.WriteUint8(0)
+			w.WriteUvarint(uint64(op.SrcOffset))
+			w.WriteUvarint(uint64(op.Length))
+		} else {
+			w.WriteUint8(1)
+			w.WriteUvarint(uint64(len(op.Data)))
+			w.WriteBytes(op.Data)
+		}
+	}
+}
+
+//reads a Patch written by WritePatch,a patch whose ops do not fit its sizes returns an error
+//wrapping ErrInvalidPatch.the position is left unchanged on error.
+func (r *ReadSeeker) ReadPatch() (*Patch, error) {
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.readPatch()
+	if err != nil {
+		r.readSeeker.Seek(initialPos, io.SeekStart)
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *ReadSeeker) readPatch() (*Patch, error) {
+	magic, err := r.ReadString(len(patchMagic))
+	if err != nil {
+		return nil, err
+	}
+	if magic != patchMagic {
+		return nil, fmt.Errorf("the magic is %q: %w", magic, ErrInvalidPatch)
+	}
+	p := new(Patch)
+	var size, count uint64
+	if size, err = r.ReadUvarint(); err != nil {
+		return nil, err
+	}
+	p.SrcSize = int64(size)
+	if p.SrcCRC32, err = r.ReadUint32(); err != nil {
+		return nil, err
+	}
+	if size, err = r.ReadUvarint(); err != nil {
+		return nil, err
+	}
+	p.DstSize = int64(size)
+	if p.DstCRC32, err = r.ReadUint32(); err != nil {
+		return nil, err
+	}
+	if count, err = r.ReadUvarint(); err != nil {
+		return nil, err
+	}
+	if p.SrcSize < 0 || p.DstSize < 0 || count > uint64(p.DstSize) {
+		return nil, fmt.Errorf("%v ops for a source of %v bytes and a target of %v bytes: %w", count, p.SrcSize, p.DstSize, ErrInvalidPatch)
+	}
+	var total int64
+	for i := uint64(0); i < count; i++ {
+		kind, err := r.ReadUint8()
+		if err != nil {
+			return nil, err
+		}
+		var op PatchOp
+		switch kind {
+		case 0:
+			offset, err := r.ReadUvarint()
+			if err != nil {
+				return nil, err
+			}
+			length, err := r.ReadUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if offset > uint64(p.SrcSize) || length > uint64(p.SrcSize)-offset {
+				return nil, fmt.Errorf("op %v copies %v bytes at %v of a source of %v bytes: %w", i, length, offset, p.SrcSize, ErrInvalidPatch)
+			}
+			op = PatchOp{Copy: true, SrcOffset: int64(offset), Length: int64(length)}
+		case 1:
+			length, err := r.ReadUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if length > uint64(p.DstSize-total) {
+				return nil, fmt.Errorf("op %v inserts %v bytes into a target of %v bytes: %w", i, length, p.DstSize, ErrInvalidPatch)
+			}
+			data, err := r.ReadBytes(int(length))
+			if err != nil {
+				return nil, err
+			}
+			op = PatchOp{Length: int64(length), Data: data}
+		default:
+			return nil, fmt.Errorf("op %v is of the unknown kind %v: %w", i, kind, ErrInvalidPatch)
+		}
+		if total += op.Length; total > p.DstSize {
+			return nil, fmt.Errorf("the ops write more than the target of %v bytes: %w", p.DstSize, ErrInvalidPatch)
+		}
+		p.Ops = append(p.Ops, op)
+	}
+	if total != p.DstSize {
+		return nil, fmt.Errorf("the ops write %v bytes,but the target has %v bytes: %w", total, p.DstSize, ErrInvalidPatch)
+	}
+	return p, nil
+}
+
+//ApplyPatch writes the target of p at the position,the copied bytes are read from src.
+//the size and CRC32 of src are checked before anything is written and the CRC32 of the target
+//after it is written,a mismatch returns an error wrapping ErrChecksumMismatch.
+func (w *Writer) ApplyPatch(src *ReadSeeker, p *Patch) error {
+	if size := src.Size(); size != p.SrcSize {
+		return fmt.Errorf("the source has %v bytes,but the patch is made for %v bytes: %w", size, p.SrcSize, ErrChecksumMismatch)
+	}
+	crc, err := src.CRC32(0, p.SrcSize-1)
+	if err != nil {
+		return err
+	}
+	if crc != p.SrcCRC32 {
+		return fmt.Errorf("the CRC32 of the source is %08x,but the patch is made for %08x: %w", crc, p.SrcCRC32, ErrChecksumMismatch)
+	}
+	h := crc32.NewIEEE()
+	out := io.MultiWriter(w, h)
+	var total int64
+	for i, op := range p.Ops {
+		if !op.Copy {
+			if _, err = out.Write(op.Data); err != nil {
+				return err
+			}
+			total += int64(len(op.Data))
+			continue
+		}
+		if op.SrcOffset < 0 || op.Length < 0 || op.SrcOffset+op.Length > p.SrcSize {
+			return fmt.Errorf("op %v copies %v bytes at %v of a source of %v bytes: %w", i, op.Length, op.SrcOffset, p.SrcSize, ErrInvalidPatch)
+		}
+		s, err := src.Section(op.SrcOffset, op.SrcOffset+op.Length-1)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(out, s.readSeeker); err != nil {
+			return err
+		}
+		total += op.Length
+	}
+	if total != p.DstSize || h.Sum32() != p.DstCRC32 {
+		return fmt.Errorf("the target has %v bytes with the CRC32 %08x,but %v bytes with %08x are expected: %w", total, h.Sum32(), p.DstSize, p.DstCRC32, ErrChecksumMismatch)
+	}
+	return w.Err()
+}
@@ -0,0 +1,149 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomBytes(n int, seed int64) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+func TestDiff(t *testing.T) {
+	data := randomBytes(4096, 1)
+	for _, c := range []struct {
+		dst  []byte
+		want []DiffRange
+	}{
+		{data, []DiffRange{{DiffEqual, 0, 4096, 0, 4096}}},
+		//an insertion resyncs
+		{concat(data[:1000], []byte("INSERTED"), data[1000:]), []DiffRange{
+			{DiffEqual, 0, 1000, 0, 1000}, {DiffInsert, 1000, 0, 1000, 8}, {DiffEqual, 1000, 3096, 1008, 3096}}},
+		//a deletion resyncs
+		{concat(data[:2000], data[2100:]), []DiffRange{
+			{DiffEqual, 0, 2000, 0, 2000}, {DiffDelete, 2000, 100, 2000, 0}, {DiffEqual, 2100, 1996, 2000, 1996}}},
+		//patched bytes are compared byte by byte
+		{concat(data[:10], []byte{^data[10], ^data[11]}, data[12:20], []byte{^data[20]}, data[21:]), []DiffRange{
+			{DiffEqual, 0, 10, 0, 10}, {DiffReplace, 10, 2, 10, 2}, {DiffEqual, 12, 8, 12, 8}, {DiffReplace, 20, 1, 20, 1}, {DiffEqual, 21, 4075, 21, 4075}}},
+		//a replaced block with another length
+		{concat(data[:500], randomBytes(300, 2), data[700:]), []DiffRange{
+			{DiffEqual, 0, 500, 0, 500}, {DiffReplace, 500, 200, 500, 300}, {DiffEqual, 700, 3396, 800, 3396}}},
+		{nil, []DiffRange{{DiffDelete, 0, 4096, 0, 0}}},
+	} {
+		ranges, err := Diff(NewReadSeekerFromBytes(data), NewReadSeekerFromBytes(c.dst), DiffOptions{})
+		if err != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		if !reflect.DeepEqual(ranges, c.want) {
+			t.Fatalf("unexpected value obtained; got %+v want %+v", ranges, c.want)
+		}
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestDiffHexDump(t *testing.T) {
+	src := NewReadSeekerFromBytes([]byte("Hello world."))
+	dst := NewReadSeekerFromBytes([]byte("Hello, world!"))
+	ranges, err := Diff(src, dst, DiffOptions{BlockSize: 4})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	dump, err := DiffHexDump(src, dst, ranges, HexDumpOptions{Width: 8, Group: 4})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	want := "00000000  48 65 6c 6c  6f           |Hello|        00000000  48 65 6c 6c  6f           |Hello|\n" +
+		"                                                >  00000005  2c                        |,|\n" +
+		"00000005  20 77 6f 72  6c 64        | world|       00000006  20 77 6f 72  6c 64        | world|\n" +
+		"0000000b  2e                        |.|         |  0000000c  21                        |!|\n" +
+		"0000000c                                           0000000d\n"
+	if dump != want {
+		t.Fatalf("unexpected value obtained; got\n%v want\n%v", dump, want)
+	}
+	//squeeze the equal ranges
+	data := bytes.Repeat([]byte("abcd"), 16)
+	ranges, _ = Diff(NewReadSeekerFromBytes(data), NewReadSeekerFromBytes(data), DiffOptions{})
+	dump, _ = DiffHexDump(NewReadSeekerFromBytes(data), NewReadSeekerFromBytes(data), ranges, HexDumpOptions{Width: 8, NoASCII: true, Squeeze: true})
+	want = "00000000  61 62 63 64 61 62 63 64     00000000  61 62 63 64 61 62 63 64\n" +
+		"*\n" +
+		"00000038  61 62 63 64 61 62 63 64     00000038  61 62 63 64 61 62 63 64\n" +
+		"00000040                              00000040\n"
+	if dump != want {
+		t.Fatalf("unexpected value obtained; got\n%v want\n%v", dump, want)
+	}
+	_, err = DiffHexDump(src, dst, []DiffRange{{DiffEqual, 0, 20, 0, 20}}, HexDumpOptions{})
+	if err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	data := randomBytes(8192, 3)
+	//a patched header,a moved block and an insertion
+	dst := concat([]byte("HDR2"), data[4:1000], data[5000:6000], data[1000:5000], []byte("new data"), data[6000:])
+	src := NewReadSeekerFromBytes(data)
+	p, err := MakePatch(src, NewReadSeekerFromBytes(dst), DiffOptions{})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	inserted := 0
+	for _, op := range p.Ops {
+		inserted += len(op.Data)
+	}
+	//the moved block is copied,not inserted
+	if inserted != 12 {
+		t.Fatalf("unexpected value obtained; got %v want %v", inserted, 12)
+	}
+	w := NewBytesBuffer()
+	w.WritePatch(p)
+	encoded := w.Bytes()
+	p2, err := NewReadSeekerFromBytes(encoded).ReadPatch()
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if !reflect.DeepEqual(p, p2) {
+		t.Fatalf("unexpected value obtained; got %+v want %+v", p2, p)
+	}
+	out := NewBytesBuffer()
+	if err = out.ApplyPatch(src, p2); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if !bytes.Equal(out.Bytes(), dst) {
+		t.Fatalf("unexpected value obtained; got %v bytes want %v bytes", len(out.Bytes()), len(dst))
+	}
+	//another source
+	err = NewBytesBuffer().ApplyPatch(NewReadSeekerFromBytes(dst), p2)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrChecksumMismatch)
+	}
+	//an empty source and target
+	p, _ = MakePatch(NewReadSeekerFromBytes(nil), NewReadSeekerFromBytes(nil), DiffOptions{})
+	out.Reset()
+	if err = out.ApplyPatch(NewReadSeekerFromBytes(nil), p); err != nil || out.Len() != 0 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", out.Len(), err, nil)
+	}
+	//truncated and corrupted patches
+	rd := NewReadSeekerFromBytes(encoded[:len(encoded)-1])
+	if _, err = rd.ReadPatch(); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+	w.Reset()
+	w.WritePatch(&Patch{SrcSize: 4, DstSize: 4, Ops: []PatchOp{{Copy: true, SrcOffset: 2, Length: 4}}})
+	corrupted := w.Bytes()
+	for _, b := range [][]byte{[]byte("IOXQ"), corrupted} {
+		if _, err = NewReadSeekerFromBytes(b).ReadPatch(); !errors.Is(err, ErrInvalidPatch) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, ErrInvalidPatch)
+		}
+	}
+}
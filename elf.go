@@ -0,0 +1,429 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidELF = errors.New("the ELF data is not valid")
+
+//the types of sections,segments and notes used by ELF.Symbols,ELF.Notes and ELF.BuildID.
+const (
+	ELFSectionNull    = 0
+	ELFSectionSymTab  = 2
+	ELFSectionStrTab  = 3
+	ELFSectionNote    = 7
+	ELFSectionNoBits  = 8
+	ELFSectionDynSym  = 11
+	ELFProgramNote    = 4
+	ELFNoteGNUBuildID = 3
+	elfSectionXIndex  = 0xffff //the section header string table index is in the link of section 0
+)
+
+//ELF is the header,the program headers and the section headers of an ELF file.
+type ELF struct {
+	Is64       bool //ELFCLASS64,otherwise ELFCLASS32
+	BigEndian  bool //ELFDATA2MSB,otherwise ELFDATA2LSB
+	OSABI      uint8
+	ABIVersion uint8
+	Type       uint16
+	Machine    uint16
+	Version    uint32
+	Entry      uint64
+	Flags      uint32
+	Programs   []*ELFProgram
+	Sections   []*ELFSection //the names are resolved through the section header string table
+	r          *ReadSeeker
+}
+
+//ELFProgram is a program header.
+type ELFProgram struct {
+	Type     uint32
+	Flags    uint32
+	Offset   uint64
+	VAddr    uint64
+	PAddr    uint64
+	FileSize uint64
+	MemSize  uint64
+	Align    uint64
+}
+
+//ELFSection is a section header.
+type ELFSection struct {
+	Name      string
+	NameIndex uint32 //of the name in the section header string table
+	Type      uint32
+	Flags     uint64
+	Addr      uint64
+	Offset    uint64
+	Size      uint64
+	Link      uint32
+	Info      uint32
+	AddrAlign uint64
+	EntSize   uint64
+}
+
+//ELFSymbol is an entry of a symbol table.
+type ELFSymbol struct {
+	Name    string
+	Value   uint64
+	Size    uint64
+	Info    uint8 //the binding in the high 4 bits and the type in the low 4 bits
+	Other   uint8
+	Section uint16 //the index of the section the symbol is defined in
+}
+
+//ELFNote is an entry of a note section or segment.
+type ELFNote struct {
+	Name string //without its NUL
+	Type uint32
+	Desc []byte
+}
+
+//read the n entries of size bytes of a table at off,which must fit the data.
+func (r *ReadSeeker) tableAt(off, n, size uint64, what string) ([]byte, error) {
+	total := n * size
+	if n != 0 && total/n != size || off > uint64(r.Size()) || total > uint64(r.Size())-off {
+		return nil, fmt.Errorf("the %v of %v entries of %v bytes at offset %v is beyond the data of %v bytes: %w", what, n, size, off, r.Size(), io.ErrUnexpectedEOF)
+	}
+	return r.bytesAt(int64(off), int64(total))
+}
+
+//ReadELF reads the header,the program headers and the section headers of the ELF file at the current position,
+//offsets are relative to it,and the position is left unchanged.the byte order and the word size of all fields
+//are taken from the ident at the start of the header.
+func (r *ReadSeeker) ReadELF() (*ELF, error) {
+	defer r.traceEnter("ReadELF")()
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.Section(pos, r.Size()-1)
+	if err != nil {
+		return nil, err
+	}
+	ident, err := data.tableAt(0, 1, 16, "ident")
+	if err != nil {
+		return nil, err
+	}
+	if string(ident[:4]) != "\x7fELF" {
+		return nil, fmt.Errorf("the magic number %q is not \\x7fELF: %w", ident[:4], ErrInvalidELF)
+	}
+	f := &ELF{OSABI: ident[7], ABIVersion: ident[8], r: data}
+	switch ident[4] {
+	case 1:
+	case 2:
+		f.Is64 = true
+	default:
+		return nil, fmt.Errorf("the class %v is neither 1 nor 2: %w", ident[4], ErrInvalidELF)
+	}
+	switch ident[5] {
+	case 1:
+	case 2:
+		f.BigEndian = true
+	default:
+		return nil, fmt.Errorf("the data encoding %v is neither 1 nor 2: %w", ident[5], ErrInvalidELF)
+	}
+	headerLen := uint64(52)
+	if f.Is64 {
+		headerLen = 64
+	}
+	header, err := data.tableAt(0, 1, headerLen, "header")
+	if err != nil {
+		return nil, err
+	}
+	d := &fieldReader{b: header[16:], bigEndian: f.BigEndian, is64: f.Is64}
+	f.Type, f.Machine, f.Version = uint16(d.uint(2)), uint16(d.uint(2)), uint32(d.uint(4))
+	f.Entry = d.word()
+	phOff, shOff := d.word(), d.word()
+	f.Flags = uint32(d.uint(4))
+	d.uint(2) //the header size
+	phEntSize, phNum := d.uint(2), d.uint(2)
+	shEntSize, shNum, shStrIndex := d.uint(2), d.uint(2), d.uint(2)
+	if err = f.readSections(shOff, shEntSize, shNum, shStrIndex); err != nil {
+		return nil, err
+	}
+	if err = f.readPrograms(phOff, phEntSize, phNum); err != nil {
+		return nil, err
+	}
+	r.traceValue(fmt.Sprintf("%v programs,%v sections", len(f.Programs), len(f.Sections)))
+	return f, nil
+}
+
+func (f *ELF) readPrograms(off, entSize, n uint64) error {
+	size := uint64(32)
+	if f.Is64 {
+		size = 56
+	}
+	if n == 0 {
+		return nil
+	}
+	if entSize < size {
+		return fmt.Errorf("the program header size %v is less than %v: %w", entSize, size, ErrInvalidELF)
+	}
+	table, err := f.r.tableAt(off, n, entSize, "program header table")
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		d := &fieldReader{b: table[i*entSize:], bigEndian: f.BigEndian, is64: f.Is64}
+		p := &ELFProgram{Type: uint32(d.uint(4))}
+		if f.Is64 {
+			p.Flags = uint32(d.uint(4))
+		}
+		p.Offset, p.VAddr, p.PAddr, p.FileSize, p.MemSize = d.word(), d.word(), d.word(), d.word(), d.word()
+		if !f.Is64 {
+			p.Flags = uint32(d.uint(4))
+		}
+		p.Align = d.word()
+		f.Programs = append(f.Programs, p)
+	}
+	return nil
+}
+
+func (f *ELF) readSections(off, entSize, n, strIndex uint64) error {
+	size := uint64(40)
+	if f.Is64 {
+		size = 64
+	}
+	if off == 0 {
+		return nil
+	}
+	if entSize < size {
+		return fmt.Errorf("the section header size %v is less than %v: %w", entSize, size, ErrInvalidELF)
+	}
+	//with 0xff00 sections or more the count is in the size of section 0
+	first, err := f.r.tableAt(off, 1, entSize, "section header table")
+	if err != nil {
+		return err
+	}
+	d := &fieldReader{b: first[20:], bigEndian: f.BigEndian, is64: f.Is64}
+	if f.Is64 {
+		d.b = first[32:]
+	}
+	if extSize := d.word(); n == 0 {
+		n = extSize
+	}
+	table, err := f.r.tableAt(off, n, entSize, "section header table")
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		d := &fieldReader{b: table[i*entSize:], bigEndian: f.BigEndian, is64: f.Is64}
+		s := &ELFSection{NameIndex: uint32(d.uint(4)), Type: uint32(d.uint(4))}
+		s.Flags, s.Addr, s.Offset, s.Size = d.word(), d.word(), d.word(), d.word()
+		s.Link, s.Info = uint32(d.uint(4)), uint32(d.uint(4))
+		s.AddrAlign, s.EntSize = d.word(), d.word()
+		f.Sections = append(f.Sections, s)
+	}
+	if strIndex == elfSectionXIndex && n > 0 {
+		strIndex = uint64(f.Sections[0].Link)
+	}
+	if strIndex == 0 {
+		return nil
+	}
+	if strIndex >= n {
+		return fmt.Errorf("the section header string table %v is not one of the %v sections: %w", strIndex, n, ErrInvalidELF)
+	}
+	names, err := f.sectionBytes(f.Sections[strIndex])
+	if err != nil {
+		return err
+	}
+	for _, s := range f.Sections {
+		s.Name = cString(names, uint64(s.NameIndex))
+	}
+	return nil
+}
+
+//the NUL terminated string at off of a string table,empty if off is outside the table.
+func cString(table []byte, off uint64) string {
+	if off >= uint64(len(table)) {
+		return ""
+	}
+	s := table[off:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+//Section returns the first section of the name,nil if there is none.
+func (f *ELF) Section(name string) *ELFSection {
+	for _, s := range f.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+//SectionData returns the data of a section in the file,a section of ELFSectionNoBits such as .bss has none.
+func (f *ELF) SectionData(s *ELFSection) (*ReadSeeker, error) {
+	size := s.Size
+	if s.Type == ELFSectionNoBits {
+		size = 0
+	}
+	if total := uint64(f.r.Size()); s.Offset > total || size > total-s.Offset {
+		return nil, fmt.Errorf("the section %q of %v bytes at offset %v is beyond the data of %v bytes: %w", s.Name, size, s.Offset, f.r.Size(), io.ErrUnexpectedEOF)
+	}
+	return f.r.Section(int64(s.Offset), int64(s.Offset+size)-1)
+}
+
+func (f *ELF) sectionBytes(s *ELFSection) ([]byte, error) {
+	data, err := f.SectionData(s)
+	if err != nil {
+		return nil, err
+	}
+	return data.allBytes()
+}
+
+//Symbols returns the symbols of the .symtab section,with their names resolved through its linked string table.
+//the first symbol,which is always null,is included so the indexes match those of relocations.
+func (f *ELF) Symbols() ([]ELFSymbol, error) {
+	return f.symbols(ELFSectionSymTab)
+}
+
+//DynamicSymbols returns the symbols of the .dynsym section,see Symbols.
+func (f *ELF) DynamicSymbols() ([]ELFSymbol, error) {
+	return f.symbols(ELFSectionDynSym)
+}
+
+func (f *ELF) symbols(typ uint32) ([]ELFSymbol, error) {
+	var table *ELFSection
+	for _, s := range f.Sections {
+		if s.Type == typ {
+			table = s
+			break
+		}
+	}
+	if table == nil {
+		return nil, nil
+	}
+	size := uint64(16)
+	if f.Is64 {
+		size = 24
+	}
+	if table.EntSize != 0 && table.EntSize < size || int(table.Link) >= len(f.Sections) {
+		return nil, fmt.Errorf("the symbol table %q has the entry size %v and the link %v: %w", table.Name, table.EntSize, table.Link, ErrInvalidELF)
+	}
+	if table.EntSize > size {
+		size = table.EntSize
+	}
+	b, err := f.sectionBytes(table)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.sectionBytes(f.Sections[table.Link])
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]ELFSymbol, 0, uint64(len(b))/size)
+	for ; uint64(len(b)) >= size; b = b[size:] {
+		d := &fieldReader{b: b, bigEndian: f.BigEndian, is64: f.Is64}
+		var s ELFSymbol
+		nameIndex := d.uint(4)
+		if f.Is64 {
+			s.Info, s.Other, s.Section = uint8(d.uint(1)), uint8(d.uint(1)), uint16(d.uint(2))
+			s.Value, s.Size = d.uint(8), d.uint(8)
+		} else {
+			s.Value, s.Size = d.uint(4), d.uint(4)
+			s.Info, s.Other, s.Section = uint8(d.uint(1)), uint8(d.uint(1)), uint16(d.uint(2))
+		}
+		s.Name = cString(names, nameIndex)
+		symbols = append(symbols, s)
+	}
+	return symbols, nil
+}
+
+//Notes returns the notes of the sections of ELFSectionNote,or of the segments of ELFProgramNote
+//if there are no section headers.sections or segments overlapping so that the notes take more bytes
+//than the data return ErrInvalidELF.
+func (f *ELF) Notes() ([]ELFNote, error) {
+	var notes []ELFNote
+	//the notes of valid data don't overlap,so they fit in the data together
+	var total uint64
+	bound := func(size uint64, where string) error {
+		if total += size; total > uint64(f.r.Size()) {
+			return fmt.Errorf("%v makes the notes %v bytes,more than the data of %v bytes: %w", where, total, f.r.Size(), ErrInvalidELF)
+		}
+		return nil
+	}
+	add := func(b []byte, align uint64, where string) error {
+		if align != 8 {
+			align = 4
+		}
+		for len(b) > 0 {
+			if len(b) < 12 {
+				return fmt.Errorf("the note of %v has %v bytes left,less than its header: %w", where, len(b), ErrInvalidELF)
+			}
+			d := &fieldReader{b: b, bigEndian: f.BigEndian}
+			nameSize, descSize, typ := d.uint(4), d.uint(4), uint32(d.uint(4))
+			//the offsets from the start of the note are aligned,not the sizes
+			descBegin := 12 + nameSize + uint64(paddingLen(int64(12+nameSize), int64(align)))
+			next := descBegin + descSize + uint64(paddingLen(int64(descBegin+descSize), int64(align)))
+			if nameSize > uint64(len(b)) || descSize > uint64(len(b)) || descBegin+descSize > uint64(len(b)) {
+				return fmt.Errorf("the note of %v with a name of %v bytes and a desc of %v bytes has only %v bytes: %w", where, nameSize, descSize, len(b), ErrInvalidELF)
+			}
+			notes = append(notes, ELFNote{
+				Name: cString(b[12:12+nameSize], 0),
+				Type: typ,
+				Desc: append([]byte{}, b[descBegin:descBegin+descSize]...),
+			})
+			if next > uint64(len(b)) {
+				next = uint64(len(b)) //the padding of the last note may be missing
+			}
+			b = b[next:]
+		}
+		return nil
+	}
+	if len(f.Sections) > 0 {
+		for _, s := range f.Sections {
+			if s.Type != ELFSectionNote {
+				continue
+			}
+			if err := bound(s.Size, "the section "+s.Name); err != nil {
+				return nil, err
+			}
+			b, err := f.sectionBytes(s)
+			if err != nil {
+				return nil, err
+			}
+			if err = add(b, s.AddrAlign, "the section "+s.Name); err != nil {
+				return nil, err
+			}
+		}
+		return notes, nil
+	}
+	for i, p := range f.Programs {
+		if p.Type != ELFProgramNote {
+			continue
+		}
+		if err := bound(p.FileSize, fmt.Sprint("the segment ", i)); err != nil {
+			return nil, err
+		}
+		b, err := f.r.tableAt(p.Offset, 1, p.FileSize, "note segment")
+		if err != nil {
+			return nil, err
+		}
+		if err = add(b, p.Align, fmt.Sprint("the segment ", i)); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+//BuildID returns the desc of the GNU build ID note,nil if there is none.
+func (f *ELF) BuildID() ([]byte, error) {
+	notes, err := f.Notes()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		if n.Name == "GNU" && n.Type == ELFNoteGNUBuildID {
+			return n.Desc, nil
+		}
+	}
+	return nil, nil
+}
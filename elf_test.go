@@ -0,0 +1,249 @@
+package iox
+
+import (
+	"bytes"
+	"debug/elf"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+//an executable with .text,a build ID note,a symbol table,string tables and .bss,and a PT_NOTE segment.
+func testELFData(is64, bigEndian bool) []byte {
+	ehLen, phLen, shLen, symLen := 52, 32, 40, 16
+	if is64 {
+		ehLen, phLen, shLen, symLen = 64, 56, 64, 24
+	}
+	shstr := "\x00.text\x00.note.gnu.build-id\x00.symtab\x00.strtab\x00.shstrtab\x00.bss\x00"
+	strtab := "\x00main\x00data\x00"
+	name := func(s string) uint64 { return uint64(strings.Index(shstr, "\x00"+s+"\x00") + 1) }
+	textOff := ehLen + phLen
+	noteOff := textOff + 4
+	strOff := noteOff + 24
+	shstrOff := strOff + len(strtab)
+	symOff := shstrOff + len(shstr) + int(paddingLen(int64(shstrOff+len(shstr)), 8))
+	shOff := symOff + 3*symLen
+	w := NewBytesBuffer()
+	u := func(x uint64, size int) { w.WriteBytes(uintToBytes(x, size, bigEndian)) }
+	word := func(x uint64) {
+		if is64 {
+			u(x, 8)
+		} else {
+			u(x, 4)
+		}
+	}
+	class, data := byte(1), byte(1)
+	if is64 {
+		class = 2
+	}
+	if bigEndian {
+		data = 2
+	}
+	w.WriteBytes([]byte{0x7f, 'E', 'L', 'F', class, data, 1, 0})
+	w.WriteZeros(8)
+	u(2, 2)  //ET_EXEC
+	u(62, 2) //EM_X86_64
+	u(1, 4)
+	word(0x401000)
+	word(uint64(ehLen))
+	word(uint64(shOff))
+	u(0, 4)
+	u(uint64(ehLen), 2)
+	u(uint64(phLen), 2)
+	u(1, 2)
+	u(uint64(shLen), 2)
+	u(7, 2)
+	u(5, 2)
+	//the PT_NOTE segment
+	u(4, 4)
+	if is64 {
+		u(4, 4)
+	}
+	word(uint64(noteOff))
+	word(0)
+	word(0)
+	word(24)
+	word(24)
+	if !is64 {
+		u(4, 4)
+	}
+	word(4)
+	//.text and the note
+	w.WriteBytes([]byte{0x90, 0x90, 0x90, 0xc3})
+	u(4, 4)
+	u(8, 4)
+	u(ELFNoteGNUBuildID, 4)
+	w.WriteString("GNU\x00")
+	w.WriteBytes([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	w.WriteString(strtab)
+	w.WriteString(shstr)
+	w.WriteZeros(symOff - int(w.Len()))
+	//the null symbol,main in .text and data in .bss
+	for _, s := range []struct {
+		name, info, section, value, size uint64
+	}{{0, 0, 0, 0, 0}, {1, 0x12, 1, 0x401000, 4}, {6, 0x11, 6, 0x402000, 16}} {
+		u(s.name, 4)
+		if is64 {
+			u(s.info, 1)
+			u(0, 1)
+			u(s.section, 2)
+			u(s.value, 8)
+			u(s.size, 8)
+		} else {
+			u(s.value, 4)
+			u(s.size, 4)
+			u(s.info, 1)
+			u(0, 1)
+			u(s.section, 2)
+		}
+	}
+	for _, s := range []struct {
+		name, typ, flags, addr, off, size, link, info, align, entSize uint64
+	}{
+		{},
+		{name(".text"), 1, 6, 0x401000, uint64(textOff), 4, 0, 0, 1, 0},
+		{name(".note.gnu.build-id"), ELFSectionNote, 2, 0, uint64(noteOff), 24, 0, 0, 4, 0},
+		{name(".symtab"), ELFSectionSymTab, 0, 0, uint64(symOff), uint64(3 * symLen), 4, 1, 8, uint64(symLen)},
+		{name(".strtab"), ELFSectionStrTab, 0, 0, uint64(strOff), uint64(len(strtab)), 0, 0, 1, 0},
+		{name(".shstrtab"), ELFSectionStrTab, 0, 0, uint64(shstrOff), uint64(len(shstr)), 0, 0, 1, 0},
+		{name(".bss"), ELFSectionNoBits, 3, 0x402000, uint64(shOff), 16, 0, 0, 8, 0},
+	} {
+		u(s.name, 4)
+		u(s.typ, 4)
+		word(s.flags)
+		word(s.addr)
+		word(s.off)
+		word(s.size)
+		u(s.link, 4)
+		u(s.info, 4)
+		word(s.align)
+		word(s.entSize)
+	}
+	return w.Bytes()
+}
+
+func TestELF(t *testing.T) {
+	for _, c := range []struct {
+		is64, bigEndian bool
+	}{{true, false}, {false, true}, {true, true}, {false, false}} {
+		data := testELFData(c.is64, c.bigEndian)
+		want, err := elf.NewFile(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewReadSeekerFromBytes(append([]byte("xx"), data...))
+		r.MoveTo(2)
+		f, err := r.ReadELF()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Is64 != c.is64 || f.BigEndian != c.bigEndian || f.Entry != want.Entry || f.Machine != uint16(want.Machine) || len(f.Programs) != 1 {
+			t.Fatalf("unexpected value obtained; got %+v want %+v", f, want.FileHeader)
+		}
+		if len(f.Sections) != len(want.Sections) {
+			t.Fatalf("unexpected value obtained; got %v want %v", len(f.Sections), len(want.Sections))
+		}
+		for i, s := range f.Sections {
+			ws := want.Sections[i]
+			if s.Name != ws.Name || s.Offset != ws.Offset || s.Size != ws.Size || s.Type != uint32(ws.Type) || s.Link != ws.Link || s.EntSize != ws.Entsize {
+				t.Fatalf("unexpected value obtained; got %+v want %+v", s, ws.SectionHeader)
+			}
+		}
+		text, err := f.SectionData(f.Section(".text"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := text.ReadBytes(4); !bytes.Equal(b, []byte{0x90, 0x90, 0x90, 0xc3}) {
+			t.Fatalf("unexpected value obtained; got %x want %v", b, "909090c3")
+		}
+		if bss, err := f.SectionData(f.Section(".bss")); err != nil || bss.Size() != 0 {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, "an empty .bss")
+		}
+		symbols, err := f.Symbols()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantSymbols, _ := want.Symbols()
+		if len(symbols) != len(wantSymbols)+1 || symbols[0].Name != "" {
+			t.Fatalf("unexpected value obtained; got %v want %v", len(symbols), len(wantSymbols)+1)
+		}
+		for i, s := range symbols[1:] {
+			ws := wantSymbols[i]
+			if s.Name != ws.Name || s.Value != ws.Value || s.Size != ws.Size || s.Info != ws.Info || s.Section != uint16(ws.Section) {
+				t.Fatalf("unexpected value obtained; got %+v want %+v", s, ws)
+			}
+		}
+		if dyn, err := f.DynamicSymbols(); err != nil || dyn != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", dyn, nil)
+		}
+		id, err := f.BuildID()
+		if err != nil || !bytes.Equal(id, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+			t.Fatalf("unexpected value obtained; got %x want %v", id, "0102030405060708")
+		}
+		//without section headers the notes come from the PT_NOTE segment
+		f.Sections = nil
+		if id, err = f.BuildID(); err != nil || !bytes.Equal(id, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+			t.Fatalf("unexpected value obtained; got %x want %v", id, "0102030405060708")
+		}
+	}
+}
+
+func TestELFErrors(t *testing.T) {
+	truncated := testELFData(true, false)
+	truncated = truncated[:len(truncated)-10]
+	badClass := testELFData(true, false)
+	badClass[4] = 3
+	for _, c := range []struct {
+		data []byte
+		want error
+	}{
+		{[]byte("\x7fELF"), io.ErrUnexpectedEOF},
+		{[]byte("\x7fELG\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00"), ErrInvalidELF},
+		{badClass, ErrInvalidELF},
+		{truncated, io.ErrUnexpectedEOF},
+	} {
+		if _, err := NewReadSeekerFromBytes(c.data).ReadELF(); !errors.Is(err, c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, c.want)
+		}
+	}
+}
+
+func TestELFNotesAlign8(t *testing.T) {
+	//.note.gnu.property is aligned to 8 on 64-bit targets,the desc starts at 16 and not at 20
+	w := NewBytesBuffer()
+	for _, n := range []struct {
+		typ  uint32
+		desc []byte
+	}{
+		{5, []byte{2, 0, 0, 0xc0, 4, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0}}, //NT_GNU_PROPERTY_TYPE_0
+		{ELFNoteGNUBuildID, bytes.Repeat([]byte{0xab}, 20)},
+	} {
+		w.WriteUint32(4)
+		w.WriteUint32(uint32(len(n.desc)))
+		w.WriteUint32(n.typ)
+		w.WriteString("GNU\x00")
+		w.WriteBytes(n.desc)
+		w.WriteZeros(int(paddingLen(w.Len(), 8)))
+	}
+	data := w.Bytes()
+	f := &ELF{
+		Sections: []*ELFSection{{Name: ".note.gnu.property", Type: ELFSectionNote, Size: uint64(len(data)), AddrAlign: 8}},
+		r:        NewReadSeekerFromBytes(data),
+	}
+	notes, err := f.Notes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].Name != "GNU" || notes[0].Type != 5 || notes[0].Desc[0] != 2 || len(notes[0].Desc) != 16 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", notes, "a property note and a build ID")
+	}
+	if id, err := f.BuildID(); err != nil || !bytes.Equal(id, bytes.Repeat([]byte{0xab}, 20)) {
+		t.Fatalf("unexpected value obtained; got %x,%v want %v", id, err, "20 bytes of ab")
+	}
+	//a second section over the same notes makes them bigger than the data
+	f.Sections = append(f.Sections, &ELFSection{Name: ".note.copy", Type: ELFSectionNote, Size: uint64(len(data)), AddrAlign: 8})
+	if _, err := f.Notes(); !errors.Is(err, ErrInvalidELF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrInvalidELF)
+	}
+}
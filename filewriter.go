@@ -0,0 +1,204 @@
+package iox
+
+import (
+	"bufio"
+	"math/rand"
+	"os"
+	"strconv"
+)
+
+//fileBackend keeps the data of a Writer in a temp file next to the final file,
+//appends go through a buffer and writes at other positions seek the file.
+type fileBackend struct {
+	file *os.File
+	path string
+	buf  *bufio.Writer
+	size int64 //the length of the data including the buffered part
+	tail int64 //where the buffered part goes in the file
+	err  error //the first error,every later write is ignored
+}
+
+//returns a *Writer that writes into a temp file in the directory of fileName,
+//the temp file becomes fileName when Close succeeds,so readers never see a half written file.
+//errors are kept and returned by Err,Flush,Sync and Close.
+func NewWriterToFile(fileName string) (*Writer, error) {
+	file, err := createTemp(fileName)
+	if err != nil {
+		return nil, err
+	}
+	f := &fileBackend{
+		file: file,
+		path: fileName,
+	}
+	f.buf = bufio.NewWriterSize(f, 64<<10)
+	w := new(Writer)
+	w.file = f
+	return w, nil
+}
+
+//create a temp file next to fileName with the mode 0666 less the umask like os.Create,
+//os.CreateTemp would make it 0600.
+func createTemp(fileName string) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := fileName + "." + strconv.FormatUint(uint64(rand.Uint32()), 10) + ".tmp"
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		}
+		return file, err
+	}
+}
+
+//Write is used by the buffer,it writes at the tail instead of the offset of the file
+//which is not moved by WriteAt and Truncate.
+func (f *fileBackend) Write(p []byte) (int, error) {
+	n, err := f.file.WriteAt(p, f.tail)
+	f.tail += int64(n)
+	return n, err
+}
+
+func (f *fileBackend) flush() error {
+	if f.err == nil {
+		f.err = f.buf.Flush()
+	}
+	return f.err
+}
+
+func (f *fileBackend) writeAt(p []byte, off int64) {
+	if f.err != nil {
+		return
+	}
+	if off == f.size {
+		_, f.err = f.buf.Write(p)
+	} else if f.flush() == nil {
+		_, f.err = f.file.WriteAt(p, off)
+	}
+	if end := off + int64(len(p)); f.err == nil && end > f.size {
+		f.size = end
+		f.tail = end - int64(f.buf.Buffered())
+	}
+}
+
+func (f *fileBackend) truncate(size int64) error {
+	if f.flush() != nil {
+		return f.err
+	}
+	if f.err = f.file.Truncate(size); f.err == nil {
+		f.size = size
+		f.tail = size
+	}
+	return f.err
+}
+
+func (f *fileBackend) bytes() []byte {
+	if f.flush() != nil {
+		return nil
+	}
+	b := make([]byte, f.size)
+	if _, err := f.file.ReadAt(b, 0); err != nil {
+		f.err = err
+		return nil
+	}
+	return b
+}
+
+func (f *fileBackend) readAt(off, n int64) []byte {
+	b := make([]byte, n)
+	if f.flush() != nil {
+		return b
+	}
+	if _, err := f.file.ReadAt(b, off); err != nil {
+		f.err = err
+	}
+	return b
+}
+
+//returns the first error of a file Writer,it is always nil for an in-memory Writer.
+func (w *Writer) Err() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.err
+}
+
+//write the buffered data into the file.
+func (w *Writer) Flush() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.flush()
+}
+
+//commit the data of a file Writer to stable storage.
+func (w *Writer) Sync() error {
+	if w.file == nil {
+		return nil
+	}
+	if w.file.flush() == nil {
+		w.file.err = w.file.file.Sync()
+	}
+	return w.file.err
+}
+
+//changes the length of the data,growing it with zero bytes if size is bigger,
+//the position is not changed.
+func (w *Writer) Truncate(size int64) error {
+	if size < 0 {
+		panic("the size can't be negative.")
+	}
+	if w.file != nil {
+		return w.file.truncate(size)
+	}
+	if size <= w.Len() {
+		w.writer.Truncate(int(size))
+	} else {
+		w.writer.Write(make([]byte, size-w.Len()))
+	}
+	return nil
+}
+
+//Close a file Writer,the temp file is synced and renamed to the final file.
+//a replaced file keeps its mode,a new file gets 0666 less the umask like os.Create.
+//if any write failed the temp file is removed and the error is returned.
+//it does nothing for an in-memory Writer.
+func (w *Writer) Close() error {
+	f := w.file
+	if f == nil {
+		return nil
+	}
+	if f.file == nil {
+		return f.err
+	}
+	err := w.Sync()
+	if info, statErr := os.Stat(f.path); err == nil && statErr == nil {
+		err = f.file.Chmod(info.Mode().Perm())
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.file.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.file.Name())
+		f.err = err
+	}
+	f.file = nil
+	if f.err == nil {
+		f.err = os.ErrClosed
+	}
+	return err
+}
+
+//Abort closes a file Writer and removes the temp file,the final file is not touched.
+func (w *Writer) Abort() error {
+	f := w.file
+	if f == nil || f.file == nil {
+		return nil
+	}
+	f.file.Close()
+	err := os.Remove(f.file.Name())
+	f.file = nil
+	f.err = os.ErrClosed
+	return err
+}
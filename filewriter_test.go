@@ -0,0 +1,105 @@
+package iox
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterToFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "data.bin")
+	wr, err := NewWriterToFile(fileName)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	mem := NewBytesBuffer()
+	for _, w := range []*Writer{wr, mem} {
+		w.WriteUint32BigEndian(0) //placeholder for the count
+		for i := 0; i < 100000; i++ {
+			w.WriteStringUint16("test str")
+			w.WriteFloat64(float64(i))
+		}
+		w.WriteUint32BigEndianAt(0, 100000)
+		w.Seek(4, io.SeekStart)
+		w.WriteStringUint16("TEST STR")
+		w.Seek(0, io.SeekEnd)
+		w.WriteZeros(10)
+		w.Truncate(w.Len() - 5)
+		w.Seek(0, io.SeekEnd)
+		w.Align(16, 0xff)
+	}
+	if wr.Len() != mem.Len() || wr.Pos() != mem.Pos() {
+		t.Fatalf("unexpected value obtained; got %v %v want %v %v", wr.Len(), wr.Pos(), mem.Len(), mem.Pos())
+	}
+	//nothing is visible before Close
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, os.ErrNotExist)
+	}
+	if err = wr.Close(); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	b, err := os.ReadFile(fileName)
+	if err != nil || !bytes.Equal(b, mem.Bytes()) {
+		t.Fatalf("unexpected value obtained; got %v bytes %v want %v bytes", len(b), err, mem.Len())
+	}
+	rd, _ := NewReadSeekerFromFile(fileName)
+	defer rd.Close()
+	n, _ := rd.ReadUint32BigEndian()
+	s, _ := rd.ReadStringUint16()
+	if n != 100000 || s != "TEST STR" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v %v", n, s, 100000, "TEST STR")
+	}
+	//writes after Close fail
+	wr.WriteUint8(1)
+	if wr.Err() == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", wr.Err())
+	}
+	//Abort leaves no file behind
+	wr, _ = NewWriterToFile(filepath.Join(filepath.Dir(fileName), "aborted.bin"))
+	wr.WriteString("test str")
+	if err = wr.Abort(); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(fileName))
+	if len(entries) != 1 {
+		t.Fatalf("unexpected value obtained; got %v want %v", len(entries), 1)
+	}
+}
+
+func TestWriterToFileMode(t *testing.T) {
+	dir := t.TempDir()
+	//a new file gets the mode of os.Create
+	ref, err := os.Create(filepath.Join(dir, "ref.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref.Close()
+	refInfo, _ := os.Stat(ref.Name())
+	fileName := filepath.Join(dir, "data.bin")
+	wr, err := NewWriterToFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wr.WriteString("new")
+	if err = wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(fileName); info.Mode() != refInfo.Mode() {
+		t.Fatalf("unexpected value obtained; got %v want %v", info.Mode(), refInfo.Mode())
+	}
+	//a replaced file keeps its mode
+	if err = os.Chmod(fileName, 0600); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.Stat(fileName)
+	wr, _ = NewWriterToFile(fileName)
+	wr.WriteString("replaced")
+	if err = wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(fileName); info.Mode() != want.Mode() {
+		t.Fatalf("unexpected value obtained; got %v want %v", info.Mode(), want.Mode())
+	}
+}
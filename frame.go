@@ -0,0 +1,316 @@
+package iox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+)
+
+//FramePrefix is the type of the length written in front of every frame.
+type FramePrefix int
+
+const (
+	FramePrefixUint32 FramePrefix = iota
+	FramePrefixUint8
+	FramePrefixUint16
+	FramePrefixUint64
+	FramePrefixVarint
+)
+
+//the max frame size used when FrameOptions.MaxFrameSize is 0.
+const DefaultMaxFrameSize = 16 << 20
+
+var (
+	ErrFrameTooLarge = errors.New("the frame is larger than the max frame size")
+	ErrFrameMagic    = errors.New("the frame magic does not match")
+	ErrFrameCRC      = errors.New("the frame crc32 does not match")
+)
+
+//FrameOptions describes the layout of a frame:
+//[HeaderMagic][length][payload][crc32 of payload][TrailerMagic]
+//the length only counts the payload, the zero value is a little endian uint32 length without magic or crc.
+type FrameOptions struct {
+	Prefix       FramePrefix
+	BigEndian    bool   //byte order of the length and the crc32,not used by FramePrefixVarint
+	MaxFrameSize int    //the biggest payload accepted or written,0 means DefaultMaxFrameSize
+	HeaderMagic  []byte //written before the length when not empty
+	TrailerMagic []byte //written after the payload when not empty
+	CRC          bool   //write the crc32(IEEE) of the payload after the payload
+}
+
+func (o FrameOptions) maxFrameSize() int {
+	if o.MaxFrameSize <= 0 {
+		return DefaultMaxFrameSize
+	}
+	return o.MaxFrameSize
+}
+
+//the biggest length the prefix can hold.
+func (o FrameOptions) maxPrefix() uint64 {
+	switch o.Prefix {
+	case FramePrefixUint8:
+		return 1<<8 - 1
+	case FramePrefixUint16:
+		return 1<<16 - 1
+	case FramePrefixUint32:
+		return 1<<32 - 1
+	case FramePrefixUint64, FramePrefixVarint:
+		return 1<<64 - 1
+	}
+	panic(fmt.Sprint("unknown frame prefix:", o.Prefix))
+}
+
+//FrameReader reads length prefixed frames from a stream such as net.Conn.
+type FrameReader struct {
+	conn io.Reader
+	rd   *Reader
+	opts FrameOptions
+}
+
+//returns a *FrameReader from io.Reader.
+func NewFrameReader(conn io.Reader, opts FrameOptions) *FrameReader {
+	opts.maxPrefix() //panics on an unknown prefix
+	r := new(FrameReader)
+	r.conn = conn
+	r.rd = NewReader(conn)
+	r.opts = opts
+	return r
+}
+
+//read the next frame and returns its payload.
+//io.EOF is returned only when the stream ends cleanly between two frames.
+func (r *FrameReader) ReadFrame() ([]byte, error) {
+	return r.ReadFrameContext(context.Background())
+}
+
+//read the next frame,giving up when ctx is done.
+//the read is interrupted when ctx is done if the connection has SetReadDeadline,
+//after a failure in the middle of a frame the stream can't be used any more.
+func (r *FrameReader) ReadFrameContext(ctx context.Context) ([]byte, error) {
+	var setDeadline func(time.Time) error
+	if conn, ok := r.conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+		setDeadline = conn.SetReadDeadline
+	}
+	stop, err := watchContext(ctx, setDeadline)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := r.readFrame()
+	stop()
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return payload, err
+}
+
+func (r *FrameReader) readFrame() ([]byte, error) {
+	begin := r.rd.Consumed()
+	if err := r.readMagic(r.opts.HeaderMagic, begin); err != nil {
+		return nil, err
+	}
+	n, err := r.readPrefix()
+	if err != nil {
+		if err == io.EOF && r.rd.Consumed() != begin {
+			err = fmt.Errorf("the frame at position %v has no length: %w", begin, io.ErrUnexpectedEOF)
+		}
+		return nil, err
+	}
+	if n > uint64(r.opts.maxFrameSize()) {
+		return nil, fmt.Errorf("the frame at position %v is %v bytes: %w", begin, n, ErrFrameTooLarge)
+	}
+	payload, err := r.rd.readBytesAfterPrefix(n)
+	if err != nil {
+		return nil, err
+	}
+	if r.opts.CRC {
+		var sum uint32
+		if r.opts.BigEndian {
+			sum, err = r.rd.ReadUint32BigEndian()
+		} else {
+			sum, err = r.rd.ReadUint32()
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sum != crc32.ChecksumIEEE(payload) {
+			return nil, fmt.Errorf("the frame at position %v: %w", begin, ErrFrameCRC)
+		}
+	}
+	if err := r.readMagic(r.opts.TrailerMagic, begin); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (r *FrameReader) readMagic(magic []byte, begin int64) error {
+	if len(magic) == 0 {
+		return nil
+	}
+	bt, err := r.rd.ReadBytes(len(magic))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(bt, magic) {
+		return fmt.Errorf("the frame at position %v has magic %X instead of %X: %w", begin, bt, magic, ErrFrameMagic)
+	}
+	return nil
+}
+
+func (r *FrameReader) readPrefix() (uint64, error) {
+	switch r.opts.Prefix {
+	case FramePrefixUint8:
+		n, err := r.rd.ReadUint8()
+		return uint64(n), err
+	case FramePrefixUint16:
+		if r.opts.BigEndian {
+			n, err := r.rd.ReadUint16BigEndian()
+			return uint64(n), err
+		}
+		n, err := r.rd.ReadUint16()
+		return uint64(n), err
+	case FramePrefixUint32:
+		if r.opts.BigEndian {
+			n, err := r.rd.ReadUint32BigEndian()
+			return uint64(n), err
+		}
+		n, err := r.rd.ReadUint32()
+		return uint64(n), err
+	case FramePrefixUint64:
+		if r.opts.BigEndian {
+			return r.rd.ReadUint64BigEndian()
+		}
+		return r.rd.ReadUint64()
+	default:
+		return r.rd.ReadUvarint()
+	}
+}
+
+//FrameWriter writes length prefixed frames into a stream such as net.Conn.
+//every frame is sent with a single Write call,so it is safe for concurrent use.
+type FrameWriter struct {
+	conn io.Writer
+	opts FrameOptions
+	mu   sync.Mutex
+}
+
+//returns a *FrameWriter from io.Writer.
+func NewFrameWriter(conn io.Writer, opts FrameOptions) *FrameWriter {
+	opts.maxPrefix() //panics on an unknown prefix
+	w := new(FrameWriter)
+	w.conn = conn
+	w.opts = opts
+	return w
+}
+
+//write p as one frame.
+func (w *FrameWriter) WriteFrame(p []byte) error {
+	return w.WriteFrameContext(context.Background(), p)
+}
+
+//write p as one frame,giving up when ctx is done.
+//the write is interrupted when ctx is done if the connection has SetWriteDeadline.
+func (w *FrameWriter) WriteFrameContext(ctx context.Context, p []byte) error {
+	if len(p) > w.opts.maxFrameSize() || uint64(len(p)) > w.opts.maxPrefix() {
+		return fmt.Errorf("the frame is %v bytes: %w", len(p), ErrFrameTooLarge)
+	}
+	frame := w.frame(p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var setDeadline func(time.Time) error
+	if conn, ok := w.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		setDeadline = conn.SetWriteDeadline
+	}
+	stop, err := watchContext(ctx, setDeadline)
+	if err != nil {
+		return err
+	}
+	_, err = w.conn.Write(frame)
+	stop()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+//build the whole frame in memory.
+func (w *FrameWriter) frame(p []byte) []byte {
+	wr := NewBytesBuffer()
+	wr.WriteBytes(w.opts.HeaderMagic)
+	switch w.opts.Prefix {
+	case FramePrefixUint8:
+		wr.WriteUint8(uint8(len(p)))
+	case FramePrefixUint16:
+		if w.opts.BigEndian {
+			wr.WriteUint16BigEndian(uint16(len(p)))
+		} else {
+			wr.WriteUint16(uint16(len(p)))
+		}
+	case FramePrefixUint32:
+		if w.opts.BigEndian {
+			wr.WriteUint32BigEndian(uint32(len(p)))
+		} else {
+			wr.WriteUint32(uint32(len(p)))
+		}
+	case FramePrefixUint64:
+		if w.opts.BigEndian {
+			wr.WriteUint64BigEndian(uint64(len(p)))
+		} else {
+			wr.WriteUint64(uint64(len(p)))
+		}
+	default:
+		wr.WriteUvarint(uint64(len(p)))
+	}
+	wr.WriteBytes(p)
+	if w.opts.CRC {
+		if w.opts.BigEndian {
+			wr.WriteUint32BigEndian(crc32.ChecksumIEEE(p))
+		} else {
+			wr.WriteUint32(crc32.ChecksumIEEE(p))
+		}
+	}
+	wr.WriteBytes(w.opts.TrailerMagic)
+	return wr.Bytes()
+}
+
+//interrupt the blocked call by moving the deadline of the connection into the past
+//when ctx is done or its deadline passes,the returned stop must be called when the call returns.
+//the deadline is cleared by stop only if it was moved,a deadline set by the caller is kept otherwise.
+func watchContext(ctx context.Context, setDeadline func(time.Time) error) (stop func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if setDeadline == nil || ctx.Done() == nil {
+		return func() {}, nil
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var interrupted bool //written by the goroutine before wg.Done
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			setDeadline(time.Unix(1, 0))
+			interrupted = true
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		if interrupted {
+			setDeadline(time.Time{})
+		}
+	}, nil
+}
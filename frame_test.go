@@ -0,0 +1,161 @@
+package iox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFrame(t *testing.T) {
+	payloads := [][]byte{[]byte("test str"), {}, bytes.Repeat([]byte{0x10}, 200)}
+	optsList := []FrameOptions{
+		{},
+		{Prefix: FramePrefixUint8},
+		{Prefix: FramePrefixUint16, BigEndian: true},
+		{Prefix: FramePrefixUint32, BigEndian: true, CRC: true},
+		{Prefix: FramePrefixUint64},
+		{Prefix: FramePrefixUint64, BigEndian: true, HeaderMagic: []byte("IOX"), TrailerMagic: []byte{0xff}},
+		{Prefix: FramePrefixVarint, CRC: true, HeaderMagic: []byte{0xca, 0xfe}},
+	}
+	for _, opts := range optsList {
+		client, server := net.Pipe()
+		fw := NewFrameWriter(client, opts)
+		go func() {
+			for _, p := range payloads {
+				if err := fw.WriteFrame(p); err != nil {
+					panic(err)
+				}
+			}
+			client.Close()
+		}()
+		fr := NewFrameReader(server, opts)
+		for _, want := range payloads {
+			got, err := fr.ReadFrame()
+			if err != nil {
+				t.Fatalf("%+v: unexpected value obtained; got %v want %v", opts, err, nil)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("%+v: unexpected value obtained; got %v want %v", opts, got, want)
+			}
+		}
+		_, err := fr.ReadFrame()
+		if err != io.EOF {
+			t.Fatalf("%+v: unexpected value obtained; got %v want %v", opts, err, io.EOF)
+		}
+		server.Close()
+	}
+	//the frame layout matches WriteBytesUint32BigEndian
+	wr := NewBytesBuffer()
+	wr.WriteBytesUint32BigEndian([]byte("test str"))
+	fr := NewFrameReader(bytes.NewReader(wr.Bytes()), FrameOptions{BigEndian: true})
+	got, err := fr.ReadFrame()
+	if err != nil || string(got) != "test str" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", got, err, "test str")
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	var buf bytes.Buffer
+	//max frame size
+	fw := NewFrameWriter(&buf, FrameOptions{Prefix: FramePrefixUint8})
+	err := fw.WriteFrame(make([]byte, 256))
+	if !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrFrameTooLarge)
+	}
+	fw = NewFrameWriter(&buf, FrameOptions{})
+	fw.WriteFrame(make([]byte, 100))
+	fr := NewFrameReader(&buf, FrameOptions{MaxFrameSize: 99})
+	_, err = fr.ReadFrame()
+	if !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrFrameTooLarge)
+	}
+	//magic
+	buf.Reset()
+	fw = NewFrameWriter(&buf, FrameOptions{HeaderMagic: []byte("AB")})
+	fw.WriteFrame([]byte("test str"))
+	fr = NewFrameReader(&buf, FrameOptions{HeaderMagic: []byte("AC")})
+	_, err = fr.ReadFrame()
+	if !errors.Is(err, ErrFrameMagic) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrFrameMagic)
+	}
+	//crc
+	buf.Reset()
+	fw = NewFrameWriter(&buf, FrameOptions{CRC: true})
+	fw.WriteFrame([]byte("test str"))
+	data := buf.Bytes()
+	data[5] ^= 0xff
+	fr = NewFrameReader(bytes.NewReader(data), FrameOptions{CRC: true})
+	_, err = fr.ReadFrame()
+	if !errors.Is(err, ErrFrameCRC) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrFrameCRC)
+	}
+	//truncated frame
+	fr = NewFrameReader(bytes.NewReader(data[:6]), FrameOptions{CRC: true})
+	_, err = fr.ReadFrame()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestFrameContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	//nobody writes,the deadline of ctx must stop the read
+	fr := NewFrameReader(server, FrameOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := fr.ReadFrameContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, context.DeadlineExceeded)
+	}
+	//nobody reads,cancel must stop the write
+	fw := NewFrameWriter(client, FrameOptions{})
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	err = fw.WriteFrameContext(ctx, []byte("test str"))
+	if err != context.Canceled {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, context.Canceled)
+	}
+	//the deadlines are cleared afterwards
+	go fw.WriteFrame([]byte("test str"))
+	got, err := NewFrameReader(server, FrameOptions{}).ReadFrame()
+	if err != nil || string(got) != "test str" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", got, err, "test str")
+	}
+}
+
+//a connection that records its read deadline.
+type deadlineConn struct {
+	io.Reader
+	deadlines []time.Time
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.deadlines = append(c.deadlines, t)
+	return nil
+}
+
+func TestFrameContextDeadline(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteUint32(3)
+	w.WriteString("abc")
+	conn := &deadlineConn{Reader: bytes.NewReader(w.Bytes())}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	//the read is not interrupted,the deadline of the caller is not touched
+	got, err := NewFrameReader(conn, FrameOptions{}).ReadFrameContext(ctx)
+	if err != nil || string(got) != "abc" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", got, err, "abc")
+	}
+	if len(conn.deadlines) != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", conn.deadlines, "no deadline set")
+	}
+}
@@ -0,0 +1,261 @@
+package iox
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//HexHighlight marks a byte range of a hexdump with a label.
+type HexHighlight struct {
+	Offset int64
+	Length int64
+	Label  string
+}
+
+//HexDumpOptions describes the layout of a hexdump,the zero value is the layout of hexdump -C.
+type HexDumpOptions struct {
+	Width      int  //bytes per line,0 means 16
+	Group      int  //an extra space is written after every Group bytes,0 means 8
+	Upper      bool //upper case hex digits
+	NoASCII    bool //leave out the |ascii| gutter
+	Squeeze    bool //replace repeated lines with a single "*" line like hexdump does
+	Highlights []HexHighlight
+}
+
+func (o HexDumpOptions) width() int {
+	if o.Width <= 0 {
+		return 16
+	}
+	return o.Width
+}
+
+func (o HexDumpOptions) group() int {
+	if o.Group <= 0 {
+		return 8
+	}
+	return o.Group
+}
+
+//column of the i-th byte of a line,counted from the end of the offset column.
+func (o HexDumpOptions) column(i int) int {
+	return 2 + 3*i + i/o.group()
+}
+
+//returns a hexdump of the data between beginPos and endPos(both included) like hexdump -C.
+//the highlighted ranges are marked on extra lines below the data,they are ignored by ParseHexDump.
+func (r *ReadSeeker) HexDump(beginPos, endPos int64, opts HexDumpOptions) (string, error) {
+	var buf bytes.Buffer
+	err := r.WriteHexDump(&buf, beginPos, endPos, opts)
+	return buf.String(), err
+}
+
+//writes the hexdump described by HexDump into w.
+func (r *ReadSeeker) WriteHexDump(w io.Writer, beginPos, endPos int64, opts HexDumpOptions) error {
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	defer r.readSeeker.Seek(initialPos, io.SeekStart)
+	if size := r.Size(); beginPos < 0 || endPos >= size || endPos+1 < beginPos {
+		return fmt.Errorf("beginPos:%v or endPos:%v is not a valid value,the size of the data is %v", beginPos, endPos, size)
+	}
+	if _, err = r.readSeeker.Seek(beginPos, io.SeekStart); err != nil {
+		return err
+	}
+	highlights := append([]HexHighlight{}, opts.Highlights...)
+	sort.SliceStable(highlights, func(i, j int) bool {
+		return highlights[i].Offset < highlights[j].Offset
+	})
+	width := int64(opts.width())
+	line := make([]byte, width)
+	var prev []byte
+	squeezed := false
+	for pos := beginPos; pos <= endPos; pos += width {
+		n := width
+		if endPos-pos+1 < n {
+			n = endPos - pos + 1
+		}
+		if _, err = io.ReadFull(r.readSeeker, line[:n]); err != nil {
+			return err
+		}
+		if opts.Squeeze && prev != nil && bytes.Equal(prev, line[:n]) && !highlighted(highlights, pos, n) {
+			if !squeezed {
+				if _, err = io.WriteString(w, "*\n"); err != nil {
+					return err
+				}
+				squeezed = true
+			}
+			continue
+		}
+		squeezed = false
+		prev = append(prev[:0], line[:n]...)
+		if _, err = io.WriteString(w, opts.formatLine(pos, line[:n])); err != nil {
+			return err
+		}
+		for _, h := range highlights {
+			if h.Offset < pos+n && h.Offset+h.Length > pos && h.Length > 0 {
+				if _, err = io.WriteString(w, opts.formatMarker(pos, n, h)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	_, err = fmt.Fprintf(w, "%08x\n", endPos+1)
+	return err
+}
+
+//reports whether any highlight covers a byte of the line.
+func highlighted(highlights []HexHighlight, pos, n int64) bool {
+	for _, h := range highlights {
+		if h.Offset < pos+n && h.Offset+h.Length > pos && h.Length > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//format one line of data.
+func (o HexDumpOptions) formatLine(pos int64, data []byte) string {
+	var sb strings.Builder
+	digits := "0123456789abcdef"
+	if o.Upper {
+		digits = "0123456789ABCDEF"
+	}
+	fmt.Fprintf(&sb, "%08x ", pos)
+	for i := 0; i < o.width(); i++ {
+		if i%o.group() == 0 {
+			sb.WriteByte(' ')
+		}
+		if i < len(data) {
+			sb.WriteByte(digits[data[i]>>4])
+			sb.WriteByte(digits[data[i]&0x0f])
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString("   ")
+		}
+	}
+	if !o.NoASCII {
+		sb.WriteString(" |")
+		for _, b := range data {
+			if b >= 0x20 && b < 0x7f {
+				sb.WriteByte(b)
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('|')
+	}
+	return strings.TrimRight(sb.String(), " ") + "\n"
+}
+
+//format the marker line of a highlight below a line of data.
+func (o HexDumpOptions) formatMarker(pos, n int64, h HexHighlight) string {
+	first := h.Offset - pos
+	if first < 0 {
+		first = 0
+	}
+	last := h.Offset + h.Length - 1 - pos
+	if last >= n {
+		last = n - 1
+	}
+	offsetWidth := len(fmt.Sprintf("%08x", pos))
+	begin := offsetWidth + o.column(int(first))
+	end := offsetWidth + o.column(int(last)) + 2
+	return strings.Repeat(" ", begin) + strings.Repeat("^", end-begin) + " " + h.Label + "\n"
+}
+
+//Highlights returns the records of the trace as hexdump highlights,
+//labelled with the label of the read or the method if it has no label.
+func (t *Trace) Highlights() []HexHighlight {
+	highlights := make([]HexHighlight, 0, len(t.Records))
+	for _, rec := range t.Records {
+		label := rec.Label
+		if label == "" {
+			label = rec.Method
+		}
+		highlights = append(highlights, HexHighlight{Offset: rec.Offset, Length: rec.Length, Label: label})
+	}
+	return highlights
+}
+
+//ParseHexDump turns the output of hexdump -C,xxd or HexDump back into bytes.
+//the data starts with the first offset of the dump,"*" lines are expanded
+//and lines beginning with a space,like highlight markers,are ignored.
+func ParseHexDump(dump string) ([]byte, error) {
+	var data, prev []byte
+	var expect int64
+	first, repeat := true, false
+	for i, line := range strings.Split(dump, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if line == "*" {
+			repeat = true
+			continue
+		}
+		offsetText := line
+		rest := ""
+		xxd := false
+		if j := strings.IndexAny(line, ": "); j >= 0 {
+			offsetText, rest = line[:j], line[j+1:]
+			xxd = line[j] == ':'
+		}
+		offset, err := strconv.ParseInt(offsetText, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v is not a valid offset", i+1, offsetText)
+		}
+		if first {
+			expect, first = offset, false
+		}
+		if repeat {
+			if len(prev) == 0 || (offset-expect)%int64(len(prev)) != 0 {
+				return nil, fmt.Errorf("line %v: the offset %x does not end a repeat of %v bytes", i+1, offset, len(prev))
+			}
+			for expect < offset {
+				data = append(data, prev...)
+				expect += int64(len(prev))
+			}
+			repeat = false
+		}
+		if offset != expect {
+			return nil, fmt.Errorf("line %v: the offset is %x,but %x is expected", i+1, offset, expect)
+		}
+		if xxd {
+			//"0000: 4865 6c6c  He.." the ascii column follows two spaces
+			rest = strings.TrimPrefix(rest, " ")
+			if j := strings.Index(rest, "  "); j >= 0 {
+				rest = rest[:j]
+			}
+		} else if j := strings.IndexByte(rest, '|'); j >= 0 {
+			rest = rest[:j]
+		}
+		bt, err := hex.DecodeString(strings.Join(strings.Fields(rest), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", i+1, err)
+		}
+		data = append(data, bt...)
+		expect += int64(len(bt))
+		if len(bt) > 0 {
+			prev = bt
+		}
+	}
+	if repeat {
+		return nil, fmt.Errorf("the dump ends with \"*\" but without the final offset")
+	}
+	return data, nil
+}
+
+//returns a *ReadSeeker from the output of hexdump -C,xxd or HexDump,see ParseHexDump.
+func NewReadSeekerFromHexDump(dump string) (*ReadSeeker, error) {
+	b, err := ParseHexDump(dump)
+	if err != nil {
+		return nil, err
+	}
+	return NewReadSeekerFromBytes(b), nil
+}
@@ -0,0 +1,94 @@
+package iox
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHexDump(t *testing.T) {
+	rd := NewReadSeekerFromBytes([]byte("Hello world.\nHello world.\n"))
+	dump, err := rd.HexDump(0, rd.Size()-1, HexDumpOptions{})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	want := "00000000  48 65 6c 6c 6f 20 77 6f  72 6c 64 2e 0a 48 65 6c  |Hello world..Hel|\n" +
+		"00000010  6c 6f 20 77 6f 72 6c 64  2e 0a                    |lo world..|\n" +
+		"0000001a\n"
+	if dump != want {
+		t.Fatalf("unexpected value obtained; got\n%v want\n%v", dump, want)
+	}
+	//range,width,group and highlights
+	dump, err = rd.HexDump(6, 12, HexDumpOptions{Width: 4, Group: 2, Upper: true, Highlights: []HexHighlight{{Offset: 9, Length: 3, Label: "rld"}}})
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	want = "00000006  77 6F  72 6C  |worl|\n" +
+		"                    ^^ rld\n" +
+		"0000000a  64 2E  0A     |d..|\n" +
+		"          ^^^^^ rld\n" +
+		"0000000d\n"
+	if dump != want {
+		t.Fatalf("unexpected value obtained; got\n%v want\n%v", dump, want)
+	}
+	//the position is left unchanged
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+	_, err = rd.HexDump(0, rd.Size(), HexDumpOptions{})
+	if err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
+
+func TestParseHexDump(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0}, 64), []byte("Hello world.\n")...)
+	rd := NewReadSeekerFromBytes(data)
+	for _, opts := range []HexDumpOptions{{}, {Squeeze: true}, {Width: 10, Group: 3, NoASCII: true}, {Highlights: []HexHighlight{{Offset: 60, Length: 8, Label: "x"}}}} {
+		dump, err := rd.HexDump(0, rd.Size()-1, opts)
+		if err != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		rd2, err := NewReadSeekerFromHexDump(dump)
+		if err != nil {
+			t.Fatalf("%+v: unexpected value obtained; got %v want %v", opts, err, nil)
+		}
+		b, _ := rd2.ReadBytesUnRead()
+		if !bytes.Equal(b, data) {
+			t.Fatalf("%+v: unexpected value obtained; got %v want %v", opts, b, data)
+		}
+	}
+	//xxd
+	xxd := "00000000: 4865 6c6c 6f20 776f 726c 642e 0a48 656c  Hello world..Hel\n" +
+		"00000010: 6c6f 2061 6263 6465 660a                 lo abcdef.\n"
+	b, err := ParseHexDump(xxd)
+	if err != nil || string(b) != "Hello world.\nHello abcdef\n" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", b, err, "Hello world.\nHello abcdef\n")
+	}
+	//a dump of a range starts with its first offset
+	dump, _ := rd.HexDump(64, 68, HexDumpOptions{})
+	b, err = ParseHexDump(dump)
+	if err != nil || string(b) != "Hello" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", b, err, "Hello")
+	}
+	//broken offsets
+	_, err = ParseHexDump(strings.Replace(xxd, "00000010", "00000011", 1))
+	if err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
+
+func TestTraceHighlights(t *testing.T) {
+	wr := NewBytesBuffer()
+	wr.WriteUint16(1)
+	wr.WriteStringUint8("iox")
+	rd := NewReadSeekerFromBytes(wr.Bytes())
+	trace := rd.EnableTrace()
+	rd.Label("version")
+	rd.ReadUint16()
+	rd.ReadStringUint8()
+	dump, _ := rd.HexDump(0, rd.Size()-1, HexDumpOptions{Highlights: trace.Highlights()})
+	if !strings.Contains(dump, "^^^^^ version") || !strings.Contains(dump, "^^^^^^^^^^^ ReadStringUint8") {
+		t.Fatalf("unexpected value obtained; got\n%v", dump)
+	}
+}
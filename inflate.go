@@ -0,0 +1,776 @@
+package iox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+)
+
+//the inflater below decodes deflate(RFC 1951) one block at a time,unlike compress/flate
+//its state at a block boundary is just a bit position and the last 32KB of output,
+//so it can be saved as a checkpoint and restored later to seek without starting over.
+
+const (
+	maxCodeLen  = 15
+	fastBits    = 9
+	windowSize  = 32768
+	maxLitCodes = 288
+	maxDistCode = 30
+	//the distance between two checkpoints in uncompressed bytes
+	inflateCheckpointSpacing = 1 << 20
+	//the most output of a block decoded at once,a longer block is decoded in parts
+	inflatePartSize = 1 << 16
+)
+
+var (
+	lengthBase  = [29]uint16{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	lengthExtra = [29]uint8{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	distBase    = [30]uint16{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	distExtra   = [30]uint8{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+	//the order of the code length codes of a dynamic block
+	codeLenOrder = [19]uint8{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+
+	fixedLit, fixedDist huffman
+)
+
+var errCorruptDeflate = errors.New("the deflate data is corrupt")
+
+func init() {
+	var lengths [maxLitCodes]uint8
+	for i := range lengths {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	fixedLit.init(lengths[:])
+	for i := 0; i < maxDistCode; i++ {
+		lengths[i] = 5
+	}
+	fixedDist.init(lengths[:maxDistCode])
+}
+
+//huffman is a canonical huffman code,codes up to fastBits long are decoded by a table lookup
+//and longer ones by walking the code lengths.
+type huffman struct {
+	fast   [1 << fastBits]uint16 //symbol<<4|length,0 if the code is longer than fastBits
+	count  [maxCodeLen + 1]uint16
+	symbol [maxLitCodes]uint16 //symbols ordered by code
+}
+
+func (h *huffman) init(lengths []uint8) error {
+	*h = huffman{}
+	for _, l := range lengths {
+		h.count[l]++
+	}
+	h.count[0] = 0
+	left := 1
+	for l := 1; l <= maxCodeLen; l++ {
+		left = left<<1 - int(h.count[l])
+		if left < 0 {
+			return errCorruptDeflate
+		}
+	}
+	var offs [maxCodeLen + 2]uint16
+	for l := 1; l <= maxCodeLen; l++ {
+		offs[l+1] = offs[l] + h.count[l]
+	}
+	var next [maxCodeLen + 1]int
+	code := 0
+	for l := 1; l <= maxCodeLen; l++ {
+		code = (code + int(h.count[l-1])) << 1
+		next[l] = code
+	}
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		h.symbol[offs[l]] = uint16(sym)
+		offs[l]++
+		c := next[l]
+		next[l]++
+		if l > fastBits {
+			continue
+		}
+		//codes are stored MSB first in a LSB first stream,so the lookup index is reversed
+		rev := 0
+		for i := uint8(0); i < l; i++ {
+			rev = rev<<1 | c>>i&1
+		}
+		for j := rev; j < 1<<fastBits; j += 1 << l {
+			h.fast[j] = uint16(sym)<<4 | uint16(l)
+		}
+	}
+	return nil
+}
+
+//bitReader reads the LSB first bit stream of deflate.
+type bitReader struct {
+	r      *bufio.Reader
+	bits   uint64
+	n      uint  //the number of valid bits
+	offset int64 //bytes taken from r,counted from the start of the source
+}
+
+func (b *bitReader) reset(r io.Reader, offset int64) {
+	if b.r == nil {
+		b.r = bufio.NewReaderSize(r, 32<<10)
+	} else {
+		b.r.Reset(r)
+	}
+	b.bits, b.n, b.offset = 0, 0, offset
+}
+
+//the position of the next bit in the source.
+func (b *bitReader) bitPos() int64 {
+	return b.offset*8 - int64(b.n)
+}
+
+//read bytes until at least n bits are buffered or the data ends.
+func (b *bitReader) fill(n uint) error {
+	for b.n < n {
+		c, err := b.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		b.bits |= uint64(c) << b.n
+		b.n += 8
+		b.offset++
+	}
+	return nil
+}
+
+func (b *bitReader) getBits(n uint) (uint32, error) {
+	if err := b.fill(n); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	v := uint32(b.bits & (1<<n - 1))
+	b.bits >>= n
+	b.n -= n
+	return v, nil
+}
+
+func (b *bitReader) alignByte() {
+	b.bits >>= b.n % 8
+	b.n -= b.n % 8
+}
+
+//read a byte after alignByte.
+func (b *bitReader) readByte() (byte, error) {
+	v, err := b.getBits(8)
+	return byte(v), err
+}
+
+//reports whether the data ends here,only valid after alignByte.
+func (b *bitReader) atEOF() bool {
+	if b.n > 0 {
+		return false
+	}
+	_, err := b.r.Peek(1)
+	return err != nil
+}
+
+func (b *bitReader) decode(h *huffman) (int, error) {
+	b.fill(fastBits) //the data may end before fastBits,a shorter code can still be decoded
+	if e := h.fast[b.bits&(1<<fastBits-1)]; e != 0 && uint(e&15) <= b.n {
+		b.bits >>= e & 15
+		b.n -= uint(e & 15)
+		return int(e >> 4), nil
+	}
+	//walk the code lengths one bit at a time
+	code, first, index := 0, 0, 0
+	for l := 1; l <= maxCodeLen; l++ {
+		bit, err := b.getBits(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= int(bit)
+		count := int(h.count[l])
+		if code-first < count {
+			return int(h.symbol[index+code-first]), nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, errCorruptDeflate
+}
+
+//inflateCheckpoint is the state of the inflater at a block boundary.
+type inflateCheckpoint struct {
+	bitPos    int64
+	out       int64
+	window    []byte
+	sum       uint32
+	memberLen uint32
+}
+
+//inflateSeeker is an io.ReadSeeker over the uncompressed data of a gzip,zlib or raw deflate stream.
+//checkpoints are recorded while decoding,so seeking backwards restarts from the closest one.
+type inflateSeeker struct {
+	src         io.ReadSeeker
+	format      CompressFormat
+	br          bitReader
+	hist        []byte   //the window followed by the output of the current block or part of a block
+	avail       []byte   //the part of that output not read yet
+	cur         int64    //the uncompressed offset of avail[0]
+	pos         int64    //the position set by Seek
+	size        int64    //-1 until the end has been decoded
+	final       bool     //the last block of the member has been begun
+	inBlock     bool     //the codes of a block are being decoded,inflatePartSize bytes at a time
+	lit, dist   *huffman //the codes of the current block,fixed or dynLit and dynDist
+	dynLit      huffman
+	dynDist     huffman
+	done        bool
+	sum         uint32 //crc32 of the gzip member or adler32 of the zlib stream
+	memberLen   uint32
+	checkpoints []inflateCheckpoint
+	err         error //the decode error of a corrupt or truncated stream
+	errAt       int64 //the uncompressed offset where err happened,the data before it can still be read
+}
+
+func newInflateSeeker(src io.ReadSeeker, format CompressFormat) (*inflateSeeker, error) {
+	start, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	s := &inflateSeeker{src: src, format: format, size: -1}
+	s.br.reset(src, start)
+	if err = s.beginMember(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//read the header of a gzip member or a zlib stream.
+func (s *inflateSeeker) beginMember() error {
+	s.final = false
+	s.memberLen = 0
+	switch s.format {
+	case CompressGzip:
+		s.sum = 0
+		return s.readGzipHeader()
+	case CompressZlib:
+		s.sum = 1
+		cmf, err := s.br.readByte()
+		if err != nil {
+			return err
+		}
+		flg, err := s.br.readByte()
+		if err != nil {
+			return err
+		}
+		if cmf&0x0f != 8 || cmf>>4 > 7 || (uint16(cmf)<<8|uint16(flg))%31 != 0 {
+			return errors.New("invalid zlib header")
+		}
+		if flg&0x20 != 0 {
+			return errors.New("zlib streams with a preset dictionary are not supported")
+		}
+	}
+	return nil
+}
+
+func (s *inflateSeeker) readGzipHeader() error {
+	var header [10]byte
+	for i := range header {
+		b, err := s.br.readByte()
+		if err != nil {
+			return err
+		}
+		header[i] = b
+	}
+	if header[0] != 0x1f || header[1] != 0x8b || header[2] != 8 {
+		return errors.New("invalid gzip header")
+	}
+	flg := header[3]
+	skip := func(n int) error {
+		for i := 0; i < n; i++ {
+			if _, err := s.br.readByte(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	skipString := func() error {
+		for {
+			b, err := s.br.readByte()
+			if err != nil || b == 0 {
+				return err
+			}
+		}
+	}
+	if flg&0x04 != 0 { //FEXTRA
+		lo, err := s.br.readByte()
+		if err != nil {
+			return err
+		}
+		hi, err := s.br.readByte()
+		if err != nil {
+			return err
+		}
+		if err = skip(int(lo) | int(hi)<<8); err != nil {
+			return err
+		}
+	}
+	if flg&0x08 != 0 { //FNAME
+		if err := skipString(); err != nil {
+			return err
+		}
+	}
+	if flg&0x10 != 0 { //FCOMMENT
+		if err := skipString(); err != nil {
+			return err
+		}
+	}
+	if flg&0x02 != 0 { //FHCRC
+		return skip(2)
+	}
+	return nil
+}
+
+//read the trailer of the member that just ended and the header of the next gzip member.
+func (s *inflateSeeker) endMember() error {
+	s.br.alignByte()
+	readUint32 := func(bigEndian bool) (uint32, error) {
+		var v uint32
+		for i := 0; i < 4; i++ {
+			b, err := s.br.readByte()
+			if err != nil {
+				return 0, err
+			}
+			if bigEndian {
+				v = v<<8 | uint32(b)
+			} else {
+				v |= uint32(b) << (8 * i)
+			}
+		}
+		return v, nil
+	}
+	switch s.format {
+	case CompressGzip:
+		crc, err := readUint32(false)
+		if err != nil {
+			return err
+		}
+		isize, err := readUint32(false)
+		if err != nil {
+			return err
+		}
+		if crc != s.sum || isize != s.memberLen {
+			return fmt.Errorf("the gzip member ending at %v: %w", s.br.offset, ErrChecksumMismatch)
+		}
+		//another member may follow,anything else is ignored like gzip does
+		if !s.br.atEOF() {
+			if magic, _ := s.br.r.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+				return s.beginMember()
+			}
+		}
+	case CompressZlib:
+		adler, err := readUint32(true)
+		if err != nil {
+			return err
+		}
+		if adler != s.sum {
+			return fmt.Errorf("the zlib stream ending at %v: %w", s.br.offset, ErrChecksumMismatch)
+		}
+	}
+	s.done = true
+	s.size = s.cur
+	return nil
+}
+
+//record a checkpoint at the current block boundary if the last one is far enough behind.
+func (s *inflateSeeker) checkpoint() {
+	if n := len(s.checkpoints); n > 0 && s.cur < s.checkpoints[n-1].out+inflateCheckpointSpacing {
+		return
+	}
+	window := s.hist
+	if len(window) > windowSize {
+		window = window[len(window)-windowSize:]
+	}
+	s.checkpoints = append(s.checkpoints, inflateCheckpoint{
+		bitPos:    s.br.bitPos(),
+		out:       s.cur,
+		window:    append([]byte{}, window...),
+		sum:       s.sum,
+		memberLen: s.memberLen,
+	})
+}
+
+func (s *inflateSeeker) restore(cp inflateCheckpoint) error {
+	if _, err := s.src.Seek(cp.bitPos/8, io.SeekStart); err != nil {
+		return err
+	}
+	s.br.reset(s.src, cp.bitPos/8)
+	if _, err := s.br.getBits(uint(cp.bitPos % 8)); err != nil {
+		return err
+	}
+	s.hist = append(s.hist[:0], cp.window...)
+	s.avail = nil
+	s.cur = cp.out
+	s.sum = cp.sum
+	s.memberLen = cp.memberLen
+	s.final = false
+	s.inBlock = false
+	s.done = false
+	return nil
+}
+
+//decode blocks until some data is available,a decode error is kept and returned again
+//until a checkpoint before it is restored.
+func (s *inflateSeeker) fill() error {
+	if s.err != nil && s.cur >= s.errAt {
+		return s.err
+	}
+	for len(s.avail) == 0 {
+		if s.done {
+			return io.EOF
+		}
+		var err error
+		if s.final && !s.inBlock {
+			err = s.endMember()
+		} else {
+			if !s.inBlock {
+				s.checkpoint()
+			}
+			err = s.block()
+		}
+		if err != nil {
+			s.err, s.errAt = err, s.cur
+			return err
+		}
+	}
+	return nil
+}
+
+//decode the next block into hist,or the next part of a block of codes,
+//so a block that expands to a lot of data is never held in memory at once.
+func (s *inflateSeeker) block() error {
+	if len(s.hist) > windowSize {
+		s.hist = append(s.hist[:0], s.hist[len(s.hist)-windowSize:]...)
+	}
+	start := len(s.hist)
+	if !s.inBlock {
+		if err := s.beginBlock(); err != nil {
+			return err
+		}
+	}
+	if s.inBlock {
+		more, err := s.codes(s.lit, s.dist, start+inflatePartSize)
+		if err != nil {
+			return err
+		}
+		s.inBlock = more
+	}
+	s.avail = s.hist[start:]
+	s.memberLen += uint32(len(s.avail))
+	switch s.format {
+	case CompressGzip:
+		s.sum = crc32.Update(s.sum, crc32.IEEETable, s.avail)
+	case CompressZlib:
+		s.sum = adler32Update(s.sum, s.avail)
+	}
+	return nil
+}
+
+//read the header of a block,a stored block is read whole since it has at most 65535 bytes,
+//the codes of the other blocks are decoded next by codes.
+func (s *inflateSeeker) beginBlock() error {
+	header, err := s.br.getBits(3)
+	if err != nil {
+		return err
+	}
+	s.final = header&1 == 1
+	switch header >> 1 {
+	case 0:
+		return s.storedBlock()
+	case 1:
+		s.lit, s.dist = &fixedLit, &fixedDist
+	case 2:
+		s.lit, s.dist = &s.dynLit, &s.dynDist
+		if err = s.dynamicTables(s.lit, s.dist); err != nil {
+			return err
+		}
+	default:
+		return errCorruptDeflate
+	}
+	s.inBlock = true
+	return nil
+}
+
+func (s *inflateSeeker) storedBlock() error {
+	s.br.alignByte()
+	length, err := s.br.getBits(16)
+	if err != nil {
+		return err
+	}
+	nlength, err := s.br.getBits(16)
+	if err != nil {
+		return err
+	}
+	if length != ^nlength&0xffff {
+		return errCorruptDeflate
+	}
+	for i := uint32(0); i < length; i++ {
+		b, err := s.br.readByte()
+		if err != nil {
+			return err
+		}
+		s.hist = append(s.hist, b)
+	}
+	return nil
+}
+
+func (s *inflateSeeker) dynamicTables(lit, dist *huffman) error {
+	hlit, err := s.br.getBits(5)
+	if err != nil {
+		return err
+	}
+	hdist, err := s.br.getBits(5)
+	if err != nil {
+		return err
+	}
+	hclen, err := s.br.getBits(4)
+	if err != nil {
+		return err
+	}
+	nlit, ndist := int(hlit)+257, int(hdist)+1
+	if nlit > 286 || ndist > maxDistCode {
+		return errCorruptDeflate
+	}
+	var lengths [maxLitCodes + maxDistCode]uint8
+	for i := 0; i < int(hclen)+4; i++ {
+		l, err := s.br.getBits(3)
+		if err != nil {
+			return err
+		}
+		lengths[codeLenOrder[i]] = uint8(l)
+	}
+	var lencode huffman
+	if err = lencode.init(lengths[:19]); err != nil {
+		return err
+	}
+	for i := range lengths[:19] {
+		lengths[i] = 0
+	}
+	for i := 0; i < nlit+ndist; {
+		sym, err := s.br.decode(&lencode)
+		if err != nil {
+			return err
+		}
+		if sym < 16 {
+			lengths[i] = uint8(sym)
+			i++
+			continue
+		}
+		var repeat uint32
+		var value uint8
+		switch sym {
+		case 16:
+			if i == 0 {
+				return errCorruptDeflate
+			}
+			value = lengths[i-1]
+			repeat, err = s.br.getBits(2)
+			repeat += 3
+		case 17:
+			repeat, err = s.br.getBits(3)
+			repeat += 3
+		default:
+			repeat, err = s.br.getBits(7)
+			repeat += 11
+		}
+		if err != nil {
+			return err
+		}
+		if i+int(repeat) > nlit+ndist {
+			return errCorruptDeflate
+		}
+		for ; repeat > 0; repeat-- {
+			lengths[i] = value
+			i++
+		}
+	}
+	if lengths[256] == 0 {
+		return errCorruptDeflate
+	}
+	if err = lit.init(lengths[:nlit]); err != nil {
+		return err
+	}
+	return dist.init(lengths[nlit : nlit+ndist])
+}
+
+//decode the codes of a block until its end or until hist reaches limit,
+//returns true if the block goes on.
+func (s *inflateSeeker) codes(lit, dist *huffman, limit int) (bool, error) {
+	for len(s.hist) < limit {
+		sym, err := s.br.decode(lit)
+		if err != nil {
+			return false, err
+		}
+		if sym < 256 {
+			s.hist = append(s.hist, byte(sym))
+			continue
+		}
+		if sym == 256 {
+			return false, nil
+		}
+		sym -= 257
+		if sym >= len(lengthBase) {
+			return false, errCorruptDeflate
+		}
+		extra, err := s.br.getBits(uint(lengthExtra[sym]))
+		if err != nil {
+			return false, err
+		}
+		length := int(lengthBase[sym]) + int(extra)
+		dsym, err := s.br.decode(dist)
+		if err != nil {
+			return false, err
+		}
+		if dsym >= maxDistCode {
+			return false, errCorruptDeflate
+		}
+		extra, err = s.br.getBits(uint(distExtra[dsym]))
+		if err != nil {
+			return false, err
+		}
+		distance := int(distBase[dsym]) + int(extra)
+		if distance > len(s.hist) {
+			return false, errCorruptDeflate
+		}
+		from := len(s.hist) - distance
+		if distance >= length {
+			s.hist = append(s.hist, s.hist[from:from+length]...)
+			continue
+		}
+		for i := 0; i < length; i++ {
+			s.hist = append(s.hist, s.hist[from+i])
+		}
+	}
+	return true, nil
+}
+
+func adler32Update(adler uint32, p []byte) uint32 {
+	s1, s2 := adler&0xffff, adler>>16
+	for len(p) > 0 {
+		//5552 is the longest run before s2 may overflow
+		chunk := p
+		if len(chunk) > 5552 {
+			chunk = chunk[:5552]
+		}
+		for _, b := range chunk {
+			s1 += uint32(b)
+			s2 += s1
+		}
+		s1 %= 65521
+		s2 %= 65521
+		p = p[len(chunk):]
+	}
+	return s2<<16 | s1
+}
+
+//move the decoder to the uncompressed offset target.
+func (s *inflateSeeker) reposition(target int64) error {
+	i := sort.Search(len(s.checkpoints), func(i int) bool {
+		return s.checkpoints[i].out > target
+	}) - 1
+	if i >= 0 && (target < s.cur || s.checkpoints[i].out > s.cur) {
+		if err := s.restore(s.checkpoints[i]); err != nil {
+			return err
+		}
+	}
+	for s.cur < target {
+		if err := s.fill(); err != nil {
+			return err
+		}
+		n := int64(len(s.avail))
+		if target-s.cur < n {
+			n = target - s.cur
+		}
+		s.avail = s.avail[n:]
+		s.cur += n
+	}
+	return nil
+}
+
+func (s *inflateSeeker) Read(p []byte) (int, error) {
+	if s.size >= 0 && s.pos >= s.size {
+		return 0, io.EOF
+	}
+	if s.pos != s.cur {
+		if err := s.reposition(s.pos); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.fill(); err != nil {
+		return 0, err
+	}
+	n := copy(p, s.avail)
+	s.avail = s.avail[n:]
+	s.cur += int64(n)
+	s.pos += int64(n)
+	return n, nil
+}
+
+//Seek only moves the position,the data is decoded by the next Read.
+//seeking relative to the end decodes the whole stream the first time to learn its size,
+//and returns the decode error if the stream is corrupt or truncated.
+func (s *inflateSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		if s.size < 0 {
+			if err := s.reposition(math.MaxInt64); err != io.EOF {
+				return 0, err
+			}
+		}
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.pos = offset
+	return offset, nil
+}
+
+//Close the compressed source if it's a io.Closer.
+func (s *inflateSeeker) Close() error {
+	if closer, ok := s.src.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+//the size of the data that can be read,which is the size decoded before the error for a corrupt stream.
+//the second result is false if the size is not known yet.
+func (s *inflateSeeker) readableSize() (int64, bool) {
+	if s.size >= 0 {
+		return s.size, true
+	}
+	if s.err != nil {
+		return s.errAt, true
+	}
+	return 0, false
+}
+
+func (s *inflateSeeker) endDecoded() bool {
+	return s.size >= 0
+}
@@ -0,0 +1,877 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidMsgpack = errors.New("the MessagePack data is not valid")
+	ErrMsgpackType    = errors.New("the MessagePack value does not have the wanted type")
+)
+
+//MsgpackType is the family of a MessagePack value.
+type MsgpackType int
+
+const (
+	MsgpackTypeNil MsgpackType = iota
+	MsgpackTypeBool
+	MsgpackTypeInt //the signed and unsigned integers
+	MsgpackTypeFloat
+	MsgpackTypeStr
+	MsgpackTypeBin
+	MsgpackTypeArray
+	MsgpackTypeMap
+	MsgpackTypeExt //including timestamps
+)
+
+func (t MsgpackType) String() string {
+	switch t {
+	case MsgpackTypeNil:
+		return "nil"
+	case MsgpackTypeBool:
+		return "bool"
+	case MsgpackTypeInt:
+		return "int"
+	case MsgpackTypeFloat:
+		return "float"
+	case MsgpackTypeStr:
+		return "str"
+	case MsgpackTypeBin:
+		return "bin"
+	case MsgpackTypeArray:
+		return "array"
+	case MsgpackTypeMap:
+		return "map"
+	case MsgpackTypeExt:
+		return "ext"
+	}
+	return fmt.Sprintf("MsgpackType(%d)", int(t))
+}
+
+//the ext type of the timestamps of the spec.
+const msgpackTimestamp = -1
+
+//MsgpackExt is an ext value of an application defined type,types below 0 are reserved by the spec.
+type MsgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+//the family of the value starting with the marker m.
+func msgpackTypeOf(m byte) (MsgpackType, bool) {
+	switch {
+	case m <= 0x7f || m >= 0xe0 || m >= 0xcc && m <= 0xd3:
+		return MsgpackTypeInt, true
+	case m&0xf0 == 0x80 || m == 0xde || m == 0xdf:
+		return MsgpackTypeMap, true
+	case m&0xf0 == 0x90 || m == 0xdc || m == 0xdd:
+		return MsgpackTypeArray, true
+	case m&0xe0 == 0xa0 || m >= 0xd9 && m <= 0xdb:
+		return MsgpackTypeStr, true
+	case m == 0xc0:
+		return MsgpackTypeNil, true
+	case m == 0xc2 || m == 0xc3:
+		return MsgpackTypeBool, true
+	case m >= 0xc4 && m <= 0xc6:
+		return MsgpackTypeBin, true
+	case m >= 0xc7 && m <= 0xc9 || m >= 0xd4 && m <= 0xd8:
+		return MsgpackTypeExt, true
+	case m == 0xca || m == 0xcb:
+		return MsgpackTypeFloat, true
+	}
+	return 0, false //0xc1 is never used
+}
+
+//NextMsgpackType returns the type of the value at the position without moving it,
+//io.EOF is returned at the end of the data.
+func (r *ReadSeeker) NextMsgpackType() (MsgpackType, error) {
+	pos, err := r.CurPos()
+	if err != nil {
+		return 0, err
+	}
+	if pos >= r.Size() {
+		return 0, io.EOF
+	}
+	m, err := r.bytesAt(pos, 1)
+	if err != nil {
+		return 0, err
+	}
+	t, ok := msgpackTypeOf(m[0])
+	if !ok {
+		return 0, fmt.Errorf("the marker 0x%02x at position %v is never used: %w", m[0], pos, ErrInvalidMsgpack)
+	}
+	return t, nil
+}
+
+//read a value of one of the wanted types,the position is left unchanged on error.
+func (r *ReadSeeker) readMsgpackTyped(want ...MsgpackType) (interface{}, error) {
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.NextMsgpackType()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range want {
+		if t == w {
+			var v interface{}
+			err = r.restoreOnError(func() (err error) {
+				v, err = r.readMsgpackValue(0)
+				return err
+			})
+			return v, err
+		}
+	}
+	return nil, fmt.Errorf("the value at position %v is %v,not %v: %w", pos, t, want[0], ErrMsgpackType)
+}
+
+//ReadMsgpack reads a value of any type and moves past it,io.EOF is returned at the end of the data.
+//nil,bool,string,[]byte,float32 and float64 are returned as they are,integers are int64,or uint64
+//if they don't fit,arrays are []interface{},maps are map[string]interface{} if all keys are strings
+//and map[interface{}]interface{} otherwise,timestamps are time.Time in UTC,and other ext values are MsgpackExt.
+//the position is left unchanged on error.
+func (r *ReadSeeker) ReadMsgpack() (interface{}, error) {
+	defer r.traceEnter("ReadMsgpack")()
+	var v interface{}
+	err := r.restoreOnError(func() (err error) {
+		v, err = r.readMsgpackValue(0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(v)
+	return v, nil
+}
+
+func (r *ReadSeeker) readMsgpackValue(depth int) (interface{}, error) {
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	if depth > maxCodecDepth {
+		return nil, fmt.Errorf("the value at position %v is nested deeper than %v: %w", pos, maxCodecDepth, ErrInvalidMsgpack)
+	}
+	m, err := r.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+	v, err := r.readMsgpackAfter(m, pos, depth)
+	if err == io.EOF {
+		err = fmt.Errorf("the value at position %v is truncated: %w", pos, io.ErrUnexpectedEOF)
+	}
+	return v, err
+}
+
+//read the value announced by the marker m at pos.
+func (r *ReadSeeker) readMsgpackAfter(m byte, pos int64, depth int) (interface{}, error) {
+	switch {
+	case m <= 0x7f:
+		return int64(m), nil
+	case m >= 0xe0:
+		return int64(int8(m)), nil
+	case m&0xe0 == 0xa0:
+		bt, err := r.readBytesAfterPrefix(int(m & 0x1f))
+		return string(bt), err
+	case m&0xf0 == 0x90:
+		return r.readMsgpackArray(int64(m&0x0f), pos, depth)
+	case m&0xf0 == 0x80:
+		return r.readMsgpackMap(int64(m&0x0f), pos, depth)
+	}
+	switch m {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4:
+		return r.ReadBytesUint8()
+	case 0xc5:
+		return r.ReadBytesUint16BigEndian()
+	case 0xc6:
+		return r.ReadBytesUint32BigEndian()
+	case 0xc7, 0xc8, 0xc9, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		ext, err := r.readMsgpackExt(m)
+		if err != nil || ext.Type != msgpackTimestamp {
+			return ext, err
+		}
+		return msgpackTime(ext, pos)
+	case 0xca:
+		return r.ReadFloat32BigEndian()
+	case 0xcb:
+		return r.ReadFloat64BigEndian()
+	case 0xcc:
+		v, err := r.ReadUint8()
+		return int64(v), err
+	case 0xcd:
+		v, err := r.ReadUint16BigEndian()
+		return int64(v), err
+	case 0xce:
+		v, err := r.ReadUint32BigEndian()
+		return int64(v), err
+	case 0xcf:
+		v, err := r.ReadUint64BigEndian()
+		if err != nil || v > math.MaxInt64 {
+			return v, err
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := r.ReadInt8()
+		return int64(v), err
+	case 0xd1:
+		v, err := r.ReadInt16BigEndian()
+		return int64(v), err
+	case 0xd2:
+		v, err := r.ReadInt32BigEndian()
+		return int64(v), err
+	case 0xd3:
+		return r.ReadInt64BigEndian()
+	case 0xd9:
+		return r.ReadStringUint8()
+	case 0xda:
+		return r.ReadStringUint16BigEndian()
+	case 0xdb:
+		return r.ReadStringUint32BigEndian()
+	case 0xdc, 0xdd, 0xde, 0xdf:
+		n, err := r.readMsgpackCount(m)
+		if err != nil {
+			return nil, err
+		}
+		if m <= 0xdd {
+			return r.readMsgpackArray(n, pos, depth)
+		}
+		return r.readMsgpackMap(n, pos, depth)
+	}
+	return nil, fmt.Errorf("the marker 0x%02x at position %v is never used: %w", m, pos, ErrInvalidMsgpack)
+}
+
+//read the count of an array16,array32,map16 or map32.
+func (r *ReadSeeker) readMsgpackCount(m byte) (int64, error) {
+	if m == 0xdc || m == 0xde {
+		n, err := r.ReadUint16BigEndian()
+		return int64(n), err
+	}
+	n, err := r.ReadUint32BigEndian()
+	return int64(n), err
+}
+
+//every element takes a byte at least,a key and a value of a map take 2.
+func (r *ReadSeeker) checkMsgpackCount(n, elemSize int64, pos int64) error {
+	if !r.countFits(uint64(n), uint64(elemSize)) {
+		return fmt.Errorf("the value at position %v has %v elements,but only %v bytes left: %w", pos, n, r.LenUnRead(), io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+func (r *ReadSeeker) readMsgpackArray(n, pos int64, depth int) (interface{}, error) {
+	if err := r.checkMsgpackCount(n, 1, pos); err != nil {
+		return nil, err
+	}
+	a := make([]interface{}, n)
+	for i := range a {
+		v, err := r.readMsgpackValue(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (r *ReadSeeker) readMsgpackMap(n, pos int64, depth int) (interface{}, error) {
+	if err := r.checkMsgpackCount(n, 2, pos); err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, n)
+	vals := make([]interface{}, n)
+	for i := range keys {
+		var err error
+		if keys[i], err = r.readMsgpackValue(depth + 1); err != nil {
+			return nil, err
+		}
+		if vals[i], err = r.readMsgpackValue(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	m, ok := decodedMap(keys, vals)
+	if !ok {
+		return nil, fmt.Errorf("the map at position %v has a key that can't be a key of a Go map: %w", pos, ErrMsgpackType)
+	}
+	return m, nil
+}
+
+//read the ext value after the marker m.
+func (r *ReadSeeker) readMsgpackExt(m byte) (MsgpackExt, error) {
+	var n int
+	switch m {
+	case 0xc7:
+		v, err := r.ReadUint8()
+		if err != nil {
+			return MsgpackExt{}, err
+		}
+		n = int(v)
+	case 0xc8:
+		v, err := r.ReadUint16BigEndian()
+		if err != nil {
+			return MsgpackExt{}, err
+		}
+		n = int(v)
+	case 0xc9:
+		v, err := r.ReadUint32BigEndian()
+		if err != nil {
+			return MsgpackExt{}, err
+		}
+		n = int(v)
+	default:
+		n = 1 << (m - 0xd4)
+	}
+	typ, err := r.ReadInt8()
+	if err != nil {
+		return MsgpackExt{}, err
+	}
+	data, err := r.readBytesAfterPrefix(n)
+	if err != nil {
+		return MsgpackExt{}, err
+	}
+	return MsgpackExt{typ, data}, nil
+}
+
+//decode the timestamp ext at pos,it has 32 bits of seconds,30 bits of nanoseconds and 34 bits of seconds,
+//or 32 bits of nanoseconds and 64 bits of seconds.
+func msgpackTime(ext MsgpackExt, pos int64) (time.Time, error) {
+	var sec int64
+	var nsec uint32
+	switch d := ext.Data; len(d) {
+	case 4:
+		sec = int64(bytesToUint(d, true))
+	case 8:
+		v := bytesToUint(d, true)
+		nsec = uint32(v >> 34)
+		sec = int64(v & (1<<34 - 1))
+	case 12:
+		nsec = uint32(bytesToUint(d[:4], true))
+		sec = int64(bytesToUint(d[4:], true))
+	default:
+		return time.Time{}, fmt.Errorf("the timestamp at position %v has %v bytes: %w", pos, len(d), ErrInvalidMsgpack)
+	}
+	if nsec >= 1e9 {
+		return time.Time{}, fmt.Errorf("the timestamp at position %v has %v nanoseconds: %w", pos, nsec, ErrInvalidMsgpack)
+	}
+	return time.Unix(sec, int64(nsec)).UTC(), nil
+}
+
+//ReadMsgpackNil reads a nil.
+func (r *ReadSeeker) ReadMsgpackNil() error {
+	defer r.traceEnter("ReadMsgpackNil")()
+	_, err := r.readMsgpackTyped(MsgpackTypeNil)
+	return err
+}
+
+//ReadMsgpackBool reads a bool.
+func (r *ReadSeeker) ReadMsgpackBool() (bool, error) {
+	defer r.traceEnter("ReadMsgpackBool")()
+	v, err := r.readMsgpackTyped(MsgpackTypeBool)
+	if err != nil {
+		return false, err
+	}
+	r.traceValue(v)
+	return v.(bool), nil
+}
+
+//ReadMsgpackInt reads an integer of any format,the position is left unchanged if it doesn't fit in int64.
+func (r *ReadSeeker) ReadMsgpackInt() (int64, error) {
+	defer r.traceEnter("ReadMsgpackInt")()
+	var i int64
+	err := r.restoreOnError(func() error {
+		pos, _ := r.CurPos()
+		v, err := r.readMsgpackTyped(MsgpackTypeInt)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if i, ok = v.(int64); !ok {
+			return fmt.Errorf("the integer %v at position %v overflows int64: %w", v, pos, ErrMsgpackType)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.traceValue(i)
+	return i, nil
+}
+
+//ReadMsgpackUint reads an integer of any format,the position is left unchanged if it is negative.
+func (r *ReadSeeker) ReadMsgpackUint() (uint64, error) {
+	defer r.traceEnter("ReadMsgpackUint")()
+	var u uint64
+	err := r.restoreOnError(func() error {
+		pos, _ := r.CurPos()
+		v, err := r.readMsgpackTyped(MsgpackTypeInt)
+		if err != nil {
+			return err
+		}
+		switch x := v.(type) {
+		case uint64:
+			u = x
+		case int64:
+			if x < 0 {
+				return fmt.Errorf("the integer %v at position %v is negative: %w", x, pos, ErrMsgpackType)
+			}
+			u = uint64(x)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.traceValue(u)
+	return u, nil
+}
+
+//ReadMsgpackFloat reads a float32 or float64,integers are accepted as well.
+func (r *ReadSeeker) ReadMsgpackFloat() (float64, error) {
+	defer r.traceEnter("ReadMsgpackFloat")()
+	v, err := r.readMsgpackTyped(MsgpackTypeFloat, MsgpackTypeInt)
+	if err != nil {
+		return 0, err
+	}
+	var f float64
+	switch x := v.(type) {
+	case float32:
+		f = float64(x)
+	case float64:
+		f = x
+	case int64:
+		f = float64(x)
+	case uint64:
+		f = float64(x)
+	}
+	r.traceValue(f)
+	return f, nil
+}
+
+//ReadMsgpackStr reads a str.
+func (r *ReadSeeker) ReadMsgpackStr() (string, error) {
+	defer r.traceEnter("ReadMsgpackStr")()
+	v, err := r.readMsgpackTyped(MsgpackTypeStr)
+	if err != nil {
+		return "", err
+	}
+	r.traceValue(v)
+	return v.(string), nil
+}
+
+//ReadMsgpackBin reads a bin.
+func (r *ReadSeeker) ReadMsgpackBin() ([]byte, error) {
+	defer r.traceEnter("ReadMsgpackBin")()
+	v, err := r.readMsgpackTyped(MsgpackTypeBin)
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(v)
+	return v.([]byte), nil
+}
+
+//read the header of an array or a map,the elements are read next.
+func (r *ReadSeeker) readMsgpackHeader(want MsgpackType, fix byte) (int, error) {
+	var n int64
+	err := r.restoreOnError(func() error {
+		pos, _ := r.CurPos()
+		t, err := r.NextMsgpackType()
+		if err != nil {
+			return err
+		}
+		if t != want {
+			return fmt.Errorf("the value at position %v is %v,not %v: %w", pos, t, want, ErrMsgpackType)
+		}
+		m, _ := r.ReadUint8()
+		if m&0xf0 == fix {
+			n = int64(m & 0x0f)
+			return nil
+		}
+		if n, err = r.readMsgpackCount(m); err == io.EOF {
+			err = fmt.Errorf("the value at position %v is truncated: %w", pos, io.ErrUnexpectedEOF)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.traceValue(n)
+	return int(n), nil
+}
+
+//ReadMsgpackArrayHeader reads the header of an array and returns the number of elements,which are read next.
+func (r *ReadSeeker) ReadMsgpackArrayHeader() (int, error) {
+	defer r.traceEnter("ReadMsgpackArrayHeader")()
+	return r.readMsgpackHeader(MsgpackTypeArray, 0x90)
+}
+
+//ReadMsgpackMapHeader reads the header of a map and returns the number of pairs,
+//which are read next as a key followed by its value.
+func (r *ReadSeeker) ReadMsgpackMapHeader() (int, error) {
+	defer r.traceEnter("ReadMsgpackMapHeader")()
+	return r.readMsgpackHeader(MsgpackTypeMap, 0x80)
+}
+
+//ReadMsgpackExt reads an ext value,a timestamp is returned undecoded with the type -1.
+func (r *ReadSeeker) ReadMsgpackExt() (MsgpackExt, error) {
+	defer r.traceEnter("ReadMsgpackExt")()
+	var ext MsgpackExt
+	err := r.restoreOnError(func() error {
+		pos, _ := r.CurPos()
+		t, err := r.NextMsgpackType()
+		if err != nil {
+			return err
+		}
+		if t != MsgpackTypeExt {
+			return fmt.Errorf("the value at position %v is %v,not %v: %w", pos, t, MsgpackTypeExt, ErrMsgpackType)
+		}
+		m, _ := r.ReadUint8()
+		if ext, err = r.readMsgpackExt(m); err == io.EOF {
+			err = fmt.Errorf("the value at position %v is truncated: %w", pos, io.ErrUnexpectedEOF)
+		}
+		return err
+	})
+	if err != nil {
+		return MsgpackExt{}, err
+	}
+	r.traceValue(ext)
+	return ext, nil
+}
+
+//ReadMsgpackTime reads a timestamp,it is returned in UTC.
+func (r *ReadSeeker) ReadMsgpackTime() (time.Time, error) {
+	defer r.traceEnter("ReadMsgpackTime")()
+	var tm time.Time
+	err := r.restoreOnError(func() error {
+		pos, _ := r.CurPos()
+		v, err := r.readMsgpackTyped(MsgpackTypeExt)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if tm, ok = v.(time.Time); !ok {
+			return fmt.Errorf("the ext value at position %v has the type %v,not %v: %w", pos, v.(MsgpackExt).Type, msgpackTimestamp, ErrMsgpackType)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	r.traceValue(tm)
+	return tm, nil
+}
+
+//ReadMsgpackInto reads a value like ReadMsgpack and stores it in the value v points to,like json.Unmarshal:
+//structs are decoded from maps by the names of the msgpack struct tags,or the field names,
+//unknown keys are skipped,and nil sets pointers,slices,maps and interfaces to nil.
+//the position is left unchanged on error.
+func (r *ReadSeeker) ReadMsgpackInto(v interface{}) error {
+	defer r.traceEnter("ReadMsgpackInto")()
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%T is not a non-nil pointer: %w", v, ErrMsgpackType)
+	}
+	return r.restoreOnError(func() error {
+		x, err := r.readMsgpackValue(0)
+		if err != nil {
+			return err
+		}
+		return setDecoded(rv.Elem(), x, "", msgpackFormat)
+	})
+}
+
+var (
+	msgpackExtType = reflect.TypeOf(MsgpackExt{})
+	msgpackFormat  = valueFormat{"msgpack", ErrMsgpackType}
+)
+
+//WriteMsgpackNil writes a nil.
+func (w *Writer) WriteMsgpackNil() {
+	w.WriteUint8(0xc0)
+}
+
+//WriteMsgpackBool writes a bool.
+func (w *Writer) WriteMsgpackBool(b bool) {
+	if b {
+		w.WriteUint8(0xc3)
+	} else {
+		w.WriteUint8(0xc2)
+	}
+}
+
+//WriteMsgpackInt writes an integer in the shortest format,values from 0 up are written like WriteMsgpackUint.
+func (w *Writer) WriteMsgpackInt(i int64) {
+	switch {
+	case i >= 0:
+		w.WriteMsgpackUint(uint64(i))
+	case i >= -32:
+		w.WriteInt8(int8(i))
+	case i >= math.MinInt8:
+		w.WriteUint8(0xd0)
+		w.WriteInt8(int8(i))
+	case i >= math.MinInt16:
+		w.WriteUint8(0xd1)
+		w.WriteInt16BigEndian(int16(i))
+	case i >= math.MinInt32:
+		w.WriteUint8(0xd2)
+		w.WriteInt32BigEndian(int32(i))
+	default:
+		w.WriteUint8(0xd3)
+		w.WriteInt64BigEndian(i)
+	}
+}
+
+//WriteMsgpackUint writes an unsigned integer in the shortest format.
+func (w *Writer) WriteMsgpackUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		w.WriteUint8(uint8(u))
+	case u <= math.MaxUint8:
+		w.WriteUint8(0xcc)
+		w.WriteUint8(uint8(u))
+	case u <= math.MaxUint16:
+		w.WriteUint8(0xcd)
+		w.WriteUint16BigEndian(uint16(u))
+	case u <= math.MaxUint32:
+		w.WriteUint8(0xce)
+		w.WriteUint32BigEndian(uint32(u))
+	default:
+		w.WriteUint8(0xcf)
+		w.WriteUint64BigEndian(u)
+	}
+}
+
+//WriteMsgpackFloat32 writes a float32.
+func (w *Writer) WriteMsgpackFloat32(f float32) {
+	w.WriteUint8(0xca)
+	w.WriteFloat32BigEndian(f)
+}
+
+//WriteMsgpackFloat64 writes a float64.
+func (w *Writer) WriteMsgpackFloat64(f float64) {
+	w.WriteUint8(0xcb)
+	w.WriteFloat64BigEndian(f)
+}
+
+//WriteMsgpackStr writes a str in the shortest format.
+func (w *Writer) WriteMsgpackStr(s string) {
+	switch {
+	case len(s) < 32:
+		w.WriteUint8(0xa0 | uint8(len(s)))
+		w.WriteString(s)
+	case len(s) <= math.MaxUint8:
+		w.WriteUint8(0xd9)
+		w.WriteStringUint8(s)
+	case len(s) <= math.MaxUint16:
+		w.WriteUint8(0xda)
+		w.WriteStringUint16BigEndian(s)
+	default:
+		w.WriteUint8(0xdb)
+		w.WriteStringUint32BigEndian(s)
+	}
+}
+
+//WriteMsgpackBin writes a bin in the shortest format.
+func (w *Writer) WriteMsgpackBin(p []byte) {
+	switch {
+	case len(p) <= math.MaxUint8:
+		w.WriteUint8(0xc4)
+		w.WriteBytesUint8(p)
+	case len(p) <= math.MaxUint16:
+		w.WriteUint8(0xc5)
+		w.WriteBytesUint16BigEndian(p)
+	default:
+		w.WriteUint8(0xc6)
+		w.WriteBytesUint32BigEndian(p)
+	}
+}
+
+//write the header of an array or a map with n elements.
+func (w *Writer) writeMsgpackHeader(n int, fix, m16, m32 byte) {
+	switch {
+	case n < 0 || int64(n) > math.MaxUint32:
+		panic(fmt.Sprint("the MessagePack element count is out of range:", n))
+	case n < 16:
+		w.WriteUint8(fix | uint8(n))
+	case n <= math.MaxUint16:
+		w.WriteUint8(m16)
+		w.WriteUint16BigEndian(uint16(n))
+	default:
+		w.WriteUint8(m32)
+		w.WriteUint32BigEndian(uint32(n))
+	}
+}
+
+//WriteMsgpackArrayHeader writes the header of an array of n elements,the elements are written next.
+func (w *Writer) WriteMsgpackArrayHeader(n int) {
+	w.writeMsgpackHeader(n, 0x90, 0xdc, 0xdd)
+}
+
+//WriteMsgpackMapHeader writes the header of a map of n pairs,the pairs are written next as a key followed by its value.
+func (w *Writer) WriteMsgpackMapHeader(n int) {
+	w.writeMsgpackHeader(n, 0x80, 0xde, 0xdf)
+}
+
+//WriteMsgpackExt writes an ext value,a fixext is used for data of 1,2,4,8 or 16 bytes.
+func (w *Writer) WriteMsgpackExt(typ int8, data []byte) {
+	switch n := len(data); {
+	case n == 1 || n == 2 || n == 4 || n == 8 || n == 16:
+		m := uint8(0xd4)
+		for size := 1; size < n; size <<= 1 {
+			m++
+		}
+		w.WriteUint8(m)
+	case n <= math.MaxUint8:
+		w.WriteUint8(0xc7)
+		w.WriteUint8(uint8(n))
+	case n <= math.MaxUint16:
+		w.WriteUint8(0xc8)
+		w.WriteUint16BigEndian(uint16(n))
+	case int64(n) <= math.MaxUint32:
+		w.WriteUint8(0xc9)
+		w.WriteUint32BigEndian(uint32(n))
+	default:
+		panic(fmt.Sprint("the ext data length:", n, " is too big for Uint32"))
+	}
+	w.WriteInt8(typ)
+	w.write(data)
+}
+
+//WriteMsgpackTime writes a timestamp in the shortest of the 32,64 and 96 bit formats.
+func (w *Writer) WriteMsgpackTime(t time.Time) {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case sec>>32 == 0 && nsec == 0:
+		w.WriteMsgpackExt(msgpackTimestamp, uintToBytes(uint64(sec), 4, true))
+	case sec>>34 == 0:
+		w.WriteMsgpackExt(msgpackTimestamp, uintToBytes(nsec<<34|uint64(sec), 8, true))
+	default:
+		w.WriteMsgpackExt(msgpackTimestamp, append(uintToBytes(nsec, 4, true), uintToBytes(uint64(sec), 8, true)...))
+	}
+}
+
+//WriteMsgpack writes v of any type in the shortest formats,[]byte and byte arrays are bin,
+//time.Time is a timestamp,MsgpackExt is an ext value,and structs are maps of their exported fields
+//named by the msgpack struct tags,see ReadMsgpackInto.the keys of maps are sorted if they are strings or numbers.
+//nothing is written if v has a value of another type or contains itself.
+func (w *Writer) WriteMsgpack(v interface{}) error {
+	buf := NewBytesBuffer()
+	if err := buf.writeMsgpackValue(reflect.ValueOf(v), "", refStack{}); err != nil {
+		return err
+	}
+	w.write(buf.Bytes())
+	return nil
+}
+
+func msgpackCycle(v reflect.Value, path string) error {
+	return fmt.Errorf("%v: the %v contains itself: %w", strings.TrimPrefix(path, "."), v.Type(), ErrMsgpackType)
+}
+
+func (w *Writer) writeMsgpackValue(v reflect.Value, path string, refs refStack) error {
+	if !v.IsValid() {
+		w.WriteMsgpackNil()
+		return nil
+	}
+	switch v.Type() {
+	case timeType:
+		w.WriteMsgpackTime(v.Interface().(time.Time))
+		return nil
+	case msgpackExtType:
+		ext := v.Interface().(MsgpackExt)
+		w.WriteMsgpackExt(ext.Type, ext.Data)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.WriteMsgpackNil()
+			return nil
+		}
+		if !refs.push(v) {
+			return msgpackCycle(v, path)
+		}
+		defer refs.pop(v)
+		return w.writeMsgpackValue(v.Elem(), path, refs)
+	case reflect.Bool:
+		w.WriteMsgpackBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteMsgpackInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.WriteMsgpackUint(v.Uint())
+	case reflect.Float32:
+		w.WriteMsgpackFloat32(float32(v.Float()))
+	case reflect.Float64:
+		w.WriteMsgpackFloat64(v.Float())
+	case reflect.String:
+		w.WriteMsgpackStr(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			w.WriteMsgpackNil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			bt := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(bt), v)
+			w.WriteMsgpackBin(bt)
+			return nil
+		}
+		if !refs.push(v) {
+			return msgpackCycle(v, path)
+		}
+		defer refs.pop(v)
+		w.WriteMsgpackArrayHeader(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := w.writeMsgpackValue(v.Index(i), fmt.Sprintf("%v[%v]", path, i), refs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.WriteMsgpackNil()
+			return nil
+		}
+		if !refs.push(v) {
+			return msgpackCycle(v, path)
+		}
+		defer refs.pop(v)
+		keys := v.MapKeys()
+		sortMapKeys(keys)
+		w.WriteMsgpackMapHeader(len(keys))
+		for _, k := range keys {
+			if err := w.writeMsgpackValue(k, path, refs); err != nil {
+				return err
+			}
+			if err := w.writeMsgpackValue(v.MapIndex(k), fmt.Sprintf("%v[%v]", path, k), refs); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var fields []taggedField
+		for _, f := range taggedFields(v.Type(), "msgpack") {
+			if !f.omitEmpty || !v.Field(f.index).IsZero() {
+				fields = append(fields, f)
+			}
+		}
+		w.WriteMsgpackMapHeader(len(fields))
+		for _, f := range fields {
+			w.WriteMsgpackStr(f.name)
+			if err := w.writeMsgpackValue(v.Field(f.index), path+"."+f.name, refs); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%v: %v can't be written as MessagePack: %w", strings.TrimPrefix(path, "."), v.Type(), ErrMsgpackType)
+	}
+	return nil
+}
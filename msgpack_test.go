@@ -0,0 +1,265 @@
+package iox
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsgpackWriter(t *testing.T) {
+	for _, c := range []struct {
+		write func(w *Writer)
+		want  string
+	}{
+		{func(w *Writer) { w.WriteMsgpackNil() }, "c0"},
+		{func(w *Writer) { w.WriteMsgpackBool(true) }, "c3"},
+		{func(w *Writer) { w.WriteMsgpackInt(127) }, "7f"},
+		{func(w *Writer) { w.WriteMsgpackInt(128) }, "cc80"},
+		{func(w *Writer) { w.WriteMsgpackInt(-32) }, "e0"},
+		{func(w *Writer) { w.WriteMsgpackInt(-33) }, "d0df"},
+		{func(w *Writer) { w.WriteMsgpackInt(-129) }, "d1ff7f"},
+		{func(w *Writer) { w.WriteMsgpackInt(math.MinInt64) }, "d38000000000000000"},
+		{func(w *Writer) { w.WriteMsgpackUint(65536) }, "ce00010000"},
+		{func(w *Writer) { w.WriteMsgpackUint(math.MaxUint64) }, "cfffffffffffffffff"},
+		{func(w *Writer) { w.WriteMsgpackFloat32(1.5) }, "ca3fc00000"},
+		{func(w *Writer) { w.WriteMsgpackFloat64(1.5) }, "cb3ff8000000000000"},
+		{func(w *Writer) { w.WriteMsgpackStr("abc") }, "a3616263"},
+		{func(w *Writer) { w.WriteMsgpackStr(strings.Repeat("a", 32)) }, "d920" + strings.Repeat("61", 32)},
+		{func(w *Writer) { w.WriteMsgpackStr(strings.Repeat("a", 256)) }, "da0100" + strings.Repeat("61", 256)},
+		{func(w *Writer) { w.WriteMsgpackBin([]byte{1, 2}) }, "c4020102"},
+		{func(w *Writer) { w.WriteMsgpackArrayHeader(15) }, "9f"},
+		{func(w *Writer) { w.WriteMsgpackArrayHeader(16) }, "dc0010"},
+		{func(w *Writer) { w.WriteMsgpackMapHeader(1 << 16) }, "df00010000"},
+		{func(w *Writer) { w.WriteMsgpackExt(5, []byte{1, 2, 3, 4}) }, "d60501020304"},
+		{func(w *Writer) { w.WriteMsgpackExt(5, []byte{1, 2, 3}) }, "c70305010203"},
+		{func(w *Writer) { w.WriteMsgpackExt(5, make([]byte, 16)) }, "d805" + strings.Repeat("00", 16)},
+		//the timestamps of 32,64 and 96 bits
+		{func(w *Writer) { w.WriteMsgpackTime(time.Unix(1, 0)) }, "d6ff00000001"},
+		{func(w *Writer) { w.WriteMsgpackTime(time.Unix(1, 1)) }, "d7ff0000000400000001"},
+		{func(w *Writer) { w.WriteMsgpackTime(time.Unix(-1, 0)) }, "c70cff00000000ffffffffffffffff"},
+	} {
+		w := NewBytesBuffer()
+		c.write(w)
+		if got := hex.EncodeToString(w.Bytes()); got != c.want {
+			t.Fatalf("unexpected value obtained; got %v want %v", got, c.want)
+		}
+	}
+}
+
+func TestMsgpackReader(t *testing.T) {
+	tm := time.Date(2600, 1, 2, 3, 4, 5, 6, time.UTC)
+	w := NewBytesBuffer()
+	w.WriteMsgpackMapHeader(2)
+	w.WriteMsgpackStr("id")
+	w.WriteMsgpackInt(-200)
+	w.WriteMsgpackStr("tags")
+	w.WriteMsgpackArrayHeader(3)
+	w.WriteMsgpackBin([]byte("bin"))
+	w.WriteMsgpackUint(math.MaxUint64)
+	w.WriteMsgpackTime(tm)
+	w.WriteMsgpackFloat32(0.5)
+	w.WriteMsgpackExt(3, []byte("x"))
+	w.WriteMsgpackMapHeader(1)
+	w.WriteMsgpackInt(1)
+	w.WriteMsgpackNil()
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	if n, err := rd.ReadMsgpackMapHeader(); err != nil || n != 2 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", n, err, 2)
+	}
+	if s, err := rd.ReadMsgpackStr(); err != nil || s != "id" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", s, err, "id")
+	}
+	//a type mismatch leaves the position unchanged
+	if _, err := rd.ReadMsgpackUint(); !errors.Is(err, ErrMsgpackType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+	}
+	if _, err := rd.ReadMsgpackStr(); !errors.Is(err, ErrMsgpackType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+	}
+	if i, err := rd.ReadMsgpackInt(); err != nil || i != -200 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", i, err, -200)
+	}
+	rd.ReadMsgpackStr()
+	v, err := rd.ReadMsgpack()
+	if want := []interface{}{[]byte("bin"), uint64(math.MaxUint64), tm}; err != nil || !reflect.DeepEqual(v, want) {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", v, err, want)
+	}
+	if f, err := rd.ReadMsgpackFloat(); err != nil || f != 0.5 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", f, err, 0.5)
+	}
+	if _, err := rd.ReadMsgpackTime(); !errors.Is(err, ErrMsgpackType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+	}
+	if ext, err := rd.ReadMsgpackExt(); err != nil || ext.Type != 3 || string(ext.Data) != "x" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", ext, err, "x")
+	}
+	v, err = rd.ReadMsgpack()
+	if want := map[interface{}]interface{}{int64(1): nil}; err != nil || !reflect.DeepEqual(v, want) {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", v, err, want)
+	}
+	if _, err = rd.ReadMsgpack(); err != io.EOF {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.EOF)
+	}
+	if _, err = rd.NextMsgpackType(); err != io.EOF {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.EOF)
+	}
+	//the timestamp as an ext value
+	rd.MoveTo(27)
+	if ext, err := rd.ReadMsgpackExt(); err != nil || ext.Type != -1 || len(ext.Data) != 12 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", ext, err, "a 96 bit timestamp")
+	}
+}
+
+type msgpackPoint struct {
+	X, Y int16
+}
+
+type msgpackRecord struct {
+	Name    string            `msgpack:"name"`
+	Count   uint32            `msgpack:"count,omitempty"`
+	Score   float64           `msgpack:"score"`
+	Raw     []byte            `msgpack:"raw"`
+	Points  []msgpackPoint    `msgpack:"points"`
+	Attrs   map[string]string `msgpack:"attrs"`
+	When    time.Time         `msgpack:"when"`
+	Parent  *msgpackRecord    `msgpack:"parent"`
+	Any     interface{}       `msgpack:"any"`
+	Ignored int               `msgpack:"-"`
+	private int
+}
+
+func TestMsgpackStruct(t *testing.T) {
+	in := msgpackRecord{
+		Name:    "root",
+		Score:   2.5,
+		Raw:     []byte{0, 1},
+		Points:  []msgpackPoint{{1, -2}, {3, 4}},
+		Attrs:   map[string]string{"b": "2", "a": "1"},
+		When:    time.Unix(1700000000, 0).UTC(),
+		Parent:  &msgpackRecord{Name: "parent", Count: 7},
+		Any:     []interface{}{"x", int64(1)},
+		Ignored: 9,
+		private: 9,
+	}
+	w := NewBytesBuffer()
+	if err := w.WriteMsgpack(in); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	//the same value gives the same bytes
+	w2 := NewBytesBuffer()
+	w2.WriteMsgpack(in)
+	if !reflect.DeepEqual(w.Bytes(), w2.Bytes()) {
+		t.Fatalf("unexpected value obtained; got %x want %x", w2.Bytes(), w.Bytes())
+	}
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	var out msgpackRecord
+	if err := rd.ReadMsgpackInto(&out); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	in.Ignored, in.private = 0, 0
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("unexpected value obtained; got %+v want %+v", out, in)
+	}
+	//count is omitted when empty,the keys are matched without case as well
+	v, _ := NewReadSeekerFromBytes(w.Bytes()).ReadMsgpack()
+	if _, ok := v.(map[string]interface{})["count"]; ok {
+		t.Fatalf("unexpected value obtained; got %v want %v", v, "no count")
+	}
+	w = NewBytesBuffer()
+	w.WriteMsgpack(map[string]interface{}{"NAME": "upper", "unknown": 1, "points": nil})
+	out = msgpackRecord{Points: []msgpackPoint{{}}}
+	if err := NewReadSeekerFromBytes(w.Bytes()).ReadMsgpackInto(&out); err != nil || out.Name != "upper" || out.Points != nil {
+		t.Fatalf("unexpected value obtained; got %+v %v want %v", out, err, "upper")
+	}
+	//a mismatch names the field and leaves the position unchanged
+	w = NewBytesBuffer()
+	w.WriteMsgpack(map[string]interface{}{"points": []interface{}{map[string]interface{}{"X": 1 << 20}}})
+	rd = NewReadSeekerFromBytes(w.Bytes())
+	err := rd.ReadMsgpackInto(&out)
+	if !errors.Is(err, ErrMsgpackType) || !strings.Contains(err.Error(), "points[0].X") {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, "points[0].X")
+	}
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+	if err := NewBytesBuffer().WriteMsgpack(make(chan int)); !errors.Is(err, ErrMsgpackType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+	}
+	if err := rd.ReadMsgpackInto(out); !errors.Is(err, ErrMsgpackType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+	}
+}
+
+func TestMsgpackWriteErrors(t *testing.T) {
+	//nothing of a value is written when a part of it fails
+	w := NewBytesBuffer()
+	w.WriteUint8(0x11)
+	err := w.WriteMsgpack([]interface{}{map[string]interface{}{"a": 1, "b": make(chan int)}})
+	if !errors.Is(err, ErrMsgpackType) || !reflect.DeepEqual(w.Bytes(), []byte{0x11}) {
+		t.Fatalf("unexpected value obtained; got %x %v want %x", w.Bytes(), err, []byte{0x11})
+	}
+	//a value containing itself is an error instead of endless recursion
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+	m := map[string]interface{}{}
+	m["m"] = []interface{}{m}
+	s := []interface{}{nil}
+	s[0] = s
+	for _, v := range []interface{}{n, m, s} {
+		if err := NewBytesBuffer().WriteMsgpack(v); !errors.Is(err, ErrMsgpackType) || !strings.Contains(err.Error(), "contains itself") {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+		}
+	}
+	//the same pointer twice is not a cycle
+	p := &node{}
+	if err := NewBytesBuffer().WriteMsgpack([]*node{p, p}); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+}
+
+func TestMsgpackErrors(t *testing.T) {
+	for _, c := range []struct {
+		hex  string
+		want error
+	}{
+		{"c1", ErrInvalidMsgpack},
+		{"d6ff0000", io.ErrUnexpectedEOF},
+		{"d7ff" + "ee6b2800" + "00000000", ErrInvalidMsgpack}, //1e9 nanoseconds
+		{"d5ff0000", ErrInvalidMsgpack},
+		{"a36162", io.ErrUnexpectedEOF},
+		{"dc00", io.ErrUnexpectedEOF},
+		{"dd0fffffff00", io.ErrUnexpectedEOF},
+		{"92c0", io.ErrUnexpectedEOF},
+		{"81c4000a", ErrMsgpackType}, //a bin key
+		{strings.Repeat("91", 600) + "c0", ErrInvalidMsgpack},
+	} {
+		data, _ := hex.DecodeString(c.hex)
+		rd := NewReadSeekerFromBytes(data)
+		if _, err := rd.ReadMsgpack(); !errors.Is(err, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, err, c.want)
+		}
+		if pos, _ := rd.CurPos(); pos != 0 {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, pos, 0)
+		}
+	}
+	rd := NewReadSeekerFromBytes([]byte{0xa3, 'a'})
+	if _, err := rd.ReadMsgpackStr(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+	rd = NewReadSeekerFromBytes([]byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	if _, err := rd.ReadMsgpackInt(); !errors.Is(err, ErrMsgpackType) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrMsgpackType)
+	}
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+}
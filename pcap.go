@@ -0,0 +1,484 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+var ErrInvalidPcap = errors.New("the pcap data is not valid")
+
+//the magic numbers of pcap files,the byte order is that of the writer.
+const (
+	pcapMagicMicro = 0xa1b2c3d4
+	pcapMagicNano  = 0xa1b23c4d
+	pcapHeaderLen  = 24
+	pcapRecordLen  = 16
+)
+
+//the block types of pcapng.
+const (
+	PcapngSectionHeader  = 0x0a0d0d0a
+	PcapngInterfaceDesc  = 0x00000001
+	PcapngSimplePacket   = 0x00000003
+	PcapngEnhancedPacket = 0x00000006
+	pcapngByteOrderMagic = 0x1a2b3c4d
+	pcapngMinBlockLen    = 12
+	pcapngOptEnd         = 0
+	PcapngOptComment     = 1
+	PcapngOptIfName      = 2
+	PcapngOptIfTSResol   = 9
+	PcapngOptIfTSOffset  = 14
+	pcapngDefaultTSResol = 6
+)
+
+//Packet is a packet of a pcap or pcapng file.
+type Packet struct {
+	Offset      int64 //of the record or the block in the walked data
+	Timestamp   time.Time
+	OriginalLen int //the length on the wire,Data may be shorter
+	Interface   int //the interface ID of pcapng,0 for pcap
+	LinkType    uint16
+	Data        []byte
+	Options     []PcapngOption //the options of an enhanced packet block,such as PcapngOptComment
+}
+
+//PcapngOption is an option of a pcapng block.
+type PcapngOption struct {
+	Code  uint16
+	Value []byte
+}
+
+//PcapInterface is an interface of a pcapng section,or the link of a pcap file.
+type PcapInterface struct {
+	LinkType uint16
+	SnapLen  uint32
+	//the if_tsresol option,the timestamp unit is 10^-TSResol seconds,or 2^-(TSResol&0x7f) if the high bit is set,
+	//6 for microseconds by default and 9 for the nanoseconds of pcap files
+	TSResol  uint8
+	TSOffset int64 //the if_tsoffset option,seconds added to the timestamps
+	Name     string
+	Options  []PcapngOption
+}
+
+//PacketWalker reads the packets of a pcap or pcapng file,it is used like a ChunkWalker:
+//
+//	walker := r.Packets()
+//	for walker.Next() {
+//		packet := walker.Packet()
+//	}
+//	if err := walker.Err(); err != nil {
+//	}
+type PacketWalker struct {
+	r          *ReadSeeker
+	pos        int64
+	ng         bool
+	bigEndian  bool            //of the pcap file or the current pcapng section
+	interfaces []PcapInterface //the link of a pcap file or the interfaces of the current pcapng section
+	packet     *Packet
+	err        error
+}
+
+//Packets returns a PacketWalker of the pcap or pcapng file at the current position,the format and the
+//byte order are found from the magic number,and reading packets does not move the position of r.
+//pcap files of microseconds and nanoseconds are read,and the pcapng blocks other than section headers,
+//interface descriptions,enhanced packets and simple packets are skipped.
+func (r *ReadSeeker) Packets() *PacketWalker {
+	walker := &PacketWalker{}
+	pos, err := r.CurPos()
+	if err == nil {
+		walker.r, err = r.Section(pos, r.Size()-1)
+	}
+	if err != nil {
+		walker.err = err
+		return walker
+	}
+	if walker.r.Size() < 4 {
+		walker.err = fmt.Errorf("the capture file of %v bytes has no magic number: %w", walker.r.Size(), io.ErrUnexpectedEOF)
+		return walker
+	}
+	magic, _ := walker.r.bytesAt(0, 4)
+	if bytesToUint(magic, false) == PcapngSectionHeader {
+		walker.ng = true
+		return walker
+	}
+	walker.err = walker.readPcapHeader()
+	return walker
+}
+
+//read the header of a pcap file.
+func (p *PacketWalker) readPcapHeader() error {
+	if p.r.Size() < pcapHeaderLen {
+		return fmt.Errorf("the pcap header needs %v bytes,but only %v bytes left: %w", pcapHeaderLen, p.r.Size(), io.ErrUnexpectedEOF)
+	}
+	bt, err := p.r.bytesAt(0, pcapHeaderLen)
+	if err != nil {
+		return err
+	}
+	iface := PcapInterface{TSResol: pcapngDefaultTSResol}
+	switch magic := bytesToUint(bt[:4], false); magic {
+	case pcapMagicMicro, pcapMagicNano:
+	default:
+		p.bigEndian = true
+		if magic = bytesToUint(bt[:4], true); magic != pcapMagicMicro && magic != pcapMagicNano {
+			return fmt.Errorf("the magic number %x is neither pcap nor pcapng: %w", bt[:4], ErrInvalidPcap)
+		}
+	}
+	d := &fieldReader{b: bt, bigEndian: p.bigEndian}
+	if d.uint(4) == pcapMagicNano {
+		iface.TSResol = 9
+	}
+	d.uint(12) //the version,the time zone and the accuracy,which are always 2.4 and 0
+	iface.SnapLen = uint32(d.uint(4))
+	iface.LinkType = uint16(d.uint(4)) //the high bits hold the FCS length
+	p.interfaces = []PcapInterface{iface}
+	p.pos = pcapHeaderLen
+	return nil
+}
+
+//Interfaces returns the interfaces of the current pcapng section,or the link of a pcap file.
+func (p *PacketWalker) Interfaces() []PcapInterface {
+	return p.interfaces
+}
+
+//Next reads the next packet,it returns false at the end or on an error.
+func (p *PacketWalker) Next() bool {
+	p.packet = nil
+	for p.err == nil && p.packet == nil && p.pos < p.r.Size() {
+		if p.ng {
+			p.err = p.readBlock()
+		} else {
+			p.err = p.readRecord()
+		}
+	}
+	return p.packet != nil
+}
+
+//Packet returns the packet read by Next.
+func (p *PacketWalker) Packet() *Packet {
+	return p.packet
+}
+
+//Err returns the error that stopped Next,a truncated record or block returns an error wrapping io.ErrUnexpectedEOF.
+func (p *PacketWalker) Err() error {
+	return p.err
+}
+
+//read a pcap record.
+func (p *PacketWalker) readRecord() error {
+	left := p.r.Size() - p.pos
+	if left < pcapRecordLen {
+		return fmt.Errorf("the record at position %v needs %v bytes,but only %v bytes left: %w", p.pos, pcapRecordLen, left, io.ErrUnexpectedEOF)
+	}
+	bt, err := p.r.bytesAt(p.pos, pcapRecordLen)
+	if err != nil {
+		return err
+	}
+	d := &fieldReader{b: bt, bigEndian: p.bigEndian}
+	sec, frac, capLen, origLen := d.uint(4), d.uint(4), int64(d.uint(4)), int(d.uint(4))
+	if capLen > left-pcapRecordLen {
+		return fmt.Errorf("the record at position %v has %v bytes,but only %v bytes left: %w", p.pos, capLen, left-pcapRecordLen, io.ErrUnexpectedEOF)
+	}
+	data, err := p.r.bytesAt(p.pos+pcapRecordLen, capLen)
+	if err != nil {
+		return err
+	}
+	iface := p.interfaces[0]
+	p.packet = &Packet{Offset: p.pos, OriginalLen: origLen, LinkType: iface.LinkType, Data: data}
+	if iface.TSResol == 9 {
+		p.packet.Timestamp = time.Unix(int64(sec), int64(frac)).UTC()
+	} else {
+		p.packet.Timestamp = time.Unix(int64(sec), int64(frac)*1000).UTC()
+	}
+	p.pos += pcapRecordLen + capLen
+	return nil
+}
+
+//read a pcapng block,the packet is set if it is a packet.
+func (p *PacketWalker) readBlock() error {
+	left := p.r.Size() - p.pos
+	if left < pcapngMinBlockLen {
+		return fmt.Errorf("the block at position %v needs %v bytes,but only %v bytes left: %w", p.pos, pcapngMinBlockLen, left, io.ErrUnexpectedEOF)
+	}
+	bt, err := p.r.bytesAt(p.pos, pcapngMinBlockLen)
+	if err != nil {
+		return err
+	}
+	if bytesToUint(bt[:4], false) == PcapngSectionHeader {
+		//the byte order of a section is given by its byte order magic
+		switch magic := bytesToUint(bt[8:12], false); magic {
+		case pcapngByteOrderMagic:
+			p.bigEndian = false
+		case bytesToUint(uintToBytes(pcapngByteOrderMagic, 4, true), false):
+			p.bigEndian = true
+		default:
+			return fmt.Errorf("the section header at position %v has the byte order magic %x: %w", p.pos, bt[8:12], ErrInvalidPcap)
+		}
+	}
+	typ, length := bytesToUint(bt[:4], p.bigEndian), int64(bytesToUint(bt[4:8], p.bigEndian))
+	if length < pcapngMinBlockLen || length%4 != 0 {
+		return fmt.Errorf("the block at position %v has the length %v: %w", p.pos, length, ErrInvalidPcap)
+	}
+	if length > left {
+		return fmt.Errorf("the block at position %v has %v bytes,but only %v bytes left: %w", p.pos, length, left, io.ErrUnexpectedEOF)
+	}
+	block, err := p.r.bytesAt(p.pos, length)
+	if err != nil {
+		return err
+	}
+	if trailer := bytesToUint(block[length-4:], p.bigEndian); trailer != uint64(length) {
+		return fmt.Errorf("the block at position %v has the length %v,but %v at its end: %w", p.pos, length, trailer, ErrInvalidPcap)
+	}
+	body := block[8 : length-4]
+	pos := p.pos
+	p.pos += length
+	switch typ {
+	case PcapngSectionHeader:
+		if len(body) < 16 {
+			return fmt.Errorf("the section header at position %v is too short: %w", pos, ErrInvalidPcap)
+		}
+		if major := bytesToUint(body[4:6], p.bigEndian); major != 1 {
+			return fmt.Errorf("the section header at position %v has the version %v: %w", pos, major, ErrInvalidPcap)
+		}
+		p.interfaces = nil
+	case PcapngInterfaceDesc:
+		if len(body) < 8 {
+			return fmt.Errorf("the interface description at position %v is too short: %w", pos, ErrInvalidPcap)
+		}
+		iface := PcapInterface{
+			LinkType: uint16(bytesToUint(body[:2], p.bigEndian)),
+			SnapLen:  uint32(bytesToUint(body[4:8], p.bigEndian)),
+			TSResol:  pcapngDefaultTSResol,
+		}
+		if iface.Options, err = p.readOptions(body[8:], pos); err != nil {
+			return err
+		}
+		for _, o := range iface.Options {
+			switch {
+			case o.Code == PcapngOptIfTSResol && len(o.Value) == 1:
+				iface.TSResol = o.Value[0]
+			case o.Code == PcapngOptIfTSOffset && len(o.Value) == 8:
+				iface.TSOffset = int64(bytesToUint(o.Value, p.bigEndian))
+			case o.Code == PcapngOptIfName:
+				iface.Name = string(o.Value)
+			}
+		}
+		p.interfaces = append(p.interfaces, iface)
+	case PcapngEnhancedPacket:
+		if len(body) < 20 {
+			return fmt.Errorf("the enhanced packet at position %v is too short: %w", pos, ErrInvalidPcap)
+		}
+		d := &fieldReader{b: body, bigEndian: p.bigEndian}
+		id, high, low, capLen, origLen := d.uint(4), d.uint(4), d.uint(4), d.uint(4), int(d.uint(4))
+		if id >= uint64(len(p.interfaces)) {
+			return fmt.Errorf("the enhanced packet at position %v has the interface %v of %v: %w", pos, id, len(p.interfaces), ErrInvalidPcap)
+		}
+		padded := capLen + uint64(paddingLen(int64(capLen), 4))
+		if padded > uint64(len(d.b)) {
+			return fmt.Errorf("the enhanced packet at position %v has %v bytes,more than its block: %w", pos, capLen, ErrInvalidPcap)
+		}
+		iface := p.interfaces[id]
+		packet := &Packet{
+			Offset:      pos,
+			Timestamp:   pcapngTime(high<<32|low, iface),
+			OriginalLen: origLen,
+			Interface:   int(id),
+			LinkType:    iface.LinkType,
+			Data:        append([]byte{}, d.b[:capLen]...),
+		}
+		if packet.Options, err = p.readOptions(d.b[padded:], pos); err != nil {
+			return err
+		}
+		p.packet = packet
+	case PcapngSimplePacket:
+		if len(body) < 4 || len(p.interfaces) == 0 {
+			return fmt.Errorf("the simple packet at position %v is too short or has no interface: %w", pos, ErrInvalidPcap)
+		}
+		origLen := bytesToUint(body[:4], p.bigEndian)
+		//the captured length is the smallest of the original length,the snap length and the block
+		capLen := uint64(len(body) - 4)
+		if origLen < capLen {
+			capLen = origLen
+		}
+		if snap := uint64(p.interfaces[0].SnapLen); snap != 0 && snap < capLen {
+			capLen = snap
+		}
+		p.packet = &Packet{Offset: pos, OriginalLen: int(origLen), LinkType: p.interfaces[0].LinkType, Data: append([]byte{}, body[4:4+capLen]...)}
+	}
+	return nil
+}
+
+//read the options of a block at pos up to opt_endofopt or the end of b.
+func (p *PacketWalker) readOptions(b []byte, pos int64) ([]PcapngOption, error) {
+	var options []PcapngOption
+	for len(b) >= 4 {
+		code, n := uint16(bytesToUint(b[:2], p.bigEndian)), int(bytesToUint(b[2:4], p.bigEndian))
+		if code == pcapngOptEnd {
+			break
+		}
+		padded := n + int(paddingLen(int64(n), 4))
+		if padded > len(b)-4 {
+			return nil, fmt.Errorf("the option %v of the block at position %v has %v bytes,more than its block: %w", code, pos, n, ErrInvalidPcap)
+		}
+		options = append(options, PcapngOption{code, append([]byte{}, b[4:4+n]...)})
+		b = b[4+padded:]
+	}
+	return options, nil
+}
+
+//convert a timestamp in the units of the interface.
+func pcapngTime(ts uint64, iface PcapInterface) time.Time {
+	var sec, nsec uint64
+	if iface.TSResol&0x80 == 0 {
+		unit := uint64(1)
+		for i := uint8(0); i < iface.TSResol && unit <= math.MaxUint64/10; i++ {
+			unit *= 10
+		}
+		sec = ts / unit
+		if frac := ts % unit; unit <= 1e9 {
+			nsec = frac * (1e9 / unit)
+		} else {
+			nsec = frac / (unit / 1e9)
+		}
+	} else {
+		shift := uint(iface.TSResol & 0x7f)
+		if shift > 63 {
+			shift = 63
+		}
+		sec = ts >> shift
+		nsec = uint64(float64(ts&(1<<shift-1)) / float64(uint64(1)<<shift) * 1e9)
+	}
+	return time.Unix(int64(sec)+iface.TSOffset, int64(nsec)).UTC()
+}
+
+//WritePcapHeader writes the header of a pcap file in little endian,of microseconds or of nanoseconds.
+func (w *Writer) WritePcapHeader(linkType uint16, snapLen uint32, nano bool) {
+	if nano {
+		w.WriteUint32(pcapMagicNano)
+	} else {
+		w.WriteUint32(pcapMagicMicro)
+	}
+	w.WriteUint16(2)
+	w.WriteUint16(4)
+	w.WriteZeros(8)
+	w.WriteUint32(snapLen)
+	w.WriteUint32(uint32(linkType))
+}
+
+//WritePcapPacket writes a record of a pcap file,nano must be that of WritePcapHeader.
+//an OriginalLen less than the length of the data is taken as the length of the data.
+func (w *Writer) WritePcapPacket(p *Packet, nano bool) {
+	w.WriteUint32(uint32(p.Timestamp.Unix()))
+	if nano {
+		w.WriteUint32(uint32(p.Timestamp.Nanosecond()))
+	} else {
+		w.WriteUint32(uint32(p.Timestamp.Nanosecond() / 1000))
+	}
+	w.WriteUint32(uint32(len(p.Data)))
+	w.WriteUint32(uint32(pcapOriginalLen(p)))
+	w.WriteBytes(p.Data)
+}
+
+func pcapOriginalLen(p *Packet) int {
+	if p.OriginalLen < len(p.Data) {
+		return len(p.Data)
+	}
+	return p.OriginalLen
+}
+
+//write a pcapng block in little endian,the body is padded to 4 bytes.
+func (w *Writer) writePcapngBlock(typ uint32, body []byte) {
+	body = append(body, make([]byte, paddingLen(int64(len(body)), 4))...)
+	w.WriteUint32(typ)
+	w.WriteUint32(uint32(len(body) + pcapngMinBlockLen))
+	w.WriteBytes(body)
+	w.WriteUint32(uint32(len(body) + pcapngMinBlockLen))
+}
+
+//append the options and opt_endofopt,nothing if there are no options.
+func appendPcapngOptions(b []byte, options []PcapngOption) []byte {
+	if len(options) == 0 {
+		return b
+	}
+	for _, o := range options {
+		if len(o.Value) > math.MaxUint16 {
+			panic(fmt.Sprintf("the pcapng option %v has %v bytes,more than 65535", o.Code, len(o.Value)))
+		}
+		b = append(b, uintToBytes(uint64(o.Code), 2, false)...)
+		b = append(b, uintToBytes(uint64(len(o.Value)), 2, false)...)
+		b = append(b, o.Value...)
+		b = append(b, make([]byte, paddingLen(int64(len(o.Value)), 4))...)
+	}
+	return append(b, 0, 0, 0, 0)
+}
+
+//WritePcapngSection writes a section header block in little endian with an unknown section length,
+//the interfaces of the section are written next.
+func (w *Writer) WritePcapngSection(options ...PcapngOption) {
+	body := uintToBytes(pcapngByteOrderMagic, 4, false)
+	body = append(body, 1, 0, 0, 0) //version 1.0
+	body = append(body, uintToBytes(math.MaxUint64, 8, false)...)
+	w.writePcapngBlock(PcapngSectionHeader, appendPcapngOptions(body, options))
+}
+
+//WritePcapngInterface writes an interface description block,its ID is the count of the interfaces written
+//before it in the section.the options if_name,if_tsresol and if_tsoffset are added for Name,a TSResol
+//other than 6 and TSOffset,after the Options.
+func (w *Writer) WritePcapngInterface(iface *PcapInterface) {
+	body := uintToBytes(uint64(iface.LinkType), 2, false)
+	body = append(body, 0, 0)
+	body = append(body, uintToBytes(uint64(iface.SnapLen), 4, false)...)
+	options := append([]PcapngOption{}, iface.Options...)
+	if iface.Name != "" {
+		options = append(options, PcapngOption{PcapngOptIfName, []byte(iface.Name)})
+	}
+	if iface.TSResol != pcapngDefaultTSResol {
+		options = append(options, PcapngOption{PcapngOptIfTSResol, []byte{iface.TSResol}})
+	}
+	if iface.TSOffset != 0 {
+		options = append(options, PcapngOption{PcapngOptIfTSOffset, uintToBytes(uint64(iface.TSOffset), 8, false)})
+	}
+	w.writePcapngBlock(PcapngInterfaceDesc, appendPcapngOptions(body, options))
+}
+
+//WritePcapngPacket writes an enhanced packet block of p.Interface with p.Options,
+//the timestamp is in the units of the interface,whose TSResol and TSOffset are given by iface.
+func (w *Writer) WritePcapngPacket(p *Packet, iface *PcapInterface) {
+	body := uintToBytes(uint64(p.Interface), 4, false)
+	ts := pcapngUnits(p.Timestamp, iface)
+	body = append(body, uintToBytes(ts>>32, 4, false)...)
+	body = append(body, uintToBytes(ts&math.MaxUint32, 4, false)...)
+	body = append(body, uintToBytes(uint64(len(p.Data)), 4, false)...)
+	body = append(body, uintToBytes(uint64(pcapOriginalLen(p)), 4, false)...)
+	body = append(body, p.Data...)
+	body = append(body, make([]byte, paddingLen(int64(len(p.Data)), 4))...)
+	w.writePcapngBlock(PcapngEnhancedPacket, appendPcapngOptions(body, p.Options))
+}
+
+//convert a time to the timestamp units of the interface.
+func pcapngUnits(t time.Time, iface *PcapInterface) uint64 {
+	sec, nsec := uint64(t.Unix()-iface.TSOffset), uint64(t.Nanosecond())
+	if iface.TSResol&0x80 != 0 {
+		shift := uint(iface.TSResol & 0x7f)
+		return sec<<shift | uint64(float64(nsec)/1e9*float64(uint64(1)<<shift))
+	}
+	unit := uint64(1)
+	for i := uint8(0); i < iface.TSResol; i++ {
+		unit *= 10
+	}
+	if unit <= 1e9 {
+		return sec*unit + nsec/(1e9/unit)
+	}
+	return sec*unit + nsec*(unit/1e9)
+}
+
+//WritePcapngSimplePacket writes a simple packet block,which belongs to the first interface of the section.
+func (w *Writer) WritePcapngSimplePacket(data []byte, originalLen int) {
+	if originalLen < len(data) {
+		originalLen = len(data)
+	}
+	body := uintToBytes(uint64(originalLen), 4, false)
+	w.writePcapngBlock(PcapngSimplePacket, append(body, data...))
+}
@@ -0,0 +1,204 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func collectPackets(t *testing.T, r *ReadSeeker) ([]*Packet, *PacketWalker) {
+	walker := r.Packets()
+	var packets []*Packet
+	for walker.Next() {
+		packets = append(packets, walker.Packet())
+	}
+	if err := walker.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return packets, walker
+}
+
+func TestPcap(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	for _, nano := range []bool{false, true} {
+		w := NewBytesBuffer()
+		w.WritePcapHeader(1, 65535, nano)
+		w.WritePcapPacket(&Packet{Timestamp: ts, OriginalLen: 100, Data: []byte("abcd")}, nano)
+		w.WritePcapPacket(&Packet{Timestamp: ts.Add(time.Second), Data: []byte("xyz")}, nano)
+		r := NewReadSeekerFromBytes(append([]byte("xx"), w.Bytes()...))
+		r.MoveTo(2)
+		packets, walker := collectPackets(t, r)
+		if pos, _ := r.CurPos(); pos != 2 {
+			t.Fatalf("unexpected value obtained; got %v want %v", pos, 2)
+		}
+		if ifaces := walker.Interfaces(); len(ifaces) != 1 || ifaces[0].LinkType != 1 || ifaces[0].SnapLen != 65535 {
+			t.Fatalf("unexpected value obtained; got %+v want %v", ifaces, "ethernet")
+		}
+		want := ts.Truncate(time.Microsecond)
+		if nano {
+			want = ts
+		}
+		if len(packets) != 2 || !packets[0].Timestamp.Equal(want) || packets[0].OriginalLen != 100 || string(packets[0].Data) != "abcd" || packets[0].Offset != pcapHeaderLen {
+			t.Fatalf("unexpected value obtained; got %+v want %v", packets[0], want)
+		}
+		if !packets[1].Timestamp.Equal(want.Add(time.Second)) || packets[1].OriginalLen != 3 || string(packets[1].Data) != "xyz" || packets[1].LinkType != 1 {
+			t.Fatalf("unexpected value obtained; got %+v want %v", packets[1], "xyz")
+		}
+	}
+}
+
+func TestPcapBigEndian(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteUint32BigEndian(pcapMagicNano)
+	w.WriteUint16BigEndian(2)
+	w.WriteUint16BigEndian(4)
+	w.WriteZeros(8)
+	w.WriteUint32BigEndian(1500)
+	w.WriteUint32BigEndian(101)
+	w.WriteUint32BigEndian(1000)
+	w.WriteUint32BigEndian(5)
+	w.WriteUint32BigEndian(2)
+	w.WriteUint32BigEndian(2)
+	w.WriteBytes([]byte{1, 2})
+	packets, walker := collectPackets(t, NewReadSeekerFromBytes(w.Bytes()))
+	if walker.Interfaces()[0].LinkType != 101 || walker.Interfaces()[0].TSResol != 9 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", walker.Interfaces(), "raw IP of nanoseconds")
+	}
+	if want := time.Unix(1000, 5).UTC(); len(packets) != 1 || !packets[0].Timestamp.Equal(want) || !bytes.Equal(packets[0].Data, []byte{1, 2}) {
+		t.Fatalf("unexpected value obtained; got %+v want %v", packets, want)
+	}
+}
+
+//write a big endian section of an interface and an enhanced packet by hand.
+func writeBigEndianPcapngSection(w *Writer) {
+	w.WriteUint32BigEndian(PcapngSectionHeader)
+	w.WriteUint32BigEndian(28)
+	w.WriteUint32BigEndian(pcapngByteOrderMagic)
+	w.WriteUint16BigEndian(1)
+	w.WriteUint16BigEndian(0)
+	w.WriteUint64BigEndian(1<<64 - 1)
+	w.WriteUint32BigEndian(28)
+	w.WriteUint32BigEndian(PcapngInterfaceDesc)
+	w.WriteUint32BigEndian(20)
+	w.WriteUint16BigEndian(105)
+	w.WriteUint16BigEndian(0)
+	w.WriteUint32BigEndian(0)
+	w.WriteUint32BigEndian(20)
+	w.WriteUint32BigEndian(PcapngEnhancedPacket)
+	w.WriteUint32BigEndian(36)
+	w.WriteUint32BigEndian(0)
+	w.WriteUint32BigEndian(0)
+	w.WriteUint32BigEndian(3000000)
+	w.WriteUint32BigEndian(3)
+	w.WriteUint32BigEndian(3)
+	w.WriteBytes([]byte{7, 8, 9, 0})
+	w.WriteUint32BigEndian(36)
+}
+
+func TestPcapng(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	w := NewBytesBuffer()
+	w.WritePcapngSection(PcapngOption{PcapngOptComment, []byte("first")})
+	eth := &PcapInterface{LinkType: 1, SnapLen: 4, TSResol: pcapngDefaultTSResol, Name: "eth0"}
+	nano := &PcapInterface{LinkType: 101, TSResol: 9, TSOffset: 100}
+	w.WritePcapngInterface(eth)
+	w.WritePcapngInterface(nano)
+	w.WritePcapngPacket(&Packet{Timestamp: ts, Interface: 1, OriginalLen: 10, Data: []byte("hello"), Options: []PcapngOption{{PcapngOptComment, []byte("hi")}}}, nano)
+	w.WriteUint32(0x0bad) //an unknown block is skipped
+	w.WriteUint32(16)
+	w.WriteUint32(0)
+	w.WriteUint32(16)
+	w.WritePcapngPacket(&Packet{Timestamp: ts, Data: []byte("abc")}, eth)
+	w.WritePcapngSimplePacket([]byte("abcd"), 6)
+	writeBigEndianPcapngSection(w)
+	packets, walker := collectPackets(t, NewReadSeekerFromBytes(w.Bytes()))
+	if len(packets) != 4 {
+		t.Fatalf("unexpected value obtained; got %v want %v", len(packets), 4)
+	}
+	p := packets[0]
+	if !p.Timestamp.Equal(ts) || p.Interface != 1 || p.LinkType != 101 || p.OriginalLen != 10 || string(p.Data) != "hello" {
+		t.Fatalf("unexpected value obtained; got %+v want %v", p, ts)
+	}
+	if len(p.Options) != 1 || p.Options[0].Code != PcapngOptComment || string(p.Options[0].Value) != "hi" {
+		t.Fatalf("unexpected value obtained; got %+v want %v", p.Options, "hi")
+	}
+	if p = packets[1]; !p.Timestamp.Equal(ts.Truncate(time.Microsecond)) || p.LinkType != 1 || string(p.Data) != "abc" || p.Options != nil {
+		t.Fatalf("unexpected value obtained; got %+v want %v", p, "abc")
+	}
+	if p = packets[2]; p.OriginalLen != 6 || string(p.Data) != "abcd" {
+		t.Fatalf("unexpected value obtained; got %+v want %v", p, "abcd")
+	}
+	if p = packets[3]; !p.Timestamp.Equal(time.Unix(3, 0)) || p.LinkType != 105 || !bytes.Equal(p.Data, []byte{7, 8, 9}) {
+		t.Fatalf("unexpected value obtained; got %+v want %v", p, "789")
+	}
+	//the interfaces are those of the last section
+	if ifaces := walker.Interfaces(); len(ifaces) != 1 || ifaces[0].LinkType != 105 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", ifaces, 105)
+	}
+	//the interfaces of the first section
+	first, err := NewReadSeekerFromBytes(w.Bytes()).Section(0, packets[0].Offset-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, walker = collectPackets(t, first)
+	if ifaces := walker.Interfaces(); len(ifaces) != 2 || ifaces[0].Name != "eth0" || ifaces[1].TSResol != 9 || ifaces[1].TSOffset != 100 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", ifaces, "eth0 and a nanosecond interface")
+	}
+}
+
+func TestPcapngTime(t *testing.T) {
+	for _, c := range []struct {
+		ts   uint64
+		res  uint8
+		want time.Time
+	}{
+		{1500, 3, time.Unix(1, 500000000)},
+		{1234567890000, 12, time.Unix(1, 234567890)},
+		{3<<10 | 512, 0x8a, time.Unix(3, 500000000)},
+	} {
+		if got := pcapngTime(c.ts, PcapInterface{TSResol: c.res}); !got.Equal(c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", got, c.want)
+		}
+		if got := pcapngUnits(c.want, &PcapInterface{TSResol: c.res}); got != c.ts {
+			t.Fatalf("unexpected value obtained; got %v want %v", got, c.ts)
+		}
+	}
+}
+
+func TestPcapErrors(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WritePcapHeader(1, 65535, false)
+	w.WritePcapPacket(&Packet{Data: []byte("abcd")}, false)
+	pcap := w.Bytes()
+	w = NewBytesBuffer()
+	w.WritePcapngSection()
+	w.WritePcapngInterface(&PcapInterface{LinkType: 1, TSResol: pcapngDefaultTSResol})
+	w.WritePcapngPacket(&Packet{Data: []byte("abcd")}, &PcapInterface{TSResol: pcapngDefaultTSResol})
+	ng := w.Bytes()
+	badTrailer := append([]byte{}, ng...)
+	badTrailer[len(badTrailer)-1] = 1
+	noInterface := append([]byte{}, ng[:28]...)
+	noInterface = append(noInterface, ng[len(ng)-36:]...)
+	for _, c := range []struct {
+		data []byte
+		want error
+	}{
+		{[]byte{0xd4}, io.ErrUnexpectedEOF},
+		{bytes.Repeat([]byte{1}, 24), ErrInvalidPcap},
+		{pcap[:20], io.ErrUnexpectedEOF},
+		{pcap[:len(pcap)-1], io.ErrUnexpectedEOF},
+		{pcap[:pcapHeaderLen+8], io.ErrUnexpectedEOF},
+		{ng[:len(ng)-1], io.ErrUnexpectedEOF},
+		{badTrailer, ErrInvalidPcap},
+		{noInterface, ErrInvalidPcap},
+	} {
+		walker := NewReadSeekerFromBytes(c.data).Packets()
+		for walker.Next() {
+		}
+		if err := walker.Err(); !errors.Is(err, c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, c.want)
+		}
+	}
+}
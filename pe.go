@@ -0,0 +1,274 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPE = errors.New("the PE data is not valid")
+
+//the indexes of the data directories.
+const (
+	PEDirectoryExport = iota
+	PEDirectoryImport
+	PEDirectoryResource
+	PEDirectoryException
+	PEDirectorySecurity //its address is a file offset,not an RVA
+	PEDirectoryBaseReloc
+	PEDirectoryDebug
+	PEDirectoryArchitecture
+	PEDirectoryGlobalPtr
+	PEDirectoryTLS
+	PEDirectoryLoadConfig
+	PEDirectoryBoundImport
+	PEDirectoryIAT
+	PEDirectoryDelayImport
+	PEDirectoryCLRRuntime
+)
+
+//the sizes of the fixed parts of the PE headers.
+const (
+	peCOFFHeaderLen = 20
+	peSectionLen    = 40
+	peSymbolLen     = 18
+	peMaxDirectory  = 16
+)
+
+//PE is the headers,the data directories and the section table of a PE file,such as an EXE or a DLL.
+type PE struct {
+	NTOffset             int64 //of the PE signature,from e_lfanew of the DOS header
+	Machine              uint16
+	TimeDateStamp        uint32
+	PointerToSymbolTable uint32
+	NumberOfSymbols      uint32
+	Characteristics      uint16
+	PE32Plus             bool //the optional header of a 64-bit image,otherwise PE32
+	EntryPoint           uint32
+	ImageBase            uint64
+	SectionAlignment     uint32
+	FileAlignment        uint32
+	SizeOfImage          uint32
+	SizeOfHeaders        uint32
+	CheckSum             uint32
+	Subsystem            uint16
+	DllCharacteristics   uint16
+	DataDirectories      []PEDataDirectory
+	Sections             []*PESection
+	r                    *ReadSeeker
+}
+
+//PEDataDirectory is the RVA and the size of a table such as the imports.
+type PEDataDirectory struct {
+	VirtualAddress uint32
+	Size           uint32
+}
+
+//PESection is an entry of the section table.
+type PESection struct {
+	Name                 string //a long name of the COFF string table is resolved
+	VirtualSize          uint32
+	VirtualAddress       uint32
+	SizeOfRawData        uint32
+	PointerToRawData     uint32
+	PointerToRelocations uint32
+	PointerToLinenumbers uint32
+	NumberOfRelocations  uint16
+	NumberOfLinenumbers  uint16
+	Characteristics      uint32
+}
+
+//ReadPE reads the DOS header,the NT headers and the section table of the PE file at the current position,
+//offsets are relative to it,and the position is left unchanged.
+func (r *ReadSeeker) ReadPE() (*PE, error) {
+	defer r.traceEnter("ReadPE")()
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.Section(pos, r.Size()-1)
+	if err != nil {
+		return nil, err
+	}
+	dos, err := data.tableAt(0, 1, 0x40, "DOS header")
+	if err != nil {
+		return nil, err
+	}
+	if string(dos[:2]) != "MZ" {
+		return nil, fmt.Errorf("the magic number %q is not MZ: %w", dos[:2], ErrInvalidPE)
+	}
+	p := &PE{NTOffset: int64(bytesToUint(dos[0x3c:0x40], false)), r: data}
+	nt, err := data.tableAt(uint64(p.NTOffset), 1, 4+peCOFFHeaderLen, "NT headers")
+	if err != nil {
+		return nil, err
+	}
+	if string(nt[:4]) != "PE\x00\x00" {
+		return nil, fmt.Errorf("the signature %q at offset %v is not PE\\0\\0: %w", nt[:4], p.NTOffset, ErrInvalidPE)
+	}
+	d := &fieldReader{b: nt[4:]}
+	p.Machine = uint16(d.uint(2))
+	numSections := d.uint(2)
+	p.TimeDateStamp, p.PointerToSymbolTable, p.NumberOfSymbols = uint32(d.uint(4)), uint32(d.uint(4)), uint32(d.uint(4))
+	optLen := d.uint(2)
+	p.Characteristics = uint16(d.uint(2))
+	optPos := uint64(p.NTOffset) + 4 + peCOFFHeaderLen
+	opt, err := data.tableAt(optPos, 1, optLen, "optional header")
+	if err != nil {
+		return nil, err
+	}
+	if err = p.readOptionalHeader(opt); err != nil {
+		return nil, err
+	}
+	table, err := data.tableAt(optPos+optLen, numSections, peSectionLen, "section table")
+	if err != nil {
+		return nil, err
+	}
+	var names []byte //the COFF string table,read for the first long name
+	for i := uint64(0); i < numSections; i++ {
+		d := &fieldReader{b: table[i*peSectionLen+8:]}
+		s := &PESection{Name: strings.TrimRight(string(table[i*peSectionLen:i*peSectionLen+8]), "\x00")}
+		s.VirtualSize, s.VirtualAddress = uint32(d.uint(4)), uint32(d.uint(4))
+		s.SizeOfRawData, s.PointerToRawData = uint32(d.uint(4)), uint32(d.uint(4))
+		s.PointerToRelocations, s.PointerToLinenumbers = uint32(d.uint(4)), uint32(d.uint(4))
+		s.NumberOfRelocations, s.NumberOfLinenumbers = uint16(d.uint(2)), uint16(d.uint(2))
+		s.Characteristics = uint32(d.uint(4))
+		if off, ok := peLongNameOffset(s.Name); ok && p.PointerToSymbolTable != 0 {
+			if names == nil {
+				if names, err = p.stringTable(); err != nil {
+					return nil, err
+				}
+			}
+			if off < 4 || off >= uint64(len(names)) {
+				return nil, fmt.Errorf("the section name %q is outside the COFF string table of %v bytes: %w", s.Name, len(names), ErrInvalidPE)
+			}
+			s.Name = cString(names, off)
+		}
+		p.Sections = append(p.Sections, s)
+	}
+	r.traceValue(fmt.Sprintf("%v sections", len(p.Sections)))
+	return p, nil
+}
+
+//read the fields of the optional header of PE32 and PE32+ images.
+func (p *PE) readOptionalHeader(opt []byte) error {
+	if len(opt) < 2 {
+		return fmt.Errorf("the optional header of %v bytes has no magic number: %w", len(opt), ErrInvalidPE)
+	}
+	d := &fieldReader{b: opt}
+	switch magic := d.uint(2); magic {
+	case 0x10b:
+	case 0x20b:
+		p.PE32Plus = true
+	default:
+		return fmt.Errorf("the optional header magic number 0x%x is neither 0x10b nor 0x20b: %w", magic, ErrInvalidPE)
+	}
+	d.is64 = p.PE32Plus
+	fixed := 96 //the fields before the data directories
+	if p.PE32Plus {
+		fixed = 112
+	}
+	if len(opt) < fixed {
+		return fmt.Errorf("the optional header has %v bytes,less than %v: %w", len(opt), fixed, ErrInvalidPE)
+	}
+	d.uint(14) //the linker version and the sizes of code and data
+	p.EntryPoint = uint32(d.uint(4))
+	d.uint(4) //BaseOfCode
+	if !p.PE32Plus {
+		d.uint(4) //BaseOfData
+	}
+	p.ImageBase = d.word()
+	p.SectionAlignment, p.FileAlignment = uint32(d.uint(4)), uint32(d.uint(4))
+	d.uint(16) //the versions
+	p.SizeOfImage, p.SizeOfHeaders, p.CheckSum = uint32(d.uint(4)), uint32(d.uint(4)), uint32(d.uint(4))
+	p.Subsystem, p.DllCharacteristics = uint16(d.uint(2)), uint16(d.uint(2))
+	d.word() //the sizes of the stack and the heap
+	d.word()
+	d.word()
+	d.word()
+	d.uint(4) //LoaderFlags
+	n := d.uint(4)
+	if n > peMaxDirectory {
+		n = peMaxDirectory
+	}
+	if left := uint64(len(d.b)) / 8; n > left {
+		n = left
+	}
+	for i := uint64(0); i < n; i++ {
+		p.DataDirectories = append(p.DataDirectories, PEDataDirectory{uint32(d.uint(4)), uint32(d.uint(4))})
+	}
+	return nil
+}
+
+//a section name of "/n" is at the offset n of the COFF string table,ok is false for other names.
+func peLongNameOffset(name string) (off uint64, ok bool) {
+	if !strings.HasPrefix(name, "/") {
+		return 0, false
+	}
+	off, err := strconv.ParseUint(name[1:], 10, 32)
+	return off, err == nil
+}
+
+//read the COFF string table that follows the symbol table,its size in the first 4 bytes included.
+func (p *PE) stringTable() ([]byte, error) {
+	tableOff := uint64(p.PointerToSymbolTable) + uint64(p.NumberOfSymbols)*peSymbolLen
+	sizeBytes, err := p.r.tableAt(tableOff, 1, 4, "COFF string table")
+	if err != nil {
+		return nil, err
+	}
+	return p.r.tableAt(tableOff, 1, bytesToUint(sizeBytes, false), "COFF string table")
+}
+
+//Section returns the first section of the name,nil if there is none.
+func (p *PE) Section(name string) *PESection {
+	for _, s := range p.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+//SectionData returns the raw data of a section in the file,of SizeOfRawData bytes,
+//which is rounded up to FileAlignment and may be less than VirtualSize.
+func (p *PE) SectionData(s *PESection) (*ReadSeeker, error) {
+	return p.dataAt(uint64(s.PointerToRawData), uint64(s.SizeOfRawData), "section "+s.Name)
+}
+
+func (p *PE) dataAt(off, size uint64, what string) (*ReadSeeker, error) {
+	if total := uint64(p.r.Size()); off > total || size > total-off {
+		return nil, fmt.Errorf("the %v of %v bytes at offset %v is beyond the data of %v bytes: %w", what, size, off, total, io.ErrUnexpectedEOF)
+	}
+	return p.r.Section(int64(off), int64(off+size)-1)
+}
+
+//RVAToOffset returns the file offset of a relative virtual address,found through the section that holds it,
+//false is returned if no section holds it in its raw data.
+func (p *PE) RVAToOffset(rva uint32) (int64, bool) {
+	for _, s := range p.Sections {
+		if rva >= s.VirtualAddress && uint64(rva) < uint64(s.VirtualAddress)+uint64(s.SizeOfRawData) {
+			return int64(s.PointerToRawData) + int64(rva-s.VirtualAddress), true
+		}
+	}
+	if rva < p.SizeOfHeaders {
+		return int64(rva), true
+	}
+	return 0, false
+}
+
+//DirectoryData returns the data of a data directory such as PEDirectoryImport,nil if the image has none.
+func (p *PE) DirectoryData(index int) (*ReadSeeker, error) {
+	if index < 0 || index >= len(p.DataDirectories) || p.DataDirectories[index].Size == 0 {
+		return nil, nil
+	}
+	dir := p.DataDirectories[index]
+	off := int64(dir.VirtualAddress)
+	if index != PEDirectorySecurity {
+		var ok bool
+		if off, ok = p.RVAToOffset(dir.VirtualAddress); !ok {
+			return nil, fmt.Errorf("the data directory %v at the RVA 0x%x is in no section: %w", index, dir.VirtualAddress, ErrInvalidPE)
+		}
+	}
+	return p.dataAt(uint64(off), uint64(dir.Size), fmt.Sprint("data directory ", index))
+}
@@ -0,0 +1,135 @@
+package iox
+
+import (
+	"bytes"
+	"debug/pe"
+	"errors"
+	"io"
+	"testing"
+)
+
+//a PE32+ image with .text and a section whose long name is in the COFF string table.
+func testPEData() []byte {
+	w := NewBytesBuffer()
+	w.WriteString("MZ")
+	w.WriteZeros(0x3a)
+	w.WriteUint32(0x40)
+	w.WriteString("PE\x00\x00")
+	w.WriteUint16(0x8664)
+	w.WriteUint16(2)
+	w.WriteUint32(0x5f000000)
+	w.WriteUint32(0x600) //the COFF string table follows no symbols
+	w.WriteUint32(0)
+	w.WriteUint16(112 + 16*8)
+	w.WriteUint16(0x22)
+	w.WriteUint16(0x20b)
+	w.WriteZeros(14)
+	w.WriteUint32(0x1010) //AddressOfEntryPoint
+	w.WriteUint32(0x1000)
+	w.WriteUint64(0x140000000)
+	w.WriteUint32(0x1000)
+	w.WriteUint32(0x200)
+	w.WriteZeros(16)
+	w.WriteUint32(0x3000) //SizeOfImage
+	w.WriteUint32(0x200)
+	w.WriteUint32(0)
+	w.WriteUint16(3)
+	w.WriteUint16(0x8160)
+	w.WriteZeros(32 + 4)
+	w.WriteUint32(16)
+	for i := 0; i < 16; i++ {
+		if i == PEDirectoryImport {
+			w.WriteUint32(0x2010)
+			w.WriteUint32(0x28)
+		} else {
+			w.WriteZeros(8)
+		}
+	}
+	for _, s := range []struct {
+		name                    string
+		va, ptr, characteristic uint32
+	}{{".text", 0x1000, 0x200, 0x60000020}, {"/4", 0x2000, 0x400, 0x40000040}} {
+		w.WriteString(s.name)
+		w.WriteZeros(8 - len(s.name))
+		w.WriteUint32(0x100)
+		w.WriteUint32(s.va)
+		w.WriteUint32(0x200)
+		w.WriteUint32(s.ptr)
+		w.WriteZeros(12)
+		w.WriteUint32(s.characteristic)
+	}
+	w.WriteZeros(0x200 - int(w.Len()))
+	w.WriteBytes(bytes.Repeat([]byte{0xcc}, 0x200))
+	w.WriteZeros(0x10)
+	w.WriteBytes(bytes.Repeat([]byte{0x11}, 0x28))
+	w.WriteZeros(0x200 - 0x38)
+	w.WriteUint32(16)
+	w.WriteString(".rdata_long\x00")
+	return w.Bytes()
+}
+
+func TestPE(t *testing.T) {
+	data := testPEData()
+	want, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewReadSeekerFromBytes(data).ReadPE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := want.OptionalHeader.(*pe.OptionalHeader64)
+	if !p.PE32Plus || p.Machine != want.Machine || p.ImageBase != opt.ImageBase || p.EntryPoint != opt.AddressOfEntryPoint ||
+		p.SizeOfImage != opt.SizeOfImage || p.Subsystem != opt.Subsystem || len(p.DataDirectories) != 16 {
+		t.Fatalf("unexpected value obtained; got %+v want %+v", p, opt)
+	}
+	for i, s := range p.Sections {
+		ws := want.Sections[i]
+		if s.Name != ws.Name || s.VirtualAddress != ws.VirtualAddress || s.PointerToRawData != ws.Offset || s.Characteristics != ws.Characteristics {
+			t.Fatalf("unexpected value obtained; got %+v want %+v", s, ws.SectionHeader)
+		}
+	}
+	if p.Section(".rdata_long") == nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", nil, ".rdata_long")
+	}
+	text, err := p.SectionData(p.Section(".text"))
+	if err != nil || text.Size() != 0x200 {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, 0x200)
+	}
+	if off, ok := p.RVAToOffset(0x1010); !ok || off != 0x210 {
+		t.Fatalf("unexpected value obtained; got %v want %v", off, 0x210)
+	}
+	if _, ok := p.RVAToOffset(0x5000); ok {
+		t.Fatalf("unexpected value obtained; got %v want %v", ok, false)
+	}
+	imports, err := p.DirectoryData(PEDirectoryImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := imports.ReadBytes(0x28); !bytes.Equal(b, bytes.Repeat([]byte{0x11}, 0x28)) {
+		t.Fatalf("unexpected value obtained; got %x want %v", b, "0x28 bytes of 0x11")
+	}
+	if exports, err := p.DirectoryData(PEDirectoryExport); err != nil || exports != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", exports, nil)
+	}
+}
+
+func TestPEErrors(t *testing.T) {
+	badSignature := testPEData()
+	badSignature[0x41] = 'X'
+	badMagic := testPEData()
+	badMagic[0x58] = 0x0c
+	for _, c := range []struct {
+		data []byte
+		want error
+	}{
+		{[]byte("MZ"), io.ErrUnexpectedEOF},
+		{append([]byte("ZM"), make([]byte, 0x40)...), ErrInvalidPE},
+		{badSignature, ErrInvalidPE},
+		{badMagic, ErrInvalidPE},
+	} {
+		if _, err := NewReadSeekerFromBytes(c.data).ReadPE(); !errors.Is(err, c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, c.want)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package iox
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidProto = errors.New("the protobuf data is not valid")
+
+//ProtoWireType is the wire type in the key of a protobuf field.
+type ProtoWireType int
+
+const (
+	ProtoVarint     ProtoWireType = 0
+	ProtoFixed64    ProtoWireType = 1
+	ProtoBytes      ProtoWireType = 2 //length-delimited:bytes,strings,messages and packed repeated fields
+	ProtoStartGroup ProtoWireType = 3
+	ProtoEndGroup   ProtoWireType = 4
+	ProtoFixed32    ProtoWireType = 5
+)
+
+func (t ProtoWireType) String() string {
+	switch t {
+	case ProtoVarint:
+		return "varint"
+	case ProtoFixed64:
+		return "fixed64"
+	case ProtoBytes:
+		return "bytes"
+	case ProtoStartGroup:
+		return "start group"
+	case ProtoEndGroup:
+		return "end group"
+	case ProtoFixed32:
+		return "fixed32"
+	}
+	return fmt.Sprintf("ProtoWireType(%d)", int(t))
+}
+
+const (
+	//the biggest field number.
+	maxProtoFieldNumber = 1<<29 - 1
+	//the deepest nesting of groups accepted,and of messages guessed by ProtoDump.
+	maxProtoDepth = 64
+)
+
+//ProtoField is a field of a protobuf message read without its schema,
+//its methods decode the value the way the types of the .proto file would.
+type ProtoField struct {
+	Number   int
+	WireType ProtoWireType
+	Offset   int64       //of the key in the data
+	Size     int64       //the key and the value
+	Value    uint64      //the value of a varint,fixed32 or fixed64 field
+	Data     *ReadSeeker //the content of a length-delimited field or the fields of a group
+}
+
+//read the field at the position and move past it,io.EOF is returned at the end of the data,
+//so the fields of a message are read until io.EOF.the fields of a nested message or a group are read
+//from the Data of the field in the same way.the position is left unchanged on error.
+func (r *ReadSeeker) ReadProtoField() (*ProtoField, error) {
+	defer r.traceEnter("ReadProtoField")()
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.readProtoField(0)
+	if err == nil && f.WireType == ProtoEndGroup {
+		err = fmt.Errorf("the end group of field %v at position %v has no start group: %w", f.Number, currentPos, ErrInvalidProto)
+	}
+	if err != nil {
+		if _, seekErr := r.readSeeker.Seek(currentPos, io.SeekStart); seekErr != nil {
+			return nil, seekErr
+		}
+		return nil, err
+	}
+	r.traceValue(fmt.Sprintf("%v %v", f.Number, f.WireType))
+	return f, nil
+}
+
+//read a field including an end group,depth is the number of groups around it.
+func (r *ReadSeeker) readProtoField(depth int) (*ProtoField, error) {
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	key, err := r.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if key>>3 == 0 || key>>3 > maxProtoFieldNumber {
+		return nil, fmt.Errorf("the field number %v at position %v is out of range: %w", key>>3, pos, ErrInvalidProto)
+	}
+	f := &ProtoField{Number: int(key >> 3), WireType: ProtoWireType(key & 7), Offset: pos}
+	switch f.WireType {
+	case ProtoVarint:
+		f.Value, err = r.ReadUvarint()
+	case ProtoFixed64:
+		f.Value, err = r.ReadUint64()
+	case ProtoFixed32:
+		var v uint32
+		v, err = r.ReadUint32()
+		f.Value = uint64(v)
+	case ProtoBytes:
+		var n int
+		if n, err = r.readUvarintLength(); err != nil {
+			break
+		}
+		begin, _ := r.CurPos()
+		if f.Data, err = r.Section(begin, begin+int64(n)-1); err != nil {
+			break
+		}
+		_, err = r.readSeeker.Seek(begin+int64(n), io.SeekStart)
+	case ProtoStartGroup:
+		if depth >= maxProtoDepth {
+			return nil, fmt.Errorf("the group of field %v at position %v is nested deeper than %v: %w", f.Number, pos, maxProtoDepth, ErrInvalidProto)
+		}
+		begin, _ := r.CurPos()
+		for {
+			var child *ProtoField
+			if child, err = r.readProtoField(depth + 1); err != nil {
+				break
+			}
+			if child.WireType != ProtoEndGroup {
+				continue
+			}
+			if child.Number != f.Number {
+				return nil, fmt.Errorf("the group of field %v at position %v ends with field %v: %w", f.Number, pos, child.Number, ErrInvalidProto)
+			}
+			f.Data, err = r.Section(begin, child.Offset-1)
+			break
+		}
+	case ProtoEndGroup:
+	default:
+		return nil, fmt.Errorf("the wire type %v of field %v at position %v is unknown: %w", int(f.WireType), f.Number, pos, ErrInvalidProto)
+	}
+	if err == io.EOF {
+		err = fmt.Errorf("the value of field %v at position %v is missing: %w", f.Number, pos, io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return nil, err
+	}
+	end, _ := r.CurPos()
+	f.Size = end - pos
+	return f, nil
+}
+
+func (f *ProtoField) wireTypeError(want ProtoWireType) error {
+	return fmt.Errorf("field %v at position %v is %v,not %v: %w", f.Number, f.Offset, f.WireType, want, ErrInvalidProto)
+}
+
+//Uint64 returns the value of a varint,fixed32 or fixed64 field,for the uint32,uint64,fixed32 and fixed64 types.
+func (f *ProtoField) Uint64() uint64 {
+	return f.Value
+}
+
+//Int64 returns the value for the int32,int64,enum,sfixed32 and sfixed64 types.
+func (f *ProtoField) Int64() int64 {
+	if f.WireType == ProtoFixed32 {
+		return int64(int32(f.Value))
+	}
+	return int64(f.Value)
+}
+
+//Sint64 returns the zigzag encoded value of the sint32 and sint64 types.
+func (f *ProtoField) Sint64() int64 {
+	return int64(f.Value>>1) ^ -int64(f.Value&1)
+}
+
+//Bool returns the value of the bool type.
+func (f *ProtoField) Bool() bool {
+	return f.Value != 0
+}
+
+//Float32 returns the value of the float type.
+func (f *ProtoField) Float32() float32 {
+	return math.Float32frombits(uint32(f.Value))
+}
+
+//Float64 returns the value of the double type.
+func (f *ProtoField) Float64() float64 {
+	return math.Float64frombits(f.Value)
+}
+
+//Bytes returns the content of a length-delimited field.
+func (f *ProtoField) Bytes() ([]byte, error) {
+	if f.WireType != ProtoBytes {
+		return nil, f.wireTypeError(ProtoBytes)
+	}
+	return f.Data.allBytes()
+}
+
+//Text returns the content of a length-delimited field as a string.
+func (f *ProtoField) Text() (string, error) {
+	bt, err := f.Bytes()
+	return string(bt), err
+}
+
+//the content of a packed field from its beginning,f.Data is left alone.
+func (f *ProtoField) packed() (*ReadSeeker, error) {
+	if f.WireType != ProtoBytes {
+		return nil, f.wireTypeError(ProtoBytes)
+	}
+	return f.Data.Section(0, f.Data.Size()-1)
+}
+
+//PackedVarints decodes a packed repeated field of varints,
+//a varint field that is not packed gives its single value,as parsers must accept both.
+func (f *ProtoField) PackedVarints() ([]uint64, error) {
+	if f.WireType == ProtoVarint {
+		return []uint64{f.Value}, nil
+	}
+	r, err := f.packed()
+	if err != nil {
+		return nil, err
+	}
+	var values []uint64
+	for r.LenUnRead() > 0 {
+		v, err := r.ReadUvarint()
+		if err != nil {
+			return nil, fmt.Errorf("the packed field %v at position %v: %w", f.Number, f.Offset, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+//PackedFixed32 decodes a packed repeated field of fixed32,sfixed32 or float,
+//a fixed32 field that is not packed gives its single value.
+func (f *ProtoField) PackedFixed32() ([]uint32, error) {
+	if f.WireType == ProtoFixed32 {
+		return []uint32{uint32(f.Value)}, nil
+	}
+	bt, err := f.packedFixed(4)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint32, len(bt)/4)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(bt[i*4:])
+	}
+	return values, nil
+}
+
+//PackedFixed64 decodes a packed repeated field of fixed64,sfixed64 or double,
+//a fixed64 field that is not packed gives its single value.
+func (f *ProtoField) PackedFixed64() ([]uint64, error) {
+	if f.WireType == ProtoFixed64 {
+		return []uint64{f.Value}, nil
+	}
+	bt, err := f.packedFixed(8)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]uint64, len(bt)/8)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(bt[i*8:])
+	}
+	return values, nil
+}
+
+func (f *ProtoField) packedFixed(size int) ([]byte, error) {
+	r, err := f.packed()
+	if err != nil {
+		return nil, err
+	}
+	if r.Size()%int64(size) != 0 {
+		return nil, fmt.Errorf("the packed field %v at position %v has %v bytes,not a multiple of %v: %w", f.Number, f.Offset, r.Size(), size, ErrInvalidProto)
+	}
+	return r.allBytes()
+}
+
+//read all fields of r up to the end.
+func readProtoFields(r *ReadSeeker) ([]*ProtoField, error) {
+	var fields []*ProtoField
+	for {
+		f, err := r.ReadProtoField()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+}
+
+//ProtoDump decodes the unread data as a protobuf message without its schema,like protoc --decode_raw,
+//see WriteProtoDump.
+func (r *ReadSeeker) ProtoDump() (string, error) {
+	var b strings.Builder
+	err := r.WriteProtoDump(&b)
+	return b.String(), err
+}
+
+//WriteProtoDump writes the fields of the unread data one per line as "number: value",
+//varints in decimal,fixed32 and fixed64 in hex,and groups as "number {" with their fields indented.
+//a length-delimited field is shown as a message if its content parses as one,otherwise as a quoted string.
+//the position is left unchanged.
+func (r *ReadSeeker) WriteProtoDump(w io.Writer) error {
+	pos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	s, err := r.Section(pos, r.Size()-1)
+	if err != nil {
+		return err
+	}
+	fields, err := readProtoFields(s)
+	if err != nil {
+		return err
+	}
+	return writeProtoFields(w, fields, 0)
+}
+
+func writeProtoFields(w io.Writer, fields []*ProtoField, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	for _, f := range fields {
+		var line string
+		var children []*ProtoField
+		switch f.WireType {
+		case ProtoVarint:
+			line = fmt.Sprintf("%v%v: %v\n", indent, f.Number, f.Value)
+		case ProtoFixed32:
+			line = fmt.Sprintf("%v%v: 0x%08x\n", indent, f.Number, f.Value)
+		case ProtoFixed64:
+			line = fmt.Sprintf("%v%v: 0x%016x\n", indent, f.Number, f.Value)
+		case ProtoStartGroup:
+			var err error
+			if children, err = readProtoFields(f.Data); err != nil {
+				return err
+			}
+			line = fmt.Sprintf("%v%v {\n", indent, f.Number)
+		case ProtoBytes:
+			bt, err := f.Bytes()
+			if err != nil {
+				return err
+			}
+			if depth < maxProtoDepth {
+				children, _ = readProtoFields(NewReadSeekerFromBytes(bt))
+			}
+			if len(children) > 0 {
+				line = fmt.Sprintf("%v%v {\n", indent, f.Number)
+			} else {
+				line = fmt.Sprintf("%v%v: %v\n", indent, f.Number, strconv.Quote(string(bt)))
+			}
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+		if len(children) == 0 && f.WireType != ProtoStartGroup {
+			continue
+		}
+		if err := writeProtoFields(w, children, depth+1); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, indent+"}\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//WriteProtoKey writes the key of a field,the value is written next.
+//a group is written as the key with ProtoStartGroup,its fields and the key with ProtoEndGroup.
+func (w *Writer) WriteProtoKey(number int, wireType ProtoWireType) {
+	if number < 1 || number > maxProtoFieldNumber {
+		panic(fmt.Sprint("the protobuf field number is out of range:", number))
+	}
+	if wireType < ProtoVarint || wireType > ProtoFixed32 {
+		panic(fmt.Sprint("unknown protobuf wire type:", int(wireType)))
+	}
+	w.WriteUvarint(uint64(number)<<3 | uint64(wireType))
+}
+
+//WriteProtoVarint writes a varint field of the uint32 or uint64 type.
+func (w *Writer) WriteProtoVarint(number int, v uint64) {
+	w.WriteProtoKey(number, ProtoVarint)
+	w.WriteUvarint(v)
+}
+
+//WriteProtoInt64 writes a varint field of the int32,int64 or enum type,negative values take 10 bytes.
+func (w *Writer) WriteProtoInt64(number int, v int64) {
+	w.WriteProtoVarint(number, uint64(v))
+}
+
+//WriteProtoSint64 writes a zigzag encoded varint field of the sint32 or sint64 type.
+func (w *Writer) WriteProtoSint64(number int, v int64) {
+	w.WriteProtoVarint(number, uint64(v<<1)^uint64(v>>63))
+}
+
+//WriteProtoBool writes a varint field of the bool type.
+func (w *Writer) WriteProtoBool(number int, b bool) {
+	var v uint64
+	if b {
+		v = 1
+	}
+	w.WriteProtoVarint(number, v)
+}
+
+//WriteProtoFixed32 writes a field of the fixed32 or sfixed32 type.
+func (w *Writer) WriteProtoFixed32(number int, v uint32) {
+	w.WriteProtoKey(number, ProtoFixed32)
+	w.WriteUint32(v)
+}
+
+//WriteProtoFixed64 writes a field of the fixed64 or sfixed64 type.
+func (w *Writer) WriteProtoFixed64(number int, v uint64) {
+	w.WriteProtoKey(number, ProtoFixed64)
+	w.WriteUint64(v)
+}
+
+//WriteProtoFloat32 writes a field of the float type.
+func (w *Writer) WriteProtoFloat32(number int, v float32) {
+	w.WriteProtoFixed32(number, math.Float32bits(v))
+}
+
+//WriteProtoFloat64 writes a field of the double type.
+func (w *Writer) WriteProtoFloat64(number int, v float64) {
+	w.WriteProtoFixed64(number, math.Float64bits(v))
+}
+
+//WriteProtoBytes writes a length-delimited field of the bytes type or an encoded message.
+func (w *Writer) WriteProtoBytes(number int, p []byte) {
+	w.WriteProtoKey(number, ProtoBytes)
+	w.WriteBytesUvarint(p)
+}
+
+//WriteProtoString writes a length-delimited field of the string type.
+func (w *Writer) WriteProtoString(number int, s string) {
+	w.WriteProtoBytes(number, []byte(s))
+}
+
+//WriteProtoPackedVarints writes a packed repeated field of varints.
+func (w *Writer) WriteProtoPackedVarints(number int, values []uint64) {
+	var p []byte
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, v := range values {
+		p = append(p, buf[:binary.PutUvarint(buf, v)]...)
+	}
+	w.WriteProtoBytes(number, p)
+}
+
+//WriteProtoPackedFixed32 writes a packed repeated field of fixed32,sfixed32 or float.
+func (w *Writer) WriteProtoPackedFixed32(number int, values []uint32) {
+	p := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(p[i*4:], v)
+	}
+	w.WriteProtoBytes(number, p)
+}
+
+//WriteProtoPackedFixed64 writes a packed repeated field of fixed64,sfixed64 or double.
+func (w *Writer) WriteProtoPackedFixed64(number int, values []uint64) {
+	p := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(p[i*8:], v)
+	}
+	w.WriteProtoBytes(number, p)
+}
+
+//BeginProtoMessage starts a nested message field,the writes until EndProtoMessage are its fields.
+//the length goes before the fields,so they are kept in memory and reach the Writer
+//with EndProtoMessage of the outermost message,Pos does not move until then.
+//Seek and WriteAt should not be used inside a message,and chunks and compressed regions panic there.
+func (w *Writer) BeginProtoMessage(number int) {
+	if w.region != nil {
+		panic("a protobuf message can't be written inside a compressed region")
+	}
+	if number < 1 || number > maxProtoFieldNumber {
+		panic(fmt.Sprint("the protobuf field number is out of range:", number))
+	}
+	w.nested = append(w.nested, &nestedRegion{proto: true, tag: number})
+}
+
+//EndProtoMessage writes the message of the last BeginProtoMessage,returns the length of its fields.
+func (w *Writer) EndProtoMessage() int {
+	region := w.endNested(true)
+	w.WriteProtoBytes(region.tag, region.content)
+	return len(region.content)
+}
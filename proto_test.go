@@ -0,0 +1,199 @@
+package iox
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestProtoWriter(t *testing.T) {
+	for _, c := range []struct {
+		write func(w *Writer)
+		want  string
+	}{
+		//the examples of the protobuf encoding guide
+		{func(w *Writer) { w.WriteProtoVarint(1, 150) }, "089601"},
+		{func(w *Writer) { w.WriteProtoString(2, "testing") }, "120774657374696e67"},
+		{func(w *Writer) { w.WriteProtoPackedVarints(4, []uint64{3, 270, 86942}) }, "2206038e029ea705"},
+		{func(w *Writer) {
+			w.BeginProtoMessage(3)
+			w.WriteProtoVarint(1, 150)
+			w.EndProtoMessage()
+		}, "1a03089601"},
+		{func(w *Writer) { w.WriteProtoInt64(1, -2) }, "08feffffffffffffffff01"},
+		{func(w *Writer) { w.WriteProtoSint64(1, -2) }, "0803"},
+		{func(w *Writer) { w.WriteProtoSint64(1, 2) }, "0804"},
+		{func(w *Writer) { w.WriteProtoBool(16, true) }, "800101"},
+		{func(w *Writer) { w.WriteProtoFloat32(1, 1) }, "0d0000803f"},
+		{func(w *Writer) { w.WriteProtoFixed64(1, 1) }, "090100000000000000"},
+		{func(w *Writer) { w.WriteProtoPackedFixed32(1, []uint32{1, 2}) }, "0a080100000002000000"},
+		{func(w *Writer) {
+			w.WriteProtoKey(5, ProtoStartGroup)
+			w.WriteProtoVarint(1, 1)
+			w.WriteProtoKey(5, ProtoEndGroup)
+		}, "2b08012c"},
+		{func(w *Writer) {
+			w.BeginProtoMessage(1)
+			w.BeginProtoMessage(2)
+			w.EndProtoMessage()
+			w.WriteProtoBytes(3, make([]byte, 200))
+			w.EndProtoMessage()
+		}, "0acd011200" + "1ac801" + hex.EncodeToString(make([]byte, 200))},
+	} {
+		w := NewBytesBuffer()
+		c.write(w)
+		if got := hex.EncodeToString(w.Bytes()); got != c.want {
+			t.Fatalf("unexpected value obtained; got %v want %v", got, c.want)
+		}
+	}
+}
+
+func TestProtoReader(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteProtoInt64(1, -7)
+	w.WriteProtoSint64(2, -7)
+	w.WriteProtoFixed32(3, 0xfffffff9)
+	w.WriteProtoFloat64(4, 2.5)
+	w.BeginProtoMessage(5)
+	w.WriteProtoString(1, "inner")
+	w.WriteProtoBool(2, true)
+	w.EndProtoMessage()
+	w.WriteProtoPackedVarints(6, []uint64{1, 300})
+	w.WriteProtoVarint(6, 7)
+	w.WriteProtoPackedFixed64(7, []uint64{1, 2})
+	w.WriteProtoKey(8, ProtoStartGroup)
+	w.WriteProtoKey(9, ProtoStartGroup)
+	w.WriteProtoKey(9, ProtoEndGroup)
+	w.WriteProtoFixed32(1, 1)
+	w.WriteProtoKey(8, ProtoEndGroup)
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	var got []string
+	for {
+		f, err := rd.ReadProtoField()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		var v interface{}
+		switch f.Number {
+		case 1, 3:
+			v = f.Int64()
+		case 2:
+			v = f.Sint64()
+		case 4:
+			v = f.Float64()
+		case 5:
+			inner, err := f.Data.ReadProtoField()
+			if err != nil {
+				t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+			}
+			text, _ := inner.Text()
+			flag, _ := f.Data.ReadProtoField()
+			v = fmt.Sprintf("%v %v", text, flag.Bool())
+		case 6:
+			v, err = f.PackedVarints()
+		case 7:
+			v, err = f.PackedFixed64()
+		case 8:
+			var fields []*ProtoField
+			fields, err = readProtoFields(f.Data)
+			v = len(fields)
+		}
+		if err != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		got = append(got, fmt.Sprintf("%v:%v@%v+%v=%v", f.Number, f.WireType, f.Offset, f.Size, v))
+	}
+	want := "[1:varint@0+11=-7 2:varint@11+2=-7 3:fixed32@13+5=-7 4:fixed64@18+9=2.5 5:bytes@27+11=inner true " +
+		"6:bytes@38+5=[1 300] 6:varint@43+2=[7] 7:bytes@45+18=[1 2] 8:start group@63+9=2]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("unexpected value obtained; got %v want %v", got, want)
+	}
+	//the same length-delimited model as ReadBytesUint32
+	rd = NewReadSeekerFromBytes(w.Bytes())
+	rd.MoveTo(28)
+	if s, err := rd.ReadStringUvarint(); err != nil || s != "\x0a\x05inner\x10\x01" {
+		t.Fatalf("unexpected value obtained; got %q %v want %v", s, err, "the inner message")
+	}
+	if _, err := NewReadSeekerFromBytes([]byte{5, 1}).ReadBytesUvarint(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestProtoDump(t *testing.T) {
+	w := NewBytesBuffer()
+	w.WriteProtoVarint(1, 150)
+	w.WriteProtoString(2, "testing")
+	w.BeginProtoMessage(3)
+	w.WriteProtoFixed32(1, 1)
+	w.BeginProtoMessage(2)
+	w.WriteProtoFixed64(4, 2)
+	w.EndProtoMessage()
+	w.EndProtoMessage()
+	w.WriteProtoKey(4, ProtoStartGroup)
+	w.WriteProtoKey(4, ProtoEndGroup)
+	w.WriteProtoBytes(5, nil)
+	rd := NewReadSeekerFromBytes(w.Bytes())
+	got, err := rd.ProtoDump()
+	want := "1: 150\n2: \"testing\"\n3 {\n  1: 0x00000001\n  2 {\n    4: 0x0000000000000002\n  }\n}\n4 {\n}\n5: \"\"\n"
+	if err != nil || got != want {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", got, err, want)
+	}
+	if pos, _ := rd.CurPos(); pos != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 0)
+	}
+}
+
+func TestProtoErrors(t *testing.T) {
+	for _, c := range []struct {
+		hex  string
+		want error
+	}{
+		{"0096", ErrInvalidProto},       //field number 0
+		{"0f01", ErrInvalidProto},       //wire type 7
+		{"0c", ErrInvalidProto},         //end group without start
+		{"0b14", ErrInvalidProto},       //group 1 ended by 2
+		{"0b0801", io.ErrUnexpectedEOF}, //no end group
+		{"08", io.ErrUnexpectedEOF},
+		{"0896", io.ErrUnexpectedEOF},
+		{"0d0102", io.ErrUnexpectedEOF},
+		{"0a0501", io.ErrUnexpectedEOF},
+	} {
+		data, _ := hex.DecodeString(c.hex)
+		rd := NewReadSeekerFromBytes(data)
+		if _, err := rd.ReadProtoField(); !errors.Is(err, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, err, c.want)
+		}
+		if pos, _ := rd.CurPos(); pos != 0 {
+			t.Fatalf("%v: unexpected value obtained; got %v want %v", c.hex, pos, 0)
+		}
+	}
+	data, _ := hex.DecodeString("0a0301020312050102030405")
+	rd := NewReadSeekerFromBytes(data)
+	f, _ := rd.ReadProtoField()
+	if _, err := f.PackedVarints(); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	if _, err := f.PackedFixed32(); !errors.Is(err, ErrInvalidProto) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrInvalidProto)
+	}
+	f, _ = rd.ReadProtoField()
+	if _, err := f.PackedVarints(); err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	//a truncated varint in a packed field
+	f = &ProtoField{Number: 1, WireType: ProtoBytes, Data: NewReadSeekerFromBytes([]byte{0x80})}
+	if _, err := f.PackedVarints(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	if _, err := (&ProtoField{Number: 1, WireType: ProtoVarint}).Bytes(); !errors.Is(err, ErrInvalidProto) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrInvalidProto)
+	}
+	if _, err := (&ProtoField{Number: 1, WireType: ProtoFixed32}).PackedFixed64(); !errors.Is(err, ErrInvalidProto) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrInvalidProto)
+	}
+}
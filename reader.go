@@ -16,6 +16,7 @@ import (
 // ReadSeeker helps you read and seek data from io.ReadSeeker,the default ByteOrder is LittleEndian.
 type ReadSeeker struct {
 	readSeeker io.ReadSeeker
+	trace      *Trace
 }
 
 //returns a *ReadSeeker from io.ReadSeeker.
@@ -53,9 +54,9 @@ func (r *ReadSeeker) Move(n int64) error {
 	if err != nil {
 		panic(err)
 	}
-	length, err := r.readSeeker.Seek(0, io.SeekEnd) //err always nil
+	length, err := r.seekEnd()
 	if err != nil {
-		panic(err)
+		return err
 	}
 	finalPos := curPos + n
 	if !(finalPos >= 0 && finalPos < length) {
@@ -78,9 +79,9 @@ func (r *ReadSeeker) MoveTo(pos int64) error {
 	if err != nil {
 		panic(err)
 	}
-	length, err := r.readSeeker.Seek(0, io.SeekEnd) //err always nil
+	length, err := r.seekEnd()
 	if err != nil {
-		panic(err)
+		return err
 	}
 	if !(pos >= 0 && pos <= length) {
 		_, err = r.readSeeker.Seek(curPos, io.SeekStart)
@@ -102,19 +103,82 @@ func (r *ReadSeeker) CurPos() (int64, error) {
 }
 
 //get the size of the data.
+//a compressed ReadSeeker decodes the whole stream the first time,if the stream is corrupt or truncated
+//the size is that of the data decoded before the error,and the reads reaching it return the error.
 func (r *ReadSeeker) Size() int64 {
 	initialPos, err := r.CurPos()
 	if err != nil {
 		panic(err)
 	}
 	defer r.MoveTo(initialPos)
-	n, err := r.readSeeker.Seek(0, io.SeekEnd)
+	n, err := r.seekEnd()
 	if err != nil {
 		panic(err)
 	}
 	return n
 }
 
+//lazySizer is a source whose size is only known once all of its data has been decoded,
+//like the inflater of NewReadSeekerFromCompressed.
+type lazySizer interface {
+	//the size of the data that can be read,which is the size decoded before the error of a corrupt source,
+	//the second result is false if it's not known yet.
+	readableSize() (int64, bool)
+	//reports whether the end has been decoded without error.
+	endDecoded() bool
+}
+
+//seek to the end and return its position,for a compressed ReadSeeker whose stream is corrupt or truncated
+//it's the end of the data decoded before the error,and the position is left unchanged.
+func (r *ReadSeeker) seekEnd() (int64, error) {
+	n, err := r.readSeeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		if s, ok := r.readSeeker.(lazySizer); ok {
+			if size, known := s.readableSize(); known {
+				return size, nil
+			}
+		}
+	}
+	return n, err
+}
+
+//reports whether the size is only known after decoding the rest of the data,like that of
+//a compressed ReadSeeker whose end has not been decoded without error,reads don't check the bytes left
+//up front then,and they return the decode error instead of running out of data.
+func (r *ReadSeeker) sizeUnknown() bool {
+	s, ok := r.readSeeker.(lazySizer)
+	return ok && !s.endDecoded()
+}
+
+//read n bytes without knowing the size,or up to the end if n is negative.the buffer grows
+//with the data read instead of being allocated for n up front,the position is left unchanged on an error.
+func (r *ReadSeeker) readUnsized(n int) ([]byte, error) {
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	src := io.Reader(r.readSeeker)
+	if n >= 0 {
+		src = io.LimitReader(src, int64(n))
+	}
+	_, err = buf.ReadFrom(src)
+	if err == nil && buf.Len() < n {
+		if buf.Len() == 0 {
+			err = io.EOF
+		} else {
+			err = fmt.Errorf("wish read %v bytes at position %v,but only %v bytes left: %w", n, currentPos, buf.Len(), io.ErrUnexpectedEOF)
+		}
+	}
+	if err != nil {
+		if _, seekErr := r.readSeeker.Seek(currentPos, io.SeekStart); seekErr != nil {
+			return nil, seekErr
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 //get the length of unread data.
 func (r *ReadSeeker) LenUnRead() int64 {
 	curPos, err := r.CurPos()
@@ -124,8 +188,38 @@ func (r *ReadSeeker) LenUnRead() int64 {
 	return r.Size() - curPos
 }
 
+//read exactly len(bt) bytes into bt.
+//returns io.EOF if nothing can be read, and an error wrapping io.ErrUnexpectedEOF with the offset
+//if the data ends before bt is full, in both cases the position is left unchanged.
+func (r *ReadSeeker) readFull(bt []byte) error {
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	n, err := io.ReadFull(r.readSeeker, bt)
+	if err == nil {
+		return nil
+	}
+	if _, seekErr := r.readSeeker.Seek(currentPos, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+	if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("wish read %v bytes at position %v,but only %v bytes left: %w", len(bt), currentPos, n, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
 //read n bytes.
 func (r *ReadSeeker) ReadBytes(n int) ([]byte, error) {
+	defer r.traceEnter("ReadBytes")()
+	if r.sizeUnknown() {
+		bt, err := r.readUnsized(n)
+		if err != nil {
+			return nil, err
+		}
+		r.traceValue(bt)
+		return bt, nil
+	}
 	currentPos, err := r.CurPos()
 	if err != nil {
 		panic(err) //always nil
@@ -135,17 +229,15 @@ func (r *ReadSeeker) ReadBytes(n int) ([]byte, error) {
 		if surplusLen <= 0 {
 			return nil, io.EOF
 		} else {
-			return nil, fmt.Errorf("%v is too long for this readSeeker,it's only %v bytes left,and the current position is:%v.", n, surplusLen, currentPos)
+			return nil, fmt.Errorf("%v is too long for this readSeeker,it's only %v bytes left,and the current position is:%v: %w", n, surplusLen, currentPos, io.ErrUnexpectedEOF)
 		}
 	}
 	bt := make([]byte, n)
-	realRead, err := r.readSeeker.Read(bt)
+	err = r.readFull(bt)
 	if err != nil {
 		return nil, err
 	}
-	if realRead != n {
-		return nil, fmt.Errorf("wish read not match real read")
-	}
+	r.traceValue(bt)
 	return bt, nil
 }
 
@@ -155,378 +247,616 @@ func (r *ReadSeeker) readBytesToDst(n int, dst []byte) ([]byte, error) {
 	if err != nil {
 		panic(err) //always nil
 	}
-	//check the surplus length of the data
-	if surplusLen := r.Size() - currentPos; surplusLen < int64(n) {
-		if surplusLen <= 0 {
-			return nil, io.EOF
-		} else {
-			return nil, fmt.Errorf("%v is too long for this readSeeker,it's only %v bytes left,and the current position is:%v.", n, surplusLen, currentPos)
+	//check the surplus length of the data,readFull finds it out without decoding the rest of compressed data
+	if !r.sizeUnknown() {
+		if surplusLen := r.Size() - currentPos; surplusLen < int64(n) {
+			if surplusLen <= 0 {
+				return nil, io.EOF
+			} else {
+				return nil, fmt.Errorf("%v is too long for this readSeeker,it's only %v bytes left,and the current position is:%v: %w", n, surplusLen, currentPos, io.ErrUnexpectedEOF)
+			}
 		}
 	}
-	realRead, err := r.readSeeker.Read(dst)
+	err = r.readFull(dst)
 	if err != nil {
 		return nil, err
 	}
-	if realRead != n {
-		return nil, fmt.Errorf("wish read not match real read")
-	}
 	return dst, nil
 }
 
+//read n bytes announced by a length prefix,running out of data here is always unexpected.
+func (r *ReadSeeker) readBytesAfterPrefix(n int) ([]byte, error) {
+	bt, err := r.ReadBytes(n)
+	if err == io.EOF {
+		currentPos, _ := r.CurPos()
+		return nil, fmt.Errorf("wish read %v bytes at position %v,but no data left: %w", n, currentPos, io.ErrUnexpectedEOF)
+	}
+	return bt, err
+}
+
 //get all  unread data.
 func (r *ReadSeeker) ReadBytesUnRead() ([]byte, error) {
-	return r.ReadBytes(int(r.LenUnRead()))
+	defer r.traceEnter("ReadBytesUnRead")()
+	if r.sizeUnknown() {
+		bt, err := r.readUnsized(-1)
+		if err != nil {
+			return nil, err
+		}
+		r.traceValue(bt)
+		return bt, nil
+	}
+	bt, err := r.ReadBytes(int(r.LenUnRead()))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint8 as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint8() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint8")()
 	n, err := r.ReadUint8()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint16 as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint16() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint16")()
 	n, err := r.ReadUint16()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint16(BigEndian) as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint16BigEndian() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint16BigEndian")()
 	n, err := r.ReadUint16BigEndian()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint32 as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint32() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint32")()
 	n, err := r.ReadUint32()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint32(BigEndian) as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint32BigEndian() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint32BigEndian")()
 	n, err := r.ReadUint32BigEndian()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint64 as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint64() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint64")()
 	n, err := r.ReadUint64()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint64(BigEndian) as the data length and then read the data.
 func (r *ReadSeeker) ReadBytesUint64BigEndian() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUint64BigEndian")()
 	n, err := r.ReadUint64BigEndian()
 	if err != nil {
 		return nil, err
 	}
-	return r.ReadBytes(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
 }
 
 //read uint8 as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint8() (string, error) {
+	defer r.traceEnter("ReadStringUint8")()
 	n, err := r.ReadUint8()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read uint16 as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint16() (string, error) {
+	defer r.traceEnter("ReadStringUint16")()
 	n, err := r.ReadUint16()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read uint16(BigEndian) as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint16BigEndian() (string, error) {
+	defer r.traceEnter("ReadStringUint16BigEndian")()
 	n, err := r.ReadUint16BigEndian()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read uint32 as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint32() (string, error) {
+	defer r.traceEnter("ReadStringUint32")()
 	n, err := r.ReadUint32()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read uint32(BigEndian) as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint32BigEndian() (string, error) {
+	defer r.traceEnter("ReadStringUint32BigEndian")()
 	n, err := r.ReadUint32BigEndian()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read uint64 as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint64() (string, error) {
+	defer r.traceEnter("ReadStringUint64")()
 	n, err := r.ReadUint64()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read uint64(BigEndian) as the data length and then read the data.
 func (r *ReadSeeker) ReadStringUint64BigEndian() (string, error) {
+	defer r.traceEnter("ReadStringUint64BigEndian")()
 	n, err := r.ReadUint64BigEndian()
 	if err != nil {
 		return "", err
 	}
-	return r.ReadString(int(n))
+	bt, err := r.readBytesAfterPrefix(int(n))
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
+}
+
+//read an unsigned varint as the data length and then read the data,like a length-delimited protobuf field.
+func (r *ReadSeeker) ReadBytesUvarint() ([]byte, error) {
+	defer r.traceEnter("ReadBytesUvarint")()
+	n, err := r.readUvarintLength()
+	if err != nil {
+		return nil, err
+	}
+	bt, err := r.readBytesAfterPrefix(n)
+	if err != nil {
+		return nil, err
+	}
+	r.traceValue(bt)
+	return bt, nil
+}
+
+//read an unsigned varint as the data length and then read the data.
+func (r *ReadSeeker) ReadStringUvarint() (string, error) {
+	defer r.traceEnter("ReadStringUvarint")()
+	n, err := r.readUvarintLength()
+	if err != nil {
+		return "", err
+	}
+	bt, err := r.readBytesAfterPrefix(n)
+	if err != nil {
+		return "", err
+	}
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
+}
+
+//read an unsigned varint that is the length of the data after it,a length beyond the data is an error.
+func (r *ReadSeeker) readUvarintLength() (int, error) {
+	n, err := r.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if left := r.LenUnRead(); n > uint64(left) {
+		currentPos, _ := r.CurPos()
+		return 0, fmt.Errorf("wish read %v bytes at position %v,but only %v bytes left: %w", n, currentPos, left, io.ErrUnexpectedEOF)
+	}
+	return int(n), nil
 }
 
 //read n bytes of the data and then returns the hexadecimal encoding of the bytes.
 func (r *ReadSeeker) ReadHexToString(n int) (string, error) {
+	defer r.traceEnter("ReadHexToString")()
 	bt, err := r.ReadBytes(n)
 	if err != nil {
 		return "", err
 	}
-	return strings.ToUpper(hex.EncodeToString(bt)), nil
+	s := strings.ToUpper(hex.EncodeToString(bt))
+	r.traceValue(s)
+	return s, nil
 }
 
 //read n bytes of the data and convert to string.
 func (r *ReadSeeker) ReadString(n int) (string, error) {
+	defer r.traceEnter("ReadString")()
 	bt, err := r.ReadBytes(n)
 	if err != nil {
 		return "", err
 	}
-	return string(bt), nil
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read all unread data and convert to string.
 func (r *ReadSeeker) ReadStringUnRead() (string, error) {
+	defer r.traceEnter("ReadStringUnRead")()
 	bt, err := r.ReadBytesUnRead()
 	if err != nil {
 		return "", err
 	}
-	return string(bt), nil
+	s := string(bt)
+	r.traceValue(s)
+	return s, nil
 }
 
 //read n bytes of data, then convert to string, and then remove spaces in the string.
 func (r *ReadSeeker) ReadStringTrimSpace(n int) (string, error) {
+	defer r.traceEnter("ReadStringTrimSpace")()
 	bt, err := r.ReadBytes(n)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(bt)), nil
-
+	s := strings.TrimSpace(string(bt))
+	r.traceValue(s)
+	return s, nil
 }
 
 //read 1 byte and then convert to int8.
 func (r *ReadSeeker) ReadInt8() (int8, error) {
+	defer r.traceEnter("ReadInt8")()
 	bt := make([]byte, 1)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
-	return int8(bt[0]), nil
+	n := int8(bt[0])
+	r.traceValue(n)
+	return n, nil
 }
 
 //read 1 byte and then convert to uint8.
 func (r *ReadSeeker) ReadUint8() (uint8, error) {
+	defer r.traceEnter("ReadUint8")()
 	bt := make([]byte, 1)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
-	return bt[0], nil
+	n := bt[0]
+	r.traceValue(n)
+	return n, nil
 }
 
 //read 2 bytes and then convert to int16.
 func (r *ReadSeeker) ReadInt16() (int16, error) {
+	defer r.traceEnter("ReadInt16")()
 	bt := make([]byte, 2)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := int16(binary.LittleEndian.Uint16(bt))
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 2 bytes and then convert to int16(BigEndian).
 func (r *ReadSeeker) ReadInt16BigEndian() (int16, error) {
+	defer r.traceEnter("ReadInt16BigEndian")()
 	bt := make([]byte, 2)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := int16(binary.BigEndian.Uint16(bt))
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 2 bytes and then convert to uint16.
 func (r *ReadSeeker) ReadUint16() (uint16, error) {
+	defer r.traceEnter("ReadUint16")()
 	bt := make([]byte, 2)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := binary.LittleEndian.Uint16(bt)
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 2 bytes and then convert to uint16(BigEndian).
 func (r *ReadSeeker) ReadUint16BigEndian() (uint16, error) {
+	defer r.traceEnter("ReadUint16BigEndian")()
 	bt := make([]byte, 2)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := binary.BigEndian.Uint16(bt)
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 4 bytes and then convert to int32.
 func (r *ReadSeeker) ReadInt32() (int32, error) {
+	defer r.traceEnter("ReadInt32")()
 	bt := make([]byte, 4)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := int32(binary.LittleEndian.Uint32(bt))
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 4 bytes and then convert to int32(BigEndian).
 func (r *ReadSeeker) ReadInt32BigEndian() (int32, error) {
+	defer r.traceEnter("ReadInt32BigEndian")()
 	bt := make([]byte, 4)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := int32(binary.BigEndian.Uint32(bt))
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 4 bytes and then convert to uint32.
 func (r *ReadSeeker) ReadUint32() (uint32, error) {
+	defer r.traceEnter("ReadUint32")()
 	bt := make([]byte, 4)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := binary.LittleEndian.Uint32(bt)
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 4 bytes and then convert to uint32(BigEndian).
 func (r *ReadSeeker) ReadUint32BigEndian() (uint32, error) {
+	defer r.traceEnter("ReadUint32BigEndian")()
 	bt := make([]byte, 4)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := binary.BigEndian.Uint32(bt)
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 8 bytes and then convert to int64.
 func (r *ReadSeeker) ReadInt64() (int64, error) {
+	defer r.traceEnter("ReadInt64")()
 	bt := make([]byte, 8)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := int64(binary.LittleEndian.Uint64(bt))
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 8 bytes and then convert to int64(BigEndian).
 func (r *ReadSeeker) ReadInt64BigEndian() (int64, error) {
+	defer r.traceEnter("ReadInt64BigEndian")()
 	bt := make([]byte, 8)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := int64(binary.BigEndian.Uint64(bt))
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 8 bytes and then convert to uint64.
 func (r *ReadSeeker) ReadUint64() (uint64, error) {
+	defer r.traceEnter("ReadUint64")()
 	bt := make([]byte, 8)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := binary.LittleEndian.Uint64(bt)
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 8 bytes and then convert to uint64(BigEndian).
 func (r *ReadSeeker) ReadUint64BigEndian() (uint64, error) {
+	defer r.traceEnter("ReadUint64BigEndian")()
 	bt := make([]byte, 8)
-	_, err := r.readSeeker.Read(bt)
+	err := r.readFull(bt)
 	if err != nil {
 		return 0, err
 	}
 	n := binary.BigEndian.Uint64(bt)
+	r.traceValue(n)
 	return n, nil
 }
 
 //read 4 bytes and convert it to float32.
 func (r *ReadSeeker) ReadFloat32() (float32, error) {
+	defer r.traceEnter("ReadFloat32")()
 	Uint32, err := r.ReadUint32()
 	if err != nil {
 		return 0, err
 	}
-	return math.Float32frombits(Uint32), nil
+	f := math.Float32frombits(Uint32)
+	r.traceValue(f)
+	return f, nil
 }
 
 //read 4 bytes and convert it to float32(BigEndian).
 func (r *ReadSeeker) ReadFloat32BigEndian() (float32, error) {
+	defer r.traceEnter("ReadFloat32BigEndian")()
 	Uint32, err := r.ReadUint32BigEndian()
 	if err != nil {
 		return 0, err
 	}
-	return math.Float32frombits(Uint32), nil
+	f := math.Float32frombits(Uint32)
+	r.traceValue(f)
+	return f, nil
 }
 
 //read 8 bytes and convert it to float64.
 func (r *ReadSeeker) ReadFloat64() (float64, error) {
+	defer r.traceEnter("ReadFloat64")()
 	Uint64, err := r.ReadUint64()
 	if err != nil {
 		return 0, err
 	}
-	return math.Float64frombits(Uint64), nil
+	f := math.Float64frombits(Uint64)
+	r.traceValue(f)
+	return f, nil
 }
 
 //read 8 bytes and convert it to float64(BigEndian).
 func (r *ReadSeeker) ReadFloat64BigEndian() (float64, error) {
+	defer r.traceEnter("ReadFloat64BigEndian")()
 	Uint64, err := r.ReadUint64BigEndian()
 	if err != nil {
 		return 0, err
 	}
-	return math.Float64frombits(Uint64), nil
+	f := math.Float64frombits(Uint64)
+	r.traceValue(f)
+	return f, nil
+}
+
+//read an unsigned varint as encoded by encoding/binary,the position is left unchanged on error.
+func (r *ReadSeeker) ReadUvarint() (uint64, error) {
+	defer r.traceEnter("ReadUvarint")()
+	currentPos, err := r.CurPos()
+	if err != nil {
+		return 0, err
+	}
+	bt := make([]byte, 1)
+	v, err := binary.ReadUvarint(byteReaderFunc(func() (byte, error) {
+		if err := r.readFull(bt); err != nil {
+			return 0, err
+		}
+		return bt[0], nil
+	}))
+	if err != nil {
+		if _, seekErr := r.readSeeker.Seek(currentPos, io.SeekStart); seekErr != nil {
+			return 0, seekErr
+		}
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("the varint at position %v is truncated: %w", currentPos, io.ErrUnexpectedEOF)
+		}
+		return 0, err
+	}
+	r.traceValue(v)
+	return v, nil
 }
 
 //Contains reports whether sep is within the data.
@@ -654,6 +984,25 @@ func (r *ReadSeeker) IndexN(beginPos int64, sep []byte, n int) int64 {
 	return findPos
 }
 
+//IndexAll returns the indexes of all non-overlapping instances of sep in data.
+func (r *ReadSeeker) IndexAll(sep []byte) []int64 {
+	return r.IndexAllGen(0, r.Size()-1, sep)
+}
+
+//IndexAllGen returns the indexes of all non-overlapping instances of sep in a range of data.
+func (r *ReadSeeker) IndexAllGen(beginPos, endPos int64, sep []byte) []int64 {
+	var indexes []int64
+	for curPos := beginPos; curPos <= endPos; {
+		findPos := r.IndexGen(curPos, endPos, sep)
+		if findPos == -1 {
+			break
+		}
+		indexes = append(indexes, findPos)
+		curPos = findPos + int64(len(sep))
+	}
+	return indexes
+}
+
 //LastIndex returns the index of the last instance of sep in data.
 func (r *ReadSeeker) LastIndex(sep []byte) int64 {
 	endPos := r.Size() - 1
@@ -662,6 +1011,7 @@ func (r *ReadSeeker) LastIndex(sep []byte) int64 {
 
 //LastIndex returns the index of the last instance of sep in a range of data.
 func (r *ReadSeeker) LastIndexGen(beginPos, endPos int64, sep []byte) int64 {
+	defer r.traceEnter("LastIndexGen")() //the reads of a search are not traced
 	initialPos, _ := r.CurPos()
 	defer r.MoveTo(initialPos)
 	if realEndpos := r.Size() - 1; endPos < beginPos ||
@@ -709,6 +1059,7 @@ func (r *ReadSeeker) LastIndexGen(beginPos, endPos int64, sep []byte) int64 {
 
 //read n bytes,read the data backwards.
 func (r *ReadSeeker) ReadBytesReverse(n int) ([]byte, error) {
+	defer r.traceEnter("ReadBytesReverse")()
 	err := r.Move(int64(-n))
 	if err != nil {
 		return nil, err
@@ -724,17 +1075,15 @@ func (r *ReadSeeker) ReadBytesReverse(n int) ([]byte, error) {
 		if surplusLen == 0 {
 			return nil, io.EOF
 		} else {
-			return nil, fmt.Errorf(fmt.Sprint(n, ` is too long for this readSeeker,it's only `, surplusLen, ` byte left,and the current position is:`, currentPos, `.`))
+			return nil, fmt.Errorf("%v is too long for this readSeeker,it's only %v byte left,and the current position is:%v: %w", n, surplusLen, currentPos, io.ErrUnexpectedEOF)
 		}
 	}
 	defer r.MoveTo(currentPos)
 	bt := make([]byte, n)
-	realRead, err := r.readSeeker.Read(bt)
+	err = r.readFull(bt)
 	if err != nil {
 		return nil, err
 	}
-	if realRead != n {
-		return nil, fmt.Errorf("wish read not match real read")
-	}
+	r.traceValue(bt)
 	return bt, nil
 }
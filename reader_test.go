@@ -1,6 +1,8 @@
 package iox
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -276,4 +278,128 @@ func TestReaderSeeker(t *testing.T) {
 	if num != 4195 {
 		t.Fatalf("unexpected value obtained; got %v want %v", num, 4195)
 	}
+	indexes := rd.IndexAllGen(5000, 29999, sep)
+	if len(indexes) != 2 || indexes[0] != 10000 || indexes[1] != 20000 {
+		t.Fatalf("unexpected value obtained; got %v want %v", indexes, []int64{10000, 20000})
+	}
+	if indexes = rd.IndexAll(sep); len(indexes) != 4195 || indexes[4194] != 41940000 {
+		t.Fatalf("unexpected value obtained; got %v want %v", len(indexes), 4195)
+	}
+}
+
+//oneByteReadSeeker reads 1 byte per Read call,like iotest.OneByteReader.
+type oneByteReadSeeker struct {
+	*bytes.Reader
+}
+
+func (r oneByteReadSeeker) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return r.Reader.Read(p[:1])
+}
+
+//halfReadSeeker reads half of the requested bytes per Read call,like iotest.HalfReader.
+type halfReadSeeker struct {
+	*bytes.Reader
+}
+
+func (r halfReadSeeker) Read(p []byte) (int, error) {
+	return r.Reader.Read(p[0 : (len(p)+1)/2])
+}
+
+//dataErrReadSeeker returns io.EOF together with the last data,like iotest.DataErrReader.
+type dataErrReadSeeker struct {
+	*bytes.Reader
+}
+
+func (r dataErrReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == nil && r.Reader.Len() == 0 {
+		err = io.EOF
+	}
+	return n, err
+}
+
+var shortReadSources = map[string]func(b []byte) *ReadSeeker{
+	"bytes":   NewReadSeekerFromBytes,
+	"oneByte": func(b []byte) *ReadSeeker { return NewReadSeeker(oneByteReadSeeker{bytes.NewReader(b)}) },
+	"half":    func(b []byte) *ReadSeeker { return NewReadSeeker(halfReadSeeker{bytes.NewReader(b)}) },
+	"dataErr": func(b []byte) *ReadSeeker { return NewReadSeeker(dataErrReadSeeker{bytes.NewReader(b)}) },
+}
+
+var shortReadCases = []struct {
+	name  string
+	write func(w *Writer)
+	read  func(r *ReadSeeker) (interface{}, error)
+	want  interface{}
+	fixed bool
+}{
+	{"Int8", func(w *Writer) { w.WriteInt8(-8) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt8() }, int8(-8), true},
+	{"Uint8", func(w *Writer) { w.WriteUint8(8) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint8() }, uint8(8), true},
+	{"Int16", func(w *Writer) { w.WriteInt16(-1616) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt16() }, int16(-1616), true},
+	{"Int16BigEndian", func(w *Writer) { w.WriteInt16BigEndian(-1616) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt16BigEndian() }, int16(-1616), true},
+	{"Uint16", func(w *Writer) { w.WriteUint16(1616) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint16() }, uint16(1616), true},
+	{"Uint16BigEndian", func(w *Writer) { w.WriteUint16BigEndian(1616) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint16BigEndian() }, uint16(1616), true},
+	{"Int32", func(w *Writer) { w.WriteInt32(-323232) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt32() }, int32(-323232), true},
+	{"Int32BigEndian", func(w *Writer) { w.WriteInt32BigEndian(-323232) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt32BigEndian() }, int32(-323232), true},
+	{"Uint32", func(w *Writer) { w.WriteUint32(323232) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint32() }, uint32(323232), true},
+	{"Uint32BigEndian", func(w *Writer) { w.WriteUint32BigEndian(323232) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint32BigEndian() }, uint32(323232), true},
+	{"Int64", func(w *Writer) { w.WriteInt64(-6464646464) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt64() }, int64(-6464646464), true},
+	{"Int64BigEndian", func(w *Writer) { w.WriteInt64BigEndian(-6464646464) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadInt64BigEndian() }, int64(-6464646464), true},
+	{"Uint64", func(w *Writer) { w.WriteUint64(6464646464) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint64() }, uint64(6464646464), true},
+	{"Uint64BigEndian", func(w *Writer) { w.WriteUint64BigEndian(6464646464) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadUint64BigEndian() }, uint64(6464646464), true},
+	{"Float32", func(w *Writer) { w.WriteFloat32(32.5) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadFloat32() }, float32(32.5), true},
+	{"Float32BigEndian", func(w *Writer) { w.WriteFloat32BigEndian(32.5) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadFloat32BigEndian() }, float32(32.5), true},
+	{"Float64", func(w *Writer) { w.WriteFloat64(64.25) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadFloat64() }, float64(64.25), true},
+	{"Float64BigEndian", func(w *Writer) { w.WriteFloat64BigEndian(64.25) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadFloat64BigEndian() }, float64(64.25), true},
+	{"Bytes", func(w *Writer) { w.WriteString("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadString(3) }, "iox", true},
+	{"HexToString", func(w *Writer) { w.WriteBytes([]byte{0xab, 0xcd}) }, func(r *ReadSeeker) (interface{}, error) { return r.ReadHexToString(2) }, "ABCD", true},
+	{"StringUint8", func(w *Writer) { w.WriteStringUint8("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint8() }, "iox", false},
+	{"StringUint16", func(w *Writer) { w.WriteStringUint16("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint16() }, "iox", false},
+	{"StringUint16BigEndian", func(w *Writer) { w.WriteStringUint16BigEndian("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint16BigEndian() }, "iox", false},
+	{"StringUint32", func(w *Writer) { w.WriteStringUint32("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint32() }, "iox", false},
+	{"StringUint32BigEndian", func(w *Writer) { w.WriteStringUint32BigEndian("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint32BigEndian() }, "iox", false},
+	{"StringUint64", func(w *Writer) { w.WriteStringUint64("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint64() }, "iox", false},
+	{"StringUint64BigEndian", func(w *Writer) { w.WriteStringUint64BigEndian("iox") }, func(r *ReadSeeker) (interface{}, error) { return r.ReadStringUint64BigEndian() }, "iox", false},
+}
+
+func TestReadSeekerShortRead(t *testing.T) {
+	wr := NewBytesBuffer()
+	for _, c := range shortReadCases {
+		wr.Reset()
+		c.write(wr)
+		data := wr.Bytes()
+		for sourceName, source := range shortReadSources {
+			//full data,every source must decode the same value
+			rd := source(data)
+			got, err := c.read(rd)
+			if err != nil {
+				t.Fatalf("%v/%v: unexpected value obtained; got %v want %v", c.name, sourceName, err, nil)
+			}
+			if got != c.want {
+				t.Fatalf("%v/%v: unexpected value obtained; got %v want %v", c.name, sourceName, got, c.want)
+			}
+			//nothing left
+			_, err = c.read(rd)
+			if err != io.EOF {
+				t.Fatalf("%v/%v: unexpected value obtained; got %v want %v", c.name, sourceName, err, io.EOF)
+			}
+			//truncated data
+			for n := 1; n < len(data); n++ {
+				rd = source(data[:n])
+				_, err = c.read(rd)
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("%v/%v/%v: unexpected value obtained; got %v want %v", c.name, sourceName, n, err, io.ErrUnexpectedEOF)
+				}
+				if !c.fixed {
+					continue
+				}
+				curPos, _ := rd.CurPos()
+				if curPos != 0 {
+					t.Fatalf("%v/%v/%v: unexpected value obtained; got %v want %v", c.name, sourceName, n, curPos, 0)
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,727 @@
+package iox
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Schema describes a binary format at runtime,ReadSchema reads it into a map[string]interface{}
+//and WriteSchema writes such a map back.build it with NewSchema or from text with ParseSchema.
+//a Schema also has a JSON form through encoding/json.
+type Schema struct {
+	Fields  []SchemaField            `json:"fields"`
+	Structs map[string][]SchemaField `json:"structs,omitempty"` //named structs that fields can use as a type
+}
+
+//SchemaField is a field,a conditional or a switch of a Schema.
+//
+//the type of a field is one of
+//
+//	u8 i8 u16 i16 u32 i32 u64 i64 f32 f64  LittleEndian numbers,add "be" for BigEndian such as u32be
+//	bytes[n] str[n]                         n bytes,n is an expression or "*" for the rest of the data
+//	name                                    a struct defined with Define
+//
+//any type but bytes and str may be followed by [count] to read an array.
+//expressions are integer expressions with the operators of Go,they can use the earlier fields
+//of the struct and of the structs around it,"header.flags" and "items.0.kind" reach into values.
+//the fields inside a conditional or a switch belong to the struct around them.
+type SchemaField struct {
+	Name   string        `json:"name,omitempty"`
+	Type   string        `json:"type,omitempty"`
+	Cond   string        `json:"if,omitempty"` //the condition of a conditional,the fields are Then if it is not zero,Else otherwise
+	Then   []SchemaField `json:"then,omitempty"`
+	Else   []SchemaField `json:"else,omitempty"`
+	Switch string        `json:"switch,omitempty"` //the expression of a switch
+	Cases  []SchemaCase  `json:"cases,omitempty"`
+}
+
+//SchemaCase is a case of a switch,the one with no Values is the default case.
+type SchemaCase struct {
+	Values []int64       `json:"values,omitempty"`
+	Fields []SchemaField `json:"fields"`
+}
+
+//returns a *Schema with the top level fields.
+func NewSchema(fields ...SchemaField) *Schema {
+	return &Schema{Fields: fields, Structs: make(map[string][]SchemaField)}
+}
+
+//define the struct name so that fields can use it as a type.
+func (s *Schema) Define(name string, fields ...SchemaField) *Schema {
+	if s.Structs == nil {
+		s.Structs = make(map[string][]SchemaField)
+	}
+	s.Structs[name] = fields
+	return s
+}
+
+//returns a field of type typ,such as NewField("u16be","count") or NewField("record[count]","items").
+func NewField(typ, name string) SchemaField {
+	return SchemaField{Name: name, Type: typ}
+}
+
+//returns a conditional,fields are used if cond is not zero,add an else branch with OrElse.
+func NewIf(cond string, fields ...SchemaField) SchemaField {
+	return SchemaField{Cond: cond, Then: fields}
+}
+
+//OrElse returns the conditional with fields used when the condition is zero.
+func (f SchemaField) OrElse(fields ...SchemaField) SchemaField {
+	f.Else = fields
+	return f
+}
+
+//returns a switch on the value of expr.
+func NewSwitch(expr string, cases ...SchemaCase) SchemaField {
+	return SchemaField{Switch: expr, Cases: cases}
+}
+
+//returns the case of a switch used when the value is one of values.
+func NewCase(values []int64, fields ...SchemaField) SchemaCase {
+	return SchemaCase{Values: values, Fields: fields}
+}
+
+//returns the case of a switch used when no other case matches.
+func NewDefaultCase(fields ...SchemaField) SchemaCase {
+	return SchemaCase{Fields: fields}
+}
+
+//the size of the numeric types.
+var schemaNumbers = map[string]int{
+	"u8": 1, "i8": 1, "u16": 2, "i16": 2, "u32": 4, "i32": 4, "u64": 8, "i64": 8, "f32": 4, "f64": 8,
+}
+
+//schemaNode is a compiled SchemaField.
+type schemaNode struct {
+	name      string
+	base      string //a number type,"bytes","str" or the name of a struct
+	bigEndian bool
+	length    schemaExpr //the length of bytes and str,nil for the rest of the data
+	count     schemaExpr //not nil for an array
+	cond      schemaExpr
+	then, els []*schemaNode
+	sw        schemaExpr
+	cases     []schemaCaseNode
+}
+
+type schemaCaseNode struct {
+	values []int64
+	fields []*schemaNode
+}
+
+//compiledSchema holds the compiled fields and structs.
+type compiledSchema struct {
+	fields   []*schemaNode
+	structs  map[string][]*schemaNode
+	minSizes map[string]int64 //the fewest bytes of the structs measured so far
+}
+
+//Validate checks the types and expressions of every field.
+func (s *Schema) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+func (s *Schema) compile() (*compiledSchema, error) {
+	c := &compiledSchema{structs: make(map[string][]*schemaNode), minSizes: make(map[string]int64)}
+	names := make([]string, 0, len(s.Structs))
+	for name := range s.Structs {
+		if _, ok := schemaNumbers[strings.TrimSuffix(strings.TrimSuffix(name, "be"), "le")]; ok || name == "bytes" || name == "str" {
+			return nil, fmt.Errorf("the struct %v has the name of a builtin type", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		nodes, err := s.compileFields(s.Structs[name], name+".")
+		if err != nil {
+			return nil, err
+		}
+		c.structs[name] = nodes
+	}
+	var err error
+	c.fields, err = s.compileFields(s.Fields, "")
+	return c, err
+}
+
+func (s *Schema) compileFields(fields []SchemaField, path string) ([]*schemaNode, error) {
+	nodes := make([]*schemaNode, 0, len(fields))
+	for _, f := range fields {
+		n, err := s.compileField(f, path)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (s *Schema) compileField(f SchemaField, path string) (*schemaNode, error) {
+	n := new(schemaNode)
+	var err error
+	switch {
+	case f.Cond != "":
+		if n.cond, err = parseSchemaExpr(f.Cond); err != nil {
+			return nil, fmt.Errorf("%vif: %v", path, err)
+		}
+		if n.then, err = s.compileFields(f.Then, path); err != nil {
+			return nil, err
+		}
+		n.els, err = s.compileFields(f.Else, path)
+		return n, err
+	case f.Switch != "":
+		if n.sw, err = parseSchemaExpr(f.Switch); err != nil {
+			return nil, fmt.Errorf("%vswitch: %v", path, err)
+		}
+		for _, c := range f.Cases {
+			fields, err := s.compileFields(c.Fields, path)
+			if err != nil {
+				return nil, err
+			}
+			n.cases = append(n.cases, schemaCaseNode{values: c.Values, fields: fields})
+		}
+		return n, nil
+	}
+	n.name = f.Name
+	if n.name == "" {
+		return nil, fmt.Errorf("%v: a field of type %v has no name", strings.TrimSuffix(path, "."), f.Type)
+	}
+	path += f.Name
+	typ, arg := f.Type, ""
+	if i := strings.IndexByte(typ, '['); i >= 0 && strings.HasSuffix(typ, "]") {
+		typ, arg = strings.TrimSpace(typ[:i]), strings.TrimSpace(typ[i+1:len(typ)-1])
+		if arg == "" {
+			return nil, fmt.Errorf("%v: the type %v has an empty []", path, f.Type)
+		}
+	}
+	n.base = typ
+	if _, ok := schemaNumbers[typ]; !ok && (strings.HasSuffix(typ, "be") || strings.HasSuffix(typ, "le")) {
+		if _, ok = schemaNumbers[typ[:len(typ)-2]]; ok && typ != "u8be" && typ != "i8be" {
+			n.base, n.bigEndian = typ[:len(typ)-2], strings.HasSuffix(typ, "be")
+		}
+	}
+	_, isNumber := schemaNumbers[n.base]
+	_, isStruct := s.Structs[n.base]
+	switch {
+	case n.base == "bytes" || n.base == "str":
+		if arg == "" {
+			return nil, fmt.Errorf("%v: %v needs a length such as %v[4] or %v[*]", path, n.base, n.base, n.base)
+		}
+		if arg != "*" {
+			if n.length, err = parseSchemaExpr(arg); err != nil {
+				return nil, fmt.Errorf("%v: %v", path, err)
+			}
+		}
+	case isNumber || isStruct:
+		if arg != "" {
+			if n.count, err = parseSchemaExpr(arg); err != nil {
+				return nil, fmt.Errorf("%v: %v", path, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%v: the type %v is not known", path, f.Type)
+	}
+	return n, nil
+}
+
+//a struct may contain itself,so the nesting is limited like that of CBOR and MessagePack.
+const maxSchemaDepth = 512
+
+//schemaScope is the struct being read or written and the structs around it.
+type schemaScope struct {
+	vals   map[string]interface{}
+	parent *schemaScope
+	depth  int //of the struct,0 for the top level fields
+}
+
+//returns the scope of a struct inside sc,or an error if it is nested too deep.
+func (sc *schemaScope) child(vals map[string]interface{}, path string) (*schemaScope, error) {
+	if sc.depth >= maxSchemaDepth {
+		return nil, fmt.Errorf("%v: the structs are nested deeper than %v", path, maxSchemaDepth)
+	}
+	return &schemaScope{vals: vals, parent: sc, depth: sc.depth + 1}, nil
+}
+
+//returns the value of a field such as "count","header.flags" or "items.0.kind".
+func (sc *schemaScope) lookup(path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	for ; sc != nil; sc = sc.parent {
+		v, ok := sc.vals[parts[0]]
+		if !ok {
+			continue
+		}
+		for _, part := range parts[1:] {
+			switch x := v.(type) {
+			case map[string]interface{}:
+				if v, ok = x[part]; !ok {
+					return nil, false
+				}
+			case []interface{}:
+				i, err := strconv.Atoi(part)
+				if err != nil || i < 0 || i >= len(x) {
+					return nil, false
+				}
+				v = x[i]
+			default:
+				return nil, false
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+//the fields of a switch for the value v,nil if no case matches.
+func (n *schemaNode) caseFields(v int64) []*schemaNode {
+	var def []*schemaNode
+	for _, c := range n.cases {
+		if c.values == nil {
+			def = c.fields
+		}
+		for _, value := range c.values {
+			if value == v {
+				return c.fields
+			}
+		}
+	}
+	return def
+}
+
+//the fewest bytes an element of n takes,bytes and str may be empty.
+//a struct that contains itself counts as empty inside itself,so the size is never too big.
+func (c *compiledSchema) elemMinSize(n *schemaNode, measuring map[string]bool) int64 {
+	if size, ok := schemaNumbers[n.base]; ok {
+		return int64(size)
+	}
+	fields, ok := c.structs[n.base]
+	if !ok || measuring[n.base] {
+		return 0
+	}
+	if size, ok := c.minSizes[n.base]; ok {
+		return size
+	}
+	measuring[n.base] = true
+	size := c.fieldsMinSize(fields, measuring)
+	delete(measuring, n.base)
+	c.minSizes[n.base] = size
+	return size
+}
+
+//the fewest bytes of fields,conditionals and switches take their smallest branch and arrays may be empty.
+func (c *compiledSchema) fieldsMinSize(nodes []*schemaNode, measuring map[string]bool) int64 {
+	var size int64
+	for _, n := range nodes {
+		switch {
+		case n.cond != nil:
+			then, els := c.fieldsMinSize(n.then, measuring), c.fieldsMinSize(n.els, measuring)
+			if els < then {
+				then = els
+			}
+			size += then
+		case n.sw != nil:
+			//with no default case a value may match nothing
+			smallest, hasDefault := int64(math.MaxInt64), false
+			for _, cs := range n.cases {
+				hasDefault = hasDefault || cs.values == nil
+				if m := c.fieldsMinSize(cs.fields, measuring); m < smallest {
+					smallest = m
+				}
+			}
+			if hasDefault {
+				size += smallest
+			}
+		case n.count == nil:
+			size += c.elemMinSize(n, measuring)
+		}
+	}
+	return size
+}
+
+//returns a non negative count or length.
+func schemaSize(e schemaExpr, sc *schemaScope) (int, error) {
+	v, err := e.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > math.MaxInt32 {
+		return 0, fmt.Errorf("%v is not a valid size", v)
+	}
+	return int(v), nil
+}
+
+//ReadSchema reads the fields of s into a map,numbers keep their Go type such as uint16 or float32,
+//bytes are []byte,str is string,arrays are []interface{} and structs are map[string]interface{}.
+//when tracing is enabled every read is labelled with the path of its field.
+func (r *ReadSeeker) ReadSchema(s *Schema) (map[string]interface{}, error) {
+	c, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+	vals := make(map[string]interface{})
+	err = r.readSchemaFields(c, c.fields, &schemaScope{vals: vals}, "")
+	return vals, err
+}
+
+func (r *ReadSeeker) readSchemaFields(c *compiledSchema, nodes []*schemaNode, sc *schemaScope, path string) error {
+	for _, n := range nodes {
+		switch {
+		case n.cond != nil:
+			v, err := n.cond.eval(sc)
+			if err != nil {
+				return fmt.Errorf("%vif: %w", path, err)
+			}
+			fields := n.then
+			if v == 0 {
+				fields = n.els
+			}
+			if err = r.readSchemaFields(c, fields, sc, path); err != nil {
+				return err
+			}
+		case n.sw != nil:
+			v, err := n.sw.eval(sc)
+			if err != nil {
+				return fmt.Errorf("%vswitch: %w", path, err)
+			}
+			if err = r.readSchemaFields(c, n.caseFields(v), sc, path); err != nil {
+				return err
+			}
+		case n.count != nil:
+			count, err := schemaSize(n.count, sc)
+			if err != nil {
+				return fmt.Errorf("%v%v: %w", path, n.name, err)
+			}
+			//a count read from the data can't be trusted,nothing is allocated for it before the elements are read,
+			//and it can't be more than the elements that fit the data left
+			if size := c.elemMinSize(n, make(map[string]bool)); size > 0 {
+				if left := r.LenUnRead(); int64(count) > left/size {
+					return fmt.Errorf("%v%v: %v elements of %v bytes at least don't fit the %v bytes left: %w", path, n.name, count, size, left, io.ErrUnexpectedEOF)
+				}
+			}
+			var arr []interface{}
+			//the array is visible while it is read,so an element can use the elements before it
+			sc.vals[n.name] = arr
+			for i := 0; i < count; i++ {
+				v, err := r.readSchemaValue(c, n, sc, fmt.Sprintf("%v%v.%v", path, n.name, i))
+				if err != nil {
+					return err
+				}
+				arr = append(arr, v)
+				sc.vals[n.name] = arr
+			}
+		default:
+			v, err := r.readSchemaValue(c, n, sc, path+n.name)
+			if err != nil {
+				return err
+			}
+			sc.vals[n.name] = v
+		}
+	}
+	return nil
+}
+
+func (r *ReadSeeker) readSchemaValue(c *compiledSchema, n *schemaNode, sc *schemaScope, path string) (interface{}, error) {
+	if fields, ok := c.structs[n.base]; ok {
+		vals := make(map[string]interface{})
+		child, err := sc.child(vals, path)
+		if err != nil {
+			return nil, err
+		}
+		err = r.readSchemaFields(c, fields, child, path+".")
+		return vals, err
+	}
+	r.Label(path)
+	var v interface{}
+	var err error
+	switch n.base {
+	case "bytes", "str":
+		if n.length == nil {
+			if n.base == "bytes" {
+				v, err = r.ReadBytesUnRead()
+			} else {
+				v, err = r.ReadStringUnRead()
+			}
+			break
+		}
+		var length int
+		if length, err = schemaSize(n.length, sc); err != nil {
+			break
+		}
+		if n.base == "bytes" {
+			v, err = r.ReadBytes(length)
+		} else {
+			v, err = r.ReadString(length)
+		}
+	case "u8":
+		v, err = r.ReadUint8()
+	case "i8":
+		v, err = r.ReadInt8()
+	case "u16":
+		if n.bigEndian {
+			v, err = r.ReadUint16BigEndian()
+		} else {
+			v, err = r.ReadUint16()
+		}
+	case "i16":
+		if n.bigEndian {
+			v, err = r.ReadInt16BigEndian()
+		} else {
+			v, err = r.ReadInt16()
+		}
+	case "u32":
+		if n.bigEndian {
+			v, err = r.ReadUint32BigEndian()
+		} else {
+			v, err = r.ReadUint32()
+		}
+	case "i32":
+		if n.bigEndian {
+			v, err = r.ReadInt32BigEndian()
+		} else {
+			v, err = r.ReadInt32()
+		}
+	case "u64":
+		if n.bigEndian {
+			v, err = r.ReadUint64BigEndian()
+		} else {
+			v, err = r.ReadUint64()
+		}
+	case "i64":
+		if n.bigEndian {
+			v, err = r.ReadInt64BigEndian()
+		} else {
+			v, err = r.ReadInt64()
+		}
+	case "f32":
+		if n.bigEndian {
+			v, err = r.ReadFloat32BigEndian()
+		} else {
+			v, err = r.ReadFloat32()
+		}
+	case "f64":
+		if n.bigEndian {
+			v, err = r.ReadFloat64BigEndian()
+		} else {
+			v, err = r.ReadFloat64()
+		}
+	}
+	r.Label("")
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return v, nil
+}
+
+//WriteSchema writes vals with the layout of s,vals has the shape returned by ReadSchema.
+//numbers may be of any Go number type,so a map decoded from JSON works too,
+//and the counts and lengths must match the arrays,bytes and strings they describe.
+func (w *Writer) WriteSchema(s *Schema, vals map[string]interface{}) error {
+	c, err := s.compile()
+	if err != nil {
+		return err
+	}
+	return w.writeSchemaFields(c, c.fields, &schemaScope{vals: vals}, "")
+}
+
+func (w *Writer) writeSchemaFields(c *compiledSchema, nodes []*schemaNode, sc *schemaScope, path string) error {
+	for _, n := range nodes {
+		switch {
+		case n.cond != nil:
+			v, err := n.cond.eval(sc)
+			if err != nil {
+				return fmt.Errorf("%vif: %w", path, err)
+			}
+			fields := n.then
+			if v == 0 {
+				fields = n.els
+			}
+			if err = w.writeSchemaFields(c, fields, sc, path); err != nil {
+				return err
+			}
+			continue
+		case n.sw != nil:
+			v, err := n.sw.eval(sc)
+			if err != nil {
+				return fmt.Errorf("%vswitch: %w", path, err)
+			}
+			if err = w.writeSchemaFields(c, n.caseFields(v), sc, path); err != nil {
+				return err
+			}
+			continue
+		}
+		v, ok := sc.vals[n.name]
+		if !ok {
+			return fmt.Errorf("%v%v: the value is missing", path, n.name)
+		}
+		if n.count == nil {
+			if err := w.writeSchemaValue(c, n, v, sc, path+n.name); err != nil {
+				return err
+			}
+			continue
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v%v: %T is not an []interface{}", path, n.name, v)
+		}
+		count, err := schemaSize(n.count, sc)
+		if err != nil {
+			return fmt.Errorf("%v%v: %w", path, n.name, err)
+		}
+		if count != len(arr) {
+			return fmt.Errorf("%v%v: the count is %v,but the array has %v elements", path, n.name, count, len(arr))
+		}
+		for i, elem := range arr {
+			if err = w.writeSchemaValue(c, n, elem, sc, fmt.Sprintf("%v%v.%v", path, n.name, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeSchemaValue(c *compiledSchema, n *schemaNode, v interface{}, sc *schemaScope, path string) error {
+	if fields, ok := c.structs[n.base]; ok {
+		vals, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v: %T is not a map[string]interface{}", path, v)
+		}
+		child, err := sc.child(vals, path)
+		if err != nil {
+			return err
+		}
+		return w.writeSchemaFields(c, fields, child, path+".")
+	}
+	switch n.base {
+	case "bytes", "str":
+		var bt []byte
+		switch x := v.(type) {
+		case []byte:
+			bt = x
+		case string:
+			bt = []byte(x)
+		default:
+			return fmt.Errorf("%v: %T is neither []byte nor string", path, v)
+		}
+		if n.length != nil {
+			length, err := schemaSize(n.length, sc)
+			if err != nil {
+				return fmt.Errorf("%v: %w", path, err)
+			}
+			if length != len(bt) {
+				return fmt.Errorf("%v: the length is %v,but the value has %v bytes", path, length, len(bt))
+			}
+		}
+		w.WriteBytes(bt)
+		return nil
+	case "f32", "f64":
+		f, ok := schemaToFloat(v)
+		if !ok {
+			return fmt.Errorf("%v: %T is not a number", path, v)
+		}
+		switch {
+		case n.base == "f32" && n.bigEndian:
+			w.WriteFloat32BigEndian(float32(f))
+		case n.base == "f32":
+			w.WriteFloat32(float32(f))
+		case n.bigEndian:
+			w.WriteFloat64BigEndian(f)
+		default:
+			w.WriteFloat64(f)
+		}
+		return nil
+	}
+	size := schemaNumbers[n.base]
+	var u uint64
+	if n.base[0] == 'u' {
+		x, ok := schemaToUint(v)
+		if !ok || size < 8 && x >= 1<<(8*size) {
+			return fmt.Errorf("%v: %v is not a valid %v", path, v, n.base)
+		}
+		u = x
+	} else {
+		x, ok := schemaToInt(v)
+		if !ok || size < 8 && (x < -1<<(8*size-1) || x >= 1<<(8*size-1)) {
+			return fmt.Errorf("%v: %v is not a valid %v", path, v, n.base)
+		}
+		u = uint64(x)
+	}
+	switch {
+	case size == 1:
+		w.WriteUint8(uint8(u))
+	case size == 2 && n.bigEndian:
+		w.WriteUint16BigEndian(uint16(u))
+	case size == 2:
+		w.WriteUint16(uint16(u))
+	case size == 4 && n.bigEndian:
+		w.WriteUint32BigEndian(uint32(u))
+	case size == 4:
+		w.WriteUint32(uint32(u))
+	case n.bigEndian:
+		w.WriteUint64BigEndian(u)
+	default:
+		w.WriteUint64(u)
+	}
+	return nil
+}
+
+//converts any Go integer,or a float64 without fraction as decoded from JSON,to int64.
+func schemaToInt(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint:
+		return int64(x), x <= math.MaxInt64
+	case uint64:
+		return int64(x), x <= math.MaxInt64
+	case float32:
+		return int64(x), float32(int64(x)) == x
+	case float64:
+		return int64(x), float64(int64(x)) == x
+	}
+	return 0, false
+}
+
+//converts any Go integer that is not negative,or a float64 as decoded from JSON,to uint64.
+func schemaToUint(v interface{}) (uint64, bool) {
+	switch x := v.(type) {
+	case uint:
+		return uint64(x), true
+	case uint64:
+		return x, true
+	case float64:
+		return uint64(x), x >= 0 && float64(uint64(x)) == x
+	}
+	i, ok := schemaToInt(v)
+	return uint64(i), ok && i >= 0
+}
+
+func schemaToFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	if i, ok := schemaToInt(v); ok {
+		return float64(i), true
+	}
+	u, ok := schemaToUint(v)
+	return float64(u), ok
+}
@@ -0,0 +1,181 @@
+package iox
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSchemaText = `
+# a record is a kind and a name
+struct record {
+	u8 kind
+	str[kind & 0x0f] name
+	switch kind >> 4 {
+	case 1, 2: u16be value
+	default:
+	}
+}
+u16 count; u8 flags
+record[count] items
+if flags & 1 { u32be ext } else if flags & 2 { i8 ext } else {}
+bytes[*] rest
+`
+
+func TestSchema(t *testing.T) {
+	s, err := ParseSchema(testSchemaText)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	wr := NewBytesBuffer()
+	wr.WriteUint16(2)
+	wr.WriteUint8(1)
+	wr.WriteUint8(0x13)
+	wr.WriteString("abc")
+	wr.WriteUint16BigEndian(500)
+	wr.WriteUint8(0x02)
+	wr.WriteString("de")
+	wr.WriteUint32BigEndian(7)
+	wr.WriteString("tail")
+	data := wr.Bytes()
+	rd := NewReadSeekerFromBytes(data)
+	trace := rd.EnableTrace()
+	vals, err := rd.ReadSchema(s)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	want := map[string]interface{}{
+		"count": uint16(2),
+		"flags": uint8(1),
+		"items": []interface{}{
+			map[string]interface{}{"kind": uint8(0x13), "name": "abc", "value": uint16(500)},
+			map[string]interface{}{"kind": uint8(0x02), "name": "de"},
+		},
+		"ext":  uint32(7),
+		"rest": []byte("tail"),
+	}
+	if !reflect.DeepEqual(vals, want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", vals, want)
+	}
+	if rec := trace.Records[4]; rec.Label != "items.0.value" || rec.Offset != 7 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", rec, "items.0.value at 7")
+	}
+	//write it back,also from the JSON form where numbers are float64
+	wr = NewBytesBuffer()
+	if err = wr.WriteSchema(s, vals); err != nil || !bytes.Equal(wr.Bytes(), data) {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v", wr.Bytes(), err, data)
+	}
+	vals["rest"] = "tail"
+	js, _ := json.Marshal(vals)
+	var decoded map[string]interface{}
+	json.Unmarshal(js, &decoded)
+	wr = NewBytesBuffer()
+	if err = wr.WriteSchema(s, decoded); err != nil || !bytes.Equal(wr.Bytes(), data) {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v", wr.Bytes(), err, data)
+	}
+	//the count must match the array
+	decoded["count"] = 3
+	if err = NewBytesBuffer().WriteSchema(s, decoded); err == nil || !strings.Contains(err.Error(), "items") {
+		t.Fatalf("unexpected value obtained; got %v want an error about items", err)
+	}
+	//a truncated input names the field
+	_, err = NewReadSeekerFromBytes(data[:6]).ReadSchema(s)
+	if err == nil || !strings.HasPrefix(err.Error(), "items.0.name") {
+		t.Fatalf("unexpected value obtained; got %v want an error about items.0.name", err)
+	}
+}
+
+func TestSchemaBuilder(t *testing.T) {
+	s := NewSchema(
+		NewField("u8", "version"),
+		NewIf("version >= 2 && version != 3", NewField("f32be", "scale")).OrElse(NewField("point[2]", "points")),
+		NewSwitch("version", NewCase([]int64{1}, NewField("i16", "tail")), NewDefaultCase()),
+	).Define("point", NewField("i8", "x"), NewField("i8", "y"))
+	vals, err := NewReadSeekerFromBytes([]byte{1, 1, 2, 0xff, 0xfe, 0x05, 0x00}).ReadSchema(s)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	want := map[string]interface{}{
+		"version": uint8(1),
+		"points": []interface{}{
+			map[string]interface{}{"x": int8(1), "y": int8(2)},
+			map[string]interface{}{"x": int8(-1), "y": int8(-2)},
+		},
+		"tail": int16(5),
+	}
+	if !reflect.DeepEqual(vals, want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", vals, want)
+	}
+	//the JSON form
+	js, _ := json.Marshal(s)
+	var decoded Schema
+	if err = json.Unmarshal(js, &decoded); err != nil || !reflect.DeepEqual(&decoded, s) {
+		t.Fatalf("unexpected value obtained; got %+v,%v want %+v", decoded, err, s)
+	}
+	for _, text := range []string{
+		"u16 a b",
+		"bytes b",
+		"foo x",
+		"if { u8 a }",
+		"u8[(1] a",
+		"switch a { u8 b }",
+	} {
+		if _, err := ParseSchema(text); err == nil {
+			t.Fatalf("%q: unexpected value obtained; got %v want an error", text, err)
+		}
+	}
+	if err = NewSchema(NewField("u8", "a"), NewIf("a +", NewField("u8", "b"))).Validate(); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+	//an out of range value is refused
+	if err = NewBytesBuffer().WriteSchema(NewSchema(NewField("u8", "a")), map[string]interface{}{"a": 256}); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
+
+func TestSchemaLimits(t *testing.T) {
+	//a huge count is refused before anything is allocated for it
+	s, err := ParseSchema("u32 n; u8[n] a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewReadSeekerFromBytes([]byte{0xff, 0xff, 0xff, 0x7f, 0x01}).ReadSchema(s); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	//elements that may be empty don't need any data
+	if s, err = ParseSchema("struct e { u8 n; bytes[n] b }\nstruct v { if 0 { u8 x } }\nu32 n; v[n] a; e[2] b"); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := NewReadSeekerFromBytes([]byte{3, 0, 0, 0, 0, 1, 7}).ReadSchema(s)
+	if err != nil || len(vals["a"].([]interface{})) != 3 {
+		t.Fatalf("unexpected value obtained; got %v,%v want %v", vals, err, "3 empty elements")
+	}
+	//a struct of 2 bytes at least
+	if s, err = ParseSchema("struct p { u8 x; switch x { case 1: u16 y\ndefault: u8 z } }\nu8 n; p[n] a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewReadSeekerFromBytes([]byte{3, 0, 0, 0, 0, 0}).ReadSchema(s); !errors.Is(err, io.ErrUnexpectedEOF) || !strings.Contains(err.Error(), "2 bytes") {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	//a struct that contains itself reads nothing,so only the depth stops it
+	if s, err = ParseSchema("struct node { bytes[0] x; node child }\nnode root"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewReadSeekerFromBytes(nil).ReadSchema(s); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, "nested deeper")
+	}
+	//a linked list of a few nodes is fine
+	if s, err = ParseSchema("struct node { u8 more; if more { node next } }\nnode head"); err != nil {
+		t.Fatal(err)
+	}
+	if vals, err = NewReadSeekerFromBytes([]byte{1, 1, 0}).ReadSchema(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (&schemaScope{vals: vals}).lookup("head.next.next.more"); !ok {
+		t.Fatalf("unexpected value obtained; got %v want %v", vals, "3 nodes")
+	}
+}
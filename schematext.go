@@ -0,0 +1,556 @@
+package iox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//schemaToken is a token of the text form of a schema or of an expression.
+type schemaToken struct {
+	kind byte //'i' ident,'n' number,'p' punctuation,';' end of a field,0 end of text
+	text string
+	pos  int
+	line int
+}
+
+func (t schemaToken) end() int {
+	return t.pos + len(t.text)
+}
+
+//split text into tokens,a newline ends a field unless it is inside brackets.
+func lexSchema(text string) ([]schemaToken, error) {
+	var tokens []schemaToken
+	line, depth := 1, 0
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\n':
+			if depth == 0 {
+				tokens = append(tokens, schemaToken{kind: ';', text: "\n", pos: i, line: line})
+			}
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(text[i:], "//"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(text) && (text[j] == '_' || text[j] == '.' || isAlnum(text[j])) {
+				j++
+			}
+			tokens = append(tokens, schemaToken{kind: 'i', text: text[i:j], pos: i, line: line})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(text) && (text[j] == '_' || isAlnum(text[j])) {
+				j++
+			}
+			tokens = append(tokens, schemaToken{kind: 'n', text: text[i:j], pos: i, line: line})
+			i = j
+		case c == ';':
+			tokens = append(tokens, schemaToken{kind: ';', text: ";", pos: i, line: line})
+			i++
+		default:
+			op := text[i : i+1]
+			if i+1 < len(text) {
+				switch two := text[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "<<", ">>", "&&", "||":
+					op = two
+				}
+			}
+			if !strings.Contains("+-*/%&|^!~<>()[]{},:", op[:1]) {
+				return nil, fmt.Errorf("line %v: unexpected character %q", line, c)
+			}
+			switch op {
+			case "(", "[":
+				depth++
+			case ")", "]":
+				depth--
+			}
+			tokens = append(tokens, schemaToken{kind: 'p', text: op, pos: i, line: line})
+			i += len(op)
+		}
+	}
+	return append(tokens, schemaToken{pos: len(text), line: line}), nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+//schemaParser turns the text form into a Schema.
+type schemaParser struct {
+	text   string
+	tokens []schemaToken
+	i      int
+}
+
+func (p *schemaParser) peek() schemaToken {
+	return p.tokens[p.i]
+}
+
+func (p *schemaParser) next() schemaToken {
+	t := p.tokens[p.i]
+	if t.kind != 0 {
+		p.i++
+	}
+	return t
+}
+
+func (p *schemaParser) is(text string) bool {
+	t := p.peek()
+	return t.kind != 0 && t.kind != ';' && t.text == text
+}
+
+func (p *schemaParser) expect(text string) error {
+	if t := p.next(); t.text != text || t.kind == ';' && text != ";" {
+		return p.errorf(t, "%q expected", text)
+	}
+	return nil
+}
+
+func (p *schemaParser) errorf(t schemaToken, format string, args ...interface{}) error {
+	found := t.text
+	switch t.kind {
+	case 0:
+		found = "end of text"
+	case ';':
+		if found == "\n" {
+			found = "newline"
+		}
+	}
+	return fmt.Errorf("line %v: %v,found %q", t.line, fmt.Sprintf(format, args...), found)
+}
+
+func (p *schemaParser) skipSeparators() {
+	for p.peek().kind == ';' {
+		p.i++
+	}
+}
+
+//read an expression up to one of the stop tokens at the same bracket depth and returns its text.
+func (p *schemaParser) exprText(stop ...string) (string, error) {
+	first := p.peek()
+	last := first
+	depth, n := 0, 0
+	for {
+		t := p.peek()
+		if t.kind == 0 || t.kind == ';' && depth == 0 {
+			break
+		}
+		if depth == 0 {
+			stopped := false
+			for _, s := range stop {
+				stopped = stopped || t.text == s
+			}
+			if stopped {
+				break
+			}
+		}
+		switch t.text {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+		}
+		last = p.next()
+		n++
+	}
+	if n == 0 {
+		return "", p.errorf(first, "expression expected")
+	}
+	return p.text[first.pos:last.end()], nil
+}
+
+//fields up to the closing brace,which is not consumed.
+func (p *schemaParser) fields(stop ...string) ([]SchemaField, error) {
+	var fields []SchemaField
+	for {
+		p.skipSeparators()
+		t := p.peek()
+		if t.kind == 0 || t.text == "}" {
+			return fields, nil
+		}
+		for _, s := range stop {
+			if t.kind == 'i' && t.text == s {
+				return fields, nil
+			}
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+}
+
+func (p *schemaParser) block() ([]SchemaField, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	fields, err := p.fields()
+	if err != nil {
+		return nil, err
+	}
+	return fields, p.expect("}")
+}
+
+func (p *schemaParser) field() (SchemaField, error) {
+	t := p.peek()
+	if t.kind != 'i' {
+		return SchemaField{}, p.errorf(t, "a field expected")
+	}
+	switch t.text {
+	case "if":
+		p.next()
+		cond, err := p.exprText("{")
+		if err != nil {
+			return SchemaField{}, err
+		}
+		f := SchemaField{Cond: cond}
+		if f.Then, err = p.block(); err != nil {
+			return f, err
+		}
+		if p.is("else") {
+			p.next()
+			if p.is("if") {
+				elseIf, err := p.field()
+				if err != nil {
+					return f, err
+				}
+				f.Else = []SchemaField{elseIf}
+			} else if f.Else, err = p.block(); err != nil {
+				return f, err
+			}
+		}
+		return f, nil
+	case "switch":
+		p.next()
+		expr, err := p.exprText("{")
+		if err != nil {
+			return SchemaField{}, err
+		}
+		f := SchemaField{Switch: expr}
+		if err = p.expect("{"); err != nil {
+			return f, err
+		}
+		for {
+			p.skipSeparators()
+			t := p.next()
+			if t.text == "}" && t.kind == 'p' {
+				return f, nil
+			}
+			var c SchemaCase
+			switch {
+			case t.kind == 'i' && t.text == "case":
+				for {
+					v, err := p.caseValue()
+					if err != nil {
+						return f, err
+					}
+					c.Values = append(c.Values, v)
+					if !p.is(",") {
+						break
+					}
+					p.next()
+				}
+			case t.kind == 'i' && t.text == "default":
+			default:
+				return f, p.errorf(t, "case or default expected")
+			}
+			if err = p.expect(":"); err != nil {
+				return f, err
+			}
+			if c.Fields, err = p.fields("case", "default"); err != nil {
+				return f, err
+			}
+			f.Cases = append(f.Cases, c)
+		}
+	}
+	typ := p.next().text
+	if p.is("[") {
+		p.next()
+		n, err := p.exprText("]")
+		if err != nil {
+			return SchemaField{}, err
+		}
+		if err = p.expect("]"); err != nil {
+			return SchemaField{}, err
+		}
+		typ += "[" + n + "]"
+	}
+	name := p.next()
+	if name.kind != 'i' {
+		return SchemaField{}, p.errorf(name, "the name of the %v field expected", typ)
+	}
+	if t := p.peek(); t.kind != ';' && t.text != "}" && t.kind != 0 {
+		return SchemaField{}, p.errorf(t, "the end of the field %v expected", name.text)
+	}
+	return NewField(typ, name.text), nil
+}
+
+func (p *schemaParser) caseValue() (int64, error) {
+	t := p.next()
+	neg := false
+	if t.text == "-" {
+		neg = true
+		t = p.next()
+	}
+	if t.kind != 'n' {
+		return 0, p.errorf(t, "a case value expected")
+	}
+	v, err := strconv.ParseInt(t.text, 0, 64)
+	if err != nil {
+		return 0, p.errorf(t, "%v", err)
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+//ParseSchema parses the text form of a schema,fields are separated by newlines or semicolons:
+//
+//	struct record {
+//		u8 kind
+//		str[kind & 0x0f] name
+//	}
+//	u16 count; u8 flags
+//	record[count] items
+//	if flags & 1 { u32be ext } else { u16 ext }
+//	switch items.0.kind { case 1, 2: f64 value; default: bytes[*] rest }
+//
+//see SchemaField for the types,"#" and "//" start a comment.
+func ParseSchema(text string) (*Schema, error) {
+	tokens, err := lexSchema(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &schemaParser{text: text, tokens: tokens}
+	s := NewSchema()
+	for {
+		p.skipSeparators()
+		t := p.peek()
+		if t.kind == 0 {
+			break
+		}
+		if t.kind == 'i' && t.text == "struct" {
+			p.next()
+			name := p.next()
+			if name.kind != 'i' {
+				return nil, p.errorf(name, "the name of the struct expected")
+			}
+			fields, err := p.block()
+			if err != nil {
+				return nil, err
+			}
+			s.Define(name.text, fields...)
+			continue
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		s.Fields = append(s.Fields, f)
+	}
+	return s, s.Validate()
+}
+
+//schemaExpr is a compiled expression,every value is an int64.
+type schemaExpr interface {
+	eval(sc *schemaScope) (int64, error)
+}
+
+type schemaNum int64
+
+func (n schemaNum) eval(*schemaScope) (int64, error) {
+	return int64(n), nil
+}
+
+//schemaRef is the value of an earlier field,such as "count" or "header.flags".
+type schemaRef string
+
+func (ref schemaRef) eval(sc *schemaScope) (int64, error) {
+	v, ok := sc.lookup(string(ref))
+	if !ok {
+		return 0, fmt.Errorf("the field %v is not known", ref)
+	}
+	if i, ok := schemaToInt(v); ok {
+		return i, nil
+	}
+	if u, ok := schemaToUint(v); ok {
+		return int64(u), nil
+	}
+	return 0, fmt.Errorf("the field %v is a %T,not an integer", ref, v)
+}
+
+type schemaUnary struct {
+	op string
+	x  schemaExpr
+}
+
+func (u schemaUnary) eval(sc *schemaScope) (int64, error) {
+	x, err := u.x.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	switch u.op {
+	case "-":
+		return -x, nil
+	case "~":
+		return ^x, nil
+	}
+	return schemaBool(x == 0), nil
+}
+
+type schemaBinary struct {
+	op   string
+	x, y schemaExpr
+}
+
+func (b schemaBinary) eval(sc *schemaScope) (int64, error) {
+	x, err := b.x.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	//&& and || don't evaluate y if x decides,so "has_ext && ext > 0" works without ext
+	switch {
+	case b.op == "&&" && x == 0:
+		return 0, nil
+	case b.op == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := b.y.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		if b.op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	case "&":
+		return x & y, nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "<<":
+		return x << uint64(y), nil
+	case ">>":
+		return x >> uint64(y), nil
+	case "==":
+		return schemaBool(x == y), nil
+	case "!=":
+		return schemaBool(x != y), nil
+	case "<":
+		return schemaBool(x < y), nil
+	case "<=":
+		return schemaBool(x <= y), nil
+	case ">":
+		return schemaBool(x > y), nil
+	case ">=":
+		return schemaBool(x >= y), nil
+	}
+	return schemaBool(y != 0), nil //&& and || once x did not decide
+}
+
+func schemaBool(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+//the precedence of the binary operators,the same as Go.
+var schemaPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+": 4, "-": 4, "|": 4, "^": 4,
+	"*": 5, "/": 5, "%": 5, "<<": 5, ">>": 5, "&": 5,
+}
+
+//parseSchemaExpr compiles an expression such as "(flags & 0x80) != 0 && count > 1".
+func parseSchemaExpr(text string) (schemaExpr, error) {
+	tokens, err := lexSchema(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &schemaParser{text: text, tokens: tokens}
+	e, err := p.binary(1)
+	if err != nil {
+		return nil, fmt.Errorf("the expression %q: %v", text, err)
+	}
+	if t := p.peek(); t.kind != 0 {
+		return nil, fmt.Errorf("the expression %q: unexpected %q", text, t.text)
+	}
+	return e, nil
+}
+
+func (p *schemaParser) binary(prec int) (schemaExpr, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := schemaPrecedence[t.text]
+		if t.kind != 'p' || op < prec {
+			return x, nil
+		}
+		p.next()
+		y, err := p.binary(op + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = schemaBinary{op: t.text, x: x, y: y}
+	}
+}
+
+func (p *schemaParser) unary() (schemaExpr, error) {
+	t := p.next()
+	switch {
+	case t.kind == 'p' && (t.text == "-" || t.text == "!" || t.text == "~"):
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return schemaUnary{op: t.text, x: x}, nil
+	case t.kind == 'p' && t.text == "(":
+		x, err := p.binary(1)
+		if err != nil {
+			return nil, err
+		}
+		if t = p.next(); t.text != ")" {
+			return nil, fmt.Errorf("\")\" expected,found %q", t.text)
+		}
+		return x, nil
+	case t.kind == 'n':
+		v, err := strconv.ParseInt(strings.ReplaceAll(t.text, "_", ""), 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return schemaNum(v), nil
+	case t.kind == 'i':
+		return schemaRef(t.text), nil
+	}
+	if t.kind == 0 {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
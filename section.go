@@ -0,0 +1,69 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+//section is an io.ReadSeeker over a part of another io.ReadSeeker.
+//the parent is shared,so every read seeks it and then puts its position back.
+type section struct {
+	parent io.ReadSeeker
+	base   int64
+	size   int64
+	pos    int64
+}
+
+func (s *section) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	if max := s.size - s.pos; int64(len(p)) > max {
+		p = p[:max]
+	}
+	parentPos, err := s.parent.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	defer s.parent.Seek(parentPos, io.SeekStart)
+	if _, err = s.parent.Seek(s.base+s.pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := s.parent.Read(p)
+	s.pos += int64(n)
+	if err == io.EOF && s.pos < s.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (s *section) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.pos = offset
+	return offset, nil
+}
+
+//returns a *ReadSeeker over the data between beginPos and endPos(both included),
+//positions in the section are relative to beginPos,endPos may be beginPos-1 for an empty section.
+//reading the section does not move the position of r.
+func (r *ReadSeeker) Section(beginPos, endPos int64) (*ReadSeeker, error) {
+	if size := r.Size(); beginPos < 0 || endPos >= size || endPos+1 < beginPos {
+		return nil, fmt.Errorf("beginPos:%v or endPos:%v is not a valid value,the size of the data is %v", beginPos, endPos, size)
+	}
+	if ra, ok := r.readSeeker.(io.ReaderAt); ok {
+		return NewReadSeeker(io.NewSectionReader(ra, beginPos, endPos-beginPos+1)), nil
+	}
+	return NewReadSeeker(&section{parent: r.readSeeker, base: beginPos, size: endPos - beginPos + 1}), nil
+}
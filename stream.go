@@ -0,0 +1,531 @@
+package iox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+//the biggest length read by a single allocation,longer data is collected while it arrives
+//so that a corrupt length prefix can't allocate a huge buffer up front.
+const maxStreamAlloc = 64 << 10
+
+//Reader helps you read data from a forward-only io.Reader such as net.Conn or os.Stdin,
+//it never seeks,the default ByteOrder is LittleEndian.
+type Reader struct {
+	rd       *bufio.Reader
+	closer   io.Closer
+	consumed int64
+}
+
+//returns a *Reader from io.Reader with the default buffer size.
+func NewReader(rd io.Reader) *Reader {
+	return NewReaderSize(rd, 4096)
+}
+
+//returns a *Reader from io.Reader whose buffer has at least the specified size.
+//the size limits the longest sep accepted by ReadUntil and ScanTo and the longest Peek.
+func NewReaderSize(rd io.Reader, size int) *Reader {
+	r := new(Reader)
+	r.rd = bufio.NewReaderSize(rd, size)
+	if closer, ok := rd.(io.Closer); ok {
+		r.closer = closer
+	}
+	return r
+}
+
+//Close if it's a io.Closer,it returns the error of its Close.
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+//get the number of bytes consumed so far.
+func (r *Reader) Consumed() int64 {
+	return r.consumed
+}
+
+//returns the next n bytes without consuming them.
+//the bytes stop being valid at the next read.
+func (r *Reader) Peek(n int) ([]byte, error) {
+	return r.rd.Peek(n)
+}
+
+//skip n bytes.
+func (r *Reader) Skip(n int64) error {
+	for n > 0 {
+		step := n
+		if step > math.MaxInt32 {
+			step = math.MaxInt32
+		}
+		skipped, err := r.rd.Discard(int(step))
+		r.consumed += int64(skipped)
+		n -= int64(skipped)
+		if err != nil {
+			return r.unexpected(err, skipped)
+		}
+	}
+	return nil
+}
+
+//turns io.EOF into io.ErrUnexpectedEOF if part of the data has been read.
+func (r *Reader) unexpected(err error, n int) error {
+	if err == io.EOF && n == 0 {
+		return io.EOF
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("the data ends at position %v: %w", r.consumed, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+//read exactly len(bt) bytes into bt.
+func (r *Reader) readFull(bt []byte) error {
+	n, err := io.ReadFull(r.rd, bt)
+	r.consumed += int64(n)
+	if err != nil {
+		return r.unexpected(err, n)
+	}
+	return nil
+}
+
+//read n bytes.
+func (r *Reader) ReadBytes(n int) ([]byte, error) {
+	if n < 0 {
+		panic(fmt.Sprint(n, " is not a valid value."))
+	}
+	if n <= maxStreamAlloc {
+		bt := make([]byte, n)
+		if err := r.readFull(bt); err != nil {
+			return nil, err
+		}
+		return bt, nil
+	}
+	var buf bytes.Buffer
+	copied, err := io.CopyN(&buf, r.rd, int64(n))
+	r.consumed += copied
+	if err != nil {
+		return nil, r.unexpected(err, int(copied))
+	}
+	return buf.Bytes(), nil
+}
+
+//read n bytes announced by a length prefix,running out of data here is always unexpected.
+func (r *Reader) readBytesAfterPrefix(n uint64) ([]byte, error) {
+	if int(n) < 0 || uint64(int(n)) != n {
+		return nil, fmt.Errorf("the data length:%v at position %v is too big", n, r.consumed)
+	}
+	bt, err := r.ReadBytes(int(n))
+	if err == io.EOF {
+		return nil, fmt.Errorf("wish read %v bytes at position %v,but no data left: %w", n, r.consumed, io.ErrUnexpectedEOF)
+	}
+	return bt, err
+}
+
+//read uint8 as the data length and then read the data.
+func (r *Reader) ReadBytesUint8() ([]byte, error) {
+	n, err := r.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(uint64(n))
+}
+
+//read uint16 as the data length and then read the data.
+func (r *Reader) ReadBytesUint16() ([]byte, error) {
+	n, err := r.ReadUint16()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(uint64(n))
+}
+
+//read uint16(BigEndian) as the data length and then read the data.
+func (r *Reader) ReadBytesUint16BigEndian() ([]byte, error) {
+	n, err := r.ReadUint16BigEndian()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(uint64(n))
+}
+
+//read uint32 as the data length and then read the data.
+func (r *Reader) ReadBytesUint32() ([]byte, error) {
+	n, err := r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(uint64(n))
+}
+
+//read uint32(BigEndian) as the data length and then read the data.
+func (r *Reader) ReadBytesUint32BigEndian() ([]byte, error) {
+	n, err := r.ReadUint32BigEndian()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(uint64(n))
+}
+
+//read uint64 as the data length and then read the data.
+func (r *Reader) ReadBytesUint64() ([]byte, error) {
+	n, err := r.ReadUint64()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(n)
+}
+
+//read uint64(BigEndian) as the data length and then read the data.
+func (r *Reader) ReadBytesUint64BigEndian() ([]byte, error) {
+	n, err := r.ReadUint64BigEndian()
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytesAfterPrefix(n)
+}
+
+//read uint8 as the data length and then read the data.
+func (r *Reader) ReadStringUint8() (string, error) {
+	bt, err := r.ReadBytesUint8()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read uint16 as the data length and then read the data.
+func (r *Reader) ReadStringUint16() (string, error) {
+	bt, err := r.ReadBytesUint16()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read uint16(BigEndian) as the data length and then read the data.
+func (r *Reader) ReadStringUint16BigEndian() (string, error) {
+	bt, err := r.ReadBytesUint16BigEndian()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read uint32 as the data length and then read the data.
+func (r *Reader) ReadStringUint32() (string, error) {
+	bt, err := r.ReadBytesUint32()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read uint32(BigEndian) as the data length and then read the data.
+func (r *Reader) ReadStringUint32BigEndian() (string, error) {
+	bt, err := r.ReadBytesUint32BigEndian()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read uint64 as the data length and then read the data.
+func (r *Reader) ReadStringUint64() (string, error) {
+	bt, err := r.ReadBytesUint64()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read uint64(BigEndian) as the data length and then read the data.
+func (r *Reader) ReadStringUint64BigEndian() (string, error) {
+	bt, err := r.ReadBytesUint64BigEndian()
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read n bytes of the data and then returns the hexadecimal encoding of the bytes.
+func (r *Reader) ReadHexToString(n int) (string, error) {
+	bt, err := r.ReadBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(bt)), nil
+}
+
+//read n bytes of the data and convert to string.
+func (r *Reader) ReadString(n int) (string, error) {
+	bt, err := r.ReadBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return string(bt), nil
+}
+
+//read n bytes of data, then convert to string, and then remove spaces in the string.
+func (r *Reader) ReadStringTrimSpace(n int) (string, error) {
+	bt, err := r.ReadBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bt)), nil
+}
+
+//read 1 byte and then convert to int8.
+func (r *Reader) ReadInt8() (int8, error) {
+	bt := make([]byte, 1)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt8(bt), nil
+}
+
+//read 1 byte and then convert to uint8.
+func (r *Reader) ReadUint8() (uint8, error) {
+	bt := make([]byte, 1)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint8(bt), nil
+}
+
+//read 2 bytes and then convert to int16.
+func (r *Reader) ReadInt16() (int16, error) {
+	bt := make([]byte, 2)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt16(bt), nil
+}
+
+//read 2 bytes and then convert to int16(BigEndian).
+func (r *Reader) ReadInt16BigEndian() (int16, error) {
+	bt := make([]byte, 2)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt16BigEndian(bt), nil
+}
+
+//read 2 bytes and then convert to uint16.
+func (r *Reader) ReadUint16() (uint16, error) {
+	bt := make([]byte, 2)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint16(bt), nil
+}
+
+//read 2 bytes and then convert to uint16(BigEndian).
+func (r *Reader) ReadUint16BigEndian() (uint16, error) {
+	bt := make([]byte, 2)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint16BigEndian(bt), nil
+}
+
+//read 4 bytes and then convert to int32.
+func (r *Reader) ReadInt32() (int32, error) {
+	bt := make([]byte, 4)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt32(bt), nil
+}
+
+//read 4 bytes and then convert to int32(BigEndian).
+func (r *Reader) ReadInt32BigEndian() (int32, error) {
+	bt := make([]byte, 4)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt32BigEndian(bt), nil
+}
+
+//read 4 bytes and then convert to uint32.
+func (r *Reader) ReadUint32() (uint32, error) {
+	bt := make([]byte, 4)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint32(bt), nil
+}
+
+//read 4 bytes and then convert to uint32(BigEndian).
+func (r *Reader) ReadUint32BigEndian() (uint32, error) {
+	bt := make([]byte, 4)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint32BigEndian(bt), nil
+}
+
+//read 8 bytes and then convert to int64.
+func (r *Reader) ReadInt64() (int64, error) {
+	bt := make([]byte, 8)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt64(bt), nil
+}
+
+//read 8 bytes and then convert to int64(BigEndian).
+func (r *Reader) ReadInt64BigEndian() (int64, error) {
+	bt := make([]byte, 8)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToInt64BigEndian(bt), nil
+}
+
+//read 8 bytes and then convert to uint64.
+func (r *Reader) ReadUint64() (uint64, error) {
+	bt := make([]byte, 8)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint64(bt), nil
+}
+
+//read 8 bytes and then convert to uint64(BigEndian).
+func (r *Reader) ReadUint64BigEndian() (uint64, error) {
+	bt := make([]byte, 8)
+	if err := r.readFull(bt); err != nil {
+		return 0, err
+	}
+	return bytesToUint64BigEndian(bt), nil
+}
+
+//read an unsigned varint as encoded by encoding/binary.
+func (r *Reader) ReadUvarint() (uint64, error) {
+	var n int
+	v, err := binary.ReadUvarint(byteReaderFunc(func() (byte, error) {
+		b, err := r.rd.ReadByte()
+		if err == nil {
+			n++
+			r.consumed++
+		}
+		return b, err
+	}))
+	if err != nil {
+		return 0, r.unexpected(err, n)
+	}
+	return v, nil
+}
+
+//byteReaderFunc turns a function into an io.ByteReader.
+type byteReaderFunc func() (byte, error)
+
+func (f byteReaderFunc) ReadByte() (byte, error) {
+	return f()
+}
+
+//read 4 bytes and convert it to float32.
+func (r *Reader) ReadFloat32() (float32, error) {
+	Uint32, err := r.ReadUint32()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(Uint32), nil
+}
+
+//read 4 bytes and convert it to float32(BigEndian).
+func (r *Reader) ReadFloat32BigEndian() (float32, error) {
+	Uint32, err := r.ReadUint32BigEndian()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(Uint32), nil
+}
+
+//read 8 bytes and convert it to float64.
+func (r *Reader) ReadFloat64() (float64, error) {
+	Uint64, err := r.ReadUint64()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(Uint64), nil
+}
+
+//read 8 bytes and convert it to float64(BigEndian).
+func (r *Reader) ReadFloat64BigEndian() (float64, error) {
+	Uint64, err := r.ReadUint64BigEndian()
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(Uint64), nil
+}
+
+//scan forward for sep,calling fn with every chunk of data before it,
+//found reports whether sep is now the next data in the buffer.
+func (r *Reader) scan(sep []byte, fn func(chunk []byte)) (found bool, err error) {
+	lenSep := len(sep)
+	if lenSep == 0 {
+		panic("sep can't be nil.")
+	}
+	if lenSep > r.rd.Size() {
+		return false, fmt.Errorf("sep of %v bytes is too long for a buffer of %v bytes", lenSep, r.rd.Size())
+	}
+	for {
+		//make sure at least lenSep bytes are buffered,then search everything buffered
+		_, err = r.rd.Peek(lenSep)
+		buf, _ := r.rd.Peek(r.rd.Buffered())
+		if i := bytes.Index(buf, sep); i >= 0 {
+			fn(buf[:i])
+			r.rd.Discard(i)
+			r.consumed += int64(i)
+			return true, nil
+		}
+		if err != nil {
+			//no more data will arrive,so the rest can't contain sep
+			fn(buf)
+			r.rd.Discard(len(buf))
+			r.consumed += int64(len(buf))
+			return false, err
+		}
+		//keep the last lenSep-1 bytes,they may be the beginning of sep
+		n := len(buf) - lenSep + 1
+		fn(buf[:n])
+		r.rd.Discard(n)
+		r.consumed += int64(n)
+	}
+}
+
+//read until the first instance of sep,the returned data includes sep.
+//if sep is not found it returns the data read before the error,often io.EOF.
+func (r *Reader) ReadUntil(sep []byte) ([]byte, error) {
+	var data []byte
+	found, err := r.scan(sep, func(chunk []byte) {
+		data = append(data, chunk...)
+	})
+	if !found {
+		return data, err
+	}
+	data = append(data, sep...)
+	r.rd.Discard(len(sep))
+	r.consumed += int64(len(sep))
+	return data, nil
+}
+
+//skip the data before the first instance of sep and returns the number of bytes skipped,
+//sep itself is not consumed so the next read starts with it.
+//if sep is not found all data is skipped and the error is returned,often io.EOF.
+func (r *Reader) ScanTo(sep []byte) (int64, error) {
+	var skipped int64
+	_, err := r.scan(sep, func(chunk []byte) {
+		skipped += int64(len(chunk))
+	})
+	return skipped, err
+}
@@ -0,0 +1,150 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReader(t *testing.T) {
+	wr := NewBytesBuffer()
+	wr.WriteInt8(-8)
+	wr.WriteUint16BigEndian(1616)
+	wr.WriteInt32(-323232)
+	wr.WriteUint64BigEndian(6464646464)
+	wr.WriteFloat32(32.5)
+	wr.WriteFloat64BigEndian(64.25)
+	wr.WriteStringUint16("test str")
+	wr.WriteBytesUint32BigEndian([]byte("test bytes"))
+	wr.WriteBytes([]byte{0xab, 0xcd})
+	wr.WriteString("  pad  ")
+	rd := NewReader(iotest.OneByteReader(bytes.NewReader(wr.Bytes())))
+	i8, err := rd.ReadInt8()
+	if err != nil || i8 != -8 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", i8, err, -8)
+	}
+	u16, err := rd.ReadUint16BigEndian()
+	if err != nil || u16 != 1616 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", u16, err, 1616)
+	}
+	i32, err := rd.ReadInt32()
+	if err != nil || i32 != -323232 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", i32, err, -323232)
+	}
+	u64, err := rd.ReadUint64BigEndian()
+	if err != nil || u64 != 6464646464 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", u64, err, 6464646464)
+	}
+	f32, err := rd.ReadFloat32()
+	if err != nil || f32 != 32.5 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", f32, err, 32.5)
+	}
+	f64, err := rd.ReadFloat64BigEndian()
+	if err != nil || f64 != 64.25 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", f64, err, 64.25)
+	}
+	s, err := rd.ReadStringUint16()
+	if err != nil || s != "test str" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", s, err, "test str")
+	}
+	b, err := rd.ReadBytesUint32BigEndian()
+	if err != nil || string(b) != "test bytes" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", string(b), err, "test bytes")
+	}
+	h, err := rd.ReadHexToString(2)
+	if err != nil || h != "ABCD" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", h, err, "ABCD")
+	}
+	//Peek does not consume
+	p, err := rd.Peek(2)
+	if err != nil || string(p) != "  " {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", p, err, "  ")
+	}
+	s, err = rd.ReadStringTrimSpace(7)
+	if err != nil || s != "pad" {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", s, err, "pad")
+	}
+	if rd.Consumed() != int64(len(wr.Bytes())) {
+		t.Fatalf("unexpected value obtained; got %v want %v", rd.Consumed(), len(wr.Bytes()))
+	}
+	_, err = rd.ReadUint32()
+	if err != io.EOF {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.EOF)
+	}
+	//truncated data
+	rd = NewReader(iotest.HalfReader(bytes.NewReader([]byte{1, 2, 3})))
+	_, err = rd.ReadUint64()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	wr.Reset()
+	wr.WriteStringUint8("test str")
+	rd = NewReader(bytes.NewReader(wr.Bytes()[:5]))
+	_, err = rd.ReadStringUint8()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	//a corrupt length must not allocate the whole length up front
+	wr.Reset()
+	wr.WriteUint64(1 << 40)
+	rd = NewReader(bytes.NewReader(wr.Bytes()))
+	_, err = rd.ReadBytesUint64()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	//the error of the underlying Close is returned
+	closeErr := errors.New("close failed")
+	if err = NewReader(struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(nil), closerFunc(func() error { return closeErr })}).Close(); err != closeErr {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, closeErr)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func TestReaderSearch(t *testing.T) {
+	//sep straddles the 16 byte buffer several times
+	data := []byte("0123456789abcdefghij<sep>klmnopqrstuvwxyz0123456789<sep>tail<se")
+	rd := NewReaderSize(iotest.OneByteReader(bytes.NewReader(data)), 16)
+	b, err := rd.ReadUntil([]byte("<sep>"))
+	if err != nil || string(b) != "0123456789abcdefghij<sep>" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", b, err, "0123456789abcdefghij<sep>")
+	}
+	n, err := rd.ScanTo([]byte("<sep>"))
+	if err != nil || n != 26 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", n, err, 26)
+	}
+	if rd.Consumed() != 51 {
+		t.Fatalf("unexpected value obtained; got %v want %v", rd.Consumed(), 51)
+	}
+	err = rd.Skip(5)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	b, err = rd.ReadUntil([]byte("<sep>"))
+	if err != io.EOF || string(b) != "tail<se" {
+		t.Fatalf("unexpected value obtained; got %q %v want %q", b, err, "tail<se")
+	}
+	if rd.Consumed() != int64(len(data)) {
+		t.Fatalf("unexpected value obtained; got %v want %v", rd.Consumed(), len(data))
+	}
+	//sep longer than the buffer
+	_, err = rd.ScanTo(bytes.Repeat([]byte{1}, 17))
+	if err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+	//Skip past the end
+	rd = NewReader(bytes.NewReader(data))
+	err = rd.Skip(int64(len(data) + 1))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+}
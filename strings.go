@@ -0,0 +1,172 @@
+package iox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//StringEncoding is the encoding of the characters looked for by Strings.
+type StringEncoding int
+
+const (
+	StringASCII   StringEncoding = iota //single bytes
+	StringUTF8                          //runs of valid UTF-8
+	StringUTF16LE                       //2 byte units with surrogate pairs,aligned to beginPos
+	StringUTF16BE
+)
+
+func (e StringEncoding) String() string {
+	switch e {
+	case StringASCII:
+		return "ascii"
+	case StringUTF8:
+		return "utf8"
+	case StringUTF16LE:
+		return "utf16le"
+	case StringUTF16BE:
+		return "utf16be"
+	}
+	return fmt.Sprintf("StringEncoding(%d)", int(e))
+}
+
+//StringsOptions describes what Strings looks for.
+type StringsOptions struct {
+	Encoding StringEncoding
+	//reports whether a character belongs to a string,nil means printable ASCII and tab
+	//for StringASCII and the UTF-16 encodings,and unicode.IsPrint and tab for StringUTF8.
+	//StringASCII never passes bytes above 0x7f.
+	Printable func(rune) bool
+}
+
+func (o StringsOptions) printable(c rune) bool {
+	if o.Encoding == StringASCII && c >= utf8.RuneSelf {
+		return false
+	}
+	if o.Printable != nil {
+		return o.Printable(c)
+	}
+	if o.Encoding == StringUTF8 {
+		return unicode.IsPrint(c) || c == '\t'
+	}
+	return c >= 0x20 && c < 0x7f || c == '\t'
+}
+
+//FoundString is a run of characters found by Strings.
+type FoundString struct {
+	Offset   int64 //of the first byte
+	Length   int64 //in bytes of the encoding
+	Encoding StringEncoding
+	Text     string //as UTF-8
+}
+
+//Strings returns the runs of at least minLen printable characters in data like strings(1).
+func (r *ReadSeeker) Strings(minLen int, opts StringsOptions) ([]FoundString, error) {
+	return r.StringsGen(0, r.Size()-1, minLen, opts)
+}
+
+//StringsGen returns the runs of at least minLen printable characters between beginPos and endPos(both included).
+func (r *ReadSeeker) StringsGen(beginPos, endPos int64, minLen int, opts StringsOptions) ([]FoundString, error) {
+	var found []FoundString
+	err := r.StringsFunc(beginPos, endPos, minLen, opts, func(s FoundString) error {
+		found = append(found, s)
+		return nil
+	})
+	return found, err
+}
+
+//StringsFunc calls f with every run of at least minLen printable characters between beginPos and endPos
+//(both included) in order,so huge data is never held in memory,an error of f stops the search and is returned.
+//a run ends at endPos,a run of the UTF-16 encodings is aligned to beginPos,so strings at the other
+//alignment are found from beginPos+1.the position is left unchanged.
+func (r *ReadSeeker) StringsFunc(beginPos, endPos int64, minLen int, opts StringsOptions, f func(FoundString) error) error {
+	if opts.Encoding < StringASCII || opts.Encoding > StringUTF16BE {
+		return fmt.Errorf("the encoding %v is not known", opts.Encoding)
+	}
+	s, err := r.Section(beginPos, endPos)
+	if err != nil {
+		return err
+	}
+	if minLen < 1 {
+		minLen = 1
+	}
+	br := bufio.NewReaderSize(s.readSeeker, 64<<10)
+	var text []byte
+	var chars int
+	start, pos := beginPos, beginPos
+	flush := func() error {
+		if chars >= minLen {
+			if err := f(FoundString{Offset: start, Length: pos - start, Encoding: opts.Encoding, Text: string(text)}); err != nil {
+				return err
+			}
+		}
+		text, chars = text[:0], 0
+		return nil
+	}
+	for {
+		c, size, err := nextStringChar(br, opts.Encoding)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if c < 0 || !opts.printable(c) {
+			if err = flush(); err != nil {
+				return err
+			}
+			pos += int64(size)
+			start = pos
+			continue
+		}
+		text = utf8.AppendRune(text, c)
+		chars++
+		pos += int64(size)
+	}
+	return flush()
+}
+
+//read the next character,an invalid sequence returns -1 with the size of the bytes skipped.
+func nextStringChar(br *bufio.Reader, encoding StringEncoding) (rune, int, error) {
+	switch encoding {
+	case StringASCII:
+		b, err := br.ReadByte()
+		return rune(b), 1, err
+	case StringUTF8:
+		c, size, err := br.ReadRune()
+		if c == utf8.RuneError && size == 1 {
+			return -1, 1, nil
+		}
+		return c, size, err
+	}
+	unit := func(b []byte) rune {
+		if encoding == StringUTF16BE {
+			return rune(b[0])<<8 | rune(b[1])
+		}
+		return rune(b[1])<<8 | rune(b[0])
+	}
+	b, err := br.Peek(4)
+	if err != nil && err != io.EOF || len(b) == 0 {
+		return 0, 0, err
+	}
+	if len(b) == 1 {
+		br.Discard(1)
+		return -1, 1, nil
+	}
+	c := unit(b)
+	if utf16.IsSurrogate(c) {
+		if len(b) == 4 {
+			if pair := utf16.DecodeRune(c, unit(b[2:])); pair != unicode.ReplacementChar {
+				br.Discard(4)
+				return pair, 4, nil
+			}
+		}
+		br.Discard(2)
+		return -1, 2, nil
+	}
+	br.Discard(2)
+	return c, 2, nil
+}
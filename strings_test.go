@@ -0,0 +1,82 @@
+package iox
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"unicode"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, bigEndian bool) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+func TestStrings(t *testing.T) {
+	//hello at 2,the UTF-16LE text at 8,the UTF-8 text at 33 and the UTF-16BE text at 56
+	data := concat([]byte("\x00\x01hello\x00"), encodeUTF16("Wide 𝄞 text", false), []byte("\xff"),
+		[]byte("日本語テキスト"), []byte{0x00, 0x01}, encodeUTF16("BE!!", true), []byte("ab\x00"))
+	rd := NewReadSeekerFromBytes(data)
+	rd.MoveTo(3)
+	for _, c := range []struct {
+		opts StringsOptions
+		want []FoundString
+	}{
+		{StringsOptions{}, []FoundString{{2, 5, StringASCII, "hello"}}},
+		{StringsOptions{Encoding: StringUTF8}, []FoundString{{2, 5, StringUTF8, "hello"}, {33, 21, StringUTF8, "日本語テキスト"}}},
+		//"o\x00" is a character as well
+		{StringsOptions{Encoding: StringUTF16LE}, []FoundString{{6, 12, StringUTF16LE, "oWide "}, {22, 10, StringUTF16LE, " text"}}},
+		{StringsOptions{Encoding: StringUTF16BE}, []FoundString{{56, 8, StringUTF16BE, "BE!!"}}},
+		{StringsOptions{Encoding: StringUTF8, Printable: func(c rune) bool { return c > 0x7f }}, []FoundString{{33, 21, StringUTF8, "日本語テキスト"}}},
+	} {
+		found, err := rd.Strings(4, c.opts)
+		if err != nil {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		if !reflect.DeepEqual(found, c.want) {
+			t.Fatalf("%v: unexpected value obtained; got %+v want %+v", c.opts.Encoding, found, c.want)
+		}
+	}
+	//a run is cut by the range
+	found, err := rd.StringsGen(3, 5, 2, StringsOptions{})
+	if err != nil || !reflect.DeepEqual(found, []FoundString{{3, 3, StringASCII, "ell"}}) {
+		t.Fatalf("unexpected value obtained; got %+v %v want %v", found, err, "ell")
+	}
+	//a surrogate pair
+	found, _ = rd.StringsGen(8, 31, 4, StringsOptions{Encoding: StringUTF16LE, Printable: unicode.IsPrint})
+	if !reflect.DeepEqual(found, []FoundString{{8, 24, StringUTF16LE, "Wide 𝄞 text"}}) {
+		t.Fatalf("unexpected value obtained; got %+v want %v", found, "Wide 𝄞 text")
+	}
+	//the other UTF-16 alignment,the odd byte at the end is not a character
+	found, _ = rd.StringsGen(7, 17, 3, StringsOptions{Encoding: StringUTF16BE})
+	if !reflect.DeepEqual(found, []FoundString{{7, 10, StringUTF16BE, "Wide "}}) {
+		t.Fatalf("unexpected value obtained; got %+v want %v", found, "Wide at 7")
+	}
+	if pos, _ := rd.CurPos(); pos != 3 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 3)
+	}
+	//the callback stops the search
+	errStop := errors.New("stop")
+	calls := 0
+	err = rd.StringsFunc(0, rd.Size()-1, 1, StringsOptions{}, func(s FoundString) error {
+		calls++
+		return errStop
+	})
+	if err != errStop || calls != 1 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v %v", err, calls, errStop, 1)
+	}
+	if _, err = rd.StringsGen(0, rd.Size(), 4, StringsOptions{}); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+	if _, err = rd.Strings(4, StringsOptions{Encoding: 9}); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+}
@@ -0,0 +1,661 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+var ErrInvalidTIFF = errors.New("the TIFF data is not valid")
+
+//TIFFType is the field type of an IFD entry.
+type TIFFType uint16
+
+const (
+	TIFFTypeByte      TIFFType = 1  //[]uint8
+	TIFFTypeASCII     TIFFType = 2  //string
+	TIFFTypeShort     TIFFType = 3  //[]uint16
+	TIFFTypeLong      TIFFType = 4  //[]uint32
+	TIFFTypeRational  TIFFType = 5  //[]TIFFRational
+	TIFFTypeSByte     TIFFType = 6  //[]int8
+	TIFFTypeUndefined TIFFType = 7  //[]byte
+	TIFFTypeSShort    TIFFType = 8  //[]int16
+	TIFFTypeSLong     TIFFType = 9  //[]int32
+	TIFFTypeSRational TIFFType = 10 //[]TIFFSRational
+	TIFFTypeFloat     TIFFType = 11 //[]float32
+	TIFFTypeDouble    TIFFType = 12 //[]float64
+	TIFFTypeIFD       TIFFType = 13 //[]uint32,the offsets of sub IFDs
+)
+
+var tiffTypeNames = []string{"", "BYTE", "ASCII", "SHORT", "LONG", "RATIONAL", "SBYTE", "UNDEFINED", "SSHORT", "SLONG", "SRATIONAL", "FLOAT", "DOUBLE", "IFD"}
+
+func (t TIFFType) String() string {
+	if t > 0 && int(t) < len(tiffTypeNames) {
+		return tiffTypeNames[t]
+	}
+	return fmt.Sprintf("TIFFType(%d)", uint16(t))
+}
+
+//Size returns the size of a value of the type in bytes,0 for an unknown type.
+func (t TIFFType) Size() int {
+	switch t {
+	case TIFFTypeByte, TIFFTypeASCII, TIFFTypeSByte, TIFFTypeUndefined:
+		return 1
+	case TIFFTypeShort, TIFFTypeSShort:
+		return 2
+	case TIFFTypeLong, TIFFTypeSLong, TIFFTypeFloat, TIFFTypeIFD:
+		return 4
+	case TIFFTypeRational, TIFFTypeSRational, TIFFTypeDouble:
+		return 8
+	}
+	return 0
+}
+
+//the tags whose values are the offsets of sub IFDs,with the names of these IFDs.
+const (
+	TIFFTagSubIFDs    = 0x014a
+	TIFFTagExifIFD    = 0x8769
+	TIFFTagGPSIFD     = 0x8825
+	TIFFTagInteropIFD = 0xa005
+)
+
+var tiffPointerNames = map[uint16]string{
+	TIFFTagSubIFDs:    "SubIFD",
+	TIFFTagExifIFD:    "Exif",
+	TIFFTagGPSIFD:     "GPS",
+	TIFFTagInteropIFD: "Interop",
+}
+
+//TIFFRational is an unsigned fraction.
+type TIFFRational struct {
+	Num, Den uint32
+}
+
+//Float64 returns the value of the fraction,a zero denominator gives an infinity or NaN.
+func (r TIFFRational) Float64() float64 {
+	return float64(r.Num) / float64(r.Den)
+}
+
+//TIFFSRational is a signed fraction.
+type TIFFSRational struct {
+	Num, Den int32
+}
+
+//Float64 returns the value of the fraction,a zero denominator gives an infinity or NaN.
+func (r TIFFSRational) Float64() float64 {
+	return float64(r.Num) / float64(r.Den)
+}
+
+//TIFF is the byte order and the chain of IFDs of a TIFF file or of the EXIF data of a JPEG or PNG file.
+type TIFF struct {
+	BigEndian bool       //"MM",otherwise "II"
+	IFDs      []*TIFFIFD //IFD0,IFD1 and so on,IFD1 holds the thumbnail of EXIF data
+}
+
+//TIFFIFD is an image file directory.
+type TIFFIFD struct {
+	Name    string //IFD0,IFD1,Exif,GPS,Interop,SubIFD0 and so on,set by ReadTIFF
+	Offset  int64  //in the TIFF data,set by ReadTIFF
+	Entries []*TIFFEntry
+}
+
+//TIFFEntry is an entry of an IFD.
+type TIFFEntry struct {
+	Tag  uint16
+	Type TIFFType
+	//the number of values,the length of an ASCII string counts its NUL,WriteTIFF sets it from Value
+	Count uint32
+	//the values in the Go type noted by the TIFFType constants,an ASCII string is without its last NUL,
+	//an entry of an unknown type keeps the 4 bytes of its value field as they are
+	Value interface{}
+	//of the value in the TIFF data,which is inside the entry if the value fits in 4 bytes,set by ReadTIFF
+	Offset int64
+	//the sub IFDs of a pointer entry,such as TIFFTagExifIFD or an entry of TIFFTypeIFD,
+	//WriteTIFF writes them and sets Value to their offsets
+	IFDs []*TIFFIFD
+}
+
+//Entry returns the entry of the tag,nil if there is none.
+func (d *TIFFIFD) Entry(tag uint16) *TIFFEntry {
+	for _, e := range d.Entries {
+		if e.Tag == tag {
+			return e
+		}
+	}
+	return nil
+}
+
+//IFD returns the first IFD of the name depth first,such as "Exif" or "GPS",nil if there is none.
+func (t *TIFF) IFD(name string) *TIFFIFD {
+	var find func(ifds []*TIFFIFD) *TIFFIFD
+	find = func(ifds []*TIFFIFD) *TIFFIFD {
+		for _, d := range ifds {
+			if d.Name == name {
+				return d
+			}
+			for _, e := range d.Entries {
+				if found := find(e.IFDs); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	return find(t.IFDs)
+}
+
+//Ints returns the values of an entry of an integer type,nil for other types.
+func (e *TIFFEntry) Ints() []int64 {
+	var out []int64
+	switch v := e.Value.(type) {
+	case []uint8:
+		if e.Type != TIFFTypeByte {
+			return nil
+		}
+		for _, x := range v {
+			out = append(out, int64(x))
+		}
+	case []uint16:
+		for _, x := range v {
+			out = append(out, int64(x))
+		}
+	case []uint32:
+		for _, x := range v {
+			out = append(out, int64(x))
+		}
+	case []int8:
+		for _, x := range v {
+			out = append(out, int64(x))
+		}
+	case []int16:
+		for _, x := range v {
+			out = append(out, int64(x))
+		}
+	case []int32:
+		for _, x := range v {
+			out = append(out, int64(x))
+		}
+	}
+	return out
+}
+
+//Floats returns the values of an entry of a numeric type,rationals included,nil for other types.
+func (e *TIFFEntry) Floats() []float64 {
+	var out []float64
+	switch v := e.Value.(type) {
+	case []TIFFRational:
+		for _, x := range v {
+			out = append(out, x.Float64())
+		}
+	case []TIFFSRational:
+		for _, x := range v {
+			out = append(out, x.Float64())
+		}
+	case []float32:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []float64:
+		out = append(out, v...)
+	default:
+		for _, x := range e.Ints() {
+			out = append(out, float64(x))
+		}
+	}
+	return out
+}
+
+//Text returns the string of an ASCII entry,with the NULs at the end removed.
+func (e *TIFFEntry) Text() string {
+	s, _ := e.Value.(string)
+	for len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+//the deepest nesting of sub IFDs followed by ReadTIFF.
+const maxTIFFDepth = 16
+
+//tiffReader reads the IFDs of the TIFF data in r.
+type tiffReader struct {
+	r         *ReadSeeker
+	bigEndian bool
+	visited   map[int64]bool //the offsets of the IFDs read,to detect cycles
+	decoded   int64          //the bytes of the values read from outside the entries
+}
+
+//ReadTIFF reads the header and the IFDs of the TIFF data at the current position to the end of the data,
+//offsets are relative to the header,the position is left unchanged.the byte order is taken from the header,
+//and the IFDs pointed to by the entries of TIFFTagSubIFDs,TIFFTagExifIFD,TIFFTagGPSIFD,TIFFTagInteropIFD
+//and TIFFTypeIFD are read into the IFDs of the entries.an IFD that is pointed to twice returns ErrInvalidTIFF,
+//so a cyclic chain of IFDs can't loop.the values stored outside the entries may take no more bytes in total
+//than the data,entries pointing again and again at the same bytes return ErrInvalidTIFF.for EXIF data in a JPEG file,read the APP1 segment after "Exif\x00\x00".
+func (r *ReadSeeker) ReadTIFF() (*TIFF, error) {
+	defer r.traceEnter("ReadTIFF")()
+	pos, err := r.CurPos()
+	if err != nil {
+		return nil, err
+	}
+	if r.Size()-pos < 8 {
+		return nil, fmt.Errorf("the TIFF header needs 8 bytes,but only %v bytes left: %w", r.Size()-pos, io.ErrUnexpectedEOF)
+	}
+	data, err := r.Section(pos, r.Size()-1)
+	if err != nil {
+		return nil, err
+	}
+	header, err := data.bytesAt(0, 8)
+	if err != nil {
+		return nil, err
+	}
+	t := &TIFF{}
+	switch string(header[:2]) {
+	case "II":
+	case "MM":
+		t.BigEndian = true
+	default:
+		return nil, fmt.Errorf("the byte order %q is neither II nor MM: %w", header[:2], ErrInvalidTIFF)
+	}
+	if magic := bytesToUint(header[2:4], t.BigEndian); magic != 42 {
+		return nil, fmt.Errorf("the magic number is %v,not 42: %w", magic, ErrInvalidTIFF)
+	}
+	tr := &tiffReader{r: data, bigEndian: t.BigEndian, visited: map[int64]bool{}}
+	next := int64(bytesToUint(header[4:8], t.BigEndian))
+	for i := 0; next != 0; i++ {
+		ifd, n, err := tr.readIFD(next, fmt.Sprint("IFD", i), 0)
+		if err != nil {
+			return nil, err
+		}
+		t.IFDs = append(t.IFDs, ifd)
+		next = n
+	}
+	r.traceValue(fmt.Sprintf("%v IFDs", len(t.IFDs)))
+	return t, nil
+}
+
+//read the IFD at the offset and its sub IFDs,returns the IFD and the offset of the next IFD.
+func (tr *tiffReader) readIFD(offset int64, name string, depth int) (*TIFFIFD, int64, error) {
+	if tr.visited[offset] {
+		return nil, 0, fmt.Errorf("the IFD at offset %v is pointed to twice: %w", offset, ErrInvalidTIFF)
+	}
+	if depth > maxTIFFDepth {
+		return nil, 0, fmt.Errorf("the IFD at offset %v is nested deeper than %v: %w", offset, maxTIFFDepth, ErrInvalidTIFF)
+	}
+	tr.visited[offset] = true
+	size := tr.r.Size()
+	if offset < 8 || size-offset < 2 {
+		return nil, 0, fmt.Errorf("the IFD %v at offset %v is outside the data of %v bytes: %w", name, offset, size, ErrInvalidTIFF)
+	}
+	bt, err := tr.r.bytesAt(offset, 2)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := int64(bytesToUint(bt, tr.bigEndian))
+	if size-offset-2 < n*12+4 {
+		return nil, 0, fmt.Errorf("the IFD %v at offset %v has %v entries,but only %v bytes left: %w", name, offset, n, size-offset-2, io.ErrUnexpectedEOF)
+	}
+	if bt, err = tr.r.bytesAt(offset+2, n*12+4); err != nil {
+		return nil, 0, err
+	}
+	ifd := &TIFFIFD{Name: name, Offset: offset, Entries: make([]*TIFFEntry, 0, n)}
+	for i := int64(0); i < n; i++ {
+		e, err := tr.readEntry(bt[i*12:i*12+12], offset+2+i*12)
+		if err != nil {
+			return nil, 0, err
+		}
+		ifd.Entries = append(ifd.Entries, e)
+	}
+	for _, e := range ifd.Entries {
+		base, ok := tiffPointerNames[e.Tag]
+		if !ok && e.Type != TIFFTypeIFD {
+			continue
+		}
+		if !ok {
+			base = "SubIFD"
+		}
+		offsets, ok := e.Value.([]uint32)
+		if !ok {
+			continue //a pointer tag of another type is left alone
+		}
+		for i, sub := range offsets {
+			subName := base
+			if base == "SubIFD" || len(offsets) > 1 {
+				subName = fmt.Sprint(base, i)
+			}
+			subIFD, _, err := tr.readIFD(int64(sub), subName, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			e.IFDs = append(e.IFDs, subIFD)
+		}
+	}
+	return ifd, int64(bytesToUint(bt[n*12:], tr.bigEndian)), nil
+}
+
+//read the entry of 12 bytes at pos,the value is read where it is stored.
+func (tr *tiffReader) readEntry(bt []byte, pos int64) (*TIFFEntry, error) {
+	e := &TIFFEntry{
+		Tag:    uint16(bytesToUint(bt[:2], tr.bigEndian)),
+		Type:   TIFFType(bytesToUint(bt[2:4], tr.bigEndian)),
+		Count:  uint32(bytesToUint(bt[4:8], tr.bigEndian)),
+		Offset: pos + 8,
+	}
+	typeSize := e.Type.Size()
+	if typeSize == 0 {
+		e.Value = append([]byte{}, bt[8:]...)
+		return e, nil
+	}
+	n := int64(e.Count) * int64(typeSize)
+	var value []byte
+	if n > 4 {
+		e.Offset = int64(bytesToUint(bt[8:], tr.bigEndian))
+		if left := tr.r.Size() - e.Offset; e.Offset < 0 || left < n {
+			return nil, fmt.Errorf("the value of the tag 0x%04x at position %v has %v bytes at offset %v,beyond the data of %v bytes: %w", e.Tag, pos, n, e.Offset, tr.r.Size(), io.ErrUnexpectedEOF)
+		}
+		//the values of valid data don't overlap,so they fit in the data together
+		if tr.decoded += n; tr.decoded > tr.r.Size() {
+			return nil, fmt.Errorf("the value of the tag 0x%04x at position %v makes the values %v bytes,more than the data of %v bytes: %w", e.Tag, pos, tr.decoded, tr.r.Size(), ErrInvalidTIFF)
+		}
+		var err error
+		if value, err = tr.r.bytesAt(e.Offset, n); err != nil {
+			return nil, err
+		}
+	} else {
+		value = bt[8 : 8+n]
+	}
+	e.Value = decodeTIFFValue(e.Type, value, int(e.Count), tr.bigEndian)
+	return e, nil
+}
+
+//decode n values of a known type.
+func decodeTIFFValue(typ TIFFType, b []byte, n int, bigEndian bool) interface{} {
+	u := func(i, size int) uint64 {
+		return bytesToUint(b[i*size:i*size+size], bigEndian)
+	}
+	switch typ {
+	case TIFFTypeByte, TIFFTypeUndefined:
+		return append([]byte{}, b...)
+	case TIFFTypeASCII:
+		s := string(b)
+		if len(s) > 0 && s[len(s)-1] == 0 {
+			s = s[:len(s)-1]
+		}
+		return s
+	case TIFFTypeShort:
+		v := make([]uint16, n)
+		for i := range v {
+			v[i] = uint16(u(i, 2))
+		}
+		return v
+	case TIFFTypeLong, TIFFTypeIFD:
+		v := make([]uint32, n)
+		for i := range v {
+			v[i] = uint32(u(i, 4))
+		}
+		return v
+	case TIFFTypeRational:
+		v := make([]TIFFRational, n)
+		for i := range v {
+			v[i] = TIFFRational{uint32(u(2*i, 4)), uint32(u(2*i+1, 4))}
+		}
+		return v
+	case TIFFTypeSByte:
+		v := make([]int8, n)
+		for i := range v {
+			v[i] = int8(b[i])
+		}
+		return v
+	case TIFFTypeSShort:
+		v := make([]int16, n)
+		for i := range v {
+			v[i] = int16(u(i, 2))
+		}
+		return v
+	case TIFFTypeSLong:
+		v := make([]int32, n)
+		for i := range v {
+			v[i] = int32(u(i, 4))
+		}
+		return v
+	case TIFFTypeSRational:
+		v := make([]TIFFSRational, n)
+		for i := range v {
+			v[i] = TIFFSRational{int32(u(2*i, 4)), int32(u(2*i+1, 4))}
+		}
+		return v
+	case TIFFTypeFloat:
+		v := make([]float32, n)
+		for i := range v {
+			v[i] = math.Float32frombits(uint32(u(i, 4)))
+		}
+		return v
+	}
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = math.Float64frombits(u(i, 8))
+	}
+	return v
+}
+
+//encode the value of an entry,returns the bytes and the count,it panics if the value does not match the type.
+func encodeTIFFValue(e *TIFFEntry, bigEndian bool) ([]byte, uint32) {
+	var b []byte
+	put := func(x uint64, size int) {
+		b = append(b, uintToBytes(x, size, bigEndian)...)
+	}
+	mismatch := func() {
+		panic(fmt.Sprintf("the value %T of the TIFF tag 0x%04x does not match the type %v", e.Value, e.Tag, e.Type))
+	}
+	n := 0
+	switch v := e.Value.(type) {
+	case []byte:
+		if e.Type.Size() == 0 {
+			if len(v) != 4 {
+				panic(fmt.Sprintf("the value of the TIFF tag 0x%04x of the unknown type %v is not 4 bytes", e.Tag, e.Type))
+			}
+			return append([]byte{}, v...), e.Count
+		}
+		if e.Type != TIFFTypeByte && e.Type != TIFFTypeUndefined {
+			mismatch()
+		}
+		b, n = append(b, v...), len(v)
+	case string:
+		if e.Type != TIFFTypeASCII {
+			mismatch()
+		}
+		b = append([]byte(v), 0)
+		n = len(b)
+	case []uint16:
+		if e.Type != TIFFTypeShort {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(x), 2)
+		}
+		n = len(v)
+	case []uint32:
+		if e.Type != TIFFTypeLong && e.Type != TIFFTypeIFD {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(x), 4)
+		}
+		n = len(v)
+	case []TIFFRational:
+		if e.Type != TIFFTypeRational {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(x.Num), 4)
+			put(uint64(x.Den), 4)
+		}
+		n = len(v)
+	case []int8:
+		if e.Type != TIFFTypeSByte {
+			mismatch()
+		}
+		for _, x := range v {
+			b = append(b, byte(x))
+		}
+		n = len(v)
+	case []int16:
+		if e.Type != TIFFTypeSShort {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(uint16(x)), 2)
+		}
+		n = len(v)
+	case []int32:
+		if e.Type != TIFFTypeSLong {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(uint32(x)), 4)
+		}
+		n = len(v)
+	case []TIFFSRational:
+		if e.Type != TIFFTypeSRational {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(uint32(x.Num)), 4)
+			put(uint64(uint32(x.Den)), 4)
+		}
+		n = len(v)
+	case []float32:
+		if e.Type != TIFFTypeFloat {
+			mismatch()
+		}
+		for _, x := range v {
+			put(uint64(math.Float32bits(x)), 4)
+		}
+		n = len(v)
+	case []float64:
+		if e.Type != TIFFTypeDouble {
+			mismatch()
+		}
+		for _, x := range v {
+			put(math.Float64bits(x), 8)
+		}
+		n = len(v)
+	default:
+		mismatch()
+	}
+	return b, uint32(n)
+}
+
+//tiffWriter lays out the IFDs of a TIFF in a buffer,offsets are relative to its start.
+type tiffWriter struct {
+	buf       *Writer
+	bigEndian bool
+	written   map[*TIFFIFD]bool
+}
+
+//WriteTIFF writes the header and the IFDs of t in its byte order,every IFD is followed by the values that
+//don't fit in its entries and by its sub IFDs,entries are sorted by tag and everything is word aligned.
+//the entries with IFDs get the offsets of these IFDs as their Value,and TIFFTypeLong as their Type
+//unless it is TIFFTypeIFD,the Count of every entry is set from its Value,
+//other values are written as they are,so offsets such as StripOffsets must be fixed by the caller.
+//offsets are relative to the position of the header,it panics if an IFD is written twice.
+func (w *Writer) WriteTIFF(t *TIFF) {
+	tw := &tiffWriter{buf: NewBytesBuffer(), bigEndian: t.BigEndian, written: map[*TIFFIFD]bool{}}
+	if t.BigEndian {
+		tw.buf.WriteString("MM")
+	} else {
+		tw.buf.WriteString("II")
+	}
+	tw.putUint(42, 2)
+	nextPos := tw.buf.pos
+	tw.putUint(0, 4)
+	for _, ifd := range t.IFDs {
+		offset, next := tw.writeIFD(ifd)
+		tw.patch(nextPos, offset)
+		nextPos = next
+	}
+	w.write(tw.buf.Bytes())
+}
+
+func (tw *tiffWriter) putUint(x uint64, size int) {
+	tw.buf.write(uintToBytes(x, size, tw.bigEndian))
+}
+
+//back-fill the offset of 4 bytes at pos.
+func (tw *tiffWriter) patch(pos, offset int64) {
+	if offset > math.MaxUint32 {
+		panic(fmt.Sprintf("the TIFF offset %v does not fit 4 bytes", offset))
+	}
+	tw.buf.writeAt(uintToBytes(uint64(offset), 4, tw.bigEndian), pos)
+}
+
+//pad to a word boundary.
+func (tw *tiffWriter) align() {
+	tw.buf.WriteZeros(int(paddingLen(tw.buf.pos, 2)))
+}
+
+//write an IFD with its values and sub IFDs,returns its offset and the position of its next IFD offset.
+func (tw *tiffWriter) writeIFD(ifd *TIFFIFD) (int64, int64) {
+	if tw.written[ifd] {
+		panic(fmt.Sprintf("the TIFF IFD %q is written twice", ifd.Name))
+	}
+	tw.written[ifd] = true
+	if len(ifd.Entries) > math.MaxUint16 {
+		panic(fmt.Sprintf("the TIFF IFD %q has %v entries,more than 65535", ifd.Name, len(ifd.Entries)))
+	}
+	entries := append([]*TIFFEntry{}, ifd.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Tag < entries[j].Tag })
+	for _, e := range entries {
+		if len(e.IFDs) > 0 {
+			if e.Type != TIFFTypeIFD {
+				e.Type = TIFFTypeLong
+			}
+			e.Value = make([]uint32, len(e.IFDs)) //filled in when the sub IFDs are written
+		}
+	}
+	tw.align()
+	offset := tw.buf.pos
+	tw.putUint(uint64(len(entries)), 2)
+	type outside struct {
+		fieldPos int64
+		value    []byte
+	}
+	var values []outside
+	valuePos := make([]int64, len(entries)) //where the value of each entry goes
+	for i, e := range entries {
+		value, count := encodeTIFFValue(e, tw.bigEndian)
+		e.Count = count
+		tw.putUint(uint64(e.Tag), 2)
+		tw.putUint(uint64(e.Type), 2)
+		tw.putUint(uint64(count), 4)
+		valuePos[i] = tw.buf.pos
+		if len(value) <= 4 {
+			tw.buf.write(value)
+			tw.buf.WriteZeros(4 - len(value))
+		} else {
+			values = append(values, outside{tw.buf.pos, value})
+			tw.putUint(0, 4)
+		}
+	}
+	next := tw.buf.pos
+	tw.putUint(0, 4)
+	for _, v := range values {
+		tw.align()
+		tw.patch(v.fieldPos, tw.buf.pos)
+		for i := range valuePos {
+			if valuePos[i] == v.fieldPos {
+				valuePos[i] = tw.buf.pos
+			}
+		}
+		tw.buf.write(v.value)
+	}
+	for i, e := range entries {
+		for j, sub := range e.IFDs {
+			subOffset, _ := tw.writeIFD(sub)
+			tw.patch(valuePos[i]+4*int64(j), subOffset)
+			e.Value.([]uint32)[j] = uint32(subOffset)
+		}
+	}
+	return offset, next
+}
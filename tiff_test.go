@@ -0,0 +1,190 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+//a little endian TIFF with a Make,an Orientation and an Exif IFD holding an ExposureTime and a Flash.
+func testTIFFData() []byte {
+	w := NewBytesBuffer()
+	w.WriteString("II")
+	w.WriteUint16(42)
+	w.WriteUint32(8)
+	//IFD0 at 8
+	w.WriteUint16(3)
+	w.WriteUint16(0x010f) //Make,6 bytes at 50
+	w.WriteUint16(2)
+	w.WriteUint32(6)
+	w.WriteUint32(50)
+	w.WriteUint16(0x0112) //Orientation,inline
+	w.WriteUint16(3)
+	w.WriteUint32(1)
+	w.WriteUint16(6)
+	w.WriteUint16(0)
+	w.WriteUint16(TIFFTagExifIFD) //the Exif IFD at 56
+	w.WriteUint16(4)
+	w.WriteUint32(1)
+	w.WriteUint32(56)
+	w.WriteUint32(0)
+	w.WriteString("Canon\x00")
+	//the Exif IFD at 56
+	w.WriteUint16(2)
+	w.WriteUint16(0x829a) //ExposureTime,a rational at 86
+	w.WriteUint16(5)
+	w.WriteUint32(1)
+	w.WriteUint32(86)
+	w.WriteUint16(0x9209) //Flash
+	w.WriteUint16(3)
+	w.WriteUint32(1)
+	w.WriteUint32(16)
+	w.WriteUint32(0)
+	w.WriteUint32(1)
+	w.WriteUint32(125)
+	return w.Bytes()
+}
+
+func TestTIFFRead(t *testing.T) {
+	data := append([]byte("Exif\x00\x00"), testTIFFData()...)
+	r := NewReadSeekerFromBytes(data)
+	r.MoveTo(6)
+	tiff, err := r.ReadTIFF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos, _ := r.CurPos(); pos != 6 {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, 6)
+	}
+	if tiff.BigEndian || len(tiff.IFDs) != 1 || tiff.IFDs[0].Name != "IFD0" || len(tiff.IFDs[0].Entries) != 3 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", tiff, "one IFD of 3 entries")
+	}
+	ifd0 := tiff.IFDs[0]
+	if maker := ifd0.Entry(0x010f); maker.Text() != "Canon" || maker.Count != 6 || maker.Offset != 50 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", maker, "Canon")
+	}
+	if orientation := ifd0.Entry(0x0112); !reflect.DeepEqual(orientation.Ints(), []int64{6}) || orientation.Offset != 30 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", orientation, 6)
+	}
+	exif := tiff.IFD("Exif")
+	if exif == nil || exif.Offset != 56 || ifd0.Entry(TIFFTagExifIFD).IFDs[0] != exif {
+		t.Fatalf("unexpected value obtained; got %+v want %v", exif, "the Exif IFD at 56")
+	}
+	exposure := exif.Entry(0x829a)
+	if !reflect.DeepEqual(exposure.Value, []TIFFRational{{1, 125}}) || exposure.Floats()[0] != 0.008 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", exposure, "1/125")
+	}
+	if tiff.IFD("GPS") != nil || ifd0.Entry(0x9999) != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", tiff.IFD("GPS"), nil)
+	}
+}
+
+func TestTIFFWrite(t *testing.T) {
+	data := testTIFFData()
+	tiff, err := NewReadSeekerFromBytes(data).ReadTIFF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the same layout is written back
+	w := NewBytesBuffer()
+	w.WriteTIFF(tiff)
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Fatalf("unexpected value obtained; got %x want %x", w.Bytes(), data)
+	}
+	//add a GPS IFD with every type and write it big endian
+	gps := &TIFFIFD{Entries: []*TIFFEntry{
+		{Tag: 2, Type: TIFFTypeRational, Value: []TIFFRational{{35, 1}, {39, 1}, {2960, 100}}},
+		{Tag: 1, Type: TIFFTypeASCII, Value: "N"},
+		{Tag: 100, Type: TIFFTypeByte, Value: []byte{1, 2, 3, 4, 5}},
+		{Tag: 101, Type: TIFFTypeSByte, Value: []int8{-1}},
+		{Tag: 102, Type: TIFFTypeSShort, Value: []int16{-2, 3}},
+		{Tag: 103, Type: TIFFTypeSLong, Value: []int32{-4}},
+		{Tag: 104, Type: TIFFTypeSRational, Value: []TIFFSRational{{-1, 2}}},
+		{Tag: 105, Type: TIFFTypeFloat, Value: []float32{1.5}},
+		{Tag: 106, Type: TIFFTypeDouble, Value: []float64{-2.25}},
+		{Tag: 107, Type: TIFFTypeUndefined, Value: []byte("0230")},
+		{Tag: 108, Type: TIFFTypeLong, Value: []uint32{7, 8}},
+		{Tag: 109, Type: 99, Count: 3, Value: []byte{1, 2, 3, 4}},
+	}}
+	tiff.BigEndian = true
+	tiff.IFDs[0].Entries = append(tiff.IFDs[0].Entries, &TIFFEntry{Tag: TIFFTagGPSIFD, IFDs: []*TIFFIFD{gps}})
+	tiff.IFDs = append(tiff.IFDs, &TIFFIFD{Entries: []*TIFFEntry{{Tag: 0x0103, Type: TIFFTypeShort, Value: []uint16{6}}}})
+	w = NewBytesBuffer()
+	w.WriteTIFF(tiff)
+	got, err := NewReadSeekerFromBytes(w.Bytes()).ReadTIFF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.BigEndian || len(got.IFDs) != 2 || got.IFDs[1].Name != "IFD1" || got.IFD("Exif") == nil {
+		t.Fatalf("unexpected value obtained; got %+v want %v", got, "IFD0 and IFD1")
+	}
+	gotGPS := got.IFD("GPS")
+	if gotGPS == nil || len(gotGPS.Entries) != len(gps.Entries) {
+		t.Fatalf("unexpected value obtained; got %+v want %v", gotGPS, gps)
+	}
+	for i, e := range gotGPS.Entries {
+		//the entries are sorted by tag
+		want := gps.Entries[(i+1)%2]
+		if i >= 2 {
+			want = gps.Entries[i]
+		}
+		if e.Tag != want.Tag || e.Type != want.Type || e.Count != want.Count || !reflect.DeepEqual(e.Value, want.Value) {
+			t.Fatalf("unexpected value obtained; got %+v want %+v", e, want)
+		}
+	}
+	if lat := gotGPS.Entry(2).Floats(); !reflect.DeepEqual(lat, []float64{35, 39, 29.6}) {
+		t.Fatalf("unexpected value obtained; got %v want %v", lat, []float64{35, 39, 29.6})
+	}
+	if ints := gotGPS.Entry(102).Ints(); !reflect.DeepEqual(ints, []int64{-2, 3}) {
+		t.Fatalf("unexpected value obtained; got %v want %v", ints, []int64{-2, 3})
+	}
+	if gotGPS.Entry(107).Ints() != nil || gotGPS.Entry(1).Count != 2 || gotGPS.Entry(1).Text() != "N" {
+		t.Fatalf("unexpected value obtained; got %+v want %v", gotGPS.Entry(1), "N")
+	}
+	if offset := got.IFDs[0].Entry(TIFFTagGPSIFD).Value.([]uint32)[0]; int64(offset) != gotGPS.Offset || offset%2 != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", offset, gotGPS.Offset)
+	}
+}
+
+func TestTIFFErrors(t *testing.T) {
+	cyclic := testTIFFData()
+	cyclic[42] = 8 //the Exif IFD pointer points to IFD0
+	loop := testTIFFData()
+	loop[46] = 8 //the next IFD of IFD0 is IFD0
+	beyond := testTIFFData()
+	beyond[18] = 0xf0 //the Make is beyond the data
+	//10 entries share a value of 100 bytes,decoding it for each is 1000 bytes out of 234
+	overlap := NewBytesBuffer()
+	overlap.WriteString("II")
+	overlap.WriteUint16(42)
+	overlap.WriteUint32(8)
+	overlap.WriteUint16(10)
+	for i := 0; i < 10; i++ {
+		overlap.WriteUint16(uint16(0x8000 + i))
+		overlap.WriteUint16(uint16(TIFFTypeUndefined))
+		overlap.WriteUint32(100)
+		overlap.WriteUint32(134)
+	}
+	overlap.WriteUint32(0)
+	overlap.WriteBytes(make([]byte, 100))
+	for _, c := range []struct {
+		data []byte
+		want error
+	}{
+		{[]byte("II*"), io.ErrUnexpectedEOF},
+		{[]byte("IM*\x00\x08\x00\x00\x00"), ErrInvalidTIFF},
+		{[]byte("II+\x00\x08\x00\x00\x00"), ErrInvalidTIFF},
+		{[]byte("MM\x00*\x00\x00\x00\x08\x00\x02"), io.ErrUnexpectedEOF},
+		{[]byte("MM\x00*\x00\x00\x01\x00"), ErrInvalidTIFF},
+		{cyclic, ErrInvalidTIFF},
+		{loop, ErrInvalidTIFF},
+		{beyond, io.ErrUnexpectedEOF},
+		{overlap.Bytes(), ErrInvalidTIFF},
+	} {
+		if _, err := NewReadSeekerFromBytes(c.data).ReadTIFF(); !errors.Is(err, c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, c.want)
+		}
+	}
+}
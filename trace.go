@@ -0,0 +1,230 @@
+package iox
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+)
+
+//TraceRecord is one read recorded by a Trace.
+type TraceRecord struct {
+	Offset int64       `json:"offset"`
+	Length int64       `json:"length"`
+	Method string      `json:"method"`
+	Value  interface{} `json:"value"`
+	Label  string      `json:"label,omitempty"`
+}
+
+//Trace records the reads of a ReadSeeker,see (*ReadSeeker).EnableTrace.
+//a read made inside another read,like the length of ReadStringUint16,is part of the outer record.
+type Trace struct {
+	Records []TraceRecord
+	depth   int
+	begin   int64
+	method  string
+	value   interface{}
+	ok      bool
+	label   string
+}
+
+//start recording every read and returns the Trace that collects them.
+//if tracing is already enabled the existing Trace is returned.
+func (r *ReadSeeker) EnableTrace() *Trace {
+	if r.trace == nil {
+		r.trace = new(Trace)
+	}
+	return r.trace
+}
+
+//stop recording and returns the Trace collected so far.
+func (r *ReadSeeker) DisableTrace() *Trace {
+	t := r.trace
+	r.trace = nil
+	return t
+}
+
+//returns the current Trace,nil if tracing is not enabled.
+func (r *ReadSeeker) Trace() *Trace {
+	return r.trace
+}
+
+//Label names the next traced read,such as "header.version".
+//it does nothing if tracing is not enabled.
+func (r *ReadSeeker) Label(label string) {
+	if r.trace != nil {
+		r.trace.label = label
+	}
+}
+
+//traceEnter is deferred by every read method:defer r.traceEnter("ReadUint16")()
+func (r *ReadSeeker) traceEnter(method string) func() {
+	t := r.trace
+	if t == nil {
+		return func() {}
+	}
+	t.depth++
+	if t.depth == 1 {
+		t.begin, _ = r.CurPos()
+		t.method = method
+		t.value = nil
+		t.ok = false
+	}
+	return func() {
+		t.depth--
+		if t.depth != 0 || !t.ok {
+			return
+		}
+		end, _ := r.CurPos()
+		offset, length := t.begin, end-t.begin
+		if length < 0 {
+			//ReadBytesReverse
+			offset, length = end, -length
+		}
+		t.Records = append(t.Records, TraceRecord{
+			Offset: offset,
+			Length: length,
+			Method: t.method,
+			Value:  t.value,
+			Label:  t.label,
+		})
+		t.label = ""
+	}
+}
+
+//traceValue marks the outermost read as successful with the decoded value.
+func (r *ReadSeeker) traceValue(value interface{}) {
+	t := r.trace
+	if t == nil || t.depth != 1 {
+		return
+	}
+	if bt, ok := value.([]byte); ok {
+		value = append([]byte{}, bt...)
+	}
+	t.value = value
+	t.ok = true
+}
+
+//Reset drops all records.
+func (t *Trace) Reset() {
+	t.Records = nil
+	t.label = ""
+}
+
+//format the decoded value of a record for humans.
+func formatTraceValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return strings.ToUpper(hex.EncodeToString(v))
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprint(value)
+}
+
+//Table returns the records as a text table.
+func (t *Trace) Table() string {
+	var buf bytes.Buffer
+	t.WriteTable(&buf)
+	return buf.String()
+}
+
+//WriteTable writes the records as a text table into w.
+func (t *Trace) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tLENGTH\tMETHOD\tLABEL\tVALUE")
+	for _, rec := range t.Records {
+		fmt.Fprintf(tw, "0x%08X\t%v\t%v\t%v\t%v\n", rec.Offset, rec.Length, rec.Method, rec.Label, formatTraceValue(rec.Value))
+	}
+	return tw.Flush()
+}
+
+//JSON returns the records as a JSON array,[]byte values are written as upper case hex.
+func (t *Trace) JSON() ([]byte, error) {
+	records := make([]TraceRecord, len(t.Records))
+	for i, rec := range t.Records {
+		switch v := rec.Value.(type) {
+		case []byte:
+			rec.Value = strings.ToUpper(hex.EncodeToString(v))
+		case float32:
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				rec.Value = fmt.Sprint(v)
+			}
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				rec.Value = fmt.Sprint(v)
+			}
+		}
+		records[i] = rec
+	}
+	return json.MarshalIndent(records, "", "  ")
+}
+
+//HexDump returns the data of r annotated with the records,one line per record,
+//the data that was never read is shown as well so it's easy to see what the parser skipped.
+//r is usually the traced ReadSeeker,its position is left unchanged.
+func (t *Trace) HexDump(r *ReadSeeker) (string, error) {
+	var buf bytes.Buffer
+	err := t.WriteHexDump(&buf, r)
+	return buf.String(), err
+}
+
+//WriteHexDump writes the annotated hexdump described by HexDump into w.
+func (t *Trace) WriteHexDump(w io.Writer, r *ReadSeeker) error {
+	const maxShow = 16
+	initialPos, err := r.CurPos()
+	if err != nil {
+		return err
+	}
+	defer r.readSeeker.Seek(initialPos, io.SeekStart)
+	size := r.Size()
+	dump := func(offset, length int64) (string, error) {
+		n := length
+		if n > maxShow {
+			n = maxShow
+		}
+		bt := make([]byte, n)
+		if _, err := r.readSeeker.Seek(offset, io.SeekStart); err != nil {
+			return "", err
+		}
+		if _, err := io.ReadFull(r.readSeeker, bt); err != nil {
+			return "", err
+		}
+		s := strings.ToUpper(hex.EncodeToString(bt))
+		if n < length {
+			s += "..."
+		}
+		return s, nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	var pos int64
+	for _, rec := range t.Records {
+		if rec.Offset > pos {
+			s, err := dump(pos, rec.Offset-pos)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "%08X\t%v\t(not read,%v bytes)\t\t\n", pos, s, rec.Offset-pos)
+		}
+		s, err := dump(rec.Offset, rec.Length)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%08X\t%v\t%v\t%v\t%v\n", rec.Offset, s, rec.Method, rec.Label, formatTraceValue(rec.Value))
+		if end := rec.Offset + rec.Length; end > pos {
+			pos = end
+		}
+	}
+	if pos < size {
+		s, err := dump(pos, size-pos)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%08X\t%v\t(not read,%v bytes)\t\t\n", pos, s, size-pos)
+	}
+	return tw.Flush()
+}
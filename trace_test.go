@@ -0,0 +1,78 @@
+package iox
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	wr := NewBytesBuffer()
+	wr.WriteString("IOX")
+	wr.WriteUint16BigEndian(2)
+	wr.WriteStringUint16("test str")
+	wr.WriteFloat32(32.5)
+	wr.WriteUint8(0xee)
+	rd := NewReadSeekerFromBytes(wr.Bytes())
+	//nothing is recorded before EnableTrace
+	rd.ReadString(3)
+	trace := rd.EnableTrace()
+	rd.Label("header.version")
+	v, err := rd.ReadUint16BigEndian()
+	if err != nil || v != 2 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", v, err, 2)
+	}
+	rd.ReadStringUint16()
+	rd.Label("scale")
+	rd.ReadFloat32()
+	//failed reads and searches are not recorded
+	rd.ReadUint32()
+	rd.LastIndex([]byte("IOX"))
+	want := []TraceRecord{
+		{Offset: 3, Length: 2, Method: "ReadUint16BigEndian", Value: uint16(2), Label: "header.version"},
+		{Offset: 5, Length: 10, Method: "ReadStringUint16", Value: "test str"},
+		{Offset: 15, Length: 4, Method: "ReadFloat32", Value: float32(32.5), Label: "scale"},
+	}
+	if len(trace.Records) != len(want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", trace.Records, want)
+	}
+	for i := range want {
+		if trace.Records[i] != want[i] {
+			t.Fatalf("unexpected value obtained; got %+v want %+v", trace.Records[i], want[i])
+		}
+	}
+	//exports
+	table := trace.Table()
+	if !strings.Contains(table, "header.version") || !strings.Contains(table, `"test str"`) {
+		t.Fatalf("unexpected value obtained; got %v", table)
+	}
+	js, err := trace.JSON()
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	var records []map[string]interface{}
+	if err = json.Unmarshal(js, &records); err != nil || len(records) != 3 || records[1]["value"] != "test str" {
+		t.Fatalf("unexpected value obtained; got %s %v", js, err)
+	}
+	curPos, _ := rd.CurPos()
+	dump, err := trace.HexDump(rd)
+	if err != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+	}
+	lines := strings.Split(strings.TrimSpace(dump), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "00000000  494F58") || !strings.Contains(lines[4], "EE") {
+		t.Fatalf("unexpected value obtained; got %v", dump)
+	}
+	if pos, _ := rd.CurPos(); pos != curPos {
+		t.Fatalf("unexpected value obtained; got %v want %v", pos, curPos)
+	}
+	//ReadBytesReverse
+	trace.Reset()
+	rd.ReadBytesReverse(2)
+	if len(trace.Records) != 1 || trace.Records[0].Offset != 17 || trace.Records[0].Length != 2 {
+		t.Fatalf("unexpected value obtained; got %+v", trace.Records)
+	}
+	if rd.DisableTrace() != trace || rd.Trace() != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", rd.Trace(), nil)
+	}
+}
@@ -168,4 +168,55 @@ func bytesToUint64(b []byte) uint64 {
 //bytesToUint64BigEndian
 func bytesToUint64BigEndian(b []byte) uint64 {
 	return binary.BigEndian.Uint64(b)
-}
\ No newline at end of file
+}
+
+//bytesToUint reads an unsigned number of 1,2,4 or 8 bytes.
+func bytesToUint(b []byte, bigEndian bool) uint64 {
+	switch {
+	case len(b) == 1:
+		return uint64(b[0])
+	case len(b) == 2 && bigEndian:
+		return uint64(binary.BigEndian.Uint16(b))
+	case len(b) == 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case len(b) == 4 && bigEndian:
+		return uint64(binary.BigEndian.Uint32(b))
+	case len(b) == 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	case bigEndian:
+		return binary.BigEndian.Uint64(b)
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+//uintToBytes writes an unsigned number into 1,2,4 or 8 bytes.
+func uintToBytes(i uint64, size int, bigEndian bool) []byte {
+	b := make([]byte, 8)
+	if bigEndian {
+		binary.BigEndian.PutUint64(b, i)
+		return b[8-size:]
+	}
+	binary.LittleEndian.PutUint64(b, i)
+	return b[:size]
+}
+
+//fieldReader reads the fixed size fields of a binary structure one after another in the given byte order.
+type fieldReader struct {
+	b         []byte
+	bigEndian bool
+	is64      bool
+}
+
+func (d *fieldReader) uint(size int) uint64 {
+	v := bytesToUint(d.b[:size], d.bigEndian)
+	d.b = d.b[size:]
+	return v
+}
+
+//an address,an offset or a size,which is 8 bytes in a 64-bit structure and 4 bytes otherwise.
+func (d *fieldReader) word() uint64 {
+	if d.is64 {
+		return d.uint(8)
+	}
+	return d.uint(4)
+}
@@ -0,0 +1,438 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidWAV = errors.New("the WAV data is not valid")
+
+//the format tags of the fmt chunk.
+const (
+	WAVFormatPCM        = 0x0001
+	WAVFormatFloat      = 0x0003
+	WAVFormatExtensible = 0xfffe
+)
+
+//the sub format GUID of WAVE_FORMAT_EXTENSIBLE after the 2 bytes of the format tag.
+const wavGUIDSuffix = "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"
+
+//WAVFormat is the fmt chunk of a WAV file.
+type WAVFormat struct {
+	Tag           uint16 //WAVFormatPCM or WAVFormatFloat,the sub format of a WAVE_FORMAT_EXTENSIBLE file
+	Extensible    bool   //the fmt chunk is WAVE_FORMAT_EXTENSIBLE
+	Channels      int
+	SampleRate    int
+	BitsPerSample int    //the container size of a sample,8,16,24 or 32 for PCM and 32 or 64 for float
+	ValidBits     int    //the bits that are used of the container,0 means BitsPerSample
+	ChannelMask   uint32 //the speaker positions of WAVE_FORMAT_EXTENSIBLE,such as 0x3 for front left and right
+}
+
+//BlockAlign returns the size of a frame,which is a sample of every channel.
+func (f *WAVFormat) BlockAlign() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+func (f *WAVFormat) check() error {
+	switch {
+	case f.Channels <= 0 || f.SampleRate <= 0:
+		return fmt.Errorf("%v channels at %v Hz: %w", f.Channels, f.SampleRate, ErrInvalidWAV)
+	case f.Tag == WAVFormatPCM && (f.BitsPerSample == 8 || f.BitsPerSample == 16 || f.BitsPerSample == 24 || f.BitsPerSample == 32):
+	case f.Tag == WAVFormatFloat && (f.BitsPerSample == 32 || f.BitsPerSample == 64):
+	default:
+		return fmt.Errorf("the format 0x%x of %v bits is not supported: %w", f.Tag, f.BitsPerSample, ErrInvalidWAV)
+	}
+	if f.ValidBits < 0 || f.ValidBits > f.BitsPerSample {
+		return fmt.Errorf("%v valid bits of %v bits: %w", f.ValidBits, f.BitsPerSample, ErrInvalidWAV)
+	}
+	return nil
+}
+
+//WAV is a WAV file of PCM or float samples,the samples are read from Data frame by frame.
+type WAV struct {
+	Format WAVFormat
+	Frames int64             //the count of the frames of the data chunk
+	Info   map[string]string //the LIST INFO chunk,such as INAM for the title and ICMT for the comment
+	Data   *ReadSeeker       //the data chunk,its position is that of the next frame
+}
+
+//ReadWAV reads the fmt,data and LIST INFO chunks of the WAV file at the current position,
+//the position is left unchanged,and the samples are read by the methods of WAV.
+func (r *ReadSeeker) ReadWAV() (*WAV, error) {
+	defer r.traceEnter("ReadWAV")()
+	walker := r.Chunks(ChunkRIFF)
+	if !walker.Next() {
+		if walker.Err() != nil {
+			return nil, walker.Err()
+		}
+		return nil, fmt.Errorf("there is no RIFF chunk: %w", ErrInvalidWAV)
+	}
+	riff := walker.Chunk()
+	if form, _ := riff.Data.bytesAt(0, 4); riff.Tag != "RIFF" || string(form) != "WAVE" {
+		return nil, fmt.Errorf("the chunk %q of the form %q is not RIFF WAVE: %w", riff.Tag, form, ErrInvalidWAV)
+	}
+	wav := &WAV{}
+	var fmtChunk, list *Chunk
+	for walker.Next() {
+		chunk := walker.Chunk()
+		if chunk.Depth == 0 {
+			break
+		}
+		switch {
+		case chunk.Depth == 1 && chunk.Tag == "fmt ":
+			fmtChunk = chunk
+		case chunk.Depth == 1 && chunk.Tag == "data":
+			wav.Data = chunk.Data
+		case chunk.Depth == 1:
+			list = nil
+			if listType, _ := chunk.Data.bytesAt(0, 4); chunk.Container && string(listType) == "INFO" {
+				list = chunk
+				wav.Info = map[string]string{}
+			}
+		case list != nil:
+			text, err := chunk.Data.bytesAt(0, chunk.Size)
+			if err != nil {
+				return nil, err
+			}
+			wav.Info[chunk.Tag] = strings.TrimRight(string(text), "\x00")
+		}
+	}
+	if err := walker.Err(); err != nil {
+		return nil, err
+	}
+	if fmtChunk == nil || wav.Data == nil {
+		return nil, fmt.Errorf("the fmt chunk or the data chunk is missing: %w", ErrInvalidWAV)
+	}
+	if err := wav.readFormat(fmtChunk); err != nil {
+		return nil, err
+	}
+	wav.Frames = wav.Data.Size() / int64(wav.Format.BlockAlign())
+	r.traceValue(fmt.Sprintf("%v frames of %v channels", wav.Frames, wav.Format.Channels))
+	return wav, nil
+}
+
+//read the fmt chunk of WAVEFORMATEX or WAVEFORMATEXTENSIBLE.
+func (wav *WAV) readFormat(chunk *Chunk) error {
+	if chunk.Size < 16 {
+		return fmt.Errorf("the fmt chunk has %v bytes,less than 16: %w", chunk.Size, ErrInvalidWAV)
+	}
+	bt, err := chunk.Data.bytesAt(0, chunk.Size)
+	if err != nil {
+		return err
+	}
+	d := &fieldReader{b: bt}
+	f := &wav.Format
+	f.Tag, f.Channels, f.SampleRate = uint16(d.uint(2)), int(d.uint(2)), int(d.uint(4))
+	d.uint(4) //the byte rate
+	blockAlign := int(d.uint(2))
+	f.BitsPerSample = int(d.uint(2))
+	if f.Tag == WAVFormatExtensible {
+		if len(d.b) < 24 || d.uint(2) < 22 {
+			return fmt.Errorf("the fmt chunk of WAVE_FORMAT_EXTENSIBLE has %v bytes,less than 40: %w", chunk.Size, ErrInvalidWAV)
+		}
+		f.Extensible = true
+		f.ValidBits, f.ChannelMask = int(d.uint(2)), uint32(d.uint(4))
+		if string(d.b[2:16]) != wavGUIDSuffix {
+			return fmt.Errorf("the sub format %x is not a WAVE format: %w", d.b[:16], ErrInvalidWAV)
+		}
+		f.Tag = uint16(d.uint(2))
+		if f.ValidBits == f.BitsPerSample {
+			f.ValidBits = 0
+		}
+	}
+	if err = f.check(); err != nil {
+		return err
+	}
+	if blockAlign != f.BlockAlign() {
+		return fmt.Errorf("the block align %v is not %v for %v channels of %v bits: %w", blockAlign, f.BlockAlign(), f.Channels, f.BitsPerSample, ErrInvalidWAV)
+	}
+	return nil
+}
+
+//SeekFrame moves Data to a frame,the next frame read is that one.
+func (wav *WAV) SeekFrame(frame int64) error {
+	if frame < 0 || frame > wav.Frames {
+		return fmt.Errorf("the frame %v is out of %v frames", frame, wav.Frames)
+	}
+	return wav.Data.MoveTo(frame * int64(wav.Format.BlockAlign()))
+}
+
+//the frames left from the position of Data.
+func (wav *WAV) framesLeft() int64 {
+	pos, _ := wav.Data.CurPos()
+	return wav.Frames - pos/int64(wav.Format.BlockAlign())
+}
+
+//read a sample,an integer is returned left-justified to 32 bits and a float as it is.
+func (wav *WAV) readSample() (int32, float64, error) {
+	f := &wav.Format
+	if f.Tag == WAVFormatFloat {
+		if f.BitsPerSample == 32 {
+			v, err := wav.Data.ReadFloat32()
+			return 0, float64(v), err
+		}
+		v, err := wav.Data.ReadFloat64()
+		return 0, v, err
+	}
+	switch f.BitsPerSample {
+	case 8:
+		v, err := wav.Data.ReadUint8()
+		return (int32(v) - 128) << 24, 0, err //8 bits samples are unsigned
+	case 16:
+		v, err := wav.Data.ReadInt16()
+		return int32(v) << 16, 0, err
+	case 24:
+		bt, err := wav.Data.ReadBytes(3)
+		if err != nil {
+			return 0, 0, err
+		}
+		return int32(uint32(bt[0])<<8 | uint32(bt[1])<<16 | uint32(bt[2])<<24), 0, nil
+	}
+	v, err := wav.Data.ReadInt32()
+	return v, 0, err
+}
+
+//read n frames of samples by fn,which is given the frame and the channel.
+func (wav *WAV) readFrames(n int64, fn func(frame, channel int, i int32, f float64)) (int, error) {
+	if left := wav.framesLeft(); left == 0 {
+		return 0, io.EOF
+	} else if n > left {
+		n = left
+	}
+	for frame := 0; frame < int(n); frame++ {
+		for channel := 0; channel < wav.Format.Channels; channel++ {
+			i, f, err := wav.readSample()
+			if err != nil {
+				return frame, err
+			}
+			fn(frame, channel, i, f)
+		}
+	}
+	return int(n), nil
+}
+
+//convert an integer sample left-justified to 32 bits to [-1,1).
+func wavIntToFloat(i int32) float64 {
+	return float64(i) / (1 << 31)
+}
+
+//convert a float sample to an integer of bits left-justified to 32 bits,clipping it to [-1,1).
+func wavFloatToInt(f float64, bits int) int32 {
+	max := float64(int64(1) << (bits - 1))
+	i := int32(math.Max(-max, math.Min(max-1, math.Round(f*max))))
+	return i << (32 - bits)
+}
+
+//ReadFloat64 reads the interleaved samples of up to len(samples)/Channels frames,
+//integers are scaled to [-1,1),returns the count of the frames read and io.EOF after the last frame.
+func (wav *WAV) ReadFloat64(samples []float64) (int, error) {
+	channels := wav.Format.Channels
+	return wav.readFrames(int64(len(samples)/channels), func(frame, channel int, i int32, f float64) {
+		if wav.Format.Tag == WAVFormatPCM {
+			f = wavIntToFloat(i)
+		}
+		samples[frame*channels+channel] = f
+	})
+}
+
+//ReadInt32 reads the interleaved samples of up to len(samples)/Channels frames,
+//integers are left-justified to 32 bits,such as a 16 bits sample shifted left by 16,and floats are scaled to the range of int32,
+//returns the count of the frames read and io.EOF after the last frame.
+func (wav *WAV) ReadInt32(samples []int32) (int, error) {
+	channels := wav.Format.Channels
+	return wav.readFrames(int64(len(samples)/channels), func(frame, channel int, i int32, f float64) {
+		if wav.Format.Tag == WAVFormatFloat {
+			i = wavFloatToInt(f, 32)
+		}
+		samples[frame*channels+channel] = i
+	})
+}
+
+//ReadFloat64Channels reads up to n frames like ReadFloat64,the samples are deinterleaved into a slice per channel.
+func (wav *WAV) ReadFloat64Channels(n int) ([][]float64, error) {
+	if left := wav.framesLeft(); int64(n) > left {
+		n = int(left)
+	}
+	channels := make([][]float64, wav.Format.Channels)
+	for i := range channels {
+		channels[i] = make([]float64, n)
+	}
+	read, err := wav.readFrames(int64(n), func(frame, channel int, i int32, f float64) {
+		if wav.Format.Tag == WAVFormatPCM {
+			f = wavIntToFloat(i)
+		}
+		channels[channel][frame] = f
+	})
+	for i := range channels {
+		channels[i] = channels[i][:read]
+	}
+	return channels, err
+}
+
+//ReadInt32Channels reads up to n frames like ReadInt32,the samples are deinterleaved into a slice per channel.
+func (wav *WAV) ReadInt32Channels(n int) ([][]int32, error) {
+	if left := wav.framesLeft(); int64(n) > left {
+		n = int(left)
+	}
+	channels := make([][]int32, wav.Format.Channels)
+	for i := range channels {
+		channels[i] = make([]int32, n)
+	}
+	read, err := wav.readFrames(int64(n), func(frame, channel int, i int32, f float64) {
+		if wav.Format.Tag == WAVFormatFloat {
+			i = wavFloatToInt(f, 32)
+		}
+		channels[channel][frame] = i
+	})
+	for i := range channels {
+		channels[i] = channels[i][:read]
+	}
+	return channels, err
+}
+
+//WAVWriter writes a WAV file into a Writer,the samples are written by WriteFloat64 or WriteInt32
+//and End back-fills the lengths.
+type WAVWriter struct {
+	w       *Writer
+	format  WAVFormat
+	factPos int64 //of the frame count of the fact chunk,0 for PCM
+	data    int64 //of the samples
+	ended   bool
+}
+
+//returns a *WAVWriter that has written the RIFF header,the fmt chunk and the LIST INFO chunk of info
+//into w and begun the data chunk,it panics if f is not valid.
+//a fact chunk is written for float samples,and WAVE_FORMAT_EXTENSIBLE if f.Extensible is set.
+//like BeginChunk,it should not be used inside a compressed region.
+func NewWAVWriter(w *Writer, f WAVFormat, info map[string]string) *WAVWriter {
+	if err := f.check(); err != nil {
+		panic(err)
+	}
+	w.BeginChunk(ChunkRIFF, "RIFF")
+	w.WriteString("WAVE")
+	w.BeginChunk(ChunkRIFF, "fmt ")
+	if f.Extensible {
+		w.WriteUint16(WAVFormatExtensible)
+	} else {
+		w.WriteUint16(f.Tag)
+	}
+	w.WriteUint16(uint16(f.Channels))
+	w.WriteUint32(uint32(f.SampleRate))
+	w.WriteUint32(uint32(f.SampleRate * f.BlockAlign()))
+	w.WriteUint16(uint16(f.BlockAlign()))
+	w.WriteUint16(uint16(f.BitsPerSample))
+	if f.Extensible {
+		validBits := f.ValidBits
+		if validBits == 0 {
+			validBits = f.BitsPerSample
+		}
+		w.WriteUint16(22)
+		w.WriteUint16(uint16(validBits))
+		w.WriteUint32(f.ChannelMask)
+		w.WriteUint16(f.Tag)
+		w.WriteString(wavGUIDSuffix)
+	} else if f.Tag != WAVFormatPCM {
+		w.WriteUint16(0)
+	}
+	w.EndChunk()
+	ww := &WAVWriter{w: w, format: f}
+	if f.Tag != WAVFormatPCM {
+		w.BeginChunk(ChunkRIFF, "fact")
+		ww.factPos = w.pos
+		w.WriteUint32(0)
+		w.EndChunk()
+	}
+	if len(info) > 0 {
+		ids := make([]string, 0, len(info))
+		for id := range info {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		w.BeginChunk(ChunkRIFF, "LIST")
+		w.WriteString("INFO")
+		for _, id := range ids {
+			w.BeginChunk(ChunkRIFF, id)
+			w.WriteString(info[id] + "\x00")
+			w.EndChunk()
+		}
+		w.EndChunk()
+	}
+	w.BeginChunk(ChunkRIFF, "data")
+	ww.data = w.pos
+	return ww
+}
+
+//write a sample of the format from an integer left-justified to 32 bits or from a float.
+func (ww *WAVWriter) writeSample(i int32, f float64, isFloat bool) {
+	w, format := ww.w, &ww.format
+	if format.Tag == WAVFormatFloat {
+		if !isFloat {
+			f = wavIntToFloat(i)
+		}
+		if format.BitsPerSample == 32 {
+			w.WriteFloat32(float32(f))
+		} else {
+			w.WriteFloat64(f)
+		}
+		return
+	}
+	if isFloat {
+		i = wavFloatToInt(f, format.BitsPerSample)
+	}
+	switch format.BitsPerSample {
+	case 8:
+		w.WriteUint8(uint8(i>>24) + 128)
+	case 16:
+		w.WriteInt16(int16(i >> 16))
+	case 24:
+		w.WriteBytes(uintToBytes(uint64(i>>8), 3, false))
+	default:
+		w.WriteInt32(i)
+	}
+}
+
+func (ww *WAVWriter) checkSamples(n int) {
+	if ww.ended {
+		panic("the WAV has ended")
+	}
+	if n%ww.format.Channels != 0 {
+		panic(fmt.Sprintf("%v samples are not whole frames of %v channels", n, ww.format.Channels))
+	}
+}
+
+//WriteFloat64 writes interleaved samples in [-1,1],which are clipped and quantized for PCM.
+func (ww *WAVWriter) WriteFloat64(samples []float64) {
+	ww.checkSamples(len(samples))
+	for _, f := range samples {
+		ww.writeSample(0, f, true)
+	}
+}
+
+//WriteInt32 writes interleaved samples left-justified to 32 bits like those of WAV.ReadInt32,
+//the low bits are dropped for PCM of less than 32 bits.
+func (ww *WAVWriter) WriteInt32(samples []int32) {
+	ww.checkSamples(len(samples))
+	for _, i := range samples {
+		ww.writeSample(i, 0, false)
+	}
+}
+
+//End ends the data chunk and the RIFF chunk,returns the count of the frames written.
+func (ww *WAVWriter) End() int64 {
+	if ww.ended {
+		panic("the WAV has ended")
+	}
+	ww.ended = true
+	w := ww.w
+	frames := (w.pos - ww.data) / int64(ww.format.BlockAlign())
+	if ww.factPos != 0 {
+		w.writeAt(uintToBytes(uint64(frames), 4, false), ww.factPos)
+	}
+	w.EndChunk()
+	w.EndChunk()
+	return frames
+}
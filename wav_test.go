@@ -0,0 +1,182 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestWAVHeader(t *testing.T) {
+	w := NewBytesBuffer()
+	ww := NewWAVWriter(w, WAVFormat{Tag: WAVFormatPCM, Channels: 2, SampleRate: 44100, BitsPerSample: 16}, nil)
+	ww.WriteInt32([]int32{1 << 16, -1 << 16, math.MaxInt32, math.MinInt32})
+	if frames := ww.End(); frames != 2 {
+		t.Fatalf("unexpected value obtained; got %v want %v", frames, 2)
+	}
+	//the canonical header of 44 bytes
+	want := NewBytesBuffer()
+	want.WriteString("RIFF")
+	want.WriteUint32(36 + 8)
+	want.WriteString("WAVEfmt ")
+	want.WriteUint32(16)
+	want.WriteUint16(WAVFormatPCM)
+	want.WriteUint16(2)
+	want.WriteUint32(44100)
+	want.WriteUint32(44100 * 4)
+	want.WriteUint16(4)
+	want.WriteUint16(16)
+	want.WriteString("data")
+	want.WriteUint32(8)
+	want.WriteBytes([]byte{1, 0, 0xff, 0xff, 0xff, 0x7f, 0, 0x80})
+	if !bytes.Equal(w.Bytes(), want.Bytes()) {
+		t.Fatalf("unexpected value obtained; got %x want %x", w.Bytes(), want.Bytes())
+	}
+	r := NewReadSeekerFromBytes(append([]byte("xx"), w.Bytes()...))
+	r.MoveTo(2)
+	wav, err := r.ReadWAV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos, _ := r.CurPos(); pos != 2 || wav.Frames != 2 || wav.Format.SampleRate != 44100 || wav.Info != nil {
+		t.Fatalf("unexpected value obtained; got %+v want %v", wav, "2 frames")
+	}
+	samples := make([]int32, 5) //a partial frame is not read
+	if n, err := wav.ReadInt32(samples); err != nil || n != 2 || samples[0] != 1<<16 || samples[1] != -1<<16 || samples[2] != math.MaxInt32&^0xffff || samples[3] != math.MinInt32 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", n, samples, 2)
+	}
+	if n, err := wav.ReadInt32(samples); err != io.EOF || n != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.EOF)
+	}
+}
+
+func TestWAV(t *testing.T) {
+	samples := []float64{-1, -0.5, 0, 0.25, 0.5, -0.125}
+	for _, f := range []WAVFormat{
+		{Tag: WAVFormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 8},
+		{Tag: WAVFormatPCM, Channels: 2, SampleRate: 44100, BitsPerSample: 16},
+		{Tag: WAVFormatPCM, Channels: 3, SampleRate: 48000, BitsPerSample: 24, Extensible: true, ChannelMask: 0x7},
+		{Tag: WAVFormatPCM, Channels: 2, SampleRate: 96000, BitsPerSample: 32, Extensible: true, ValidBits: 24, ChannelMask: 0x3},
+		{Tag: WAVFormatFloat, Channels: 2, SampleRate: 48000, BitsPerSample: 32},
+		{Tag: WAVFormatFloat, Channels: 1, SampleRate: 48000, BitsPerSample: 64, Extensible: true, ChannelMask: 0x4},
+	} {
+		info := map[string]string{"INAM": "title", "ICMT": "odd"}
+		w := NewBytesBuffer()
+		ww := NewWAVWriter(w, f, info)
+		ww.WriteFloat64(samples)
+		ww.WriteFloat64([]float64{})
+		frames := ww.End()
+		wav, err := NewReadSeekerFromBytes(w.Bytes()).ReadWAV()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wav.Format != f || wav.Frames != frames || wav.Frames != int64(len(samples)/f.Channels) {
+			t.Fatalf("unexpected value obtained; got %+v want %+v", wav.Format, f)
+		}
+		if len(wav.Info) != 2 || wav.Info["INAM"] != "title" || wav.Info["ICMT"] != "odd" {
+			t.Fatalf("unexpected value obtained; got %v want %v", wav.Info, info)
+		}
+		got := make([]float64, len(samples))
+		if n, err := wav.ReadFloat64(got); err != nil || n != len(samples)/f.Channels {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, nil)
+		}
+		for i := range samples {
+			if got[i] != samples[i] {
+				t.Fatalf("unexpected value obtained; got %v want %v", got, samples)
+			}
+		}
+		//deinterleaved from the second frame
+		if err = wav.SeekFrame(1); err != nil {
+			t.Fatal(err)
+		}
+		channels, err := wav.ReadInt32Channels(100)
+		if err != nil || len(channels) != f.Channels || len(channels[0]) != int(wav.Frames)-1 {
+			t.Fatalf("unexpected value obtained; got %v want %v", channels, wav.Frames-1)
+		}
+		for c := range channels {
+			for i, v := range channels[c] {
+				if want := int32(samples[(i+1)*f.Channels+c] * (1 << 31)); v != want {
+					t.Fatalf("unexpected value obtained; got %v want %v", v, want)
+				}
+			}
+		}
+		if _, err = wav.ReadFloat64Channels(1); err != io.EOF {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, io.EOF)
+		}
+	}
+}
+
+func TestWAVQuantize(t *testing.T) {
+	w := NewBytesBuffer()
+	ww := NewWAVWriter(w, WAVFormat{Tag: WAVFormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 16}, nil)
+	ww.WriteFloat64([]float64{2, -2, 0.3 / 32768, -0.7 / 32768})
+	ww.End()
+	wav, err := NewReadSeekerFromBytes(w.Bytes()).ReadWAV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels, err := wav.ReadInt32Channels(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int32{math.MaxInt16 << 16, math.MinInt16 << 16, 0, -1 << 16}; len(channels[0]) != 4 || channels[0][0] != want[0] || channels[0][1] != want[1] || channels[0][2] != want[2] || channels[0][3] != want[3] {
+		t.Fatalf("unexpected value obtained; got %v want %v", channels[0], want)
+	}
+	//the fact chunk of float samples holds the count of the frames
+	w = NewBytesBuffer()
+	ww = NewWAVWriter(w, WAVFormat{Tag: WAVFormatFloat, Channels: 1, SampleRate: 8000, BitsPerSample: 32}, nil)
+	ww.WriteInt32([]int32{1 << 30, 0, 0})
+	ww.End()
+	if fact := bytes.Index(w.Bytes(), []byte("fact")); fact < 0 || bytesToUint(w.Bytes()[fact+8:fact+12], false) != 3 {
+		t.Fatalf("unexpected value obtained; got %x want %v", w.Bytes(), "a fact chunk of 3 frames")
+	}
+}
+
+func TestWAVErrors(t *testing.T) {
+	w := NewBytesBuffer()
+	ww := NewWAVWriter(w, WAVFormat{Tag: WAVFormatPCM, Channels: 2, SampleRate: 8000, BitsPerSample: 16}, nil)
+	ww.WriteInt32([]int32{1, 2})
+	ww.End()
+	good := w.Bytes()
+	adpcm := append([]byte{}, good...)
+	adpcm[20] = 2
+	badAlign := append([]byte{}, good...)
+	badAlign[32] = 3
+	noData := append([]byte{}, good[:36]...)
+	noData[4] = 28
+	for _, c := range []struct {
+		data []byte
+		want error
+	}{
+		{[]byte("RIFF"), io.ErrUnexpectedEOF},
+		{good[:len(good)-1], io.ErrUnexpectedEOF},
+		{append([]byte("RIFX"), good[4:]...), ErrInvalidWAV},
+		{adpcm, ErrInvalidWAV},
+		{badAlign, ErrInvalidWAV},
+		{noData, ErrInvalidWAV},
+	} {
+		if _, err := NewReadSeekerFromBytes(c.data).ReadWAV(); !errors.Is(err, c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, c.want)
+		}
+	}
+}
+
+func TestWAVWriterReset(t *testing.T) {
+	f := WAVFormat{Tag: WAVFormatPCM, Channels: 2, SampleRate: 8000, BitsPerSample: 16}
+	w := NewBytesBuffer()
+	ww := NewWAVWriter(w, f, nil)
+	ww.WriteInt32([]int32{1, 2})
+	ww.End()
+	want := append([]byte{}, w.Bytes()...)
+	//a WAV left unfinished by Reset does not affect the next one
+	w.Reset()
+	ww = NewWAVWriter(w, f, nil)
+	ww.WriteInt32([]int32{3, 4})
+	w.Reset()
+	ww = NewWAVWriter(w, f, nil)
+	ww.WriteInt32([]int32{1, 2})
+	if frames := ww.End(); frames != 1 || !bytes.Equal(w.Bytes(), want) {
+		t.Fatalf("unexpected value obtained; got %v,%x want %v,%x", frames, w.Bytes(), 1, want)
+	}
+}
@@ -2,6 +2,11 @@ package iox
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
 	"math"
 	"strconv"
 )
@@ -10,6 +15,12 @@ import (
 // the default ByteOrder is LittleEndian.
 type Writer struct {
 	writer bytes.Buffer
+	file   *fileBackend    //not nil for a Writer from NewWriterToFile
+	pos    int64           //where the next write goes,see Seek
+	hash   hash.Hash       //the running checksum,see BeginChecksum
+	region *compressRegion //not nil between BeginCompress and EndCompress
+	chunks []chunkRegion   //the open chunks of BeginChunk
+	nested []*nestedRegion //the open elements of BeginASN1 and BeginProtoMessage
 }
 
 //NewBytesBuffer returns a *Writer.
@@ -21,27 +32,168 @@ func NewBytesBuffer(buf ...bytes.Buffer) *Writer {
 	} else {
 		r.writer = buf[0]
 	}
+	r.pos = int64(r.writer.Len())
 	return r
 }
 
 //when write finished get the data from the bytes.Buffer.
+//a file Writer reads the whole file back.
 func (w *Writer) Bytes() []byte {
+	if w.file != nil {
+		return w.file.bytes()
+	}
 	return w.writer.Bytes()
 }
 
 //resets the buffer to be empty.
 func (w *Writer) Reset() {
+	if w.file != nil {
+		w.file.truncate(0)
+	}
 	w.writer.Reset()
+	w.pos = 0
+	w.hash = nil
+	w.region = nil
+	w.chunks = nil
+	w.nested = nil
+}
+
+//get the length of the data,it does not count a gap left by seeking past the end until it is written.
+func (w *Writer) Len() int64 {
+	if w.file != nil {
+		return w.file.size
+	}
+	return int64(w.writer.Len())
+}
+
+//get the position where the next write goes.
+func (w *Writer) Pos() int64 {
+	return w.pos
+}
+
+//Seek sets the position of the next write,it implements io.Seeker.
+//seeking past the end is allowed,the gap is filled with zero bytes by the next write.
+func (w *Writer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += w.pos
+	case io.SeekEnd:
+		offset += w.Len()
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("the position %v is negative", offset)
+	}
+	w.pos = offset
+	return offset, nil
+}
+
+//all data goes through here so that it can be kept for a nested element,compressed and fed into the running checksum.
+func (w *Writer) write(p []byte) {
+	if n := len(w.nested); n > 0 {
+		w.nested[n-1].content = append(w.nested[n-1].content, p...)
+		return
+	}
+	if w.region != nil {
+		w.region.compressor.Write(p)
+		return
+	}
+	w.writeRaw(p)
+}
+
+//nestedRegion is an open ASN.1 element or protobuf message,its length goes before the content,
+//so the content is kept in memory until the element ends.
+type nestedRegion struct {
+	proto   bool //a protobuf message,otherwise an ASN.1 element
+	class   ASN1Class
+	tag     int  //the ASN.1 tag or the protobuf field number
+	setOf   bool //an ASN.1 SET OF,its elements are sorted by encoding instead of by tag
+	content []byte
+}
+
+//remove the innermost nested element,it panics if there is none or it is not of the wanted kind.
+func (w *Writer) endNested(proto bool) *nestedRegion {
+	if len(w.nested) == 0 || w.nested[len(w.nested)-1].proto != proto {
+		if proto {
+			panic("there is no protobuf message,call BeginProtoMessage first")
+		}
+		panic("there is no ASN.1 element,call BeginASN1 first")
+	}
+	region := w.nested[len(w.nested)-1]
+	w.nested = w.nested[:len(w.nested)-1]
+	return region
+}
+
+//write p at the position without compressing it.
+func (w *Writer) writeRaw(p []byte) {
+	if w.file != nil {
+		w.file.writeAt(p, w.pos)
+	} else if w.pos == w.Len() {
+		w.writer.Write(p)
+	} else {
+		w.writeAt(p, w.pos)
+	}
+	w.pos += int64(len(p))
+	if w.hash != nil {
+		w.hash.Write(p)
+	}
+}
+
+//write p at off,growing the data with zero bytes if off is past the end.
+func (w *Writer) writeAt(p []byte, off int64) {
+	if off < 0 {
+		panic(fmt.Sprintf("the position %v is negative", off))
+	}
+	if w.file != nil {
+		w.file.writeAt(p, off)
+		return
+	}
+	if grow := off + int64(len(p)) - w.Len(); grow > 0 {
+		w.writer.Write(make([]byte, grow))
+	}
+	copy(w.writer.Bytes()[off:], p)
+}
+
+//read n bytes at off back from the data.
+func (w *Writer) bytesAt(off, n int64) []byte {
+	if w.file != nil {
+		return w.file.readAt(off, n)
+	}
+	return append([]byte{}, w.writer.Bytes()[off:off+n]...)
+}
+
+//Write writes p at the current position,it implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.write(p)
+	if err := w.Err(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+//WriteAt writes p at off without moving the position,it implements io.WriterAt.
+//it is meant for patching,so the data is not fed into the running checksum.
+func (w *Writer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("the position %v is negative", off)
+	}
+	w.writeAt(p, off)
+	if err := w.Err(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
 //Write Byte into writer.
 func (w *Writer) WriteBytes(p []byte) {
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write String into writer
 func (w *Writer) WriteString(s string) {
-	w.writer.Write([]byte(s))
+	w.write([]byte(s))
 }
 
 //Write the length(Uint8) of the byte first, then write the byte.
@@ -50,7 +202,7 @@ func (w *Writer) WriteBytesUint8(p []byte) {
 		panic("the data length:" + strconv.Itoa(len(p)) + " is too big for Uint8")
 	}
 	w.WriteUint8(uint8(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint8) of the string first, then write the string.
@@ -64,7 +216,7 @@ func (w *Writer) WriteBytesUint16(p []byte) {
 		panic("the data length:" + strconv.Itoa(len(p)) + " is too big for Uint16")
 	}
 	w.WriteUint16(uint16(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint16 BigEndian) of the byte first, then write the byte.
@@ -73,7 +225,7 @@ func (w *Writer) WriteBytesUint16BigEndian(p []byte) {
 		panic("the data length:" + strconv.Itoa(len(p)) + " is too big for Uint16")
 	}
 	w.WriteUint16BigEndian(uint16(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint16) of the string first, then write the string.
@@ -92,7 +244,7 @@ func (w *Writer) WriteBytesUint32(p []byte) {
 		panic("the data length:" + strconv.Itoa(len(p)) + " is too big for Uint32")
 	}
 	w.WriteUint32(uint32(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint32 BigEndian) of the byte first, then write the byte.
@@ -101,7 +253,7 @@ func (w *Writer) WriteBytesUint32BigEndian(p []byte) {
 		panic("the data length:" + strconv.Itoa(len(p)) + " is too big for Uint32")
 	}
 	w.WriteUint32BigEndian(uint32(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint32) of the string first, then write the string.
@@ -117,13 +269,13 @@ func (w *Writer) WriteStringUint32BigEndian(s string) {
 //Write the length(Uint16) of the byte first, then write the byte.
 func (w *Writer) WriteBytesUint64(p []byte) {
 	w.WriteUint64(uint64(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint16) of the byte first, then write the byte.
 func (w *Writer) WriteBytesUint64BigEndian(p []byte) {
 	w.WriteUint64BigEndian(uint64(len(p)))
-	w.writer.Write(p)
+	w.write(p)
 }
 
 //Write the length(Uint16) of the string first, then write the string.
@@ -136,74 +288,85 @@ func (w *Writer) WriteStringUint64BigEndian(s string) {
 	w.WriteBytesUint64BigEndian([]byte(s))
 }
 
+//Write the length(unsigned varint) of the byte first, then write the byte.
+func (w *Writer) WriteBytesUvarint(p []byte) {
+	w.WriteUvarint(uint64(len(p)))
+	w.write(p)
+}
+
+//Write the length(unsigned varint) of the string first, then write the string.
+func (w *Writer) WriteStringUvarint(s string) {
+	w.WriteBytesUvarint([]byte(s))
+}
+
 //Write int8 into Writer.
 func (w *Writer) WriteInt8(i int8) {
-	w.writer.Write([]byte{uint8(i)})
+	w.write([]byte{uint8(i)})
 }
 
 //Write uint8 into Writer.
 func (w *Writer) WriteUint8(i uint8) {
-	w.writer.Write([]byte{i})
+	w.write([]byte{i})
 }
 
 //Write int16 with LittleEndian into Writer.
 func (w *Writer) WriteInt16(i int16) {
-	w.writer.Write(int16ToBytes(i))
+	w.write(int16ToBytes(i))
 }
 
 //Write int16 with BigEndian into Writer.
 func (w *Writer) WriteInt16BigEndian(i int16) {
-	w.writer.Write(int16ToBytesBigEndian(i))
+	w.write(int16ToBytesBigEndian(i))
 }
 
 //Write uint16 with LittleEndian into Writer.
 func (w *Writer) WriteUint16(i uint16) {
-	w.writer.Write(uint16ToBytes(i))
+	w.write(uint16ToBytes(i))
 }
 
 //Write uint16 with BigEndian into Writer.
 func (w *Writer) WriteUint16BigEndian(i uint16) {
-	w.writer.Write(uint16ToBytesBigEndian(i))
+	w.write(uint16ToBytesBigEndian(i))
 }
 
 //Write int32 with LittleEndian into Writer.
 func (w *Writer) WriteInt32(i int32) {
-	w.writer.Write(int32ToBytes(i))
+	w.write(int32ToBytes(i))
 }
 
 //Write int32 with BigEndian into Writer.
 func (w *Writer) WriteInt32BigEndian(i int32) {
-	w.writer.Write(int32ToBytesBigEndian(i))
+	w.write(int32ToBytesBigEndian(i))
 }
 
 //Write uint32 with LittleEndian into Writer.
 func (w *Writer) WriteUint32(i uint32) {
-	w.writer.Write(uint32ToBytes(i))
+	w.write(uint32ToBytes(i))
 }
 
 //Write uint32 with BigEndian into Writer.
 func (w *Writer) WriteUint32BigEndian(i uint32) {
-	w.writer.Write(uint32ToBytesBigEndian(i))
+	w.write(uint32ToBytesBigEndian(i))
 }
 
 //Write int64 with LittleEndian into Writer.
 func (w *Writer) WriteInt64(i int64) {
-	w.writer.Write(int64ToBytes(i))
+	w.write(int64ToBytes(i))
 }
 
 //Write int64 with BigEndian into Writer.
 func (w *Writer) WriteInt64BigEndian(i int64) {
-	w.writer.Write(int64ToBytesBigEndian(i))
+	w.write(int64ToBytesBigEndian(i))
 }
 
 //Write uint64 with LittleEndian into Writer.
 func (w *Writer) WriteUint64(i uint64) {
-	w.writer.Write(uint64ToBytes(i))
+	w.write(uint64ToBytes(i))
 }
 
 //Write uint64 with BigEndian into Writer.
 func (w *Writer) WriteUint64BigEndian(i uint64) {
-	w.writer.Write(uint64ToBytesBigEndian(i))
+	w.write(uint64ToBytesBigEndian(i))
 }
 
 //Write float32 with LittleEndian into Writer.
@@ -225,3 +388,99 @@ func (w *Writer) WriteFloat64(i float64) {
 func (w *Writer) WriteFloat64BigEndian(i float64) {
 	w.WriteUint64BigEndian(math.Float64bits(i))
 }
+
+//Write uint64 as an unsigned varint into Writer.
+func (w *Writer) WriteUvarint(i uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	w.write(buf[:binary.PutUvarint(buf, i)])
+}
+
+//Write int8 at off without moving the position.
+func (w *Writer) WriteInt8At(off int64, i int8) {
+	w.writeAt([]byte{uint8(i)}, off)
+}
+
+//Write uint8 at off without moving the position.
+func (w *Writer) WriteUint8At(off int64, i uint8) {
+	w.writeAt([]byte{i}, off)
+}
+
+//Write int16 with LittleEndian at off without moving the position.
+func (w *Writer) WriteInt16At(off int64, i int16) {
+	w.writeAt(int16ToBytes(i), off)
+}
+
+//Write int16 with BigEndian at off without moving the position.
+func (w *Writer) WriteInt16BigEndianAt(off int64, i int16) {
+	w.writeAt(int16ToBytesBigEndian(i), off)
+}
+
+//Write uint16 with LittleEndian at off without moving the position.
+func (w *Writer) WriteUint16At(off int64, i uint16) {
+	w.writeAt(uint16ToBytes(i), off)
+}
+
+//Write uint16 with BigEndian at off without moving the position.
+func (w *Writer) WriteUint16BigEndianAt(off int64, i uint16) {
+	w.writeAt(uint16ToBytesBigEndian(i), off)
+}
+
+//Write int32 with LittleEndian at off without moving the position.
+func (w *Writer) WriteInt32At(off int64, i int32) {
+	w.writeAt(int32ToBytes(i), off)
+}
+
+//Write int32 with BigEndian at off without moving the position.
+func (w *Writer) WriteInt32BigEndianAt(off int64, i int32) {
+	w.writeAt(int32ToBytesBigEndian(i), off)
+}
+
+//Write uint32 with LittleEndian at off without moving the position.
+func (w *Writer) WriteUint32At(off int64, i uint32) {
+	w.writeAt(uint32ToBytes(i), off)
+}
+
+//Write uint32 with BigEndian at off without moving the position.
+func (w *Writer) WriteUint32BigEndianAt(off int64, i uint32) {
+	w.writeAt(uint32ToBytesBigEndian(i), off)
+}
+
+//Write int64 with LittleEndian at off without moving the position.
+func (w *Writer) WriteInt64At(off int64, i int64) {
+	w.writeAt(int64ToBytes(i), off)
+}
+
+//Write int64 with BigEndian at off without moving the position.
+func (w *Writer) WriteInt64BigEndianAt(off int64, i int64) {
+	w.writeAt(int64ToBytesBigEndian(i), off)
+}
+
+//Write uint64 with LittleEndian at off without moving the position.
+func (w *Writer) WriteUint64At(off int64, i uint64) {
+	w.writeAt(uint64ToBytes(i), off)
+}
+
+//Write uint64 with BigEndian at off without moving the position.
+func (w *Writer) WriteUint64BigEndianAt(off int64, i uint64) {
+	w.writeAt(uint64ToBytesBigEndian(i), off)
+}
+
+//Write float32 with LittleEndian at off without moving the position.
+func (w *Writer) WriteFloat32At(off int64, i float32) {
+	w.writeAt(uint32ToBytes(math.Float32bits(i)), off)
+}
+
+//Write float32 with BigEndian at off without moving the position.
+func (w *Writer) WriteFloat32BigEndianAt(off int64, i float32) {
+	w.writeAt(uint32ToBytesBigEndian(math.Float32bits(i)), off)
+}
+
+//Write float64 with LittleEndian at off without moving the position.
+func (w *Writer) WriteFloat64At(off int64, i float64) {
+	w.writeAt(uint64ToBytes(math.Float64bits(i)), off)
+}
+
+//Write float64 with BigEndian at off without moving the position.
+func (w *Writer) WriteFloat64BigEndianAt(off int64, i float64) {
+	w.writeAt(uint64ToBytesBigEndian(math.Float64bits(i)), off)
+}
@@ -2,6 +2,7 @@ package iox
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -288,3 +289,98 @@ func TestWriter(t *testing.T) {
 		t.Fatalf("unexpected value obtained; got %v want %v", ss, s)
 	}
 }
+
+func TestWriterSeek(t *testing.T) {
+	wr := NewBytesBuffer()
+	//placeholder for the length of the body
+	wr.WriteUint32BigEndian(0)
+	wr.WriteString("body")
+	wr.WriteUint32BigEndianAt(0, uint32(wr.Len()-4))
+	if wr.Pos() != 8 || wr.Len() != 8 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", wr.Pos(), wr.Len(), 8)
+	}
+	want := []byte{0, 0, 0, 4, 'b', 'o', 'd', 'y'}
+	if !bytes.Equal(wr.Bytes(), want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", wr.Bytes(), want)
+	}
+	//overwrite in the middle and append across the end
+	pos, err := wr.Seek(-2, io.SeekEnd)
+	if err != nil || pos != 6 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v", pos, err, 6)
+	}
+	wr.WriteString("DYX")
+	want = []byte{0, 0, 0, 4, 'b', 'o', 'D', 'Y', 'X'}
+	if !bytes.Equal(wr.Bytes(), want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", wr.Bytes(), want)
+	}
+	//seeking past the end leaves a zero gap after the next write
+	wr.Seek(2, io.SeekCurrent)
+	if wr.Len() != 9 {
+		t.Fatalf("unexpected value obtained; got %v want %v", wr.Len(), 9)
+	}
+	wr.WriteUint8(0xff)
+	want = append(want, 0, 0, 0xff)
+	if !bytes.Equal(wr.Bytes(), want) {
+		t.Fatalf("unexpected value obtained; got %v want %v", wr.Bytes(), want)
+	}
+	//WriteAt past the end grows the data but keeps the position
+	n, err := wr.WriteAt([]byte{1, 2}, 13)
+	if err != nil || n != 2 || wr.Pos() != 12 || wr.Len() != 15 {
+		t.Fatalf("unexpected value obtained; got %v %v %v %v", n, err, wr.Pos(), wr.Len())
+	}
+	if _, err = wr.Seek(-1, io.SeekStart); err == nil {
+		t.Fatalf("unexpected value obtained; got %v want an error", err)
+	}
+	//every typed At variant
+	wr.Reset()
+	wr.WriteInt8At(0, -8)
+	wr.WriteUint8At(1, 8)
+	wr.WriteInt16At(2, -16)
+	wr.WriteInt16BigEndianAt(4, -16)
+	wr.WriteUint16At(6, 16)
+	wr.WriteUint16BigEndianAt(8, 16)
+	wr.WriteInt32At(10, -32)
+	wr.WriteInt32BigEndianAt(14, -32)
+	wr.WriteUint32At(18, 32)
+	wr.WriteUint32BigEndianAt(22, 32)
+	wr.WriteInt64At(26, -64)
+	wr.WriteInt64BigEndianAt(34, -64)
+	wr.WriteUint64At(42, 64)
+	wr.WriteUint64BigEndianAt(50, 64)
+	wr.WriteFloat32At(58, 32.5)
+	wr.WriteFloat32BigEndianAt(62, 32.5)
+	wr.WriteFloat64At(66, 64.25)
+	wr.WriteFloat64BigEndianAt(74, 64.25)
+	if wr.Pos() != 0 || wr.Len() != 82 {
+		t.Fatalf("unexpected value obtained; got %v %v want %v %v", wr.Pos(), wr.Len(), 0, 82)
+	}
+	wr2 := NewBytesBuffer()
+	wr2.WriteInt8(-8)
+	wr2.WriteUint8(8)
+	wr2.WriteInt16(-16)
+	wr2.WriteInt16BigEndian(-16)
+	wr2.WriteUint16(16)
+	wr2.WriteUint16BigEndian(16)
+	wr2.WriteInt32(-32)
+	wr2.WriteInt32BigEndian(-32)
+	wr2.WriteUint32(32)
+	wr2.WriteUint32BigEndian(32)
+	wr2.WriteInt64(-64)
+	wr2.WriteInt64BigEndian(-64)
+	wr2.WriteUint64(64)
+	wr2.WriteUint64BigEndian(64)
+	wr2.WriteFloat32(32.5)
+	wr2.WriteFloat32BigEndian(32.5)
+	wr2.WriteFloat64(64.25)
+	wr2.WriteFloat64BigEndian(64.25)
+	if !bytes.Equal(wr.Bytes(), wr2.Bytes()) {
+		t.Fatalf("unexpected value obtained; got %v want %v", wr.Bytes(), wr2.Bytes())
+	}
+}
+
+//reports whether f panics,misusing a Writer does.
+func panics(f func()) (ok bool) {
+	defer func() { ok = recover() != nil }()
+	f()
+	return false
+}
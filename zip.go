@@ -0,0 +1,330 @@
+package iox
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+)
+
+var (
+	ErrInvalidZip = errors.New("the ZIP data is not valid")
+	ErrZipMethod  = errors.New("the ZIP compression method is not supported")
+)
+
+//the compression methods read by ZipEntry.Open.
+const (
+	ZipStored   = 0
+	ZipDeflated = 8
+)
+
+//the signatures of the ZIP records.
+var (
+	zipLocalSig     = []byte("PK\x03\x04")
+	zipCentralSig   = []byte("PK\x01\x02")
+	zipEndSig       = []byte("PK\x05\x06")
+	zip64LocatorSig = []byte("PK\x06\x07")
+	zip64EndSig     = []byte("PK\x06\x06")
+)
+
+//the sizes of the fixed parts of the ZIP records.
+const (
+	zipLocalLen       = 30
+	zipCentralLen     = 46
+	zipEndLen         = 22
+	zip64LocatorLen   = 20
+	zip64EndLen       = 56
+	zipMaxCommentLen  = 0xffff
+	zipExtraZip64     = 0x0001
+	zipExtraTimestamp = 0x5455
+	zipFlagEncrypted  = 0x1
+)
+
+//Zip is the central directory of a ZIP archive.
+type Zip struct {
+	Entries []*ZipEntry
+	Comment string
+	//the size of the data before the archive,such as the program of a self-extracting archive,
+	//the offsets of the archive are relative to it
+	Offset int64
+	Zip64  bool //the end of central directory was found through a ZIP64 locator
+}
+
+//ZipExtra is a field of the extra data of an entry.
+type ZipExtra struct {
+	ID   uint16
+	Data []byte
+}
+
+//ZipEntry is a file or a directory of the central directory of a ZIP archive.
+type ZipEntry struct {
+	Name           string
+	Comment        string
+	CreatorVersion uint16
+	ReaderVersion  uint16
+	Flags          uint16
+	Method         uint16
+	Modified       time.Time //of the extended timestamp if there is one,otherwise the MS-DOS time in UTC
+	CRC32          uint32
+	CompressedSize uint64 //the sizes and the offset are those of the ZIP64 extra field if there is one
+	Size           uint64
+	ExternalAttrs  uint32
+	LocalOffset    int64 //of the local file header in the data,the prepended data included
+	Extra          []ZipExtra
+	r              *ReadSeeker
+}
+
+//Entry returns the entry of the name,nil if there is none.
+func (z *Zip) Entry(name string) *ZipEntry {
+	for _, e := range z.Entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+//IsDir reports whether the entry is a directory,whose name ends with a slash.
+func (e *ZipEntry) IsDir() bool {
+	return len(e.Name) > 0 && e.Name[len(e.Name)-1] == '/'
+}
+
+//ReadZip reads the central directory of the ZIP archive that ends at the end of the data.
+//the end of central directory record is found with LastIndexGen in the last 64KB and 22 bytes,
+//a ZIP64 locator before it is followed to the ZIP64 end of central directory record,
+//and data before the archive is allowed,the offsets of its entries are fixed by the difference
+//between where the central directory is and where it is said to be.the position is left unchanged.
+func (r *ReadSeeker) ReadZip() (*Zip, error) {
+	defer r.traceEnter("ReadZip")()
+	size := r.Size()
+	if size < zipEndLen {
+		return nil, fmt.Errorf("the ZIP data of %v bytes is shorter than the end of central directory: %w", size, ErrInvalidZip)
+	}
+	begin := size - zipEndLen - zipMaxCommentLen
+	if begin < 0 {
+		begin = 0
+	}
+	//a comment may hold the signature,so the last record whose comment ends inside the data is taken
+	var end []byte
+	endPos := size
+	for end == nil {
+		if endPos <= begin {
+			return nil, fmt.Errorf("the end of central directory is not found: %w", ErrInvalidZip)
+		}
+		if endPos = r.LastIndexGen(begin, endPos-1, zipEndSig); endPos < 0 {
+			return nil, fmt.Errorf("the end of central directory is not found: %w", ErrInvalidZip)
+		}
+		if size-endPos < zipEndLen {
+			continue
+		}
+		bt, err := r.bytesAt(endPos, zipEndLen)
+		if err != nil {
+			return nil, err
+		}
+		if endPos+zipEndLen+int64(bytesToUint(bt[20:22], false)) <= size {
+			end = bt
+		}
+	}
+	z := &Zip{}
+	comment, err := r.bytesAt(endPos+zipEndLen, int64(bytesToUint(end[20:22], false)))
+	if err != nil {
+		return nil, err
+	}
+	z.Comment = string(comment)
+	count := bytesToUint(end[10:12], false)
+	dirSize := bytesToUint(end[12:16], false)
+	dirOffset := bytesToUint(end[16:20], false)
+	dirEnd := endPos //where the central directory ends
+	if endPos >= zip64LocatorLen {
+		locator, err := r.bytesAt(endPos-zip64LocatorLen, zip64LocatorLen)
+		if err != nil {
+			return nil, err
+		}
+		if string(locator[:4]) == string(zip64LocatorSig) {
+			z.Zip64 = true
+			if count, dirSize, dirOffset, dirEnd, err = r.readZip64End(endPos-zip64LocatorLen, locator); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if dirSize > uint64(dirEnd) || count > dirSize/zipCentralLen {
+		return nil, fmt.Errorf("the central directory of %v entries and %v bytes does not fit before position %v: %w", count, dirSize, dirEnd, ErrInvalidZip)
+	}
+	dirPos := dirEnd - int64(dirSize)
+	if dirOffset > uint64(dirPos) {
+		return nil, fmt.Errorf("the central directory at position %v is said to be at offset %v: %w", dirPos, dirOffset, ErrInvalidZip)
+	}
+	z.Offset = dirPos - int64(dirOffset)
+	dir, err := r.bytesAt(dirPos, int64(dirSize))
+	if err != nil {
+		return nil, err
+	}
+	z.Entries = make([]*ZipEntry, 0, count)
+	for i := uint64(0); i < count; i++ {
+		e, n, err := readZipEntry(dir, dirPos)
+		if err != nil {
+			return nil, err
+		}
+		e.LocalOffset += z.Offset
+		e.r = r
+		z.Entries = append(z.Entries, e)
+		dir, dirPos = dir[n:], dirPos+int64(n)
+	}
+	r.traceValue(fmt.Sprintf("%v entries", len(z.Entries)))
+	return z, nil
+}
+
+//read the ZIP64 end of central directory record pointed to by the locator at pos,
+//returns the entry count,the size of the central directory,its offset and the position of the record.
+func (r *ReadSeeker) readZip64End(pos int64, locator []byte) (uint64, uint64, uint64, int64, error) {
+	offset := bytesToUint(locator[8:16], false)
+	//the record is right before the locator,its offset gives the size of the prepended data
+	recordPos := pos - zip64EndLen
+	if recordPos < 0 || offset > uint64(recordPos) {
+		return 0, 0, 0, 0, fmt.Errorf("the ZIP64 end of central directory at offset %v does not fit before position %v: %w", offset, pos, ErrInvalidZip)
+	}
+	bt, err := r.bytesAt(recordPos, zip64EndLen)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if string(bt[:4]) != string(zip64EndSig) {
+		//a record with an extensible data sector is found by its offset,which assumes no prepended data
+		recordPos = int64(offset)
+		if bt, err = r.bytesAt(recordPos, zip64EndLen); err != nil {
+			return 0, 0, 0, 0, err
+		}
+		if string(bt[:4]) != string(zip64EndSig) {
+			return 0, 0, 0, 0, fmt.Errorf("the ZIP64 end of central directory is not at offset %v: %w", offset, ErrInvalidZip)
+		}
+	}
+	return bytesToUint(bt[32:40], false), bytesToUint(bt[40:48], false), bytesToUint(bt[48:56], false), recordPos, nil
+}
+
+//read the central directory entry at the start of dir,pos is its position in the data,
+//returns the entry and its size.
+func readZipEntry(dir []byte, pos int64) (*ZipEntry, int, error) {
+	if len(dir) < zipCentralLen || string(dir[:4]) != string(zipCentralSig) {
+		return nil, 0, fmt.Errorf("there is no central directory entry at position %v: %w", pos, ErrInvalidZip)
+	}
+	u := func(b, e int) uint64 { return bytesToUint(dir[b:e], false) }
+	nameLen, extraLen, commentLen := int(u(28, 30)), int(u(30, 32)), int(u(32, 34))
+	n := zipCentralLen + nameLen + extraLen + commentLen
+	if n > len(dir) {
+		return nil, 0, fmt.Errorf("the central directory entry at position %v has %v bytes,but only %v bytes left: %w", pos, n, len(dir), ErrInvalidZip)
+	}
+	e := &ZipEntry{
+		CreatorVersion: uint16(u(4, 6)),
+		ReaderVersion:  uint16(u(6, 8)),
+		Flags:          uint16(u(8, 10)),
+		Method:         uint16(u(10, 12)),
+		Modified:       msdosTime(uint16(u(14, 16)), uint16(u(12, 14))),
+		CRC32:          uint32(u(16, 20)),
+		CompressedSize: u(20, 24),
+		Size:           u(24, 28),
+		ExternalAttrs:  uint32(u(38, 42)),
+		LocalOffset:    int64(u(42, 46)),
+		Name:           string(dir[zipCentralLen : zipCentralLen+nameLen]),
+		Comment:        string(dir[zipCentralLen+nameLen+extraLen : n]),
+	}
+	extra := dir[zipCentralLen+nameLen : zipCentralLen+nameLen+extraLen]
+	for len(extra) >= 4 {
+		id, size := uint16(bytesToUint(extra[:2], false)), int(bytesToUint(extra[2:4], false))
+		if size > len(extra)-4 {
+			return nil, 0, fmt.Errorf("the extra field 0x%04x of %q has %v bytes,but only %v bytes left: %w", id, e.Name, size, len(extra)-4, ErrInvalidZip)
+		}
+		data := append([]byte{}, extra[4:4+size]...)
+		e.Extra = append(e.Extra, ZipExtra{id, data})
+		extra = extra[4+size:]
+		switch id {
+		case zipExtraZip64:
+			//only the values that are all ones in the entry are there,in this order
+			for _, v := range []*uint64{&e.Size, &e.CompressedSize} {
+				if *v == 0xffffffff && len(data) >= 8 {
+					*v, data = bytesToUint(data[:8], false), data[8:]
+				}
+			}
+			if e.LocalOffset == 0xffffffff && len(data) >= 8 {
+				e.LocalOffset = int64(bytesToUint(data[:8], false))
+			}
+		case zipExtraTimestamp:
+			if len(data) >= 5 && data[0]&1 != 0 {
+				e.Modified = time.Unix(int64(int32(bytesToUint(data[1:5], false))), 0).UTC()
+			}
+		}
+	}
+	if e.LocalOffset < 0 {
+		return nil, 0, fmt.Errorf("the local header offset of %q is negative: %w", e.Name, ErrInvalidZip)
+	}
+	return e, n, nil
+}
+
+//convert an MS-DOS date and time,which have no time zone.
+func msdosTime(date, t uint16) time.Time {
+	return time.Date(int(date>>9)+1980, time.Month(date>>5&0xf), int(date&0x1f),
+		int(t>>11), int(t>>5&0x3f), int(t&0x1f)*2, 0, time.UTC)
+}
+
+//Raw returns the data of the entry as it is stored,which follows its local file header.
+func (e *ZipEntry) Raw() (*ReadSeeker, error) {
+	if e.r.Size()-e.LocalOffset < zipLocalLen {
+		return nil, fmt.Errorf("the local header of %q at position %v is beyond the data: %w", e.Name, e.LocalOffset, io.ErrUnexpectedEOF)
+	}
+	bt, err := e.r.bytesAt(e.LocalOffset, zipLocalLen)
+	if err != nil {
+		return nil, err
+	}
+	if string(bt[:4]) != string(zipLocalSig) {
+		return nil, fmt.Errorf("there is no local header of %q at position %v: %w", e.Name, e.LocalOffset, ErrInvalidZip)
+	}
+	//the lengths of the local header may differ from those of the central directory
+	begin := e.LocalOffset + zipLocalLen + int64(bytesToUint(bt[26:28], false)) + int64(bytesToUint(bt[28:30], false))
+	if left := e.r.Size() - begin; e.CompressedSize > uint64(left) || left < 0 {
+		return nil, fmt.Errorf("the data of %q has %v bytes,but only %v bytes left: %w", e.Name, e.CompressedSize, left, io.ErrUnexpectedEOF)
+	}
+	return e.r.Section(begin, begin+int64(e.CompressedSize)-1)
+}
+
+//Open returns the uncompressed data of a stored or deflated entry,
+//the data is inflated as it is read,see NewReadSeekerFromCompressed,
+//so corrupt deflated data makes the reads reaching it return an error.
+func (e *ZipEntry) Open() (*ReadSeeker, error) {
+	if e.Flags&zipFlagEncrypted != 0 {
+		return nil, fmt.Errorf("%q is encrypted: %w", e.Name, ErrZipMethod)
+	}
+	if e.Method != ZipStored && e.Method != ZipDeflated {
+		return nil, fmt.Errorf("%q has the method %v: %w", e.Name, e.Method, ErrZipMethod)
+	}
+	raw, err := e.Raw()
+	if err != nil || e.Method == ZipStored {
+		return raw, err
+	}
+	return NewReadSeekerFromDeflate(raw.readSeeker)
+}
+
+//Verify reads the uncompressed data of the entry and compares its size and crc32 with the central directory,
+//a mismatch returns an error wrapping ErrChecksumMismatch.no more than a byte past the recorded size is inflated,
+//so an entry whose deflated data expands far beyond its size fails without being inflated entirely.
+func (e *ZipEntry) Verify() error {
+	data, err := e.Open()
+	if err != nil {
+		return err
+	}
+	limit := int64(math.MaxInt64)
+	if e.Size < math.MaxInt64 {
+		limit = int64(e.Size) + 1
+	}
+	h := crc32.NewIEEE()
+	n, err := io.CopyN(h, data.readSeeker, limit)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("the data of %q can't be read: %w", e.Name, err)
+	}
+	if uint64(n) > e.Size {
+		return fmt.Errorf("%q has more than the %v bytes recorded: %w", e.Name, e.Size, ErrChecksumMismatch)
+	}
+	if uint64(n) != e.Size || h.Sum32() != e.CRC32 {
+		return fmt.Errorf("%q has %v bytes with the crc32 %08x,but %v bytes with %08x are recorded: %w", e.Name, n, h.Sum32(), e.Size, e.CRC32, ErrChecksumMismatch)
+	}
+	return nil
+}
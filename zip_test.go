@@ -0,0 +1,237 @@
+package iox
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+//a ZIP archive of a stored file,a deflated file and a directory made by archive/zip.
+func testZipData(t *testing.T, comment string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	modified := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)
+	for _, f := range []struct {
+		name   string
+		method uint16
+		data   string
+	}{
+		{"stored.txt", zip.Store, "hello,world"},
+		{"dir/", zip.Store, ""},
+		{"dir/deflated.txt", zip.Deflate, strings.Repeat("iox ", 1000)},
+	} {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method, Modified: modified, Comment: "c:" + f.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, f.data)
+	}
+	if err := zw.SetComment(comment); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checkZipEntries(t *testing.T, z *Zip) {
+	if len(z.Entries) != 3 || z.Entries[1].Name != "dir/" || !z.Entries[1].IsDir() || z.Entries[0].IsDir() {
+		t.Fatalf("unexpected value obtained; got %v want %v", len(z.Entries), 3)
+	}
+	stored, deflated := z.Entry("stored.txt"), z.Entry("dir/deflated.txt")
+	if stored.Method != ZipStored || deflated.Method != ZipDeflated || deflated.Size != 4000 || deflated.CompressedSize >= 4000 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", deflated, "a deflated entry of 4000 bytes")
+	}
+	if want := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC); !stored.Modified.Equal(want) || stored.Comment != "c:stored.txt" {
+		t.Fatalf("unexpected value obtained; got %v want %v", stored.Modified, want)
+	}
+	//archive/zip adds the extended timestamp
+	if len(stored.Extra) == 0 || stored.Extra[len(stored.Extra)-1].ID != zipExtraTimestamp {
+		t.Fatalf("unexpected value obtained; got %v want %v", stored.Extra, "an extended timestamp")
+	}
+	for _, c := range []struct {
+		e    *ZipEntry
+		want string
+	}{
+		{stored, "hello,world"},
+		{deflated, strings.Repeat("iox ", 1000)},
+	} {
+		data, err := c.e.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(data.readSeeker)
+		if err != nil || string(got) != c.want {
+			t.Fatalf("unexpected value obtained; got %q want %q", got, c.want)
+		}
+		if err = c.e.Verify(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if missing := z.Entry("missing"); missing != nil {
+		t.Fatalf("unexpected value obtained; got %v want %v", missing, nil)
+	}
+}
+
+func TestZip(t *testing.T) {
+	data := testZipData(t, "archive comment")
+	r := NewReadSeekerFromBytes(data)
+	z, err := r.ReadZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.Comment != "archive comment" || z.Offset != 0 || z.Zip64 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", z, "archive comment")
+	}
+	checkZipEntries(t, z)
+	//a self-extracting archive has a program before it
+	sfx := append([]byte("MZ"+strings.Repeat("\x00", 998)), data...)
+	if z, err = NewReadSeekerFromBytes(sfx).ReadZip(); err != nil {
+		t.Fatal(err)
+	}
+	if z.Offset != 1000 || z.Entries[0].LocalOffset != 1000 {
+		t.Fatalf("unexpected value obtained; got %v want %v", z.Offset, 1000)
+	}
+	checkZipEntries(t, z)
+	//the signature inside the comment is not taken for the end of central directory
+	trap := "x" + string(zipEndSig) + strings.Repeat("a", 16) + "\xff\xff"
+	if z, err = NewReadSeekerFromBytes(testZipData(t, trap)).ReadZip(); err != nil {
+		t.Fatal(err)
+	}
+	if z.Comment != trap {
+		t.Fatalf("unexpected value obtained; got %q want %q", z.Comment, trap)
+	}
+}
+
+//replace the end of central directory by a ZIP64 one with a locator.
+func toZip64(data []byte) []byte {
+	end := bytes.LastIndex(data, zipEndSig)
+	count := binary.LittleEndian.Uint16(data[end+10:])
+	dirSize := binary.LittleEndian.Uint32(data[end+12:])
+	dirOffset := binary.LittleEndian.Uint32(data[end+16:])
+	w := NewBytesBuffer()
+	w.WriteBytes(data[:end])
+	w.WriteBytes(zip64EndSig)
+	w.WriteUint64(zip64EndLen - 12)
+	w.WriteUint16(45)
+	w.WriteUint16(45)
+	w.WriteUint32(0)
+	w.WriteUint32(0)
+	w.WriteUint64(uint64(count))
+	w.WriteUint64(uint64(count))
+	w.WriteUint64(uint64(dirSize))
+	w.WriteUint64(uint64(dirOffset))
+	w.WriteBytes(zip64LocatorSig)
+	w.WriteUint32(0)
+	w.WriteUint64(uint64(end))
+	w.WriteUint32(1)
+	w.WriteBytes(zipEndSig)
+	w.WriteZeros(4)
+	w.WriteUint16(0xffff)
+	w.WriteUint16(0xffff)
+	w.WriteUint32(0xffffffff)
+	w.WriteUint32(0xffffffff)
+	w.WriteUint16(0)
+	return w.Bytes()
+}
+
+func TestZip64(t *testing.T) {
+	data := toZip64(testZipData(t, ""))
+	z, err := NewReadSeekerFromBytes(data).ReadZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !z.Zip64 || z.Offset != 0 {
+		t.Fatalf("unexpected value obtained; got %+v want %v", z, "ZIP64")
+	}
+	checkZipEntries(t, z)
+	sfx := append([]byte(strings.Repeat("\x00", 100)), data...)
+	if z, err = NewReadSeekerFromBytes(sfx).ReadZip(); err != nil {
+		t.Fatal(err)
+	}
+	if z.Offset != 100 {
+		t.Fatalf("unexpected value obtained; got %v want %v", z.Offset, 100)
+	}
+	checkZipEntries(t, z)
+}
+
+func TestZipErrors(t *testing.T) {
+	data := testZipData(t, "")
+	truncated := data[len(data)-zipEndLen-10:]
+	for _, c := range []struct {
+		data []byte
+		want error
+	}{
+		{[]byte("PK"), ErrInvalidZip},
+		{bytes.Repeat([]byte{0}, 100), ErrInvalidZip},
+		{truncated, ErrInvalidZip},
+	} {
+		if _, err := NewReadSeekerFromBytes(c.data).ReadZip(); !errors.Is(err, c.want) {
+			t.Fatalf("unexpected value obtained; got %v want %v", err, c.want)
+		}
+	}
+	z, err := NewReadSeekerFromBytes(data).ReadZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := z.Entry("stored.txt")
+	e.CRC32++
+	if err = e.Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrChecksumMismatch)
+	}
+	//a deflated entry bigger than its recorded size stops a byte past the size
+	big := z.Entry("dir/deflated.txt")
+	big.Size = 10
+	if err = big.Verify(); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "more than the 10 bytes") {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrChecksumMismatch)
+	}
+	e.Method = 99
+	if _, err = e.Open(); !errors.Is(err, ErrZipMethod) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrZipMethod)
+	}
+	e.Method, e.LocalOffset = ZipStored, 1
+	if _, err = e.Open(); !errors.Is(err, ErrInvalidZip) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, ErrInvalidZip)
+	}
+}
+
+func TestZipCorruptEntry(t *testing.T) {
+	data := testZipData(t, "")
+	z, err := NewReadSeekerFromBytes(data).ReadZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := z.Entry("dir/deflated.txt")
+	//a truncated entry ends inside its deflate data
+	e.CompressedSize = 10
+	if err = e.Verify(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	//the first block of the entry gets the reserved block type
+	local := data[e.LocalOffset:]
+	begin := zipLocalLen + int(binary.LittleEndian.Uint16(local[26:])) + int(binary.LittleEndian.Uint16(local[28:]))
+	local[begin] = 0x07
+	if z, err = NewReadSeekerFromBytes(data).ReadZip(); err != nil {
+		t.Fatal(err)
+	}
+	e = z.Entry("dir/deflated.txt")
+	if err = e.Verify(); !errors.Is(err, errCorruptDeflate) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, errCorruptDeflate)
+	}
+	rd, err := e.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rd.ReadBytesUnRead(); !errors.Is(err, errCorruptDeflate) {
+		t.Fatalf("unexpected value obtained; got %v want %v", err, errCorruptDeflate)
+	}
+	if size := rd.Size(); size != 0 {
+		t.Fatalf("unexpected value obtained; got %v want %v", size, 0)
+	}
+}